@@ -6,6 +6,7 @@ require (
 	github.com/gabriel-vasile/mimetype v1.4.9
 	golang.org/x/image v0.26.0
 	golang.org/x/sys v0.32.0
+	golang.org/x/text v0.24.0
 )
 
 require (
@@ -18,7 +19,6 @@ require (
 	github.com/lib/pq v1.10.9 // indirect
 	go.uber.org/atomic v1.7.0 // indirect
 	golang.org/x/crypto v0.37.0 // indirect
-	golang.org/x/text v0.24.0 // indirect
 )
 
 require (
@@ -26,6 +26,6 @@ require (
 	github.com/golang-migrate/migrate/v4 v4.18.3
 	github.com/jackc/pgx/v5 v5.7.5
 	github.com/psanford/memfs v0.0.0-20241019191636-4ef911798f9b
-	golang.org/x/net v0.39.0 // indirect
+	golang.org/x/net v0.39.0
 	golang.org/x/sync v0.14.0
 )
@@ -42,6 +42,11 @@ func NewPaddle(config PaddleConfig) *Paddle {
 	}
 }
 
+// Configuration that changes the OCR result. Used in the cache keys.
+func (p *Paddle) CacheFingerprint() string {
+	return fmt.Sprintf("url=%s languages=%q", p.config.BaseURL, p.config.Languages)
+}
+
 func (p *Paddle) OCR(ctx context.Context, image io.Reader) (string, error) {
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -1,6 +1,7 @@
 package ocr
 
 import (
+	"fmt"
 	"path"
 	"runtime"
 )
@@ -41,6 +42,11 @@ type TesseractConfig struct {
 	SupportedImageFormats []string `json:"supportedImageFormats"`
 }
 
+// Describes options that change recognized text. Location of the models and image formats are not included.
+func (c TesseractConfig) cacheFingerprint() string {
+	return fmt.Sprintf("languages=%q model=%s custom=%t variables=%q", c.Languages, c.ModelType, c.LoadCustomModels, c.Variables)
+}
+
 func DefaultTesseractConfig() TesseractConfig {
 	nullFile := "/dev/null"
 	if runtime.GOOS == "windows" {
@@ -13,10 +13,18 @@ var errTesseractProviderNotCompiled = errors.New("OCR is not possible because bi
 const FeatureTesseractEnabled = false
 
 type Tesseract struct {
+	config TesseractConfig
 }
 
 func NewTesseract(config TesseractConfig) *Tesseract {
-	return &Tesseract{}
+	return &Tesseract{
+		config: config,
+	}
+}
+
+// Configuration that changes the OCR result. Used in the cache keys.
+func (p *Tesseract) CacheFingerprint() string {
+	return p.config.cacheFingerprint()
 }
 
 func (p *Tesseract) OCR(ctx context.Context, image io.Reader) (string, error) {
@@ -36,6 +36,11 @@ func NewTesseract(config TesseractConfig) *Tesseract {
 	}
 }
 
+// Configuration that changes the OCR result. Used in the cache keys.
+func (p *Tesseract) CacheFingerprint() string {
+	return p.config.cacheFingerprint()
+}
+
 func (p *Tesseract) filterVisible(s string) string {
 	var b strings.Builder
 	b.Grow(len(s)) // preallocate memory
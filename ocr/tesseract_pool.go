@@ -29,6 +29,11 @@ func NewTesseractPool(size uint32, workerConfig TesseractConfig) *TesseractPool
 	}
 }
 
+// Configuration of the workers that changes the OCR result. Used in the cache keys.
+func (p *TesseractPool) CacheFingerprint() string {
+	return p.workerConfig.cacheFingerprint()
+}
+
 func (p *TesseractPool) Init(ctx context.Context) error {
 	if err := p.workLock.Acquire(ctx, int64(p.size)); err != nil {
 		return errors.Join(errors.New("failed to accuire exclusive lock on entire pool"), err)
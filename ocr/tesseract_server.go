@@ -42,6 +42,11 @@ func NewTesseractServer(config TesseractServerConfig) *TesseractServer {
 	}
 }
 
+// Configuration that changes the OCR result. Used in the cache keys.
+func (p *TesseractServer) CacheFingerprint() string {
+	return fmt.Sprintf("url=%s languages=%q", p.config.BaseURL, p.config.Languages)
+}
+
 func (p *TesseractServer) OCR(ctx context.Context, image io.Reader) (string, error) {
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
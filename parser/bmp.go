@@ -23,11 +23,16 @@ func NewBMPParser(ocrProvider ocr.Provider) *BMPParser {
 	}
 }
 
+// Configuration that changes the result. Used in the cache keys.
+func (p *BMPParser) CacheFingerprint() string {
+	return "ocr=" + cacheFingerprint(p.ocrProvider)
+}
+
 func (p *BMPParser) SupportedMimeTypes() []string {
 	return []string{"image/bmp"}
 }
 
-func (p *BMPParser) prepareData(file io.Reader) (io.Reader, error) {
+func (p *BMPParser) prepareData(file io.Reader) (io.Reader, *ImageMetadata, error) {
 	var imageData io.Reader
 
 	if p.ocrProvider.IsMimeTypeSupported("image/bmp") {
@@ -35,21 +40,21 @@ func (p *BMPParser) prepareData(file io.Reader) (io.Reader, error) {
 	} else {
 		img, err := bmp.Decode(file)
 		if err != nil {
-			return nil, errors.Join(ErrBadFile, errors.New("failed to decode bmp image for transcoding"), err)
+			return nil, nil, errors.Join(ErrBadFile, errors.New("failed to decode bmp image for transcoding"), err)
 		}
 
 		var outBuf bytes.Buffer
 		if err := png.Encode(&outBuf, img); err != nil {
-			return nil, errors.Join(errors.New("failed to transcode image to PNG"), err)
+			return nil, nil, errors.Join(errors.New("failed to transcode image to PNG"), err)
 		}
 		imageData = &outBuf
 	}
 
-	return imageData, nil
+	return imageData, nil, nil
 }
 
 func (p *BMPParser) Parse(ctx context.Context, file io.Reader, path string) Result {
-	imageData, err := p.prepareData(file)
+	imageData, _, err := p.prepareData(file)
 	if err != nil {
 		return &ImageParserResult{Err: errors.Join(errors.New("failed to prepare image data"), err), FullPath: path}
 	}
@@ -0,0 +1,622 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// Version of the stored results. Changing it invalidates all the cached results.
+const cacheVersion = "1"
+
+// Inputs up to this size are held in memory while hashing
+const cacheDefaultInMemoryLimit = 32 * 1024 * 1024
+
+// Storage of the cached results. Implementations must be thread safe.
+type CacheStore interface {
+	// Returns stored data. Second value is false if key is missing.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+// Implemented by parsers and OCR providers to describe their configuration in the cache keys.
+// Fingerprint includes only options that change the result, not the runtime state. Parsers do not include their inner parsers, they are described by [CompositeParser].
+type CacheFingerprinter interface {
+	CacheFingerprint() string
+}
+
+type cacheConfig struct {
+	fingerprint   string
+	inMemoryLimit int64
+}
+
+// Configures [CachingParser]
+type CacheOption func(c *cacheConfig)
+
+// Fingerprint of the configuration. Results cached with other fingerprint are not reused.
+// Overrides fingerprint reported by the inner parser with [CacheFingerprinter], so it has to describe all the options that change the result. Use [ConfigFingerprint] to build it.
+func WithCacheFingerprint(fingerprint string) CacheOption {
+	return func(c *cacheConfig) {
+		c.fingerprint = fingerprint
+	}
+}
+
+// Inputs larger than this are written to the temporary file while hashing. Default is 32 MB.
+func WithCacheInMemoryLimit(bytes int64) CacheOption {
+	return func(c *cacheConfig) {
+		c.inMemoryLimit = bytes
+	}
+}
+
+// Hash of the configuration values encoded as JSON, for example `ocr.TesseractConfig` and PDF DPI
+func ConfigFingerprint(values ...any) (string, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", errors.Join(errors.New("failed to encode configuration"), err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Caches results of the inner parser by content hash, mime type, configuration of the inner parser and fingerprint.
+// Input is read completely to compute the hash before it is parsed. Results with errors in the file or its subfiles and results of the cancelled parsing are not cached.
+type CachingParser struct {
+	inner  Parser
+	store  CacheStore
+	config cacheConfig
+}
+
+func NewCachingParser(inner Parser, store CacheStore, options ...CacheOption) *CachingParser {
+	parser := &CachingParser{
+		inner: inner,
+		store: store,
+		config: cacheConfig{
+			inMemoryLimit: cacheDefaultInMemoryLimit,
+		},
+	}
+
+	for _, option := range options {
+		option(&parser.config)
+	}
+
+	return parser
+}
+
+func (p *CachingParser) SupportedMimeTypes() []string {
+	return p.inner.SupportedMimeTypes()
+}
+
+func (p *CachingParser) Parse(ctx context.Context, file io.Reader, path string) Result {
+	source, err := p.readSource(file)
+	if err != nil {
+		return &CachedResult{FullPath: path, err: err}
+	}
+	defer source.close()
+
+	return p.parse(ctx, source, path)
+}
+
+// Returns cached result or parses the source and stores its result
+func (p *CachingParser) parse(ctx context.Context, source *cacheSource, path string) Result {
+	key, err := p.key(source, path)
+	if err != nil {
+		return &CachedResult{FullPath: path, err: err}
+	}
+
+	if data, ok, err := p.store.Get(ctx, key); err == nil && ok {
+		var cached CachedResult
+		if err := json.Unmarshal(data, &cached); err == nil {
+			return cached.replay(cached.FullPath, path)
+		}
+	}
+
+	reader, err := source.reader()
+	if err != nil {
+		return &CachedResult{FullPath: path, err: err}
+	}
+	result := p.inner.Parse(ctx, reader, path)
+	if resultHasError(result) || ctx.Err() != nil {
+		return result
+	}
+
+	// Result is valid even if it was not stored, so store errors are ignored
+	if data, err := json.Marshal(cacheSnapshot(result)); err == nil {
+		p.store.Put(ctx, key, data)
+	}
+	return result
+}
+
+// Checks result and all its subfiles for errors
+func resultHasError(result Result) bool {
+	if result.Error() != nil {
+		return true
+	}
+	for _, subfile := range result.Subfiles() {
+		if resultHasError(subfile) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stream of the cached result. On cache miss results of the inner stream are passed as they arrive and stored when the stream is completed.
+func (p *CachingParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
+	return &CachedStreamResultIterator{
+		parser: p,
+		ctx:    ctx,
+		file:   file,
+		path:   path,
+	}
+}
+
+// Key of the source: content hash, mime type, fingerprint of the configuration and cache version
+func (p *CachingParser) key(source *cacheSource, path string) (string, error) {
+	mimeType, err := p.mimeType(source, path)
+	if err != nil {
+		return "", err
+	}
+
+	keyHash := sha256.New()
+	fingerprint := p.config.fingerprint
+	if fingerprint == "" {
+		fingerprint = cacheFingerprint(p.inner)
+	}
+	for _, part := range []string{cacheVersion, hex.EncodeToString(source.hash), mimeType, fingerprint} {
+		keyHash.Write([]byte(part))
+		keyHash.Write([]byte{0})
+	}
+	return hex.EncodeToString(keyHash.Sum(nil)), nil
+}
+
+// Fingerprint of the parser or OCR provider that implements [CacheFingerprinter]. Other values are described by their type.
+func cacheFingerprint(value any) string {
+	if value == nil {
+		return "nil"
+	}
+	if fingerprinter, ok := value.(CacheFingerprinter); ok {
+		return fmt.Sprintf("%T(%s)", value, fingerprinter.CacheFingerprint())
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// Mime type detected the same way the inner parser will detect it
+func (p *CachingParser) mimeType(source *cacheSource, path string) (string, error) {
+	if composite, ok := p.inner.(*CompositeParser); ok {
+		reader, err := source.reader()
+		if err != nil {
+			return "", err
+		}
+		mimeType, _, _, err := composite.detect(reader, path)
+		var mimeErr *ErrMimeTypeNotSupported
+		if err != nil && !errors.As(err, &mimeErr) {
+			return "", err
+		}
+		return mimeType, nil
+	}
+
+	head := source.head
+	if len(head) > 3072 {
+		head = head[:3072]
+	}
+	return magicMimeType(mimetype.Detect(head), head), nil
+}
+
+// Input that can be read multiple times
+type cacheSource struct {
+	hash []byte
+	// Whole input or its beginning if input is in the temporary file
+	head    []byte
+	tmpFile *os.File
+}
+
+// Reads input to the memory or to the temporary file and computes its hash
+func (p *CachingParser) readSource(file io.Reader) (*cacheSource, error) {
+	contentHash := sha256.New()
+	head, err := io.ReadAll(io.TeeReader(io.LimitReader(file, p.config.inMemoryLimit+1), contentHash))
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to read file"), err)
+	}
+	if int64(len(head)) <= p.config.inMemoryLimit {
+		return &cacheSource{hash: contentHash.Sum(nil), head: head}, nil
+	}
+
+	tmpFile, err := os.CreateTemp("", "file2llm-cache-*")
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to create temporary file"), err)
+	}
+	source := &cacheSource{head: head, tmpFile: tmpFile}
+	if err := source.copy(file, contentHash); err != nil {
+		source.close()
+		return nil, err
+	}
+	source.hash = contentHash.Sum(nil)
+	return source, nil
+}
+
+func (s *cacheSource) copy(file io.Reader, contentHash hash.Hash) error {
+	if _, err := s.tmpFile.Write(s.head); err != nil {
+		return errors.Join(errors.New("failed to write temporary file"), err)
+	}
+	if _, err := io.Copy(io.MultiWriter(s.tmpFile, contentHash), file); err != nil {
+		return errors.Join(errors.New("failed to copy file to temporary file"), err)
+	}
+	return nil
+}
+
+// Reader from the beginning of the input
+func (s *cacheSource) reader() (io.Reader, error) {
+	if s.tmpFile == nil {
+		return bytes.NewReader(s.head), nil
+	}
+	if _, err := s.tmpFile.Seek(0, io.SeekStart); err != nil {
+		return nil, errors.Join(errors.New("failed to read temporary file"), err)
+	}
+	return s.tmpFile, nil
+}
+
+func (s *cacheSource) close() {
+	if s.tmpFile != nil {
+		s.tmpFile.Close()
+		os.Remove(s.tmpFile.Name())
+	}
+}
+
+// Stored result of the file. It is replayed with [CompositeParserResult] around it if file mime type is known.
+type CachedResult struct {
+	FullPath string `json:"path"`
+	MimeType string `json:"mimeType,omitempty"`
+	// Output of `String()`
+	Text string `json:"text"`
+	// Text of the file without subfiles sent in the stream
+	StreamText string          `json:"streamText,omitempty"`
+	ErrMessage string          `json:"error,omitempty"`
+	Metadata   []MetadataField `json:"metadata,omitempty"`
+	Doc        *Document       `json:"document,omitempty"`
+	Files      []*CachedResult `json:"subfiles,omitempty"`
+
+	err error
+}
+
+// Copies result with its subfiles
+func cacheSnapshot(result Result) *CachedResult {
+	inner, mimeType := unwrapResult(result)
+	cached := &CachedResult{
+		FullPath:   result.Path(),
+		MimeType:   mimeType,
+		Text:       result.String(),
+		StreamText: resultText(inner),
+		Metadata:   resultMetadata(inner),
+		Doc:        resultDocument(inner),
+	}
+	if len(inner.Subfiles()) == 0 {
+		cached.StreamText = cached.Text
+	}
+	if err := result.Error(); err != nil {
+		cached.ErrMessage = err.Error()
+	}
+	for _, subfile := range inner.Subfiles() {
+		cached.Files = append(cached.Files, cacheSnapshot(subfile))
+	}
+	return cached
+}
+
+// Result for the file at `path`. Paths of the subfiles and images are moved from `storedPath` to `path`.
+// Paths in the text are kept as they were stored.
+func (r *CachedResult) replay(storedPath string, path string) Result {
+	replayed := r.withPath(storedPath, path)
+	if replayed.MimeType == "" {
+		return replayed
+	}
+	return &CompositeParserResult{Inner: replayed, MimeType: replayed.MimeType}
+}
+
+func (r *CachedResult) withPath(storedPath string, path string) *CachedResult {
+	replayed := *r
+	replayed.FullPath = cachedPath(r.FullPath, storedPath, path)
+	if r.ErrMessage != "" {
+		replayed.err = errors.New(r.ErrMessage)
+	}
+	if r.Doc != nil {
+		replayed.Doc = &Document{Blocks: cachedBlocks(r.Doc.Blocks, storedPath, path)}
+	}
+	replayed.Files = make([]*CachedResult, 0, len(r.Files))
+	for _, subfile := range r.Files {
+		replayed.Files = append(replayed.Files, subfile.withPath(storedPath, path))
+	}
+	return &replayed
+}
+
+func cachedPath(filePath string, storedPath string, path string) string {
+	if filePath == storedPath {
+		return path
+	}
+	if strings.HasPrefix(filePath, storedPath+"/") {
+		return path + filePath[len(storedPath):]
+	}
+	return filePath
+}
+
+func cachedBlocks(blocks []Block, storedPath string, path string) []Block {
+	if len(blocks) == 0 {
+		return nil
+	}
+	replayed := make([]Block, len(blocks))
+	for i, block := range blocks {
+		block.Path = cachedPath(block.Path, storedPath, path)
+		block.Children = cachedBlocks(block.Children, storedPath, path)
+		replayed[i] = block
+	}
+	return replayed
+}
+
+func (r *CachedResult) Path() string {
+	return r.FullPath
+}
+
+func (r *CachedResult) String() string {
+	return r.Text
+}
+
+func (r *CachedResult) Error() error {
+	return r.err
+}
+
+func (r *CachedResult) MetadataFields() []MetadataField {
+	return r.Metadata
+}
+
+func (r *CachedResult) Document() *Document {
+	return r.Doc
+}
+
+func (r *CachedResult) Subfiles() []Result {
+	subfiles := make([]Result, 0, len(r.Files))
+	for _, subfile := range r.Files {
+		if subfile.MimeType != "" {
+			subfiles = append(subfiles, &CompositeParserResult{Inner: subfile, MimeType: subfile.MimeType})
+		} else {
+			subfiles = append(subfiles, subfile)
+		}
+	}
+	return subfiles
+}
+
+// Stream results of the file: new file, updates with the results of subfiles and completed file with its own text
+func (r *CachedResult) streamResults() []StreamResult {
+	results := []StreamResult{&CachedStreamResult{FullPath: r.FullPath, CurrentStage: ProgressNew}}
+	for i, subfile := range r.Files {
+		progress := uint8(i * 100 / len(r.Files))
+		for _, subResult := range subfile.streamResults() {
+			results = append(results, &CachedStreamResult{
+				FullPath:        r.FullPath,
+				CurrentStage:    ProgressUpdate,
+				CurrentProgress: progress,
+				CurrentSubfile:  subResult,
+			})
+		}
+	}
+	return append(results, &CachedStreamResult{
+		FullPath:        r.FullPath,
+		CurrentStage:    ProgressCompleted,
+		CurrentProgress: 100,
+		Text:            r.StreamText,
+		Err:             r.err,
+	})
+}
+
+type CachedStreamResultIterator struct {
+	parser *CachingParser
+	ctx    context.Context
+	file   io.Reader
+	path   string
+
+	started bool
+	// Results replayed from the cache
+	results []StreamResult
+	current StreamResult
+
+	// Stream of the inner parser on cache miss. Its results are recorded and stored when the stream is completed.
+	source   *cacheSource
+	key      string
+	inner    StreamResultIterator
+	recorded []*cachedStreamStep
+	failed   bool
+}
+
+func (i *CachedStreamResultIterator) Next(ctx context.Context) bool {
+	if !i.started {
+		i.started = true
+		i.start()
+	}
+	if i.inner != nil {
+		return i.nextInner(ctx)
+	}
+	if len(i.results) == 0 || ctx.Err() != nil {
+		i.current = nil
+		return false
+	}
+	i.current, i.results = i.results[0], i.results[1:]
+	return true
+}
+
+// Replays cached results or starts the stream of the inner parser
+func (i *CachedStreamResultIterator) start() {
+	source, err := i.parser.readSource(i.file)
+	if err != nil {
+		i.results = (&CachedResult{FullPath: i.path, err: err}).streamResults()
+		return
+	}
+
+	key, err := i.parser.key(source, i.path)
+	if err == nil {
+		if results, ok := i.parser.cachedStream(i.ctx, key, i.path); ok {
+			source.close()
+			i.results = results
+			return
+		}
+	}
+	var reader io.Reader
+	if err == nil {
+		reader, err = source.reader()
+	}
+	if err != nil {
+		source.close()
+		i.results = (&CachedResult{FullPath: i.path, err: err}).streamResults()
+		return
+	}
+
+	i.source, i.key = source, key
+	i.inner = i.parser.inner.ParseStream(i.ctx, reader, i.path)
+}
+
+// Passes result of the inner stream and records it. Recorded stream is stored when inner stream is completed without errors.
+func (i *CachedStreamResultIterator) nextInner(ctx context.Context) bool {
+	if !i.inner.Next(ctx) {
+		if !i.failed && ctx.Err() == nil && i.ctx.Err() == nil {
+			if data, err := json.Marshal(i.recorded); err == nil {
+				i.parser.store.Put(i.ctx, i.key+cacheStreamKeySuffix, data)
+			}
+		}
+		i.current = nil
+		i.Close()
+		return false
+	}
+
+	i.current = i.inner.Current()
+	step := streamSnapshot(i.current)
+	i.failed = i.failed || step.hasError()
+	i.recorded = append(i.recorded, step)
+	return true
+}
+
+func (i *CachedStreamResultIterator) Current() StreamResult {
+	return i.current
+}
+
+func (i *CachedStreamResultIterator) Close() {
+	i.results = nil
+	if i.inner != nil {
+		i.inner.Close()
+		i.inner = nil
+	}
+	if i.source != nil {
+		i.source.close()
+		i.source = nil
+	}
+	i.recorded = nil
+}
+
+// Key suffix of the recorded streams. Results stored by `Parse` are also replayed as streams.
+const cacheStreamKeySuffix = "-stream"
+
+// Recorded stream or stream of the stored result
+func (p *CachingParser) cachedStream(ctx context.Context, key string, path string) ([]StreamResult, bool) {
+	if data, ok, err := p.store.Get(ctx, key+cacheStreamKeySuffix); err == nil && ok {
+		var steps []*cachedStreamStep
+		if err := json.Unmarshal(data, &steps); err == nil && len(steps) != 0 {
+			storedPath := steps[0].FullPath
+			results := make([]StreamResult, 0, len(steps))
+			for _, step := range steps {
+				results = append(results, step.replay(storedPath, path))
+			}
+			return results, true
+		}
+	}
+
+	if data, ok, err := p.store.Get(ctx, key); err == nil && ok {
+		var cached CachedResult
+		if err := json.Unmarshal(data, &cached); err == nil {
+			return cached.withPath(cached.FullPath, path).streamResults(), true
+		}
+	}
+	return nil, false
+}
+
+// Stored stream result with the results of its subfiles
+type cachedStreamStep struct {
+	FullPath   string             `json:"path"`
+	Stage      ParseProgressStage `json:"stage"`
+	Progress   uint8              `json:"progress"`
+	Text       string             `json:"text,omitempty"`
+	ErrMessage string             `json:"error,omitempty"`
+	SubResult  *cachedStreamStep  `json:"subResult,omitempty"`
+}
+
+func streamSnapshot(result StreamResult) *cachedStreamStep {
+	step := &cachedStreamStep{
+		FullPath: result.Path(),
+		Stage:    result.Stage(),
+		Progress: result.Progress(),
+		Text:     result.String(),
+	}
+	if err := result.Error(); err != nil {
+		step.ErrMessage = err.Error()
+	}
+	if subResult := result.SubResult(); subResult != nil {
+		step.SubResult = streamSnapshot(subResult)
+	}
+	return step
+}
+
+func (s *cachedStreamStep) hasError() bool {
+	return s.ErrMessage != "" || (s.SubResult != nil && s.SubResult.hasError())
+}
+
+func (s *cachedStreamStep) replay(storedPath string, path string) StreamResult {
+	result := &CachedStreamResult{
+		FullPath:        cachedPath(s.FullPath, storedPath, path),
+		CurrentStage:    s.Stage,
+		CurrentProgress: s.Progress,
+		Text:            s.Text,
+	}
+	if s.ErrMessage != "" {
+		result.Err = errors.New(s.ErrMessage)
+	}
+	if s.SubResult != nil {
+		result.CurrentSubfile = s.SubResult.replay(storedPath, path)
+	}
+	return result
+}
+
+type CachedStreamResult struct {
+	FullPath        string             `json:"path"`
+	CurrentStage    ParseProgressStage `json:"stage"`
+	CurrentProgress uint8              `json:"progress"`
+	CurrentSubfile  StreamResult       `json:"subResult"`
+	Text            string             `json:"text"`
+	Err             error              `json:"error"`
+}
+
+func (r *CachedStreamResult) Path() string {
+	return r.FullPath
+}
+
+func (r *CachedStreamResult) Stage() ParseProgressStage {
+	return r.CurrentStage
+}
+
+func (r *CachedStreamResult) Progress() uint8 {
+	return r.CurrentProgress
+}
+
+func (r *CachedStreamResult) SubResult() StreamResult {
+	return r.CurrentSubfile
+}
+
+func (r *CachedStreamResult) String() string {
+	return r.Text
+}
+
+func (r *CachedStreamResult) Error() error {
+	return r.Err
+}
@@ -0,0 +1,210 @@
+package parser
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Files with cached results are named `f2l-<key>.json`. Other files in the directory are never touched.
+const (
+	dirCachePrefix    = "f2l-"
+	dirCacheExtension = ".json"
+	// Suffix of the files written by the interrupted `Put`
+	dirCacheTmpExtension = ".tmp"
+	// Temporary files older than this are left by the interrupted writes. Younger ones may be written by other process sharing the directory.
+	dirCacheTmpMaxAge = time.Hour
+)
+
+// Stores cached results as files in the directory. When total size exceeds the limit, least recently used results are removed.
+// Access time is kept in the modification time of the files, so order of the eviction survives restarts.
+type DirCacheStore struct {
+	dir      string
+	maxBytes int64
+
+	mutex sync.Mutex
+	// Keys from the most recently used to the least recently used
+	order      *list.List
+	entries    map[string]*list.Element
+	totalBytes int64
+}
+
+type dirCacheEntry struct {
+	key  string
+	size int64
+}
+
+// Opens store in the directory and creates it if needed. Existing results are kept if they fit to `maxBytes`.
+// Directory may be shared, only files created by the store are counted and removed. Temporary files are removed only when they are older than an hour.
+func NewDirCacheStore(dir string, maxBytes int64) (*DirCacheStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Join(errors.New("failed to create cache directory"), err)
+	}
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to read cache directory"), err)
+	}
+
+	type storedFile struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var files []storedFile
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if dirEntry.IsDir() || !strings.HasPrefix(name, dirCachePrefix) {
+			continue
+		}
+		if strings.HasSuffix(name, dirCacheTmpExtension) {
+			// Temporary file of the interrupted write
+			if info, err := dirEntry.Info(); err == nil && time.Since(info.ModTime()) > dirCacheTmpMaxAge {
+				os.Remove(filepath.Join(dir, name))
+			}
+			continue
+		}
+		key, ok := strings.CutSuffix(strings.TrimPrefix(name, dirCachePrefix), dirCacheExtension)
+		if !ok || !validCacheKey(key) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, storedFile{key: key, size: info.Size(), modTime: info.ModTime()})
+	}
+	slices.SortFunc(files, func(a, b storedFile) int {
+		return b.modTime.Compare(a.modTime)
+	})
+
+	store := &DirCacheStore{
+		dir:      dir,
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element, len(files)),
+	}
+	for _, file := range files {
+		store.entries[file.key] = store.order.PushBack(&dirCacheEntry{key: file.key, size: file.size})
+		store.totalBytes += file.size
+	}
+	store.evict()
+
+	return store, nil
+}
+
+func (s *DirCacheStore) path(key string) string {
+	return filepath.Join(s.dir, dirCachePrefix+key+dirCacheExtension)
+}
+
+func (s *DirCacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if !validCacheKey(key) {
+		return nil, false, nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	element, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		s.remove(element)
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, errors.Join(errors.New("failed to read cached result"), err)
+	}
+
+	s.order.MoveToFront(element)
+	now := time.Now()
+	os.Chtimes(s.path(key), now, now)
+	return data, true, nil
+}
+
+// Stores data and evicts least recently used results. Data larger than the size limit is not stored.
+func (s *DirCacheStore) Put(ctx context.Context, key string, data []byte) error {
+	if !validCacheKey(key) {
+		return errors.New("cache key must contain only letters, digits, `-` and `_`")
+	}
+	if int64(len(data)) > s.maxBytes {
+		return nil
+	}
+
+	// File is renamed after it is written, so readers never see partial data
+	tmpFile, err := os.CreateTemp(s.dir, dirCachePrefix+key+"-*"+dirCacheTmpExtension)
+	if err != nil {
+		return errors.Join(errors.New("failed to create cache file"), err)
+	}
+	_, err = tmpFile.Write(data)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return errors.Join(errors.New("failed to write cache file"), err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := os.Rename(tmpFile.Name(), s.path(key)); err != nil {
+		os.Remove(tmpFile.Name())
+		return errors.Join(errors.New("failed to write cache file"), err)
+	}
+	if element, ok := s.entries[key]; ok {
+		entry := element.Value.(*dirCacheEntry)
+		s.totalBytes += int64(len(data)) - entry.size
+		entry.size = int64(len(data))
+		s.order.MoveToFront(element)
+	} else {
+		s.entries[key] = s.order.PushFront(&dirCacheEntry{key: key, size: int64(len(data))})
+		s.totalBytes += int64(len(data))
+	}
+	s.evict()
+
+	return nil
+}
+
+// Total size of the stored results
+func (s *DirCacheStore) Size() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.totalBytes
+}
+
+// Removes least recently used results until store fits to the limit. Caller holds the mutex.
+func (s *DirCacheStore) evict() {
+	for s.totalBytes > s.maxBytes && s.order.Len() != 0 {
+		element := s.order.Back()
+		os.Remove(s.path(element.Value.(*dirCacheEntry).key))
+		s.remove(element)
+	}
+}
+
+func (s *DirCacheStore) remove(element *list.Element) {
+	entry := element.Value.(*dirCacheEntry)
+	s.order.Remove(element)
+	delete(s.entries, entry.key)
+	s.totalBytes -= entry.size
+}
+
+// Keys are used as file names
+func validCacheKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, c := range key {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
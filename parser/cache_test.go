@@ -0,0 +1,314 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opengs/file2llm/ocr"
+)
+
+// Counts files parsed by the inner parser
+type countingParser struct {
+	Parser
+	calls atomic.Int32
+}
+
+func (p *countingParser) Parse(ctx context.Context, file io.Reader, path string) Result {
+	p.calls.Add(1)
+	return p.Parser.Parse(ctx, file, path)
+}
+
+func (p *countingParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
+	p.calls.Add(1)
+	return p.Parser.ParseStream(ctx, file, path)
+}
+
+func newCacheTestParser(t *testing.T, options ...CacheOption) (*CachingParser, *countingParser) {
+	text := &countingParser{Parser: NewTextParser()}
+	composite := NewCompositeParser(text)
+	composite.AddParsers(NewTARParser(composite))
+
+	store, err := NewDirCacheStore(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewCachingParser(composite, store, options...), text
+}
+
+func cacheTestArchive(t *testing.T) []byte {
+	return buildTar(t,
+		tarTestFile{name: "a.txt", data: []byte("first file")},
+		tarTestFile{name: "b.txt", data: []byte("second file")},
+	)
+}
+
+func TestCacheParse(t *testing.T) {
+	parser, text := newCacheTestParser(t)
+	data := cacheTestArchive(t)
+
+	parsed := parser.Parse(context.Background(), bytes.NewReader(data), "archive.tar")
+	if parsed.Error() != nil {
+		t.Fatal(parsed.Error())
+	}
+	cached := parser.Parse(context.Background(), bytes.NewReader(data), "archive.tar")
+	if text.calls.Load() != 2 {
+		t.Fatalf("expected files to be parsed once, got %d calls", text.calls.Load())
+	}
+
+	if cached.String() != parsed.String() || cached.Error() != nil {
+		t.Errorf("cached text differs:\n%s\n%s", cached.String(), parsed.String())
+	}
+	if composite, ok := cached.(*CompositeParserResult); !ok || composite.MimeType != "application/x-tar" {
+		t.Errorf("cached result must keep mime type, got %+v", cached)
+	}
+	subfiles := cached.Subfiles()
+	if len(subfiles) != 2 || subfiles[1].Path() != "archive.tar/b.txt" || subfiles[1].String() != "second file" {
+		t.Errorf("unexpected cached subfiles: %+v", subfiles)
+	}
+	document, ok := subfiles[0].(DocumentResult)
+	if !ok || document.Document() == nil || document.Document().String() != "first file" {
+		t.Errorf("cached subfile must keep its document")
+	}
+
+	// Same content under other path is taken from the cache
+	moved := parser.Parse(context.Background(), bytes.NewReader(data), "copy.tar")
+	if text.calls.Load() != 2 || moved.Path() != "copy.tar" || moved.Subfiles()[0].Path() != "copy.tar/a.txt" {
+		t.Errorf("unexpected result for the moved file: %s, %d calls", moved.Subfiles()[0].Path(), text.calls.Load())
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	store, err := NewDirCacheStore(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := &countingParser{Parser: NewTextParser()}
+	data := []byte("plain text")
+
+	for _, fingerprint := range []string{"dpi=300", "dpi=300", "dpi=150"} {
+		parser := NewCachingParser(NewCompositeParser(text), store, WithCacheFingerprint(fingerprint))
+		parser.Parse(context.Background(), bytes.NewReader(data), "file.txt")
+	}
+	if text.calls.Load() != 2 {
+		t.Errorf("expected new parsing only for the changed fingerprint, got %d calls", text.calls.Load())
+	}
+
+	parser := NewCachingParser(NewCompositeParser(text), store, WithCacheFingerprint("dpi=150"))
+	parser.Parse(context.Background(), bytes.NewReader([]byte("other text")), "file.txt")
+	if text.calls.Load() != 3 {
+		t.Errorf("expected new parsing for the changed content, got %d calls", text.calls.Load())
+	}
+
+	// Large inputs are hashed through the temporary file
+	parser = NewCachingParser(NewCompositeParser(text), store, WithCacheFingerprint("dpi=150"), WithCacheInMemoryLimit(4))
+	result := parser.Parse(context.Background(), bytes.NewReader([]byte("other text")), "file.txt")
+	if text.calls.Load() != 3 || result.String() != "other text" {
+		t.Errorf("expected cached result for the large input, got %q and %d calls", result.String(), text.calls.Load())
+	}
+
+	// Changed options of the inner parser give other key without fingerprint
+	for _, limits := range []Limits{DefaultLimits, DefaultLimits, {MaxEntries: 100}} {
+		composite := NewCompositeParser(text)
+		composite.Configure(WithCompositeLimits(limits))
+		NewCachingParser(composite, store).Parse(context.Background(), bytes.NewReader(data), "file.txt")
+	}
+	if text.calls.Load() != 5 {
+		t.Errorf("expected new parsing only for the changed options, got %d calls", text.calls.Load())
+	}
+
+	// OCR provider is described by its configuration, not by the instance
+	ocrParser := func(languages ...string) Parser {
+		config := ocr.DefaultTesseractServerConfig()
+		config.Languages = languages
+		return NewCompositeParser(NewPNGParser(ocr.NewTesseractServer(config)), NewTextParser())
+	}
+	if cacheFingerprint(ocrParser("eng")) != cacheFingerprint(ocrParser("eng")) {
+		t.Error("parsers with the same configuration must have the same fingerprint")
+	}
+	if cacheFingerprint(ocrParser("eng")) == cacheFingerprint(ocrParser("eng", "ukr")) {
+		t.Error("changed OCR languages must change the fingerprint")
+	}
+	if cacheFingerprint(NewPDFParser(ocrParser("eng"), 300)) != cacheFingerprint(NewPDFParser(ocrParser("eng"), 300)) {
+		t.Error("PDF parser fingerprint must not depend on the instance")
+	}
+
+	first, _ := ConfigFingerprint(300, []string{"eng"})
+	second, _ := ConfigFingerprint(300, []string{"eng", "ukr"})
+	if first == second || first == "" {
+		t.Errorf("fingerprints of different configurations must differ")
+	}
+}
+
+func TestCacheErrors(t *testing.T) {
+	parser, text := newCacheTestParser(t)
+	parser.inner.(*CompositeParser).Configure(WithCompositeLimits(Limits{MaxEntries: 1}))
+	data := cacheTestArchive(t)
+
+	for range 2 {
+		result := parser.Parse(context.Background(), bytes.NewReader(data), "archive.tar")
+		if !errors.Is(result.Error(), ErrLimitExceeded) {
+			t.Fatalf("expected limit error, got %v", result.Error())
+		}
+	}
+	if text.calls.Load() != 2 {
+		t.Errorf("results with errors must not be cached, got %d calls", text.calls.Load())
+	}
+
+	// Error of the subfile is not reported by the archive itself
+	parser, text = newCacheTestParser(t)
+	data = buildTar(t,
+		tarTestFile{name: "a.txt", data: []byte("first file")},
+		tarTestFile{name: "b.bin", data: []byte{0x00, 0x01, 0x02, 0xFF, 0xFE}},
+	)
+	for range 2 {
+		result := parser.Parse(context.Background(), bytes.NewReader(data), "archive.tar")
+		if result.Error() != nil || !resultHasError(result) {
+			t.Fatalf("expected error only in the subfile, got %v", result.Error())
+		}
+	}
+	for range 2 {
+		iterator := parser.ParseStream(context.Background(), bytes.NewReader(data), "archive.tar")
+		for iterator.Next(context.Background()) {
+		}
+		iterator.Close()
+	}
+	if text.calls.Load() != 4 {
+		t.Errorf("results with errors in subfiles must not be cached, got %d calls", text.calls.Load())
+	}
+}
+
+func TestCacheStream(t *testing.T) {
+	parser, text := newCacheTestParser(t)
+	data := cacheTestArchive(t)
+
+	for run := range 2 {
+		iterator := parser.ParseStream(context.Background(), bytes.NewReader(data), "archive.tar")
+		if !iterator.Next(context.Background()) {
+			t.Fatal("expected stream results")
+		}
+		if run == 0 && text.calls.Load() != 0 {
+			t.Errorf("first result must be passed before the files are parsed, got %d calls", text.calls.Load())
+		}
+		texts := make(map[string]string)
+		var stages []ParseProgressStage
+		for ok := true; ok; ok = iterator.Next(context.Background()) {
+			current := iterator.Current()
+			if current.Path() == "archive.tar" {
+				stages = append(stages, current.Stage())
+			}
+			for current.SubResult() != nil {
+				current = current.SubResult()
+			}
+			texts[current.Path()] += current.String()
+		}
+		iterator.Close()
+
+		if texts["archive.tar/a.txt"] != "first file" || texts["archive.tar/b.txt"] != "second file" || texts["archive.tar"] != "" {
+			t.Errorf("unexpected stream texts: %v", texts)
+		}
+		if len(stages) == 0 || stages[0] != ProgressNew || stages[len(stages)-1] != ProgressCompleted {
+			t.Errorf("unexpected stages of the archive: %v", stages)
+		}
+	}
+	if text.calls.Load() != 2 {
+		t.Errorf("expected files to be parsed once, got %d calls", text.calls.Load())
+	}
+
+	// Stream is also replayed from the result stored by `Parse`
+	parser, text = newCacheTestParser(t)
+	parser.Parse(context.Background(), bytes.NewReader(data), "archive.tar")
+	iterator := parser.ParseStream(context.Background(), bytes.NewReader(data), "copy.tar")
+	var paths []string
+	for iterator.Next(context.Background()) {
+		if sub := iterator.Current().SubResult(); sub != nil {
+			paths = append(paths, sub.Path())
+		}
+	}
+	iterator.Close()
+	if text.calls.Load() != 2 || len(paths) == 0 || paths[0] != "copy.tar/a.txt" {
+		t.Errorf("expected stream of the stored result, got %v and %d calls", paths, text.calls.Load())
+	}
+}
+
+func TestDirCacheStore(t *testing.T) {
+	dir := t.TempDir()
+	// Files of the user in the shared directory are not counted, evicted or removed
+	foreign := map[string]string{"notes.txt": "user notes", "data.json": strings.Repeat("x", 100), "upload.tmp": "partial"}
+	for name, content := range foreign {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Temporary file written by other process right now is kept, the one left by the interrupted write is removed
+	writing, interrupted := filepath.Join(dir, dirCachePrefix+"a-1"+dirCacheTmpExtension), filepath.Join(dir, dirCachePrefix+"a-2"+dirCacheTmpExtension)
+	for _, name := range []string{writing, interrupted} {
+		if err := os.WriteFile(name, []byte("partial"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-2 * dirCacheTmpMaxAge)
+	if err := os.Chtimes(interrupted, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewDirCacheStore(dir, 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.Size() != 0 {
+		t.Errorf("foreign files must not be counted, got size %d", store.Size())
+	}
+	if _, err := os.Stat(writing); err != nil {
+		t.Error("temporary file that is being written must be kept")
+	}
+	if _, err := os.Stat(interrupted); !errors.Is(err, os.ErrNotExist) {
+		t.Error("old temporary file must be removed")
+	}
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b"} {
+		if err := store.Put(ctx, key, []byte(strings.Repeat(key, 10))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok, _ := store.Get(ctx, "a"); !ok {
+		t.Fatal("expected stored value")
+	}
+	store.Put(ctx, "c", []byte(strings.Repeat("c", 10)))
+	store.Put(ctx, "d", []byte(strings.Repeat("d", 30)))
+
+	if _, ok, _ := store.Get(ctx, "b"); ok {
+		t.Errorf("least recently used value must be evicted")
+	}
+	if _, ok, _ := store.Get(ctx, "d"); ok {
+		t.Errorf("value larger than the limit must not be stored")
+	}
+	if store.Size() != 20 {
+		t.Errorf("unexpected size: %d", store.Size())
+	}
+	if err := store.Put(ctx, "../escape", []byte("x")); err == nil {
+		t.Errorf("key with path separators must be rejected")
+	}
+
+	reopened, err := NewDirCacheStore(dir, 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, ok, err := reopened.Get(ctx, "c")
+	if err != nil || !ok || string(data) != strings.Repeat("c", 10) || reopened.Size() != 20 {
+		t.Errorf("values must survive reopening, got %q, size %d", data, reopened.Size())
+	}
+	for name, content := range foreign {
+		if data, err := os.ReadFile(filepath.Join(dir, name)); err != nil || string(data) != content {
+			t.Errorf("foreign file %s must be kept", name)
+		}
+	}
+}
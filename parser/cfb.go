@@ -0,0 +1,273 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// Signature of the OLE2 compound file
+var cfbSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+const (
+	cfbEndOfChain = 0xFFFFFFFE
+	cfbNoStream   = 0xFFFFFFFF
+
+	cfbTypeStorage = 1
+	cfbTypeStream  = 2
+	cfbTypeRoot    = 5
+)
+
+// Storage or stream of the compound file
+type cfbEntry struct {
+	name     string
+	kind     byte
+	start    uint32
+	size     uint64
+	children []*cfbEntry
+}
+
+// Returns child entry with the given name. Names are compared case insensitive.
+func (e *cfbEntry) child(name string) *cfbEntry {
+	for _, child := range e.children {
+		if strings.EqualFold(child.name, name) {
+			return child
+		}
+	}
+	return nil
+}
+
+func (e *cfbEntry) isStorage() bool {
+	return e.kind == cfbTypeStorage || e.kind == cfbTypeRoot
+}
+
+// OLE2 compound file (Microsoft Compound File Binary) loaded into the memory
+type cfbFile struct {
+	data         []byte
+	sectorSize   int
+	miniCutoff   uint64
+	fat          []uint32
+	miniFAT      []uint32
+	miniStream   []byte
+	root         *cfbEntry
+	miniSectSize int
+}
+
+func openCFB(data []byte) (*cfbFile, error) {
+	if len(data) < 512 || !bytes.HasPrefix(data, cfbSignature) {
+		return nil, errors.New("not an OLE2 compound file")
+	}
+
+	sectorShift := binary.LittleEndian.Uint16(data[30:])
+	miniShift := binary.LittleEndian.Uint16(data[32:])
+	if sectorShift != 9 && sectorShift != 12 || miniShift > sectorShift {
+		return nil, fmt.Errorf("unsupported compound file sector size 2^%d", sectorShift)
+	}
+	f := &cfbFile{
+		data:         data,
+		sectorSize:   1 << sectorShift,
+		miniSectSize: 1 << miniShift,
+		miniCutoff:   uint64(binary.LittleEndian.Uint32(data[56:])),
+	}
+
+	// Sectors of the FAT are listed in the header and in the DIFAT sectors
+	fatSectors := make([]uint32, 0, 109)
+	for i := range 109 {
+		sector := binary.LittleEndian.Uint32(data[76+i*4:])
+		if sector >= cfbEndOfChain {
+			continue
+		}
+		fatSectors = append(fatSectors, sector)
+	}
+	difatSector := binary.LittleEndian.Uint32(data[68:])
+	for visited := 0; difatSector < cfbEndOfChain; visited++ {
+		sector, err := f.fullSector(difatSector)
+		if err != nil || visited > len(data)/f.sectorSize {
+			return nil, errors.Join(errors.New("broken DIFAT"), err)
+		}
+		entries := f.sectorSize/4 - 1
+		for i := range entries {
+			if value := binary.LittleEndian.Uint32(sector[i*4:]); value < cfbEndOfChain {
+				fatSectors = append(fatSectors, value)
+			}
+		}
+		difatSector = binary.LittleEndian.Uint32(sector[entries*4:])
+	}
+	for _, fatSector := range fatSectors {
+		sector, err := f.fullSector(fatSector)
+		if err != nil {
+			return nil, errors.Join(errors.New("broken FAT"), err)
+		}
+		for i := 0; i < len(sector); i += 4 {
+			f.fat = append(f.fat, binary.LittleEndian.Uint32(sector[i:]))
+		}
+	}
+
+	miniFAT, err := f.chain(binary.LittleEndian.Uint32(data[60:]), f.fat, f.sectorSize, f.sector)
+	if err != nil {
+		return nil, errors.Join(errors.New("broken mini FAT"), err)
+	}
+	for i := 0; i+4 <= len(miniFAT); i += 4 {
+		f.miniFAT = append(f.miniFAT, binary.LittleEndian.Uint32(miniFAT[i:]))
+	}
+
+	directory, err := f.chain(binary.LittleEndian.Uint32(data[48:]), f.fat, f.sectorSize, f.sector)
+	if err != nil {
+		return nil, errors.Join(errors.New("broken directory"), err)
+	}
+	if err := f.readDirectory(directory); err != nil {
+		return nil, err
+	}
+
+	if f.root.size != 0 {
+		f.miniStream, err = f.chain(f.root.start, f.fat, f.sectorSize, f.sector)
+		if err != nil {
+			return nil, errors.Join(errors.New("broken mini stream"), err)
+		}
+	}
+	return f, nil
+}
+
+func (f *cfbFile) sector(index uint32) ([]byte, error) {
+	offset := (int(index) + 1) * f.sectorSize
+	if offset+f.sectorSize > len(f.data) {
+		// Last sector of the file may be truncated
+		if offset < len(f.data) {
+			return f.data[offset:], nil
+		}
+		return nil, fmt.Errorf("sector %d is out of the file", index)
+	}
+	return f.data[offset : offset+f.sectorSize], nil
+}
+
+// Reads sector that is parsed as the table. Truncated sectors are rejected.
+func (f *cfbFile) fullSector(index uint32) ([]byte, error) {
+	sector, err := f.sector(index)
+	if err != nil {
+		return nil, err
+	}
+	if len(sector) < f.sectorSize {
+		return nil, fmt.Errorf("sector %d is truncated", index)
+	}
+	return sector, nil
+}
+
+func (f *cfbFile) miniSector(index uint32) ([]byte, error) {
+	offset := int(index) * f.miniSectSize
+	if offset+f.miniSectSize > len(f.miniStream) {
+		return nil, fmt.Errorf("mini sector %d is out of the mini stream", index)
+	}
+	return f.miniStream[offset : offset+f.miniSectSize], nil
+}
+
+// Reads sectors of the chain starting from `start`
+func (f *cfbFile) chain(start uint32, table []uint32, sectorSize int, read func(uint32) ([]byte, error)) ([]byte, error) {
+	var result []byte
+	for sector := start; sector < cfbEndOfChain; sector = table[sector] {
+		if int(sector) >= len(table) || len(result) > len(f.data) {
+			return nil, fmt.Errorf("invalid sector %d in the chain", sector)
+		}
+		data, err := read(sector)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, data...)
+	}
+	return result, nil
+}
+
+type cfbDirectoryEntry struct {
+	entry              *cfbEntry
+	left, right, child uint32
+}
+
+// Builds tree of the storages from the red-black trees of the directory
+func (f *cfbFile) readDirectory(directory []byte) error {
+	entries := make([]cfbDirectoryEntry, 0, len(directory)/128)
+	for offset := 0; offset+128 <= len(directory); offset += 128 {
+		raw := directory[offset : offset+128]
+		nameLength := min(int(binary.LittleEndian.Uint16(raw[64:])), 64)
+		name := make([]uint16, 0, 32)
+		for i := 0; i+2 <= nameLength; i += 2 {
+			if c := binary.LittleEndian.Uint16(raw[i:]); c != 0 {
+				name = append(name, c)
+			}
+		}
+
+		size := binary.LittleEndian.Uint64(raw[120:])
+		if f.sectorSize == 512 {
+			// Version 3 files may have garbage in the high part of the size
+			size &= 0xFFFFFFFF
+		}
+		entries = append(entries, cfbDirectoryEntry{
+			entry: &cfbEntry{
+				name:  string(utf16.Decode(name)),
+				kind:  raw[66],
+				start: binary.LittleEndian.Uint32(raw[116:]),
+				size:  size,
+			},
+			left:  binary.LittleEndian.Uint32(raw[68:]),
+			right: binary.LittleEndian.Uint32(raw[72:]),
+			child: binary.LittleEndian.Uint32(raw[76:]),
+		})
+	}
+	if len(entries) == 0 || entries[0].entry.kind != cfbTypeRoot {
+		return errors.New("compound file has no root storage")
+	}
+
+	visited := make([]bool, len(entries))
+	var collect func(storage *cfbEntry, index uint32) error
+	collect = func(storage *cfbEntry, index uint32) error {
+		if index == cfbNoStream {
+			return nil
+		}
+		if int(index) >= len(entries) || visited[index] {
+			return fmt.Errorf("invalid directory entry %d", index)
+		}
+		visited[index] = true
+
+		directoryEntry := entries[index]
+		if err := collect(storage, directoryEntry.left); err != nil {
+			return err
+		}
+		storage.children = append(storage.children, directoryEntry.entry)
+		if directoryEntry.entry.isStorage() {
+			if err := collect(directoryEntry.entry, directoryEntry.child); err != nil {
+				return err
+			}
+		}
+		return collect(storage, directoryEntry.right)
+	}
+
+	f.root = entries[0].entry
+	visited[0] = true
+	return collect(f.root, entries[0].child)
+}
+
+// Reads content of the stream
+func (f *cfbFile) read(entry *cfbEntry) ([]byte, error) {
+	if entry.kind != cfbTypeStream {
+		return nil, fmt.Errorf("%s is not a stream", entry.name)
+	}
+	if entry.size == 0 {
+		return nil, nil
+	}
+
+	var data []byte
+	var err error
+	if entry.size < f.miniCutoff {
+		data, err = f.chain(entry.start, f.miniFAT, f.miniSectSize, f.miniSector)
+	} else {
+		data, err = f.chain(entry.start, f.fat, f.sectorSize, f.sector)
+	}
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to read stream %s", entry.name), err)
+	}
+	if uint64(len(data)) < entry.size {
+		return nil, fmt.Errorf("stream %s is truncated", entry.name)
+	}
+	return data[:entry.size], nil
+}
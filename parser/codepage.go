@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"fmt"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// Windows code page identifiers to the encodings. Code page 1252 is used as fallback for unknown values.
+var codepageEncodings = map[int]encoding.Encoding{
+	437:   charmap.CodePage437,
+	850:   charmap.CodePage850,
+	852:   charmap.CodePage852,
+	866:   charmap.CodePage866,
+	874:   charmap.Windows874,
+	932:   japanese.ShiftJIS,
+	936:   simplifiedchinese.GBK,
+	949:   korean.EUCKR,
+	950:   traditionalchinese.Big5,
+	1250:  charmap.Windows1250,
+	1251:  charmap.Windows1251,
+	1252:  charmap.Windows1252,
+	1253:  charmap.Windows1253,
+	1254:  charmap.Windows1254,
+	1255:  charmap.Windows1255,
+	1256:  charmap.Windows1256,
+	1257:  charmap.Windows1257,
+	1258:  charmap.Windows1258,
+	10000: charmap.Macintosh,
+	20866: charmap.KOI8R,
+	28591: charmap.ISO8859_1,
+	28592: charmap.ISO8859_2,
+	28605: charmap.ISO8859_15,
+}
+
+func codepageEncoding(codepage int) encoding.Encoding {
+	if enc, ok := codepageEncodings[codepage]; ok {
+		return enc
+	}
+	return charmap.Windows1252
+}
+
+// Returns name of the code page encoding, for example `windows-1251`
+func codepageName(codepage int) string {
+	if name, err := htmlindex.Name(codepageEncoding(codepage)); err == nil {
+		return name
+	}
+	return fmt.Sprintf("cp%d", codepage)
+}
+
+// Decodes bytes from the code page into UTF-8 string
+func decodeCodepage(data []byte, codepage int) string {
+	decoded, err := codepageEncoding(codepage).NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data)
+	}
+	return string(decoded)
+}
@@ -4,15 +4,28 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"maps"
+	"slices"
+	"strings"
 
 	"github.com/gabriel-vasile/mimetype"
 )
 
 type CompositeParser struct {
 	mimeToParser map[string]Parser
+	config       compositeConfig
 }
 
+type compositeConfig struct {
+	detectors      []MimeDetector
+	containerLimit int64
+	limits         Limits
+}
+
+type CompositeOption func(c *compositeConfig)
+
 func NewCompositeParser(parsers ...Parser) *CompositeParser {
 	mimeToParser := make(map[string]Parser, 32)
 	for _, parser := range parsers {
@@ -23,6 +36,11 @@ func NewCompositeParser(parsers ...Parser) *CompositeParser {
 
 	return &CompositeParser{
 		mimeToParser: mimeToParser,
+		config: compositeConfig{
+			detectors:      CompositeDefaultDetectors,
+			containerLimit: CompositeDefaultContainerLimit,
+			limits:         DefaultLimits,
+		},
 	}
 }
 
@@ -34,6 +52,23 @@ func (p *CompositeParser) AddParsers(parsers ...Parser) {
 	}
 }
 
+// Changes mime type detection and limits. Not thread safe, configure parser before using it.
+func (p *CompositeParser) Configure(options ...CompositeOption) {
+	for _, option := range options {
+		option(&p.config)
+	}
+}
+
+// Configuration that changes the result: detection options, limits and fingerprints of the parsers. Used in the cache keys.
+func (p *CompositeParser) CacheFingerprint() string {
+	var fingerprint strings.Builder
+	fmt.Fprintf(&fingerprint, "detectors=%v container=%d limits=%+v", p.config.detectors, p.config.containerLimit, p.config.limits)
+	for _, mimeType := range slices.Sorted(maps.Keys(p.mimeToParser)) {
+		fmt.Fprintf(&fingerprint, " %s=%s", mimeType, cacheFingerprint(p.mimeToParser[mimeType]))
+	}
+	return fingerprint.String()
+}
+
 func (p *CompositeParser) SupportedMimeTypes() []string {
 	mimeTypes := make([]string, 0, len(p.mimeToParser))
 	for k := range p.mimeToParser {
@@ -43,20 +78,117 @@ func (p *CompositeParser) SupportedMimeTypes() []string {
 	return mimeTypes
 }
 
-func (p *CompositeParser) Parse(ctx context.Context, file io.Reader, path string) Result {
+// Finds parser for the mime type. Parameters like `charset` are ignored if there is no parser for the full mime type.
+func (p *CompositeParser) parserFor(mimeType string) (Parser, bool) {
+	if parser, ok := p.mimeToParser[mimeType]; ok {
+		return parser, true
+	}
+
+	parser, ok := p.mimeToParser[mimeBaseType(mimeType)]
+	return parser, ok
+}
+
+// Detects mime type of the file with configured detectors and finds parser for it. Returned reader replays bytes consumed by the detection.
+func (p *CompositeParser) detect(file io.Reader, path string) (string, Parser, io.Reader, error) {
 	mimeBlock := make([]byte, 1024)
 	readed, err := io.ReadFull(file, mimeBlock)
 	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
-		return &CompositeParserResult{Err: errors.Join(errors.New("failed to read file to determine mime type"), err), FullPath: path}
+		return "", nil, nil, errors.Join(errors.New("failed to read file to determine mime type"), err)
 	}
+	reader := io.MultiReader(bytes.NewReader(mimeBlock[:readed]), file)
+	magic := mimetype.Detect(mimeBlock[:readed])
+
+	var fallbackMimeType string
+	var fallbackParser Parser
+	for _, detector := range p.config.detectors {
+		var mimeType string
+		switch detector {
+		case MimeDetectorMagic:
+			mimeType = magicMimeType(magic, mimeBlock[:readed])
+		case MimeDetectorExtension:
+			mimeType = extensionMimeType(path)
+		case MimeDetectorContainer:
+			if !isContainerMimeType(magic) || p.config.containerLimit <= 0 {
+				continue
+			}
+			data, err := io.ReadAll(io.LimitReader(reader, p.config.containerLimit+1))
+			if err != nil {
+				return "", nil, nil, errors.Join(errors.New("failed to read container to determine mime type"), err)
+			}
+			reader = io.MultiReader(bytes.NewReader(data), file)
+			if int64(len(data)) <= p.config.containerLimit {
+				mimeType = containerMimeType(data)
+			}
+		}
 
-	mime := mimetype.Detect(mimeBlock[:readed])
-	if parser, ok := p.mimeToParser[mime.String()]; ok {
-		result := parser.Parse(ctx, io.MultiReader(bytes.NewBuffer(mimeBlock[:readed]), file), path)
-		return &CompositeParserResult{Inner: result, MimeType: mime.String()}
+		if mimeType == "" {
+			continue
+		}
+		parser, ok := p.parserFor(mimeType)
+		if !ok {
+			continue
+		}
+		if !isGenericMimeType(mimeType) {
+			return mimeType, parser, reader, nil
+		}
+		if fallbackParser == nil {
+			fallbackMimeType, fallbackParser = mimeType, parser
+		}
 	}
 
-	return &CompositeParserResult{Err: &ErrMimeTypeNotSupported{MimeType: mime}, MimeType: mime.String(), FullPath: path}
+	if fallbackParser != nil {
+		return fallbackMimeType, fallbackParser, reader, nil
+	}
+	return magic.String(), nil, reader, &ErrMimeTypeNotSupported{MimeType: magic}
+}
+
+func (p *CompositeParser) Parse(ctx context.Context, file io.Reader, path string) Result {
+	return p.parse(ctx, file, path, "")
+}
+
+// Parses file as the given mime type. Detection is skipped.
+func (p *CompositeParser) ParseAs(ctx context.Context, file io.Reader, path string, mimeType string) Result {
+	return p.parse(ctx, file, path, mimeType)
+}
+
+// Parses file with limits. Mime type is detected if `mimeType` is empty.
+func (p *CompositeParser) parse(ctx context.Context, file io.Reader, path string, mimeType string) Result {
+	state, err := newParseState(ctx, p.config.limits, path)
+	if err != nil {
+		return &CompositeParserResult{Err: err, MimeType: mimeType, FullPath: path}
+	}
+	ctx, cancel := state.context(ctx)
+	defer cancel()
+
+	limited := state.reader(file, path)
+	parser, reader, err := p.parserForFile(limited, path, &mimeType)
+	if err != nil {
+		return &CompositeParserResult{Err: err, MimeType: mimeType, FullPath: path}
+	}
+
+	result := &CompositeParserResult{Inner: parser.Parse(ctx, reader, path), MimeType: mimeType}
+	if err := result.Inner.Error(); limited.wrapError(err) != err {
+		result.Err = limited.wrapError(err)
+	}
+	if err := state.timeoutError(path); err != nil && state.depth == 0 {
+		result.Err = errors.Join(err, result.Error())
+	}
+	return result
+}
+
+// Returns parser for the forced mime type or detects it. Detected mime type is written to `mimeType`.
+func (p *CompositeParser) parserForFile(file io.Reader, path string, mimeType *string) (Parser, io.Reader, error) {
+	if *mimeType == "" {
+		detected, parser, reader, err := p.detect(file, path)
+		*mimeType = detected
+		return parser, reader, err
+	}
+
+	parser, ok := p.parserFor(*mimeType)
+	if !ok {
+		return nil, nil, &ErrMimeTypeNotSupported{MimeType: mimetype.Lookup(mimeBaseType(*mimeType))}
+	}
+	return parser, file, nil
 }
 
 func (p *CompositeParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
@@ -68,11 +200,27 @@ func (p *CompositeParser) ParseStream(ctx context.Context, file io.Reader, path
 	}
 }
 
+// Streams file parsed as the given mime type. Detection is skipped.
+func (p *CompositeParser) ParseStreamAs(ctx context.Context, file io.Reader, path string, mimeType string) StreamResultIterator {
+	return &CompositeStreamResultIterator{
+		compositeParser: p,
+		ctx:             ctx,
+		file:            file,
+		path:            path,
+		mimeType:        mimeType,
+	}
+}
+
 type CompositeStreamResultIterator struct {
 	compositeParser *CompositeParser
 	ctx             context.Context
 	file            io.Reader
 	path            string
+	// Forced mime type. Empty if it has to be detected.
+	mimeType string
+
+	state  *parseState
+	cancel context.CancelFunc
 
 	initialized   bool
 	initError     error
@@ -85,23 +233,21 @@ type CompositeStreamResultIterator struct {
 func (i *CompositeStreamResultIterator) Next(ctx context.Context) bool {
 	if !i.initialized {
 		i.initialized = true
-		mimeBlock := make([]byte, 1024)
-		readed, err := io.ReadFull(i.file, mimeBlock)
-		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
-			i.initError = errors.Join(errors.New("failed to read file to determine mime type"), err)
-			i.initResult = &CompositeParserStreamResult{FullPath: i.path, CurrentStage: ProgressNew}
-			return true
+		i.state, i.initError = newParseState(i.ctx, i.compositeParser.config.limits, i.path)
+		if i.initError == nil {
+			var parser Parser
+			var reader io.Reader
+			parser, reader, i.initError = i.compositeParser.parserForFile(i.state.reader(i.file, i.path), i.path, &i.mimeType)
+			if i.initError == nil {
+				var parseCtx context.Context
+				parseCtx, i.cancel = i.state.context(i.ctx)
+				i.parseStream = parser.ParseStream(parseCtx, reader, i.path)
+				return i.nextInner(ctx)
+			}
 		}
 
-		mime := mimetype.Detect(mimeBlock[:readed])
-		if parser, ok := i.compositeParser.mimeToParser[mime.String()]; ok {
-			i.parseStream = parser.ParseStream(i.ctx, io.MultiReader(bytes.NewBuffer(mimeBlock[:readed]), i.file), i.path)
-			return i.parseStream.Next(ctx)
-		} else {
-			i.initError = &ErrMimeTypeNotSupported{MimeType: mime}
-			i.initResult = &CompositeParserStreamResult{FullPath: i.path, CurrentStage: ProgressNew}
-			return true
-		}
+		i.initResult = &CompositeParserStreamResult{FullPath: i.path, CurrentStage: ProgressNew}
+		return true
 	}
 
 	if i.initError != nil {
@@ -118,20 +264,59 @@ func (i *CompositeStreamResultIterator) Next(ctx context.Context) bool {
 		return true
 	}
 
-	return i.parseStream.Next(ctx)
+	if err := i.state.timeoutError(i.path); err != nil && i.state.depth == 0 {
+		// Inner parser is stopped and stream completes with the timeout error. Owner of the stream closes it.
+		i.stop()
+		i.initError = err
+		i.initErrorSent = true
+		i.initResult = &CompositeParserStreamResult{
+			FullPath:     i.path,
+			CurrentStage: ProgressCompleted,
+			Err:          err,
+		}
+		return true
+	}
+
+	return i.nextInner(ctx)
+}
+
+// Subfiles created by the inner parser during the `Next` call inherit limits of this file
+func (i *CompositeStreamResultIterator) nextInner(ctx context.Context) bool {
+	if i.parseStream == nil {
+		return false
+	}
+	if i.parseStream.Next(context.WithValue(ctx, parseStateContextKey{}, i.state)) {
+		return true
+	}
+	i.stop()
+	return false
+}
+
+// Cancels context of the inner parser. Inner stream is released by Close.
+func (i *CompositeStreamResultIterator) stop() {
+	if i.cancel != nil {
+		i.cancel()
+		i.cancel = nil
+	}
 }
 
 func (i *CompositeStreamResultIterator) Current() StreamResult {
 	if i.initError != nil {
 		return i.initResult
 	}
+	if i.parseStream == nil {
+		return nil
+	}
 	return i.parseStream.Current()
 }
 
+// Closes the inner stream. It is safe to call Close more than once.
 func (i *CompositeStreamResultIterator) Close() {
 	if i.parseStream != nil {
 		i.parseStream.Close()
+		i.parseStream = nil
 	}
+	i.stop()
 }
 
 type CompositeParserResult struct {
@@ -156,10 +341,29 @@ func (r *CompositeParserResult) String() string {
 }
 
 func (r *CompositeParserResult) Error() error {
+	if r.Err != nil {
+		return r.Err
+	}
 	if r.Inner != nil {
 		return r.Inner.Error()
 	}
-	return r.Err
+	return nil
+}
+
+// Metadata of the inner result. Nil if parser of the file does not provide it.
+func (r *CompositeParserResult) MetadataFields() []MetadataField {
+	if inner, ok := r.Inner.(MetadataResult); ok {
+		return inner.MetadataFields()
+	}
+	return nil
+}
+
+// Document of the inner result. Nil if parser of the file does not provide it.
+func (r *CompositeParserResult) Document() *Document {
+	if inner, ok := r.Inner.(DocumentResult); ok {
+		return inner.Document()
+	}
+	return nil
 }
 
 func (r *CompositeParserResult) Subfiles() []Result {
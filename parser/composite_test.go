@@ -0,0 +1,210 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/gabriel-vasile/mimetype"
+	testdata "github.com/opengs/file2llm/test_data"
+)
+
+func TestCompositeExtensionDetection(t *testing.T) {
+	composite := NewCompositeParser(NewCSVParser(), NewTextParser())
+
+	result := composite.Parse(context.Background(), bytes.NewReader(testdata.CSV), "data.csv").(*CompositeParserResult)
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+	if result.MimeType != "text/csv" {
+		t.Errorf("expected text/csv, got %s", result.MimeType)
+	}
+	if _, ok := result.Inner.(*SpreadsheetParserResult); !ok {
+		t.Errorf("expected spreadsheet parser, got %T", result.Inner)
+	}
+
+	result = composite.Parse(context.Background(), bytes.NewReader(testdata.CSV), "data").(*CompositeParserResult)
+	if mimeBaseType(result.MimeType) != "text/plain" {
+		t.Errorf("expected text/plain without extension, got %s", result.MimeType)
+	}
+
+	result = composite.Parse(context.Background(), bytes.NewReader(testdata.CSV), "notes.MD").(*CompositeParserResult)
+	if result.MimeType != "text/markdown" {
+		t.Errorf("expected text/markdown, got %s", result.MimeType)
+	}
+
+	if mimeType := extensionMimeType("mail.eml/WINMAIL.DAT"); mimeType != "application/ms-tnef" {
+		t.Errorf("expected application/ms-tnef, got %s", mimeType)
+	}
+}
+
+func TestCompositeDetectionOrder(t *testing.T) {
+	composite := NewCompositeParser(NewCSVParser(), NewHTMLParser(nil))
+
+	result := composite.Parse(context.Background(), bytes.NewReader(testdata.HTML), "page.csv").(*CompositeParserResult)
+	if mimeBaseType(result.MimeType) != "text/html" {
+		t.Errorf("magic bytes must win by default, got %s", result.MimeType)
+	}
+
+	composite.Configure(WithCompositeDetectors(MimeDetectorExtension, MimeDetectorMagic))
+	result = composite.Parse(context.Background(), bytes.NewReader(testdata.HTML), "page.csv").(*CompositeParserResult)
+	if result.MimeType != "text/csv" {
+		t.Errorf("extension must win when it goes first, got %s", result.MimeType)
+	}
+
+	composite.Configure(WithCompositeDetectors(MimeDetectorMagic))
+	result = composite.Parse(context.Background(), bytes.NewReader(testdata.CSV), "data.csv").(*CompositeParserResult)
+	var notSupported *ErrMimeTypeNotSupported
+	if !errors.As(result.Error(), &notSupported) {
+		t.Errorf("expected not supported error when extension detection is disabled, got %v", result.Error())
+	}
+}
+
+// Moves `[Content_Types].xml` to the end of the archive so it is not visible in the first bytes of the file
+func reorderZip(t *testing.T, data []byte) []byte {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	copyFile := func(f *zip.File) {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		w, err := writer.Create(f.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.Copy(w, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Stored entry pushes other file names out of the first bytes
+	w, _ := writer.CreateHeader(&zip.FileHeader{Name: "customXml/item1.xml", Method: zip.Store})
+	w.Write(bytes.Repeat([]byte("<item/>"), 300))
+	var contentTypes *zip.File
+	for _, f := range reader.File {
+		if f.Name == "[Content_Types].xml" {
+			contentTypes = f
+			continue
+		}
+		copyFile(f)
+	}
+	copyFile(contentTypes)
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestCompositeContainerDetection(t *testing.T) {
+	xlsx := reorderZip(t, testdata.XLSX)
+	if mime := mimetype.Detect(xlsx[:1024]); mime.String() != "application/zip" {
+		t.Fatalf("test file must look like generic zip, got %s", mime)
+	}
+
+	composite := NewCompositeParser(NewXLSXParser())
+	result := composite.Parse(context.Background(), bytes.NewReader(xlsx), "report").(*CompositeParserResult)
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+	if result.MimeType != "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet" {
+		t.Errorf("unexpected mime type %s", result.MimeType)
+	}
+
+	composite.Configure(WithCompositeContainerLimit(100))
+	result = composite.Parse(context.Background(), bytes.NewReader(xlsx), "report").(*CompositeParserResult)
+	if result.Error() == nil {
+		t.Error("container larger than limit must not be sniffed")
+	}
+
+	if mimeType := containerMimeType(testdata.MSG); mimeType != "application/vnd.ms-outlook" {
+		t.Errorf("expected application/vnd.ms-outlook, got %s", mimeType)
+	}
+	if mimeType := containerMimeType(testdata.EPUB); mimeType != "application/epub+zip" {
+		t.Errorf("expected application/epub+zip, got %s", mimeType)
+	}
+}
+
+func TestCompositeForcedMimeType(t *testing.T) {
+	composite := NewCompositeParser(NewCSVParser(), NewTextParser())
+
+	result := composite.ParseAs(context.Background(), bytes.NewReader(testdata.CSV), "data", "text/csv").(*CompositeParserResult)
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+	if _, ok := result.Inner.(*SpreadsheetParserResult); !ok {
+		t.Errorf("expected spreadsheet parser, got %T", result.Inner)
+	}
+
+	result = composite.ParseAs(context.Background(), bytes.NewReader(testdata.CSV), "data", "application/x-unknown").(*CompositeParserResult)
+	var notSupported *ErrMimeTypeNotSupported
+	if !errors.As(result.Error(), &notSupported) || result.Error().Error() == "" {
+		t.Errorf("expected not supported error, got %v", result.Error())
+	}
+
+	stream := composite.ParseStreamAs(context.Background(), bytes.NewReader(testdata.CSV), "data", "text/csv")
+	defer stream.Close()
+	hasCSV := false
+	for stream.Next(context.Background()) {
+		if stream.Current().Error() != nil {
+			t.Fatal(stream.Current().Error())
+		}
+		_, ok := stream.Current().(*SpreadsheetParserStreamResult)
+		hasCSV = hasCSV || ok
+	}
+	if !hasCSV {
+		t.Error("stream is not parsed with CSV parser")
+	}
+}
+
+// Counts how many times streams of the inner parser are closed
+type closeCountingParser struct {
+	Parser
+	closed int
+}
+
+func (p *closeCountingParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
+	return &closeCountingIterator{StreamResultIterator: p.Parser.ParseStream(ctx, file, path), parser: p}
+}
+
+type closeCountingIterator struct {
+	StreamResultIterator
+	parser *closeCountingParser
+}
+
+func (i *closeCountingIterator) Close() {
+	i.parser.closed++
+	i.StreamResultIterator.Close()
+}
+
+func TestCompositeStreamClose(t *testing.T) {
+	inner := &closeCountingParser{Parser: NewTextParser()}
+	composite := NewCompositeParser(inner)
+
+	stream := composite.ParseStream(context.Background(), bytes.NewReader(testdata.CSV), "data.txt")
+	for stream.Next(context.Background()) {
+	}
+	if inner.closed != 0 {
+		t.Error("inner stream must be closed by the owner, not when it is completed")
+	}
+	if stream.Next(context.Background()) || stream.Current() != nil {
+		t.Error("completed stream must stay completed")
+	}
+
+	stream.Close()
+	stream.Close()
+	if inner.closed != 1 {
+		t.Errorf("inner stream must be closed once, closed %d times", inner.closed)
+	}
+	if stream.Next(context.Background()) {
+		t.Error("closed stream must not return results")
+	}
+}
@@ -0,0 +1,161 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	pathlib "path"
+)
+
+// Parses `text/csv` and `text/tab-separated-values` files. Delimiter is detected from the first lines of the file.
+type CSVParser struct {
+	config spreadsheetConfig
+}
+
+func NewCSVParser(options ...SpreadsheetOption) *CSVParser {
+	parser := &CSVParser{
+		config: defaultSpreadsheetConfig(),
+	}
+
+	for _, option := range options {
+		option(&parser.config)
+	}
+
+	return parser
+}
+
+// Configuration that changes the result. Used in the cache keys.
+func (p *CSVParser) CacheFingerprint() string {
+	return fmt.Sprintf("%+v", p.config)
+}
+
+func (p *CSVParser) SupportedMimeTypes() []string {
+	return []string{"text/csv", "text/tab-separated-values"}
+}
+
+func (p *CSVParser) Parse(ctx context.Context, file io.Reader, path string) Result {
+	sheet, err := readSpreadsheetSheet(p.sheetSource(file, path), p.config.maxRowsPerSheet)
+	if err != nil {
+		return &SpreadsheetParserResult{Err: err, FullPath: path}
+	}
+
+	return &SpreadsheetParserResult{Sheets: []SpreadsheetSheet{sheet}, FullPath: path}
+}
+
+func (p *CSVParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
+	return &SpreadsheetStreamResultIterator{
+		path:    path,
+		maxRows: p.config.maxRowsPerSheet,
+		openBook: func() ([]spreadsheetSheetSource, error) {
+			return []spreadsheetSheetSource{p.sheetSource(file, path)}, nil
+		},
+	}
+}
+
+// CSV file is represented as single sheet named after the file
+func (p *CSVParser) sheetSource(file io.Reader, path string) spreadsheetSheetSource {
+	name := "Sheet1"
+	if path != "" {
+		name = pathlib.Base(path)
+	}
+
+	return spreadsheetSheetSource{
+		name: name,
+		open: func() (spreadsheetRowReader, error) {
+			reader := bufio.NewReaderSize(file, 64*1024)
+			sample, err := reader.Peek(64 * 1024)
+			if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+				return nil, errors.Join(errors.New("failed to read beginning of the file"), err)
+			}
+			if bytes.HasPrefix(sample, []byte("\xEF\xBB\xBF")) {
+				reader.Discard(3)
+				sample = sample[3:]
+			}
+
+			csvReader := csv.NewReader(reader)
+			csvReader.Comma = sniffCSVDelimiter(sample)
+			csvReader.FieldsPerRecord = -1
+			csvReader.LazyQuotes = true
+
+			return &csvRowReader{reader: csvReader}, nil
+		},
+	}
+}
+
+var csvDelimiterCandidates = []rune{',', ';', '\t', '|'}
+
+// Selects delimiter that splits sample lines into the same non zero number of fields most consistently
+func sniffCSVDelimiter(sample []byte) rune {
+	lines := bytes.Split(sample, []byte("\n"))
+	if len(lines) > 1 {
+		// Last line may be cut in the middle
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > 20 {
+		lines = lines[:20]
+	}
+
+	bestDelimiter := ','
+	bestScore := 0
+	bestFields := 0
+	for _, delimiter := range csvDelimiterCandidates {
+		counts := make(map[int]int)
+		for _, line := range lines {
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			counts[countCSVDelimiters(line, delimiter)] += 1
+		}
+
+		score, fields := 0, 0
+		for count, lines := range counts {
+			if count > 0 && (lines > score || (lines == score && count > fields)) {
+				score, fields = lines, count
+			}
+		}
+		if score > bestScore || (score == bestScore && fields > bestFields) {
+			bestDelimiter, bestScore, bestFields = delimiter, score, fields
+		}
+	}
+
+	return bestDelimiter
+}
+
+// Counts delimiters outside of the quoted fields
+func countCSVDelimiters(line []byte, delimiter rune) int {
+	count := 0
+	inQuotes := false
+	for _, c := range string(line) {
+		if c == '"' {
+			inQuotes = !inQuotes
+		} else if c == delimiter && !inQuotes {
+			count += 1
+		}
+	}
+	return count
+}
+
+type csvRowReader struct {
+	reader *csv.Reader
+	row    int
+}
+
+func (r *csvRowReader) next() (int, []string, error) {
+	record, err := r.reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return 0, nil, io.EOF
+		}
+		return 0, nil, errors.Join(ErrBadFile, fmt.Errorf("failed to read row %d", r.row+1), err)
+	}
+
+	r.row += 1
+	return r.row, record, nil
+}
+
+func (r *csvRowReader) close() {
+}
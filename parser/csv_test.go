@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	testdata "github.com/opengs/file2llm/test_data"
+)
+
+func TestCSV(t *testing.T) {
+	csvParser := NewCSVParser()
+	result := csvParser.Parse(context.Background(), bytes.NewReader(testdata.CSV), "dir/people.csv")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+
+	expected := "------ Sheet people.csv ------\n" +
+		"Columns: Name | City | Note\n" +
+		"Row 2: Name: Alice | City: Berlin | Note: likes; semicolons\n" +
+		"Row 3: Name: Bob | City: Paris\n" +
+		"Row 4: City: Rome | Note: no name\n"
+	if !strings.Contains(result.String(), expected) {
+		t.Error(result.String())
+	}
+}
+
+func TestCSVDelimiterSniffing(t *testing.T) {
+	cases := map[string]rune{
+		"a,b,c\n1,2,3\n":         ',',
+		"a;b;c\n1;2,5;3\n":       ';',
+		"a\tb\tc\n1\t2\t3\n":     '\t',
+		"a|b\n\"x|y\"|z\n":       '|',
+		"single column\nvalue\n": ',',
+	}
+	for sample, delimiter := range cases {
+		if detected := sniffCSVDelimiter([]byte(sample)); detected != delimiter {
+			t.Errorf("%q: expected %q, got %q", sample, delimiter, detected)
+		}
+	}
+}
+
+func TestCSVStream(t *testing.T) {
+	csvParser := NewCSVParser(WithMaxRowsPerSheet(2))
+
+	hasNewStage := false
+	hasCompletedStage := false
+	var lastResult StreamResult
+
+	var resultString string
+	parseProgress := csvParser.ParseStream(context.Background(), bytes.NewReader(testdata.CSV), "people.csv")
+	defer parseProgress.Close()
+	for parseProgress.Next(t.Context()) {
+		progress := parseProgress.Current()
+		resultString += progress.String()
+		hasNewStage = hasNewStage || (progress.Stage() == ProgressNew)
+		hasCompletedStage = hasCompletedStage || (progress.Stage() == ProgressCompleted)
+		lastResult = progress
+	}
+	if !hasNewStage || !hasCompletedStage {
+		t.Fail()
+	}
+	if lastResult.Error() != nil {
+		t.Fatal(lastResult.Error())
+	}
+
+	if !strings.Contains(resultString, "Row 3: Name: Bob") || strings.Contains(resultString, "Rome") || !strings.Contains(resultString, "------ Sheet truncated after 2 rows ------") {
+		t.Error(resultString)
+	}
+}
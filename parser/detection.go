@@ -0,0 +1,295 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	pathlib "path"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// Source of the mime type for [CompositeParser]
+type MimeDetector string
+
+const (
+	// Magic bytes from the beginning of the file
+	MimeDetectorMagic MimeDetector = "magic"
+	// File name and extension from the path
+	MimeDetectorExtension MimeDetector = "extension"
+	// Content of the ZIP and OLE2 containers, for example `[Content_Types].xml` of the Office documents
+	MimeDetectorContainer MimeDetector = "container"
+)
+
+// Detectors are tried in this order by default
+var CompositeDefaultDetectors = []MimeDetector{MimeDetectorContainer, MimeDetectorMagic, MimeDetectorExtension}
+
+// Containers larger than this are not sniffed by default. ZIP keeps its directory at the end, so the whole container is read into the memory.
+// Larger files are detected by magic bytes and extension.
+const CompositeDefaultContainerLimit = 8 * 1024 * 1024
+
+// Order of the mime type detectors. Mime type from the first detector that has a parser is used.
+// Generic types like `text/plain`, `application/zip` and `application/octet-stream` are used only if no detector found more specific type.
+// Detectors that are not listed are disabled.
+func WithCompositeDetectors(detectors ...MimeDetector) CompositeOption {
+	return func(c *compositeConfig) {
+		c.detectors = detectors
+	}
+}
+
+// Maximum size of the ZIP or OLE2 file that is read into the memory to inspect its content. Zero or negative value disables the container detector.
+func WithCompositeContainerLimit(limit int64) CompositeOption {
+	return func(c *compositeConfig) {
+		c.containerLimit = limit
+	}
+}
+
+// Mime types that only say how file is stored, but not what it contains
+var genericMimeTypes = map[string]bool{
+	"application/octet-stream":  true,
+	"text/plain":                true,
+	"text/xml":                  true,
+	"application/zip":           true,
+	"application/x-ole-storage": true,
+}
+
+func isGenericMimeType(mimeType string) bool {
+	return genericMimeTypes[mimeBaseType(mimeType)]
+}
+
+// Mime type without parameters like `charset`
+func mimeBaseType(mimeType string) string {
+	baseType, _, _ := strings.Cut(mimeType, ";")
+	return strings.TrimSpace(baseType)
+}
+
+// Mime types of the well known file names
+var fileNameMimeTypes = map[string]string{
+	"winmail.dat": "application/ms-tnef",
+	"makefile":    "text/plain",
+	"dockerfile":  "text/plain",
+	"readme":      "text/plain",
+}
+
+// Mime types of the file extensions
+var extensionMimeTypes = map[string]string{
+	".pdf":   "application/pdf",
+	".doc":   "application/msword",
+	".docx":  "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".ppt":   "application/vnd.ms-powerpoint",
+	".pptx":  "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".pptm":  "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".xls":   "application/vnd.ms-excel",
+	".xlsx":  "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".xlsm":  "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".odt":   "application/vnd.oasis.opendocument.text",
+	".pages": "application/vnd.apple.pages",
+	".rtf":   "application/rtf",
+	".epub":  "application/epub+zip",
+
+	".csv":      "text/csv",
+	".tsv":      "text/tab-separated-values",
+	".html":     "text/html",
+	".htm":      "text/html",
+	".xhtml":    "application/xhtml+xml",
+	".txt":      "text/plain",
+	".text":     "text/plain",
+	".log":      "text/x-log",
+	".md":       "text/markdown",
+	".markdown": "text/markdown",
+	".json":     "application/json",
+	".ndjson":   "application/x-ndjson",
+	".jsonl":    "application/x-ndjson",
+	".yaml":     "application/yaml",
+	".yml":      "application/yaml",
+	".toml":     "application/toml",
+	".xml":      "text/xml",
+	".sql":      "application/sql",
+	".css":      "text/css",
+
+	".py":    "text/x-python",
+	".php":   "text/x-php",
+	".js":    "text/javascript",
+	".mjs":   "text/javascript",
+	".cjs":   "text/javascript",
+	".ts":    "text/x-typescript",
+	".tsx":   "text/x-typescript",
+	".jsx":   "text/javascript",
+	".lua":   "text/x-lua",
+	".pl":    "text/x-perl",
+	".tcl":   "text/x-tcl",
+	".sh":    "text/x-shellscript",
+	".bash":  "text/x-shellscript",
+	".c":     "text/x-c",
+	".h":     "text/x-c",
+	".cc":    "text/x-c++",
+	".cpp":   "text/x-c++",
+	".cxx":   "text/x-c++",
+	".hpp":   "text/x-c++",
+	".cs":    "text/x-csharp",
+	".go":    "text/x-go",
+	".java":  "text/x-java",
+	".rs":    "text/x-rust",
+	".rb":    "text/x-ruby",
+	".kt":    "text/x-kotlin",
+	".kts":   "text/x-kotlin",
+	".swift": "text/x-swift",
+
+	".eml":  "message/rfc822",
+	".mbox": "application/mbox",
+	".msg":  "application/vnd.ms-outlook",
+	".tnef": "application/ms-tnef",
+
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".bmp":  "image/bmp",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+	".webp": "image/webp",
+
+	".zip": "application/zip",
+	".tar": "application/x-tar",
+	".gz":  "application/gzip",
+	".tgz": "application/gzip",
+	".bz2": "application/x-bzip2",
+	".7z":  "application/x-7z-compressed",
+	".rar": "application/vnd.rar",
+}
+
+// Detects mime type from the file name or extension of the path. Returns empty string if it is unknown.
+func extensionMimeType(path string) string {
+	name := strings.ToLower(pathlib.Base(strings.ReplaceAll(path, "\\", "/")))
+	if mimeType, ok := fileNameMimeTypes[name]; ok {
+		return mimeType
+	}
+	return extensionMimeTypes[pathlib.Ext(name)]
+}
+
+// Formats that mimetype library recognizes only as their generic parent type. Detectors are tried in order.
+var magicSubtypes = []struct {
+	parent   string
+	mimeType string
+	detect   func(data []byte) bool
+}{
+	{"text/xml", "application/xhtml+xml", xhtmlMimeDetector},
+	{"text/plain", "application/mbox", mboxMimeDetector},
+	{"text/plain", "message/rfc822", emlMimeDetector},
+	{"application/octet-stream", "application/ms-tnef", tnefMimeDetector},
+}
+
+// Detects mime type from the magic bytes at the beginning of the file
+func magicMimeType(magic *mimetype.MIME, data []byte) string {
+	parent := mimeBaseType(magic.String())
+	for _, subtype := range magicSubtypes {
+		if subtype.parent == parent && subtype.detect(data) {
+			return subtype.mimeType
+		}
+	}
+	return magic.String()
+}
+
+// Returns true if file is a ZIP or OLE2 container and its content can tell the real type
+func isContainerMimeType(mime *mimetype.MIME) bool {
+	for ; mime != nil; mime = mime.Parent() {
+		if mime.Is("application/zip") || mime.Is("application/x-ole-storage") {
+			return true
+		}
+	}
+	return false
+}
+
+// Detects mime type of the file from the container content. Returns empty string if it is unknown.
+func containerMimeType(data []byte) string {
+	if bytes.HasPrefix(data, cfbSignature) {
+		return oleMimeType(data)
+	}
+	return zipMimeType(data)
+}
+
+// Main parts of the Office Open XML packages. Templates and macro enabled files are parsed the same way as documents.
+var ooxmlMainPartMimeTypes = map[string]string{
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.template.main+xml": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"application/vnd.ms-word.document.macroEnabled.main+xml":                           "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"application/vnd.ms-word.template.macroEnabledTemplate.main+xml":                   "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation.main+xml": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	"application/vnd.openxmlformats-officedocument.presentationml.slideshow.main+xml":    "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	"application/vnd.openxmlformats-officedocument.presentationml.template.main+xml":     "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	"application/vnd.ms-powerpoint.presentation.macroEnabled.main+xml":                   "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	"application/vnd.ms-powerpoint.slideshow.macroEnabled.main+xml":                      "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml":    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.template.main+xml": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"application/vnd.ms-excel.sheet.macroEnabled.main+xml":                          "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// Reads `mimetype` file of the ODF and EPUB packages or `[Content_Types].xml` of the Office Open XML packages
+func zipMimeType(data []byte) string {
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return ""
+	}
+
+	for _, f := range zipReader.File {
+		switch f.Name {
+		case "mimetype":
+			r, err := f.Open()
+			if err != nil {
+				continue
+			}
+			content, err := io.ReadAll(io.LimitReader(r, 256))
+			r.Close()
+			if err == nil && len(bytes.TrimSpace(content)) != 0 {
+				return string(bytes.TrimSpace(content))
+			}
+		case "[Content_Types].xml":
+			r, err := f.Open()
+			if err != nil {
+				continue
+			}
+			var types struct {
+				Overrides []struct {
+					ContentType string `xml:"ContentType,attr"`
+				} `xml:"Override"`
+			}
+			err = xml.NewDecoder(r).Decode(&types)
+			r.Close()
+			if err != nil {
+				continue
+			}
+			for _, override := range types.Overrides {
+				if mimeType, ok := ooxmlMainPartMimeTypes[override.ContentType]; ok {
+					return mimeType
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// Streams in the root storage of the OLE2 files that identify the application
+var oleRootStreamMimeTypes = map[string]string{
+	"__properties_version1.0": "application/vnd.ms-outlook",
+	"WordDocument":            "application/msword",
+	"PowerPoint Document":     "application/vnd.ms-powerpoint",
+	"Workbook":                "application/vnd.ms-excel",
+	"Book":                    "application/vnd.ms-excel",
+}
+
+func oleMimeType(data []byte) string {
+	cfb, err := openCFB(data)
+	if err != nil {
+		return ""
+	}
+	for _, child := range cfb.root.children {
+		if mimeType, ok := oleRootStreamMimeTypes[child.name]; ok {
+			return mimeType
+		}
+	}
+	return ""
+}
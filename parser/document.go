@@ -0,0 +1,195 @@
+package parser
+
+import (
+	"strings"
+)
+
+// Type of the [Block]
+type BlockKind string
+
+const (
+	BlockHeading   BlockKind = "heading"
+	BlockParagraph BlockKind = "paragraph"
+	BlockListItem  BlockKind = "listItem"
+	BlockTable     BlockKind = "table"
+	// Image with its recognized text. Whole OCR region of the image is a single block.
+	BlockImage BlockKind = "image"
+	// Beginning of the page, slide, sheet, frame or chapter from the location
+	BlockPageBreak BlockKind = "pageBreak"
+)
+
+// Place of the block in the parsed file
+type Location struct {
+	// Page, slide, sheet, frame or chapter number starting from 1. Zero if file has no pages.
+	Page int `json:"page"`
+	// Byte offset of the block in the text of the file. Negative if unknown.
+	Offset int64 `json:"offset"`
+}
+
+// Unknown location in the file without pages
+var noLocation = Location{Offset: -1}
+
+// Part of the [Document] like heading or table
+type Block struct {
+	Kind BlockKind `json:"kind"`
+	// Heading level starting from 1 or nesting level of the list item starting from 0
+	Level int `json:"level"`
+	// Marker of the heading or list item in the plain text, like `##`, `-` or `1.`
+	Marker string `json:"marker,omitempty"`
+	// Text of the block. Tables keep their plain text form, images keep recognized text and page breaks keep the page label.
+	Text string `json:"text"`
+	// Cells of the table. First row is the header, it is empty if table has no header.
+	Rows [][]string `json:"rows,omitempty"`
+	// Path of the image subfile
+	Path string `json:"path,omitempty"`
+	// Content of the list item that has more than one paragraph, including nested lists
+	Children []Block  `json:"children,omitempty"`
+	Location Location `json:"location"`
+	// Text between this block and the next one in the plain text, usually line breaks
+	Separator string `json:"separator,omitempty"`
+}
+
+// Plain text of the block without separator
+func (b *Block) String() string {
+	switch b.Kind {
+	case BlockHeading:
+		if b.Marker != "" {
+			return b.Marker + " " + b.Text
+		}
+		return b.Text
+	case BlockListItem:
+		content := b.Text
+		if len(b.Children) != 0 {
+			content = blocksString(b.Children)
+		}
+		return b.Marker + " " + indentLines(content, strings.Repeat(" ", len(b.Marker)+1))
+	case BlockImage:
+		if b.Path == "" {
+			return b.Text
+		}
+		if b.Text == "" {
+			return "--- Image " + b.Path + " ---"
+		}
+		return "--- Image " + b.Path + " ---\n" + b.Text
+	case BlockPageBreak:
+		if b.Text == "" {
+			return ""
+		}
+		return "------ " + b.Text + " ------"
+	default:
+		return b.Text
+	}
+}
+
+// Structured content of the file: tree of headings, paragraphs, lists, tables, images and page breaks
+type Document struct {
+	Blocks []Block `json:"blocks"`
+}
+
+// Plain text of the document. Results add their metadata and subfiles around it.
+func (d *Document) String() string {
+	return blocksString(d.Blocks)
+}
+
+// Result that knows the structure of the file content
+type DocumentResult interface {
+	Result
+	// Content of the file without subfiles. Nil if structure of the file is unknown.
+	Document() *Document
+}
+
+func blocksString(blocks []Block) string {
+	var result strings.Builder
+	for i := range blocks {
+		result.WriteString(blocks[i].String())
+		result.WriteString(blocks[i].Separator)
+	}
+	return result.String()
+}
+
+// Prefixes every non empty line except the first one
+func indentLines(text string, indent string) string {
+	lines := strings.Split(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = indent + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Adds separator after the last block
+func appendSeparator(blocks []Block, separator string) {
+	if len(blocks) != 0 {
+		blocks[len(blocks)-1].Separator += separator
+	}
+}
+
+// Copies blocks with offsets moved by `offset`. Unknown offsets are kept.
+func moveBlocks(blocks []Block, offset int64) []Block {
+	if len(blocks) == 0 {
+		return nil
+	}
+	moved := make([]Block, len(blocks))
+	for i, block := range blocks {
+		if block.Location.Offset >= 0 {
+			block.Location.Offset += offset
+		}
+		block.Children = moveBlocks(block.Children, offset)
+		moved[i] = block
+	}
+	return moved
+}
+
+// Copies blocks with the location of the page
+func pageBlocks(blocks []Block, location Location) []Block {
+	if len(blocks) == 0 {
+		return nil
+	}
+	paged := make([]Block, len(blocks))
+	for i, block := range blocks {
+		block.Location = location
+		block.Children = pageBlocks(block.Children, location)
+		paged[i] = block
+	}
+	return paged
+}
+
+func isBlankLine(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// Splits text to paragraphs separated by empty lines. Paragraphs with their separators are the original text.
+func textBlocks(text string, location Location) []Block {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var blocks []Block
+	offset := 0
+	for i := 0; i < len(lines); {
+		start := i
+		// Empty lines at the beginning of the text stay in the first paragraph
+		for len(blocks) == 0 && i < len(lines) && isBlankLine(lines[i]) {
+			i++
+		}
+		for i < len(lines) && !isBlankLine(lines[i]) {
+			i++
+		}
+		paragraph := strings.Join(lines[start:i], "")
+		separatorStart := i
+		for i < len(lines) && isBlankLine(lines[i]) {
+			i++
+		}
+
+		block := Block{Kind: BlockParagraph, Text: strings.TrimSuffix(paragraph, "\n"), Location: location}
+		block.Separator = paragraph[len(block.Text):] + strings.Join(lines[separatorStart:i], "")
+		if location.Offset >= 0 {
+			block.Location.Offset = location.Offset + int64(offset)
+		}
+		offset += len(block.Text) + len(block.Separator)
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
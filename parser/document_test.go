@@ -0,0 +1,186 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	testdata "github.com/opengs/file2llm/test_data"
+)
+
+func TestDocumentText(t *testing.T) {
+	text := "\nfirst line\nsecond line\n\n\nnext paragraph  \n \nlast"
+	result := NewTextParser().Parse(context.Background(), bytes.NewReader([]byte(text)), "notes.txt").(*TextParserResult)
+
+	document := result.Document()
+	if document.String() != text || result.String() != text {
+		t.Fatalf("document text differs from the file: %q", document.String())
+	}
+
+	expected := []struct {
+		text   string
+		offset int64
+	}{
+		{"\nfirst line\nsecond line", 0},
+		{"next paragraph  ", 26},
+		{"last", 45},
+	}
+	if len(document.Blocks) != len(expected) {
+		t.Fatalf("unexpected blocks: %+v", document.Blocks)
+	}
+	for i, block := range document.Blocks {
+		if block.Kind != BlockParagraph || block.Text != expected[i].text || block.Location.Offset != expected[i].offset {
+			t.Errorf("unexpected block %d: %+v", i, block)
+		}
+		if text[block.Location.Offset:block.Location.Offset+int64(len(block.Text))] != block.Text {
+			t.Errorf("offset of the block %d does not point to its text", i)
+		}
+	}
+}
+
+func TestDocumentHTML(t *testing.T) {
+	page := `<h2>Plan</h2><p>Intro</p>
+<ul><li>one</li><li>two<ol start="3"><li>nested</li></ol></li></ul>
+<table><tr><th>Name</th><th>Value</th></tr><tr><td>a|b</td><td>1</td></tr></table>`
+	result := NewHTMLParser(nil).Parse(context.Background(), bytes.NewReader([]byte(page)), "page.html").(*HTMLParserResult)
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+
+	document := result.Document()
+	if document.String() != result.Text {
+		t.Fatalf("document text differs from the converted text:\n%s", document.String())
+	}
+
+	blocks := document.Blocks
+	if len(blocks) != 5 {
+		t.Fatalf("unexpected blocks: %+v", blocks)
+	}
+	if blocks[0].Kind != BlockHeading || blocks[0].Level != 2 || blocks[0].Text != "Plan" {
+		t.Errorf("unexpected heading: %+v", blocks[0])
+	}
+	if blocks[1].Kind != BlockParagraph || blocks[1].Text != "Intro" {
+		t.Errorf("unexpected paragraph: %+v", blocks[1])
+	}
+	if blocks[2].Kind != BlockListItem || blocks[2].Text != "one" {
+		t.Errorf("unexpected list item: %+v", blocks[2])
+	}
+
+	nested := blocks[3].Children
+	if blocks[3].Kind != BlockListItem || len(nested) != 2 || nested[0].Text != "two" || nested[1].Kind != BlockListItem || nested[1].Marker != "3." || nested[1].Level != 1 {
+		t.Errorf("unexpected nested list: %+v", blocks[3])
+	}
+
+	table := blocks[4]
+	if table.Kind != BlockTable || len(table.Rows) != 2 || table.Rows[0][0] != "Name" || table.Rows[1][0] != "a|b" {
+		t.Errorf("unexpected table: %+v", table)
+	}
+	checkBlockOffsets(t, result.Text, blocks)
+
+	// Text outside of the block elements is kept as paragraphs
+	page = `<div>loose text</div><h1>Title</h1>after heading<p>first<br>second</p><blockquote><p>quote</p></blockquote>`
+	result = NewHTMLParser(nil).Parse(context.Background(), bytes.NewReader([]byte(page)), "page.html").(*HTMLParserResult)
+	blocks = result.Document().Blocks
+	if len(blocks) != 5 || blocks[0].Text != "loose text" || blocks[1].Kind != BlockHeading || blocks[2].Text != "after heading" || blocks[3].Text != "first\nsecond" || blocks[4].Text != "> quote" {
+		t.Errorf("unexpected blocks: %+v", blocks)
+	}
+	checkBlockOffsets(t, result.Text, blocks)
+}
+
+func TestDocumentRTF(t *testing.T) {
+	result := NewRTFParser(nil).Parse(context.Background(), bytes.NewReader(testdata.RTF), "doc.rtf").(*RTFParserResult)
+	blocks := result.Document().Blocks
+	if blocksString(blocks) != result.Text {
+		t.Fatalf("document text differs from the text: %q", blocksString(blocks))
+	}
+
+	var table *Block
+	for i := range blocks {
+		if blocks[i].Kind == BlockTable {
+			table = &blocks[i]
+		}
+	}
+	if table == nil || len(table.Rows) != 2 || table.Rows[0][0] != "Region" || table.Rows[1][1] != "120" {
+		t.Errorf("unexpected table: %+v", table)
+	}
+	if blocks[0].Text != "Summary" {
+		t.Errorf("paragraphs must be ended by `\\par`, got %q", blocks[0].Text)
+	}
+	checkBlockOffsets(t, result.Text, blocks)
+}
+
+func TestDocumentEML(t *testing.T) {
+	result := NewEMLParser(nil).Parse(context.Background(), bytes.NewReader(testdata.EMLHTML), "mail.eml").(*EMLParserResult)
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+	blocks := result.Document().Blocks
+	if len(blocks) == 0 || blocksString(blocks) != result.Text {
+		t.Fatalf("document text differs from the body: %q", blocksString(blocks))
+	}
+	checkBlockOffsets(t, result.Text, blocks)
+}
+
+func TestDocumentSheetWithoutHeader(t *testing.T) {
+	sheet := SpreadsheetSheet{Name: "Data", Rows: []SpreadsheetRow{{Number: 1, Cells: []string{"a", "1"}}}}
+	blocks := sheet.blocks(1)
+	if len(blocks) != 2 || blocks[1].Kind != BlockTable || len(blocks[1].Rows) != 2 || len(blocks[1].Rows[0]) != 0 || blocks[1].Rows[1][0] != "a" {
+		t.Errorf("table without header must start with empty header row: %+v", blocks)
+	}
+}
+
+// Offsets of the blocks point to their text
+func checkBlockOffsets(t *testing.T, text string, blocks []Block) {
+	t.Helper()
+	for i, block := range blocks {
+		blockText := block.String()
+		offset := int(block.Location.Offset)
+		if offset < 0 || offset+len(blockText) > len(text) || text[offset:offset+len(blockText)] != blockText {
+			t.Errorf("offset %d of the block %d does not point to %q", offset, i, blockText)
+		}
+	}
+}
+
+func TestDocumentPages(t *testing.T) {
+	var result Result = NewCompositeParser(NewXLSXParser()).Parse(context.Background(), bytes.NewReader(testdata.XLSX), "book.xlsx")
+	documentResult, ok := result.(DocumentResult)
+	if !ok || documentResult.Document() == nil {
+		t.Fatal("composite result must provide document of the spreadsheet")
+	}
+
+	document := documentResult.Document()
+	if document.String() != result.String() {
+		t.Errorf("spreadsheet text must be rendered from the document")
+	}
+
+	var sheets, tables int
+	for _, block := range document.Blocks {
+		switch block.Kind {
+		case BlockPageBreak:
+			sheets += 1
+			if block.Location.Page != sheets {
+				t.Errorf("unexpected page of the sheet %q: %d", block.Text, block.Location.Page)
+			}
+		case BlockTable:
+			tables += 1
+			if block.Rows[0][0] == "" || block.Location.Page != sheets {
+				t.Errorf("unexpected table: %+v", block)
+			}
+		}
+	}
+	if sheets != 2 || tables != 2 {
+		t.Errorf("expected 2 sheets with tables, got %d sheets and %d tables", sheets, tables)
+	}
+
+	pptx := NewPPTXParser(nil).Parse(context.Background(), bytes.NewReader(testdata.PPTX), "deck.pptx").(*PPTXParserResult)
+	blocks := pptx.Document().Blocks
+	var kinds []BlockKind
+	for _, block := range blocks {
+		if block.Location.Page == 2 {
+			kinds = append(kinds, block.Kind)
+		}
+	}
+	if len(kinds) < 3 || kinds[0] != BlockPageBreak || kinds[1] != BlockHeading || kinds[2] != BlockTable {
+		t.Errorf("unexpected blocks of the second slide: %v", kinds)
+	}
+}
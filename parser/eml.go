@@ -1,38 +1,89 @@
 package parser
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
-	"mime"
 	pathlib "path"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/emersion/go-message"
 	_ "github.com/emersion/go-message/charset"
 	"github.com/emersion/go-message/mail"
+	"golang.org/x/net/html"
 )
 
-// Parses `message/rfc822` files (.eml)
+// Parses `message/rfc822` files (.eml). Plain text and HTML parts are the body of the email, attached and forwarded messages are parsed
+// recursively, other parts are parsed with inner parser. Only one alternative of `multipart/alternative` is used: plain text is preferred,
+// HTML converted to text is used when there is no plain text.
 type EMLParser struct {
 	innerParser Parser
+	config      emlConfig
 }
 
-func NewEMLParser(innerParser Parser) *EMLParser {
+type emlConfig struct {
+	headers        []string
+	stripQuoted    bool
+	stripSignature bool
+}
+
+type EMLOption func(c *emlConfig)
+
+// Headers included into the result by default, in the output order. Parser copies them when it is created.
+var EMLDefaultHeaders = []string{"From", "To", "Cc", "Date", "Subject"}
+
+// Headers included into the result, in the output order. Headers missing in the email are skipped. Default is [EMLDefaultHeaders].
+func WithEMLHeaders(headers ...string) EMLOption {
+	return func(c *emlConfig) {
+		c.headers = slices.Clone(headers)
+	}
+}
+
+// Removes quoted reply history from the body: lines starting with `>`, "On ... wrote:" attributions and everything after
+// "-----Original Message-----" or Outlook reply headers. Default is false.
+func WithEMLStripQuoted(strip bool) EMLOption {
+	return func(c *emlConfig) {
+		c.stripQuoted = strip
+	}
+}
+
+// Removes signature from the body: everything after "-- " separator line and "Sent from my ..." footers. Default is false.
+func WithEMLStripSignature(strip bool) EMLOption {
+	return func(c *emlConfig) {
+		c.stripSignature = strip
+	}
+}
+
+func NewEMLParser(innerParser Parser, options ...EMLOption) *EMLParser {
+	config := emlConfig{
+		headers: slices.Clone(EMLDefaultHeaders),
+	}
+	for _, option := range options {
+		option(&config)
+	}
+
 	return &EMLParser{
 		innerParser: innerParser,
+		config:      config,
 	}
 }
 
+// Configuration that changes the result. Used in the cache keys.
+func (p *EMLParser) CacheFingerprint() string {
+	return fmt.Sprintf("%+v", p.config)
+}
+
 func (p *EMLParser) SupportedMimeTypes() []string {
 	return []string{"message/rfc822"}
 }
 
 func (p *EMLParser) Parse(ctx context.Context, file io.Reader, path string) Result {
-	mailReader, err := mail.CreateReader(file)
-	if err != nil {
+	entity, err := message.Read(file)
+	if err != nil && !message.IsUnknownCharset(err) {
 		return &EMLParserResult{
 			Err:      errors.Join(ErrBadFile, err),
 			FullPath: path,
@@ -40,14 +91,17 @@ func (p *EMLParser) Parse(ctx context.Context, file io.Reader, path string) Resu
 	}
 
 	result := EMLParserResult{
-		Headers:  mailReader.Header.Map(),
+		Headers:  p.headers(entity.Header),
+		Thread:   emlThread(entity.Header),
 		FullPath: path,
 	}
 
+	walker := emlPartWalker{root: entity}
 	partID := -1
+	entries := 0
 	for {
 		partID += 1
-		part, err := mailReader.NextPart()
+		part, err := walker.next()
 		if err == io.EOF {
 			break
 		} else if err != nil {
@@ -57,34 +111,58 @@ func (p *EMLParser) Parse(ctx context.Context, file io.Reader, path string) Resu
 			}
 		}
 
-		contentType, ctParams, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
-		disposition, dispParams, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
-
-		if contentType == "text/plain" {
-			body, _ := io.ReadAll(part.Body)
-			if result.Text != "" {
-				result.Text += "\n"
+		blocks, isBody, err := part.bodyBlocks()
+		if err != nil {
+			return &EMLParserResult{
+				Err:      errors.Join(ErrBadFile, errors.New("error while reading email body"), err),
+				FullPath: path,
 			}
-			result.Text += string(body)
+		}
+		if isBody {
+			result.appendBlocks(p.config.cleanBodyBlocks(blocks))
+			continue
+		}
+
+		entries += 1
+		if err := checkEntryLimit(ctx, entries, path); err != nil {
+			result.Err = err
+			break
+		}
+
+		contentType, ctParams, _ := part.header.ContentType()
+		disposition, dispParams, _ := part.header.ContentDisposition()
+		filename := p.getFileName(contentType, ctParams, dispParams, partID)
+		var r Result
+		if emlIsMessage(contentType) {
+			r = p.parseNested(ctx, part.body, pathlib.Join(path, filename))
 		} else {
-			filename := p.getFileName(ctParams, dispParams, partID)
-			r := p.innerParser.Parse(ctx, part.Body, pathlib.Join(path, filename))
-			if r.Error() != nil || disposition == "attachment" {
-				result.Attachments = append(result.Attachments, r)
-			} else {
-				if result.Text != "" {
-					result.Text += "\n"
-				}
-				result.Text += fmt.Sprintf("--- Inline attachment begin: %s ---\n", r.Path())
-				result.Text += r.String()
-				result.Text += fmt.Sprintf("--- Inline attachment end: %s ---\n", r.Path())
-			}
+			r = p.innerParser.Parse(ctx, part.body, pathlib.Join(path, filename))
+		}
+		if r.Error() != nil || disposition == "attachment" {
+			result.Attachments = append(result.Attachments, r)
+		} else {
+			text := fmt.Sprintf("--- Inline attachment begin: %s ---\n", r.Path()) + r.String() + fmt.Sprintf("--- Inline attachment end: %s ---", r.Path())
+			result.appendBlocks([]Block{{Kind: BlockParagraph, Text: text, Location: Location{}, Separator: "\n"}})
 		}
 	}
 
 	return &result
 }
 
+// Parses attached email with the limits of the subfile
+func (p *EMLParser) parseNested(ctx context.Context, file io.Reader, path string) Result {
+	state, err := nestedParseState(ctx, path)
+	if err != nil {
+		return &EMLParserResult{Err: err, FullPath: path}
+	}
+	ctx, cancel := state.context(ctx)
+	defer cancel()
+	reader := state.reader(file, path)
+	result := p.Parse(ctx, reader, path).(*EMLParserResult)
+	result.Err = reader.wrapError(result.Err)
+	return result
+}
+
 func (p *EMLParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
 	return &EMLStreamResultIterator{
 		emlParser: p,
@@ -94,16 +172,233 @@ func (p *EMLParser) ParseStream(ctx context.Context, file io.Reader, path string
 	}
 }
 
-func (p *EMLParser) getFileName(ctParams, dispParams map[string]string, partID int) string {
+// Streams attached email with the limits of the subfile
+func (p *EMLParser) parseNestedStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
+	state, err := nestedParseState(ctx, path)
+	if err != nil {
+		return &EMLStreamResultIterator{emlParser: p, ctx: ctx, path: path, initializationError: err}
+	}
+	reader := state.reader(file, path)
+	return &EMLStreamResultIterator{emlParser: p, ctx: ctx, file: reader, path: path, state: state, reader: reader}
+}
+
+func (p *EMLParser) getFileName(contentType string, ctParams, dispParams map[string]string, partID int) string {
 	if name := dispParams["filename"]; name != "" {
 		return filepath.Base(name)
 	}
 	if name := ctParams["name"]; name != "" {
 		return filepath.Base(name)
 	}
+	if emlIsMessage(contentType) {
+		return fmt.Sprintf("message_%d.eml", partID)
+	}
 	return fmt.Sprintf("ext_%d", partID)
 }
 
+// Attached and forwarded emails
+func emlIsMessage(contentType string) bool {
+	return contentType == "message/rfc822" || contentType == "message/global"
+}
+
+// Selects configured headers in the configured order. Encoded words are decoded.
+func (p *EMLParser) headers(header message.Header) []EMLHeader {
+	var headers []EMLHeader
+	for _, name := range p.config.headers {
+		fields := header.FieldsByKey(name)
+		var values []string
+		for fields.Next() {
+			value, err := fields.Text()
+			if err != nil {
+				value = fields.Value()
+			}
+			values = append(values, strings.TrimSpace(value))
+		}
+		if len(values) != 0 {
+			headers = append(headers, EMLHeader{Name: name, Values: values})
+		}
+	}
+	return headers
+}
+
+// Leaf part of the email
+type emlPart struct {
+	header message.Header
+	body   io.Reader
+}
+
+func (p *emlPart) partHeader() mail.PartHeader {
+	if disposition, _, _ := p.header.ContentDisposition(); disposition == "attachment" {
+		return &mail.AttachmentHeader{Header: p.header}
+	}
+	return &mail.InlineHeader{Header: p.header}
+}
+
+// Reads blocks of the plain text and HTML body parts. HTML is converted to text. Returns false for attachments and other parts.
+func (p *emlPart) bodyBlocks() ([]Block, bool, error) {
+	contentType, ctParams, _ := p.header.ContentType()
+	disposition, _, _ := p.header.ContentDisposition()
+	if disposition == "attachment" || (contentType != "text/plain" && contentType != "text/html") {
+		return nil, false, nil
+	}
+
+	body, err := io.ReadAll(p.body)
+	if err != nil {
+		return nil, true, errors.Join(errors.New("failed to read part body"), err)
+	}
+	if contentType == "text/plain" {
+		return textBlocks(string(body), Location{}), true, nil
+	}
+
+	// Body with declared charset is already converted to UTF-8, otherwise encoding is detected from the HTML itself
+	htmlContentType := ""
+	if _, ok := ctParams["charset"]; ok {
+		htmlContentType = "text/html; charset=utf-8"
+	}
+	root, err := html.Parse(bytes.NewReader(decodeHTML(body, htmlContentType)))
+	if err != nil {
+		return nil, true, errors.Join(errors.New("failed to parse html part"), err)
+	}
+	blocks := newHTMLConverter().convert(root)
+	appendSeparator(blocks, "\n")
+	return blocks, true, nil
+}
+
+// Walks leaf parts of the email in order. Only the preferred alternative of `multipart/alternative` is returned.
+type emlPartWalker struct {
+	root    *message.Entity
+	started bool
+	readers []message.MultipartReader
+	pending []emlPart
+}
+
+func (w *emlPartWalker) next() (*emlPart, error) {
+	for {
+		if len(w.pending) != 0 {
+			part := w.pending[0]
+			w.pending = w.pending[1:]
+			return &part, nil
+		}
+
+		var entity *message.Entity
+		if !w.started {
+			w.started = true
+			entity = w.root
+		} else {
+			if len(w.readers) == 0 {
+				return nil, io.EOF
+			}
+
+			var err error
+			entity, err = w.readers[len(w.readers)-1].NextPart()
+			if err == io.EOF {
+				w.readers = w.readers[:len(w.readers)-1]
+				continue
+			} else if err != nil && !message.IsUnknownCharset(err) {
+				return nil, err
+			}
+		}
+
+		mediaType, _, _ := entity.Header.ContentType()
+		reader := entity.MultipartReader()
+		switch {
+		case reader == nil:
+			return &emlPart{header: entity.Header, body: entity.Body}, nil
+		case mediaType == "multipart/alternative":
+			parts, err := emlAlternative(reader)
+			if err != nil {
+				return nil, err
+			}
+			w.pending = parts
+		default:
+			w.readers = append(w.readers, reader)
+		}
+	}
+}
+
+// Reads all the alternatives and returns parts of the preferred one: plain text, then the last alternative with HTML, then the last
+// alternative. Alternatives are buffered in memory, because preferred one is known only after all of them are read.
+func emlAlternative(reader message.MultipartReader) ([]emlPart, error) {
+	var alternatives [][]emlPart
+	for {
+		entity, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil && !message.IsUnknownCharset(err) {
+			return nil, err
+		}
+
+		parts, err := emlBufferEntity(entity)
+		if err != nil {
+			return nil, err
+		}
+		alternatives = append(alternatives, parts)
+	}
+
+	for _, parts := range alternatives {
+		if len(parts) == 1 && parts[0].isPlainText() {
+			return parts, nil
+		}
+	}
+	for index := len(alternatives) - 1; index >= 0; index-- {
+		for _, part := range alternatives[index] {
+			if contentType, _, _ := part.header.ContentType(); contentType == "text/html" {
+				return alternatives[index], nil
+			}
+		}
+	}
+	if len(alternatives) != 0 {
+		return alternatives[len(alternatives)-1], nil
+	}
+	return nil, nil
+}
+
+// Reads leaf parts of the entity into memory
+func emlBufferEntity(entity *message.Entity) ([]emlPart, error) {
+	reader := entity.MultipartReader()
+	if reader == nil {
+		body, err := io.ReadAll(entity.Body)
+		if err != nil {
+			return nil, errors.Join(errors.New("failed to read part body"), err)
+		}
+		return []emlPart{{header: entity.Header, body: bytes.NewReader(body)}}, nil
+	}
+
+	if mediaType, _, _ := entity.Header.ContentType(); mediaType == "multipart/alternative" {
+		return emlAlternative(reader)
+	}
+
+	var parts []emlPart
+	for {
+		child, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil && !message.IsUnknownCharset(err) {
+			return nil, err
+		}
+
+		childParts, err := emlBufferEntity(child)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, childParts...)
+	}
+	return parts, nil
+}
+
+// True for the buffered plain text part with some text
+func (p *emlPart) isPlainText() bool {
+	contentType, _, _ := p.header.ContentType()
+	disposition, _, _ := p.header.ContentDisposition()
+	body, ok := p.body.(*bytes.Reader)
+	if contentType != "text/plain" || disposition == "attachment" || !ok {
+		return false
+	}
+
+	text := make([]byte, body.Len())
+	body.ReadAt(text, 0)
+	return len(bytes.TrimSpace(text)) != 0
+}
+
 type EMLStreamResultIterator struct {
 	emlParser *EMLParser
 	ctx       context.Context
@@ -112,16 +407,33 @@ type EMLStreamResultIterator struct {
 
 	completed           bool
 	initializationError error
-	reader              *mail.Reader
-	part                *mail.Part
+	walker              *emlPartWalker
+	part                *emlPart
 	partDisposition     string
 	partIndex           int
+	entries             int
 	partParse           StreamResultIterator
+	// Limits state and reader of the attached email. Nil for the top level email.
+	state  *parseState
+	reader *limitedReader
 
 	current StreamResult
 }
 
 func (i *EMLStreamResultIterator) Next(ctx context.Context) bool {
+	if i.state == nil {
+		return i.next(ctx)
+	}
+	if !i.next(context.WithValue(ctx, parseStateContextKey{}, i.state)) {
+		return false
+	}
+	if current, ok := i.current.(*EMLParserStreamResult); ok {
+		current.Err = i.reader.wrapError(current.Err)
+	}
+	return true
+}
+
+func (i *EMLStreamResultIterator) next(ctx context.Context) bool {
 	if i.completed {
 		i.current = nil
 		return false
@@ -130,16 +442,17 @@ func (i *EMLStreamResultIterator) Next(ctx context.Context) bool {
 	if i.initializationError != nil {
 		i.completed = true
 		i.current = &EMLParserStreamResult{
-			FullPath: i.path,
-			Err:      i.initializationError,
+			FullPath:     i.path,
+			CurrentStage: ProgressCompleted,
+			Err:          i.initializationError,
 		}
 		return true
 	}
 
-	if i.reader == nil {
-		msg, err := message.Read(i.file)
-		if err != nil {
-			i.initializationError = err
+	if i.walker == nil {
+		entity, err := message.Read(i.file)
+		if err != nil && !message.IsUnknownCharset(err) {
+			i.initializationError = errors.Join(ErrBadFile, err)
 			i.current = &EMLParserStreamResult{
 				FullPath:     i.path,
 				CurrentStage: ProgressNew,
@@ -147,18 +460,20 @@ func (i *EMLStreamResultIterator) Next(ctx context.Context) bool {
 			return true
 		}
 
-		i.reader = mail.NewReader(msg)
+		i.walker = &emlPartWalker{root: entity}
+		thread := emlThread(entity.Header)
 		i.current = &EMLParserStreamResult{
 			FullPath:     i.path,
 			CurrentStage: ProgressNew,
-			Headers:      i.reader.Header.Map(),
+			Headers:      i.emlParser.headers(entity.Header),
+			Thread:       &thread,
 		}
 		return true
 	}
 
 	if i.part == nil {
 		i.partIndex += 1
-		part, err := i.reader.NextPart()
+		part, err := i.walker.next()
 		if err != nil {
 			if err == io.EOF {
 				i.completed = true
@@ -178,33 +493,46 @@ func (i *EMLStreamResultIterator) Next(ctx context.Context) bool {
 			return true
 		}
 
-		contentType, ctParams, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
-		disposition, dispParams, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
-		i.partDisposition = disposition
-
-		if contentType == "text/plain" {
-			body, err := io.ReadAll(part.Body)
-			if err != nil {
-				i.completed = true
-				i.current = &EMLParserStreamResult{
-					FullPath:     i.path,
-					CurrentStage: ProgressCompleted,
-					Err:          errors.Join(errors.New("failed to read part body"), err),
-				}
-				return true
+		blocks, isBody, err := part.bodyBlocks()
+		if err != nil {
+			i.completed = true
+			i.current = &EMLParserStreamResult{
+				FullPath:     i.path,
+				CurrentStage: ProgressCompleted,
+				Err:          err,
 			}
-
+			return true
+		}
+		if isBody {
 			i.current = &EMLParserStreamResult{
 				FullPath:          i.path,
 				CurrentStage:      ProgressUpdate,
-				CurrentPartHeader: i.part.Header,
-				Text:              string(body),
+				CurrentPartHeader: part.partHeader(),
+				Text:              blocksString(i.emlParser.config.cleanBodyBlocks(blocks)),
+			}
+			return true
+		}
+
+		i.entries += 1
+		if err := checkEntryLimit(ctx, i.entries, i.path); err != nil {
+			i.completed = true
+			i.current = &EMLParserStreamResult{
+				FullPath:     i.path,
+				CurrentStage: ProgressCompleted,
+				Err:          err,
 			}
-			i.part = nil
 			return true
+		}
+
+		contentType, ctParams, _ := part.header.ContentType()
+		disposition, dispParams, _ := part.header.ContentDisposition()
+		i.part = part
+		i.partDisposition = disposition
+		filename := i.emlParser.getFileName(contentType, ctParams, dispParams, i.partIndex)
+		if emlIsMessage(contentType) {
+			i.partParse = i.emlParser.parseNestedStream(ctx, part.body, pathlib.Join(i.path, filename))
 		} else {
-			filename := i.emlParser.getFileName(ctParams, dispParams, i.partIndex)
-			i.partParse = i.emlParser.innerParser.ParseStream(ctx, part.Body, pathlib.Join(i.path, filename))
+			i.partParse = i.emlParser.innerParser.ParseStream(ctx, part.body, pathlib.Join(i.path, filename))
 		}
 	}
 
@@ -228,15 +556,16 @@ func (i *EMLStreamResultIterator) Next(ctx context.Context) bool {
 			i.current = &EMLParserStreamResult{
 				FullPath:          i.path,
 				CurrentStage:      ProgressUpdate,
-				CurrentPartHeader: i.part.Header,
+				CurrentPartHeader: i.part.partHeader(),
 				CurrentPart:       i.partParse.Current(),
 			}
 		}
 		return true
 	} else {
 		i.partParse.Close()
+		i.partParse = nil
 		i.part = nil
-		return i.Next(ctx)
+		return i.next(ctx)
 	}
 }
 
@@ -251,12 +580,153 @@ func (i *EMLStreamResultIterator) Close() {
 	}
 }
 
+// Email header with decoded values
+type EMLHeader struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// Identity of the email in the thread. Message IDs are without angle brackets.
+type EMLThread struct {
+	MessageID  string   `json:"messageId"`
+	InReplyTo  []string `json:"inReplyTo"`
+	References []string `json:"references"`
+}
+
+// ID of the first message in the thread. Equals to MessageID for the messages that are not replies.
+func (t *EMLThread) ThreadID() string {
+	if len(t.References) != 0 {
+		return t.References[0]
+	}
+	if len(t.InReplyTo) != 0 {
+		return t.InReplyTo[0]
+	}
+	return t.MessageID
+}
+
+func emlThread(header message.Header) EMLThread {
+	mailHeader := mail.Header{Header: header}
+	var thread EMLThread
+	thread.MessageID, _ = mailHeader.MessageID()
+	thread.InReplyTo, _ = mailHeader.MsgIDList("In-Reply-To")
+	thread.References, _ = mailHeader.MsgIDList("References")
+	return thread
+}
+
+// Applies configured body cleanups to the blocks. Changed body is split to paragraphs again.
+func (c *emlConfig) cleanBodyBlocks(blocks []Block) []Block {
+	text := blocksString(blocks)
+	if cleaned := c.cleanBody(text); cleaned != text {
+		return textBlocks(cleaned, Location{})
+	}
+	return blocks
+}
+
+// Applies configured body cleanups
+func (c *emlConfig) cleanBody(text string) string {
+	if c.stripQuoted {
+		text = emlStripQuoted(text)
+	}
+	if c.stripSignature {
+		text = emlStripSignature(text)
+	}
+	return text
+}
+
+// Starts of the reply history in the Outlook and other clients
+var emlHistorySeparators = []string{"-----Original Message-----", "----- Original Message -----", "-------- Original Message --------"}
+
+func emlStripQuoted(text string) string {
+	lines := strings.SplitAfter(text, "\n")
+	var result []string
+	for index := 0; index < len(lines); index++ {
+		line := strings.TrimSpace(lines[index])
+		if slices.Contains(emlHistorySeparators, line) || emlIsReplyHeader(lines, index) {
+			break
+		}
+		if strings.HasPrefix(line, ">") {
+			continue
+		}
+		if strings.HasSuffix(line, "wrote:") && emlNextIsQuote(lines, index+1) {
+			// Long attributions are wrapped to the second line
+			if !strings.HasPrefix(line, "On ") && len(result) != 0 && strings.HasPrefix(strings.TrimSpace(result[len(result)-1]), "On ") {
+				result = result[:len(result)-1]
+			}
+			continue
+		}
+		result = append(result, lines[index])
+	}
+	return emlTrimTrailingLines(result)
+}
+
+// Outlook puts "From:" and "Sent:" lines, separated from the reply by the line of underscores or nothing at all
+func emlIsReplyHeader(lines []string, index int) bool {
+	line := strings.TrimSpace(lines[index])
+	if strings.Trim(line, "_") == "" && len(line) >= 10 {
+		return index+1 < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[index+1]), "From:")
+	}
+	if !strings.HasPrefix(line, "From:") || index+1 >= len(lines) {
+		return false
+	}
+	if index > 0 && strings.Contains(strings.ToLower(lines[index-1]), "forwarded message") {
+		return false
+	}
+	return strings.HasPrefix(strings.TrimSpace(lines[index+1]), "Sent:")
+}
+
+// True if next non empty line is quoted
+func emlNextIsQuote(lines []string, index int) bool {
+	for ; index < len(lines); index++ {
+		line := strings.TrimSpace(lines[index])
+		if line != "" {
+			return strings.HasPrefix(line, ">")
+		}
+	}
+	return false
+}
+
+func emlStripSignature(text string) string {
+	lines := strings.SplitAfter(text, "\n")
+	for index, line := range lines {
+		if strings.TrimRight(line, "\r\n") == "-- " || strings.TrimSpace(line) == "--" {
+			lines = lines[:index]
+			break
+		}
+	}
+	for len(lines) != 0 {
+		last := strings.TrimSpace(lines[len(lines)-1])
+		if last != "" && !strings.HasPrefix(last, "Sent from my ") && !strings.HasPrefix(last, "Get Outlook for ") {
+			break
+		}
+		lines = lines[:len(lines)-1]
+	}
+	return emlTrimTrailingLines(lines)
+}
+
+// Joins lines back without trailing empty lines
+func emlTrimTrailingLines(lines []string) string {
+	for len(lines) != 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	text := strings.Join(lines, "")
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return text
+}
+
 type EMLParserResult struct {
-	FullPath    string              `json:"path"`
-	Headers     map[string][]string `json:"headers"`
-	Text        string              `json:"text"`
-	Err         error               `json:"error"`
-	Attachments []Result            `json:"attachments"`
+	FullPath string      `json:"path"`
+	Headers  []EMLHeader `json:"headers"`
+	Thread   EMLThread   `json:"thread"`
+	Text     string      `json:"text"`
+	// Paragraphs of the body and blocks of the HTML body
+	Blocks      []Block  `json:"blocks"`
+	Err         error    `json:"error"`
+	Attachments []Result `json:"attachments"`
 }
 
 func (r *EMLParserResult) Path() string {
@@ -268,14 +738,14 @@ func (r *EMLParserResult) String() string {
 
 	if len(r.Headers) > 0 {
 		result.WriteString("----- Headers -----\n")
-		for key, val := range r.Headers {
-			result.WriteString(fmt.Sprintf("%s: %s\n", key, strings.Join(val, ", ")))
+		for _, header := range r.Headers {
+			result.WriteString(fmt.Sprintf("%s: %s\n", header.Name, strings.Join(header.Values, ", ")))
 		}
 	}
 
 	if r.Text != "" {
 		result.WriteString("----- Body -----\n")
-		result.WriteString(r.Text)
+		result.WriteString(r.Document().String())
 	}
 
 	if len(r.Attachments) != 0 {
@@ -290,6 +760,36 @@ func (r *EMLParserResult) String() string {
 	return result.String()
 }
 
+// Headers of the email. Values of the repeated headers are joined.
+func (r *EMLParserResult) MetadataFields() []MetadataField {
+	fields := make([]MetadataField, 0, len(r.Headers))
+	for _, header := range r.Headers {
+		fields = append(fields, MetadataField{Name: header.Name, Value: strings.Join(header.Values, ", ")})
+	}
+	return fields
+}
+
+// Body of the email with inline attachments. Offsets are in the `Text`.
+func (r *EMLParserResult) Document() *Document {
+	if r.Blocks == nil && r.Text != "" {
+		return &Document{Blocks: textBlocks(r.Text, Location{})}
+	}
+	return &Document{Blocks: r.Blocks}
+}
+
+// Adds blocks of the body part or inline attachment. Parts are separated with line break.
+func (r *EMLParserResult) appendBlocks(blocks []Block) {
+	if len(blocks) == 0 {
+		return
+	}
+	if r.Text != "" {
+		r.Text += "\n"
+		appendSeparator(r.Blocks, "\n")
+	}
+	r.Blocks = append(r.Blocks, moveBlocks(blocks, int64(len(r.Text)))...)
+	r.Text += blocksString(blocks)
+}
+
 func (r *EMLParserResult) Error() error {
 	return r.Err
 }
@@ -298,14 +798,23 @@ func (r *EMLParserResult) Subfiles() []Result {
 	return r.Attachments
 }
 
+// Copy of the result with other subfiles in the same order, so they are rendered in its text
+func (r *EMLParserResult) withSubfiles(subfiles []Result) Result {
+	result := *r
+	result.Attachments = subfiles
+	return &result
+}
+
 type EMLParserStreamResult struct {
-	FullPath          string              `json:"path"`
-	Text              string              `json:"text"`
-	CurrentStage      ParseProgressStage  `json:"stage"`
-	Headers           map[string][]string `json:"headers"`
-	CurrentPartHeader mail.PartHeader     `json:"subResultHeader"`
-	CurrentPart       StreamResult        `json:"subResult"`
-	Err               error               `json:"error"`
+	FullPath     string             `json:"path"`
+	Text         string             `json:"text"`
+	CurrentStage ParseProgressStage `json:"stage"`
+	Headers      []EMLHeader        `json:"headers"`
+	// Sent once with the first update
+	Thread            *EMLThread      `json:"thread"`
+	CurrentPartHeader mail.PartHeader `json:"subResultHeader"`
+	CurrentPart       StreamResult    `json:"subResult"`
+	Err               error           `json:"error"`
 }
 
 func (r *EMLParserStreamResult) Path() string {
@@ -329,10 +838,10 @@ func (r *EMLParserStreamResult) String() string {
 
 	if len(r.Headers) != 0 {
 		result.WriteString("------ Headers start------\n")
-		for header, vals := range r.Headers {
-			result.WriteString(header)
+		for _, header := range r.Headers {
+			result.WriteString(header.Name)
 			result.WriteString(": ")
-			result.WriteString(strings.Join(vals, ", "))
+			result.WriteString(strings.Join(header.Values, ", "))
 			result.WriteString("\n")
 		}
 		result.WriteString("------ Headers end------\n\n")
@@ -0,0 +1,167 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	testdata "github.com/opengs/file2llm/test_data"
+)
+
+func TestEML(t *testing.T) {
+	emlParser := NewEMLParser(NewCompositeParser(NewCSVParser()))
+	result := emlParser.Parse(context.Background(), bytes.NewReader(testdata.EML), "mail.eml")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+
+	expected := "----- Headers -----\n" +
+		"From: Jane Doe <jane@example.com>\n" +
+		"To: John Smith <john@example.org>, Anna Brown <anna@example.org>\n" +
+		"Cc: team@example.org\n" +
+		"Date: Tue, 2 Jan 2024 10:00:00 +0000\n" +
+		"Subject: Quarterly report ✓\n" +
+		"----- Body -----\n" +
+		"Hello team,\r\nrevenue grew by 12% this quarter.\r\n"
+	if !strings.HasPrefix(result.String(), expected) {
+		t.Errorf("unexpected result:\n%q", result.String())
+	}
+	if strings.Contains(result.String(), "<b>") || strings.Count(result.String(), "revenue grew") != 1 {
+		t.Error("HTML alternative is included together with plain text")
+	}
+
+	if len(result.Subfiles()) != 1 || result.Subfiles()[0].Path() != "mail.eml/numbers.csv" {
+		t.Fatalf("unexpected attachments: %v", result.Subfiles())
+	}
+	if !strings.Contains(result.String(), "--- Attachment mail.eml/numbers.csv ---\n") || !strings.Contains(result.String(), "north") {
+		t.Error(result.String())
+	}
+
+	// Output is the same on every run
+	for range 10 {
+		if again := emlParser.Parse(context.Background(), bytes.NewReader(testdata.EML), "mail.eml"); again.String() != result.String() {
+			t.Fatal("result is not deterministic")
+		}
+	}
+}
+
+func TestEMLHeaders(t *testing.T) {
+	emlParser := NewEMLParser(NewCompositeParser(), WithEMLHeaders("Subject", "Message-ID", "X-Missing"))
+	result := emlParser.Parse(context.Background(), bytes.NewReader(testdata.EML), "mail.eml").(*EMLParserResult)
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+
+	if !strings.HasPrefix(result.String(), "----- Headers -----\nSubject: Quarterly report ✓\nMessage-ID: <report-1@example.com>\n----- Body -----\n") {
+		t.Errorf("unexpected result:\n%q", result.String())
+	}
+}
+
+func TestEMLHTMLBody(t *testing.T) {
+	emlParser := NewEMLParser(NewCompositeParser())
+	result := emlParser.Parse(context.Background(), bytes.NewReader(testdata.EMLHTML), "mail.eml")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+
+	if !strings.HasSuffix(result.String(), "----- Body -----\n# Café menu\n\n- Espresso\n- Croissant\n") {
+		t.Errorf("unexpected result:\n%q", result.String())
+	}
+	if len(result.Subfiles()) != 0 {
+		t.Errorf("unexpected attachments: %v", result.Subfiles())
+	}
+}
+
+func TestEMLStream(t *testing.T) {
+	emlParser := NewEMLParser(NewCompositeParser(NewCSVParser()))
+
+	hasNewStage := false
+	hasCompletedStage := false
+	var text strings.Builder
+	var attachments []string
+
+	parseProgress := emlParser.ParseStream(context.Background(), bytes.NewReader(testdata.EML), "mail.eml")
+	defer parseProgress.Close()
+	for parseProgress.Next(t.Context()) {
+		progress := parseProgress.Current()
+		if progress.Error() != nil {
+			t.Fatal(progress.Error())
+		}
+		hasNewStage = hasNewStage || (progress.Stage() == ProgressNew)
+		hasCompletedStage = hasCompletedStage || (progress.Stage() == ProgressCompleted)
+		text.WriteString(progress.String())
+		if sub := progress.SubResult(); sub != nil && sub.Stage() == ProgressCompleted {
+			attachments = append(attachments, sub.Path())
+		}
+	}
+	if !hasNewStage || !hasCompletedStage {
+		t.Fail()
+	}
+
+	if !strings.HasPrefix(text.String(), "------ Headers start------\nFrom: Jane Doe <jane@example.com>\nTo:") ||
+		strings.Count(text.String(), "revenue grew") != 1 {
+		t.Errorf("unexpected result:\n%q", text.String())
+	}
+	if len(attachments) != 1 || attachments[0] != "mail.eml/numbers.csv" {
+		t.Errorf("unexpected attachments: %v", attachments)
+	}
+}
+
+func TestEMLNestedMessages(t *testing.T) {
+	emlParser := NewEMLParser(NewCompositeParser())
+	result := emlParser.Parse(context.Background(), bytes.NewReader(testdata.EMLThread), "mail.eml").(*EMLParserResult)
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+
+	if result.Thread.MessageID != "reply-2@example.org" || !slices.Equal(result.Thread.InReplyTo, []string{"report-1@example.com"}) ||
+		result.Thread.ThreadID() != "report-1@example.com" {
+		t.Errorf("unexpected thread: %+v", result.Thread)
+	}
+
+	// Forwarded message without disposition is the part of the body
+	for _, expected := range []string{
+		"--- Inline attachment begin: mail.eml/message_1.eml ---\n----- Headers -----\nFrom: Anna Brown <anna@example.org>\n",
+		"Subject: Regional numbers\n----- Body -----\nNorth region closed the quarter at 120.\r\n",
+	} {
+		if !strings.Contains(result.Text, expected) {
+			t.Errorf("missing %q in:\n%s", expected, result.Text)
+		}
+	}
+
+	if len(result.Attachments) != 1 {
+		t.Fatalf("unexpected attachments: %v", result.Attachments)
+	}
+	attached, ok := result.Attachments[0].(*EMLParserResult)
+	if !ok || attached.Path() != "mail.eml/original.eml" || attached.Thread.MessageID != "report-1@example.com" {
+		t.Errorf("attached message is not parsed as email: %+v", result.Attachments[0])
+	}
+}
+
+func TestEMLStripQuoted(t *testing.T) {
+	emlParser := NewEMLParser(NewCompositeParser(), WithEMLHeaders(), WithEMLStripQuoted(true))
+	result := emlParser.Parse(context.Background(), bytes.NewReader(testdata.EMLThread), "mail.eml").(*EMLParserResult)
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+	if !strings.HasPrefix(result.Text, "Thanks Jane, the numbers look good.\r\n\r\n-- \r\nJohn Smith\r\nSales department\r\n\n--- Inline") {
+		t.Errorf("unexpected body:\n%q", result.Text)
+	}
+
+	emlParser = NewEMLParser(NewCompositeParser(), WithEMLHeaders(), WithEMLStripQuoted(true), WithEMLStripSignature(true))
+	result = emlParser.Parse(context.Background(), bytes.NewReader(testdata.EMLThread), "mail.eml").(*EMLParserResult)
+	if !strings.HasPrefix(result.Text, "Thanks Jane, the numbers look good.\r\n\n--- Inline") {
+		t.Errorf("unexpected body:\n%q", result.Text)
+	}
+
+	outlook := "Sounds good.\n\nSent from my iPhone\n\n________________________________\nFrom: Jane Doe\nSent: Tuesday, January 2, 2024\nSubject: Report\n\nHello team\n"
+	if stripped := emlStripSignature(emlStripQuoted(outlook)); stripped != "Sounds good.\n" {
+		t.Errorf("unexpected body:\n%q", stripped)
+	}
+	forwarded := "See below.\n\n---------- Forwarded message ---------\nFrom: Jane Doe\nSent: Tuesday\n\nHello team\n"
+	if stripped := emlStripQuoted(forwarded); stripped != forwarded {
+		t.Errorf("forwarded message is removed:\n%q", stripped)
+	}
+}
@@ -0,0 +1,480 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	pathlib "path"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Parses `application/epub+zip` e-books. Chapters are read in the spine order and converted to Markdown like text.
+type EPUBParser struct {
+}
+
+func NewEPUBParser() *EPUBParser {
+	return &EPUBParser{}
+}
+
+func (p *EPUBParser) SupportedMimeTypes() []string {
+	return []string{"application/epub+zip"}
+}
+
+func (p *EPUBParser) Parse(ctx context.Context, file io.Reader, path string) Result {
+	book, err := openEPUB(ctx, file, path)
+	if err != nil {
+		return &EPUBParserResult{Err: err, FullPath: path}
+	}
+
+	result := &EPUBParserResult{
+		FullPath: path,
+		Metadata: book.metadata,
+	}
+	for index := range book.spine {
+		if ctx.Err() != nil {
+			result.Err = ctx.Err()
+			return result
+		}
+
+		chapter, err := book.chapter(index)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		if chapter.Text != "" {
+			chapter.Number = len(result.Chapters) + 1
+			result.Chapters = append(result.Chapters, chapter)
+		}
+	}
+
+	return result
+}
+
+func (p *EPUBParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
+	return &EPUBStreamResultIterator{
+		file: file,
+		path: path,
+	}
+}
+
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath  string `xml:"full-path,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type epubPackage struct {
+	Metadata struct {
+		Titles      []string `xml:"title"`
+		Creators    []string `xml:"creator"`
+		Languages   []string `xml:"language"`
+		Publishers  []string `xml:"publisher"`
+		Dates       []string `xml:"date"`
+		Identifiers []struct {
+			Scheme string `xml:"scheme,attr"`
+			Value  string `xml:",chardata"`
+		} `xml:"identifier"`
+	} `xml:"metadata"`
+	Manifest []struct {
+		ID        string `xml:"id,attr"`
+		Href      string `xml:"href,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// Book metadata fields in the order they are printed
+var epubMetadataFields = []string{"Title", "Authors", "Language", "ISBN", "Publisher", "Date"}
+
+type epubBook struct {
+	pkg      *ooxmlPackage
+	metadata map[string]string
+	// Part names of the chapters in the reading order
+	spine []string
+}
+
+// EPUB is a zip container, so it is opened same way as OOXML package. Root file location is taken from `META-INF/container.xml`.
+func openEPUB(ctx context.Context, file io.Reader, path string) (*epubBook, error) {
+	pkg, err := openOOXMLPackage(ctx, file, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var container epubContainer
+	if err := pkg.readXML("META-INF/container.xml", &container); err != nil {
+		return nil, errors.Join(ErrBadFile, errors.New("failed to read container"), err)
+	}
+	opfName := ""
+	for _, rootfile := range container.Rootfiles {
+		if rootfile.MediaType == "" || rootfile.MediaType == "application/oebps-package+xml" {
+			opfName = strings.TrimPrefix(rootfile.FullPath, "/")
+			break
+		}
+	}
+	if opfName == "" {
+		return nil, errors.Join(ErrBadFile, errors.New("container doesnt have package document"))
+	}
+
+	var opf epubPackage
+	if err := pkg.readXML(opfName, &opf); err != nil {
+		return nil, errors.Join(ErrBadFile, errors.New("failed to read package document"), err)
+	}
+
+	book := &epubBook{
+		pkg:      pkg,
+		metadata: epubMetadata(&opf),
+	}
+
+	manifest := make(map[string]string, len(opf.Manifest))
+	for _, item := range opf.Manifest {
+		if item.MediaType != "application/xhtml+xml" && item.MediaType != "text/html" {
+			continue
+		}
+		href, err := url.PathUnescape(item.Href)
+		if err != nil {
+			href = item.Href
+		}
+		href, _, _ = strings.Cut(href, "#")
+		manifest[item.ID] = pathlib.Join(pathlib.Dir(opfName), href)
+	}
+	for _, itemRef := range opf.Spine {
+		if name, ok := manifest[itemRef.IDRef]; ok {
+			book.spine = append(book.spine, name)
+		}
+	}
+
+	return book, nil
+}
+
+func epubMetadata(opf *epubPackage) map[string]string {
+	metadata := make(map[string]string)
+	set := func(key string, values []string) {
+		var cleaned []string
+		for _, value := range values {
+			if value = strings.Join(strings.Fields(value), " "); value != "" {
+				cleaned = append(cleaned, value)
+			}
+		}
+		if len(cleaned) != 0 {
+			metadata[key] = strings.Join(cleaned, ", ")
+		}
+	}
+
+	if len(opf.Metadata.Titles) != 0 {
+		set("Title", opf.Metadata.Titles[:1])
+	}
+	set("Authors", opf.Metadata.Creators)
+	set("Language", opf.Metadata.Languages)
+	set("Publisher", opf.Metadata.Publishers)
+	if len(opf.Metadata.Dates) != 0 {
+		set("Date", opf.Metadata.Dates[:1])
+	}
+	for _, identifier := range opf.Metadata.Identifiers {
+		if isbn, ok := epubISBN(identifier.Scheme, identifier.Value); ok {
+			metadata["ISBN"] = isbn
+			break
+		}
+	}
+
+	return metadata
+}
+
+// Recognizes ISBN identifiers marked with scheme, `urn:isbn:` prefix or just looking like ISBN
+func epubISBN(scheme string, value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(scheme, "isbn") {
+		return value, value != ""
+	}
+
+	lowerValue := strings.ToLower(value)
+	if strings.HasPrefix(lowerValue, "urn:isbn:") {
+		return value[len("urn:isbn:"):], true
+	}
+	if strings.HasPrefix(lowerValue, "isbn:") {
+		return strings.TrimSpace(value[len("isbn:"):]), true
+	}
+
+	digits := strings.NewReplacer("-", "", " ", "").Replace(value)
+	for i, c := range digits {
+		if (c < '0' || c > '9') && !(i == len(digits)-1 && (c == 'X' || c == 'x')) {
+			return "", false
+		}
+	}
+	if len(digits) == 13 && (strings.HasPrefix(digits, "978") || strings.HasPrefix(digits, "979")) || len(digits) == 10 {
+		return value, true
+	}
+	return "", false
+}
+
+// Reads and converts chapter with the index in the spine
+func (b *epubBook) chapter(index int) (EPUBChapter, error) {
+	name := b.spine[index]
+	chapter := EPUBChapter{Path: name}
+
+	r, err := b.pkg.open(name)
+	if err != nil {
+		return chapter, errors.Join(ErrBadFile, fmt.Errorf("failed to open chapter %s", name), err)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return chapter, errors.Join(ErrBadFile, fmt.Errorf("failed to read chapter %s", name), err)
+	}
+
+	root, err := html.Parse(bytes.NewReader(decodeHTML(data, "")))
+	if err != nil {
+		return chapter, errors.Join(ErrBadFile, fmt.Errorf("failed to parse chapter %s", name), err)
+	}
+
+	chapter.Title = epubChapterTitle(root)
+	chapter.Blocks = newHTMLConverter().convert(root)
+	chapter.Text = blocksString(chapter.Blocks)
+	return chapter, nil
+}
+
+// Chapter title is the first heading. Title of the document is used only when there are no headings because it often repeats book title.
+func epubChapterTitle(root *html.Node) string {
+	var heading *html.Node
+	var find func(n *html.Node)
+	find = func(n *html.Node) {
+		for child := n.FirstChild; child != nil && heading == nil; child = child.NextSibling {
+			if child.Type == html.ElementNode && (child.DataAtom == atom.H1 || child.DataAtom == atom.H2 || child.DataAtom == atom.H3) {
+				heading = child
+				return
+			}
+			find(child)
+		}
+	}
+	find(root)
+
+	if heading != nil {
+		if title := strings.Join(strings.Fields(htmlNodeText(heading)), " "); title != "" {
+			return title
+		}
+	}
+	return htmlMetadata(root)["Title"]
+}
+
+type EPUBChapter struct {
+	// Position of the chapter in the reading order starting from 1. Spine items without text are not counted.
+	Number int `json:"number"`
+	// Path of the chapter inside of the book
+	Path  string `json:"path"`
+	Title string `json:"title"`
+	Text  string `json:"text"`
+	// Headings, paragraphs, lists, tables and images of the text
+	Blocks []Block `json:"blocks"`
+}
+
+func (c *EPUBChapter) String() string {
+	return blocksString(c.blocks())
+}
+
+// Chapter label followed by the blocks of the chapter text
+func (c *EPUBChapter) blocks() []Block {
+	location := Location{Page: c.Number, Offset: -1}
+	label := fmt.Sprintf("Chapter %d", c.Number)
+	if c.Title != "" {
+		label += ": " + c.Title
+	}
+
+	content := pageBlocks(c.Blocks, location)
+	if c.Blocks == nil {
+		content = textBlocks(c.Text, location)
+	}
+	blocks := append([]Block{{Kind: BlockPageBreak, Text: label, Location: location, Separator: "\n"}}, content...)
+	appendSeparator(blocks, "\n\n")
+	return blocks
+}
+
+func epubMetadataString(metadata map[string]string) string {
+	if len(metadata) == 0 {
+		return ""
+	}
+	return metadataString(metadataFields(metadata, epubMetadataFields))
+}
+
+type EPUBStreamResultIterator struct {
+	file io.Reader
+	path string
+
+	started      bool
+	completed    bool
+	book         *epubBook
+	chapterIndex int
+	// Number of emitted chapters
+	chapterNumber int
+
+	current StreamResult
+}
+
+func (i *EPUBStreamResultIterator) Next(ctx context.Context) bool {
+	if i.completed {
+		i.current = nil
+		return false
+	}
+
+	if !i.started {
+		i.started = true
+		i.current = &EPUBParserStreamResult{
+			FullPath:     i.path,
+			CurrentStage: ProgressNew,
+		}
+		return true
+	}
+
+	if ctx.Err() != nil {
+		i.current = nil
+		return false
+	}
+
+	if i.book == nil {
+		book, err := openEPUB(ctx, i.file, i.path)
+		if err != nil {
+			return i.fail(err)
+		}
+		i.book = book
+
+		if metadata := epubMetadataString(book.metadata); metadata != "" {
+			i.current = &EPUBParserStreamResult{
+				FullPath:     i.path,
+				CurrentStage: ProgressUpdate,
+				Text:         metadata,
+			}
+			return true
+		}
+	}
+
+	for i.chapterIndex < len(i.book.spine) {
+		chapter, err := i.book.chapter(i.chapterIndex)
+		if err != nil {
+			return i.fail(err)
+		}
+		i.chapterIndex += 1
+		if chapter.Text == "" {
+			continue
+		}
+		i.chapterNumber += 1
+		chapter.Number = i.chapterNumber
+
+		i.current = &EPUBParserStreamResult{
+			FullPath:        i.path,
+			CurrentStage:    ProgressUpdate,
+			CurrentProgress: uint8(float64(i.chapterIndex) / float64(len(i.book.spine)) * 100),
+			Chapter:         &chapter,
+			Text:            chapter.String(),
+		}
+		return true
+	}
+
+	i.completed = true
+	i.current = &EPUBParserStreamResult{
+		FullPath:        i.path,
+		CurrentStage:    ProgressCompleted,
+		CurrentProgress: 100,
+	}
+	return true
+}
+
+func (i *EPUBStreamResultIterator) fail(err error) bool {
+	i.completed = true
+	i.current = &EPUBParserStreamResult{
+		FullPath:     i.path,
+		CurrentStage: ProgressCompleted,
+		Err:          err,
+	}
+	return true
+}
+
+func (i *EPUBStreamResultIterator) Current() StreamResult {
+	return i.current
+}
+
+func (i *EPUBStreamResultIterator) Close() {
+}
+
+type EPUBParserResult struct {
+	FullPath string `json:"path"`
+	// Book information like title, authors and ISBN
+	Metadata map[string]string `json:"metadata"`
+	Chapters []EPUBChapter     `json:"chapters"`
+	Err      error             `json:"error"`
+}
+
+func (r *EPUBParserResult) Path() string {
+	return r.FullPath
+}
+
+func (r *EPUBParserResult) String() string {
+	var result strings.Builder
+
+	result.WriteString(epubMetadataString(r.Metadata))
+	result.WriteString(r.Document().String())
+
+	return result.String()
+}
+
+// Chapters of the book. Every chapter begins with page break.
+func (r *EPUBParserResult) Document() *Document {
+	document := &Document{}
+	for i := range r.Chapters {
+		document.Blocks = append(document.Blocks, r.Chapters[i].blocks()...)
+	}
+	return document
+}
+
+func (r *EPUBParserResult) MetadataFields() []MetadataField {
+	return metadataFields(r.Metadata, epubMetadataFields)
+}
+
+func (r *EPUBParserResult) Error() error {
+	return r.Err
+}
+
+func (r *EPUBParserResult) Subfiles() []Result {
+	return nil
+}
+
+type EPUBParserStreamResult struct {
+	FullPath        string             `json:"path"`
+	CurrentStage    ParseProgressStage `json:"stage"`
+	CurrentProgress uint8              `json:"progress"`
+	// Chapter emitted in this update. Nil for the metadata update.
+	Chapter *EPUBChapter `json:"chapter"`
+	Text    string       `json:"text"`
+	Err     error        `json:"error"`
+}
+
+func (r *EPUBParserStreamResult) Path() string {
+	return r.FullPath
+}
+
+func (r *EPUBParserStreamResult) Stage() ParseProgressStage {
+	return r.CurrentStage
+}
+
+func (r *EPUBParserStreamResult) Progress() uint8 {
+	return r.CurrentProgress
+}
+
+func (r *EPUBParserStreamResult) SubResult() StreamResult {
+	return nil
+}
+
+func (r *EPUBParserStreamResult) String() string {
+	return r.Text
+}
+
+func (r *EPUBParserStreamResult) Error() error {
+	return r.Err
+}
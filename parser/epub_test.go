@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	testdata "github.com/opengs/file2llm/test_data"
+)
+
+func TestEPUB(t *testing.T) {
+	epubParser := NewCompositeParser(NewEPUBParser())
+	result := epubParser.Parse(context.Background(), bytes.NewReader(testdata.EPUB), "manual.epub")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+
+	epubResult := result.(*CompositeParserResult).Inner.(*EPUBParserResult)
+	expectedMetadata := map[string]string{
+		"Title":     "Pump Maintenance Manual",
+		"Authors":   "Jane Doe, John Smith",
+		"Language":  "en",
+		"ISBN":      "9780306406157",
+		"Publisher": "Example Press",
+	}
+	for key, value := range expectedMetadata {
+		if epubResult.Metadata[key] != value {
+			t.Errorf("expected %s to be %q, got %q", key, value, epubResult.Metadata[key])
+		}
+	}
+
+	// Cover without text is skipped and chapters follow spine order, not manifest order
+	if len(epubResult.Chapters) != 2 {
+		t.Fatalf("expected 2 chapters, got %d", len(epubResult.Chapters))
+	}
+	first, second := epubResult.Chapters[0], epubResult.Chapters[1]
+	if first.Number != 1 || first.Title != "Introduction" || first.Path != "OEBPS/text/chapter1.xhtml" {
+		t.Errorf("unexpected first chapter: %+v", first)
+	}
+	if first.Text != "# Introduction\n\nThis manual describes maintenance of the pump.\n\n## Safety\n\n- Disconnect power\n- Release pressure" {
+		t.Errorf("unexpected first chapter text: %q", first.Text)
+	}
+	if second.Title != "Replacing the seal" || !strings.Contains(second.Text, "1. Remove the cover\n2. Replace the seal") || !strings.Contains(second.Text, "| Bolt M8 | 25 Nm |") {
+		t.Errorf("unexpected second chapter: %+v", second)
+	}
+
+	if !strings.HasPrefix(result.String(), "------ Metadata ------\nTitle: Pump Maintenance Manual\nAuthors: Jane Doe, John Smith\nLanguage: en\nISBN: 9780306406157\n") ||
+		!strings.Contains(result.String(), "------ Chapter 2: Replacing the seal ------\n") {
+		t.Error(result.String())
+	}
+}
+
+func TestEPUBStream(t *testing.T) {
+	epubParser := NewEPUBParser()
+	expected := epubParser.Parse(context.Background(), bytes.NewReader(testdata.EPUB), "manual.epub").String()
+
+	stream := epubParser.ParseStream(context.Background(), bytes.NewReader(testdata.EPUB), "manual.epub")
+	defer stream.Close()
+
+	var text strings.Builder
+	var last StreamResult
+	chapters := 0
+	for stream.Next(context.Background()) {
+		last = stream.Current()
+		if last.Error() != nil {
+			t.Fatal(last.Error())
+		}
+		if last.(*EPUBParserStreamResult).Chapter != nil {
+			chapters += 1
+		}
+		text.WriteString(last.String())
+	}
+
+	if last == nil || last.Stage() != ProgressCompleted {
+		t.Errorf("stream is not completed")
+	}
+	if chapters != 2 {
+		t.Errorf("expected update for every chapter, got %d", chapters)
+	}
+	if text.String() != expected {
+		t.Errorf("stream text differs from the parse result:\n%s", text.String())
+	}
+}
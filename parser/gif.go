@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 
 	"image/gif"
@@ -12,22 +13,29 @@ import (
 	"github.com/opengs/file2llm/ocr"
 )
 
-// Parses `image/gif` files. Only decodes first frame
+// Parses `image/gif` files. Every frame of the animation is recognized, near identical frames are skipped
 type GIFParser struct {
 	ocrProvider ocr.Provider
+	config      imageConfig
 }
 
-func NewGIFParser(ocrProvider ocr.Provider) *GIFParser {
+func NewGIFParser(ocrProvider ocr.Provider, options ...ImageOption) *GIFParser {
 	return &GIFParser{
 		ocrProvider: ocrProvider,
+		config:      newImageConfig(options),
 	}
 }
 
+// Configuration that changes the result. Used in the cache keys.
+func (p *GIFParser) CacheFingerprint() string {
+	return fmt.Sprintf("ocr=%s %+v", cacheFingerprint(p.ocrProvider), p.config)
+}
+
 func (p *GIFParser) SupportedMimeTypes() []string {
 	return []string{"image/gif"}
 }
 
-func (p *GIFParser) prepareData(file io.Reader) (io.Reader, error) {
+func (p *GIFParser) prepareData(file io.Reader) (io.Reader, *ImageMetadata, error) {
 	var imageData io.Reader
 
 	if p.ocrProvider.IsMimeTypeSupported("image/gif") {
@@ -35,21 +43,34 @@ func (p *GIFParser) prepareData(file io.Reader) (io.Reader, error) {
 	} else {
 		img, err := gif.Decode(file)
 		if err != nil {
-			return nil, errors.Join(ErrBadFile, errors.New("failed to decode gif image for transcoding"), err)
+			return nil, nil, errors.Join(ErrBadFile, errors.New("failed to decode gif image for transcoding"), err)
 		}
 
 		var outBuf bytes.Buffer
 		if err := png.Encode(&outBuf, img); err != nil {
-			return nil, errors.Join(errors.New("failed to transcode image to PNG"), err)
+			return nil, nil, errors.Join(errors.New("failed to transcode image to PNG"), err)
 		}
 		imageData = &outBuf
 	}
 
-	return imageData, nil
+	return imageData, nil, nil
+}
+
+// Decodes only frames that can be sent to OCR
+func (p *GIFParser) decodeFrames(data []byte) (imageFrames, error) {
+	return decodeGIFFrames(data, p.config.maxFrames)
 }
 
 func (p *GIFParser) Parse(ctx context.Context, file io.Reader, path string) Result {
-	imageData, err := p.prepareData(file)
+	data, frames, err := readImageFrames(file, p.decodeFrames)
+	if err != nil {
+		return &ImageParserResult{Err: err, FullPath: path}
+	}
+	if frames != nil {
+		return parseImageFrames(ctx, p.ocrProvider, p.config, frames, nil, path)
+	}
+
+	imageData, _, err := p.prepareData(bytes.NewReader(data))
 	if err != nil {
 		return &ImageParserResult{Err: errors.Join(errors.New("failed to prepare image data"), err), FullPath: path}
 	}
@@ -63,11 +84,22 @@ func (p *GIFParser) Parse(ctx context.Context, file io.Reader, path string) Resu
 }
 
 func (p *GIFParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
-	return &ImageStreamResultIterator{
-		path:             path,
-		file:             file,
-		imagePreparation: p.prepareData,
-		ocrProvider:      p.ocrProvider,
-		baseContext:      ctx,
+	return &ImageFramesStreamResultIterator{
+		path:        path,
+		mimeType:    "image/gif",
+		ocrProvider: p.ocrProvider,
+		config:      p.config,
+		file:        file,
+		decode:      p.decodeFrames,
+		single: func(file io.Reader) StreamResultIterator {
+			return &ImageStreamResultIterator{
+				path:             path,
+				file:             file,
+				imagePreparation: p.prepareData,
+				ocrProvider:      p.ocrProvider,
+				baseContext:      ctx,
+			}
+		},
+		baseContext: ctx,
 	}
 }
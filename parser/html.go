@@ -0,0 +1,1019 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	pathlib "path"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+)
+
+// Parses `text/html` and `application/xhtml+xml` files into Markdown like text. Inline `data:` images are parsed with inner parser. Pass nil inner parser to ignore them.
+type HTMLParser struct {
+	innerParser Parser
+}
+
+func NewHTMLParser(innerParser Parser) *HTMLParser {
+	return &HTMLParser{
+		innerParser: innerParser,
+	}
+}
+
+func (p *HTMLParser) SupportedMimeTypes() []string {
+	return []string{"text/html", "application/xhtml+xml"}
+}
+
+func (p *HTMLParser) Parse(ctx context.Context, file io.Reader, path string) Result {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return &HTMLParserResult{Err: errors.Join(errors.New("failed to read data to the bytes buffer"), err), FullPath: path}
+	}
+
+	root, err := html.Parse(bytes.NewReader(decodeHTML(data, "")))
+	if err != nil {
+		return &HTMLParserResult{Err: errors.Join(ErrBadFile, errors.New("failed to parse html"), err), FullPath: path}
+	}
+
+	result := &HTMLParserResult{
+		FullPath: path,
+		Metadata: htmlMetadata(root),
+	}
+
+	converter := newHTMLConverter()
+	if p.innerParser != nil {
+		converter.parseImage = func(name string, data []byte) Result {
+			imageResult := p.innerParser.Parse(ctx, bytes.NewReader(data), pathlib.Join(path, name))
+			if imageResult.Error() != nil {
+				result.Images = append(result.Images, imageResult)
+			}
+			return imageResult
+		}
+	}
+	result.Blocks = converter.convert(root)
+	result.Text = blocksString(result.Blocks)
+
+	return result
+}
+
+func (p *HTMLParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
+	return &HTMLStreamResultIterator{
+		htmlParser: p,
+		ctx:        ctx,
+		file:       file,
+		path:       path,
+	}
+}
+
+type HTMLStreamResultIterator struct {
+	htmlParser *HTMLParser
+	ctx        context.Context
+	file       io.Reader
+	path       string
+
+	started   bool
+	completed bool
+	result    *HTMLParserResult
+	current   StreamResult
+}
+
+func (i *HTMLStreamResultIterator) Next(ctx context.Context) bool {
+	if i.completed {
+		i.current = nil
+		return false
+	}
+
+	if !i.started {
+		i.started = true
+		i.current = &HTMLParserStreamResult{
+			FullPath:     i.path,
+			CurrentStage: ProgressNew,
+		}
+		return true
+	}
+
+	if i.result == nil {
+		i.result = i.htmlParser.Parse(i.ctx, i.file, i.path).(*HTMLParserResult)
+		if i.result.Err == nil {
+			i.current = &HTMLParserStreamResult{
+				FullPath:        i.path,
+				CurrentStage:    ProgressUpdate,
+				CurrentProgress: 100,
+				Text:            i.result.String(),
+			}
+			return true
+		}
+	}
+
+	i.completed = true
+	i.current = &HTMLParserStreamResult{
+		FullPath:        i.path,
+		CurrentStage:    ProgressCompleted,
+		CurrentProgress: 100,
+		Err:             i.result.Err,
+	}
+	return true
+}
+
+func (i *HTMLStreamResultIterator) Current() StreamResult {
+	return i.current
+}
+
+func (i *HTMLStreamResultIterator) Close() {
+}
+
+// Converts HTML to UTF-8. Encoding is taken from BOM, content type or meta tag. Documents without declared encoding are treated as UTF-8 when valid.
+func decodeHTML(data []byte, contentType string) []byte {
+	enc, name, certain := charset.DetermineEncoding(data, contentType)
+	if !certain && name == "windows-1252" && utf8.Valid(data) {
+		return data
+	}
+	if enc == encoding.Nop {
+		return data
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return data
+	}
+	return bytes.TrimPrefix(decoded, []byte("\xEF\xBB\xBF"))
+}
+
+// Document information fields in the order they are printed
+var htmlMetadataFields = []string{"Title", "Description", "Author", "Keywords"}
+
+// Reads title and meta tags of the document
+func htmlMetadata(root *html.Node) map[string]string {
+	metadata := make(map[string]string)
+	openGraph := make(map[string]string)
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Title:
+				if _, ok := metadata["Title"]; !ok {
+					if title := strings.Join(strings.Fields(htmlNodeText(n)), " "); title != "" {
+						metadata["Title"] = title
+					}
+				}
+				return
+			case atom.Meta:
+				content := strings.Join(strings.Fields(htmlAttr(n, "content")), " ")
+				if content == "" {
+					return
+				}
+				switch strings.ToLower(htmlAttr(n, "name")) {
+				case "description":
+					metadata["Description"] = content
+				case "author":
+					metadata["Author"] = content
+				case "keywords":
+					metadata["Keywords"] = content
+				}
+				switch strings.ToLower(htmlAttr(n, "property")) {
+				case "og:title":
+					openGraph["Title"] = content
+				case "og:description":
+					openGraph["Description"] = content
+				}
+				return
+			case atom.Body:
+				return
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	for key, value := range openGraph {
+		if _, ok := metadata[key]; !ok {
+			metadata[key] = value
+		}
+	}
+
+	return metadata
+}
+
+func htmlAttr(n *html.Node, name string) string {
+	for _, attr := range n.Attr {
+		if attr.Namespace == "" && strings.EqualFold(attr.Key, name) {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func htmlNodeText(n *html.Node) string {
+	var result strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			result.WriteString(n.Data)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return result.String()
+}
+
+// Elements that never contain readable text or contain page boilerplate
+var htmlSkippedElements = map[atom.Atom]bool{
+	atom.Head: true, atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Nav: true, atom.Footer: true, atom.Iframe: true, atom.Object: true, atom.Embed: true,
+	atom.Svg: true, atom.Math: true, atom.Canvas: true, atom.Button: true, atom.Select: true,
+	atom.Input: true, atom.Textarea: true, atom.Dialog: true,
+}
+
+// Elements separated from the surrounding text by line break
+var htmlLineElements = map[atom.Atom]bool{
+	atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true, atom.Header: true,
+	atom.Aside: true, atom.Address: true, atom.Figure: true, atom.Figcaption: true, atom.Dl: true,
+	atom.Dt: true, atom.Dd: true, atom.Form: true, atom.Fieldset: true, atom.Legend: true,
+	atom.Details: true, atom.Summary: true, atom.Center: true, atom.Caption: true, atom.Tr: true,
+	atom.Label: true, atom.Option: true,
+}
+
+// Extensions of the images that can be embedded with `data:` URL
+var htmlImageExtensions = map[string]string{
+	"image/png": ".png", "image/jpeg": ".jpg", "image/jpg": ".jpg", "image/gif": ".gif", "image/webp": ".webp",
+	"image/bmp": ".bmp", "image/tiff": ".tiff", "image/svg+xml": ".svg",
+}
+
+type htmlList struct {
+	ordered bool
+	index   int
+}
+
+// Converts HTML nodes to Markdown like text
+type htmlConverter struct {
+	writer htmlTextWriter
+	lists  []htmlList
+	// Blocks of the top level and open blocks of the elements. Nil is open element that is part of its parent block.
+	spans []*htmlBlockSpan
+	open  []*htmlBlockSpan
+	// Parses image embedded with `data:` URL. Nil if images are ignored.
+	parseImage func(name string, data []byte) Result
+	// Shared between converter and its children created for links and table cells
+	imageIndex *int
+}
+
+func newHTMLConverter() *htmlConverter {
+	return &htmlConverter{
+		writer:     htmlTextWriter{lineStart: true},
+		imageIndex: new(int),
+	}
+}
+
+// Converts children of the node to the single line of text
+func (c *htmlConverter) inlineText(n *html.Node) string {
+	child := &htmlConverter{
+		writer:     htmlTextWriter{lineStart: true},
+		parseImage: c.parseImage,
+		imageIndex: c.imageIndex,
+	}
+	child.children(n)
+	return strings.Join(strings.Fields(child.writer.out.String()), " ")
+}
+
+// Converts node to blocks. Blocks with their separators are the converted text.
+func (c *htmlConverter) convert(n *html.Node) []Block {
+	c.node(n)
+	out := c.writer.out.String()
+	start := len(out) - len(strings.TrimLeftFunc(out, unicode.IsSpace))
+	end := start + len(strings.TrimSpace(out))
+	return htmlBlocks(out, c.spans, start, end, "", start)
+}
+
+// Block written by the HTML element. Offsets are in the output of the writer.
+type htmlBlockSpan struct {
+	block Block
+	// Prefix of the continuation lines, like indentation of the parent list items
+	prefix string
+	// Offset of the first written text. Negative if element has no text.
+	start int
+	end   int
+	// Paragraph is ended by the nested block. Its remaining text becomes separate paragraph.
+	breakable bool
+	ended     bool
+	// Blocks of the list item
+	children []*htmlBlockSpan
+}
+
+// Starts block of the element. Blocks are tracked on the top level and in the list items, other nested elements are part of their parent block.
+func (c *htmlConverter) openBlock(block Block, breakable bool) {
+	parent := &c.spans
+	for index := len(c.open) - 1; index >= 0 && parent != nil; index-- {
+		open := c.open[index]
+		switch {
+		case open == nil:
+			parent = nil
+		case open.ended:
+			continue
+		case open.block.Kind == BlockListItem:
+			parent = &open.children
+		case open.breakable:
+			open.ended = true
+			open.end = c.writer.out.Len()
+			c.writer.dropStart(open)
+			continue
+		default:
+			parent = nil
+		}
+		break
+	}
+
+	if parent == nil {
+		c.open = append(c.open, nil)
+		return
+	}
+	span := &htmlBlockSpan{block: block, prefix: strings.Join(c.writer.prefixes, ""), start: -1, breakable: breakable}
+	*parent = append(*parent, span)
+	c.open = append(c.open, span)
+	c.writer.starts = append(c.writer.starts, span)
+}
+
+func (c *htmlConverter) closeBlock() {
+	span := c.open[len(c.open)-1]
+	c.open = c.open[:len(c.open)-1]
+	if span != nil && !span.ended {
+		span.ended = true
+		span.end = c.writer.out.Len()
+		c.writer.dropStart(span)
+	}
+}
+
+// Builds blocks of the spans between `start` and `end` of the output. Text that is not in the spans is split to paragraphs.
+// Offsets are moved by `lead`, the whitespace trimmed at the beginning of the output.
+func htmlBlocks(out string, spans []*htmlBlockSpan, start int, end int, prefix string, lead int) []Block {
+	var blocks []Block
+	position := start
+	for _, span := range spans {
+		if span.start < position || span.start >= end {
+			continue
+		}
+		blocks = appendHTMLText(blocks, out, position, span.start, prefix, lead)
+		blocks = append(blocks, span.build(out, end, lead))
+		position = min(span.end, end)
+	}
+	return appendHTMLText(blocks, out, position, end, prefix, lead)
+}
+
+// Adds text between the blocks. Whitespace is separator of the previous block, other text is split to paragraphs.
+func appendHTMLText(blocks []Block, out string, start int, end int, prefix string, lead int) []Block {
+	if start >= end {
+		return blocks
+	}
+	text := out[start:end]
+	content := text
+	if len(blocks) != 0 {
+		content = strings.TrimLeftFunc(text, unicode.IsSpace)
+		appendSeparator(blocks, dedentLines(text[:len(text)-len(content)], prefix))
+	}
+	if content == "" {
+		return blocks
+	}
+
+	for _, block := range textBlocks(content, Location{Offset: int64(end - len(content) - lead)}) {
+		block.Text = dedentLines(block.Text, prefix)
+		block.Separator = dedentLines(block.Separator, prefix)
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// Block of the span. Span that does not match its block is kept as paragraph.
+func (s *htmlBlockSpan) build(out string, end int, lead int) Block {
+	end = min(s.end, end)
+	raw := dedentLines(out[s.start:end], s.prefix)
+	block := s.block
+	block.Location = Location{Offset: int64(s.start - lead)}
+
+	switch block.Kind {
+	case BlockHeading:
+		block.Text = strings.TrimPrefix(raw, block.Marker+" ")
+	case BlockImage:
+		_, block.Text, _ = strings.Cut(raw, "\n")
+	case BlockListItem:
+		indent := strings.Repeat(" ", len(block.Marker)+1)
+		children := htmlBlocks(out, s.children, min(s.start+len(indent), end), end, s.prefix+indent, lead)
+		if len(children) == 1 && children[0].Kind == BlockParagraph {
+			block.Text = children[0].Text
+		} else {
+			block.Children = children
+		}
+	default:
+		block.Text = raw
+	}
+
+	if block.String() != raw {
+		return Block{Kind: BlockParagraph, Text: raw, Location: block.Location}
+	}
+	return block
+}
+
+// Removes prefix from the lines after the first one
+func dedentLines(text string, prefix string) string {
+	if prefix == "" {
+		return text
+	}
+	return strings.ReplaceAll(text, "\n"+prefix, "\n")
+}
+
+func (c *htmlConverter) children(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.node(child)
+	}
+}
+
+func (c *htmlConverter) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		c.writer.text(n.Data)
+	case html.ElementNode:
+		c.element(n)
+	case html.DocumentNode:
+		c.children(n)
+	}
+}
+
+func (c *htmlConverter) element(n *html.Node) {
+	if htmlSkippedElements[n.DataAtom] || htmlHidden(n) {
+		return
+	}
+
+	w := &c.writer
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		marker := strings.Repeat("#", level)
+		w.block(2)
+		c.openBlock(Block{Kind: BlockHeading, Level: level, Marker: marker}, false)
+		w.marker(marker + " ")
+		c.children(n)
+		c.closeBlock()
+		w.block(2)
+	case atom.P:
+		w.block(2)
+		c.openBlock(Block{Kind: BlockParagraph}, true)
+		c.children(n)
+		c.closeBlock()
+		w.block(2)
+	case atom.Br:
+		w.lineBreak()
+	case atom.Hr:
+		w.block(2)
+		c.openBlock(Block{Kind: BlockParagraph}, false)
+		w.raw("---")
+		c.closeBlock()
+		w.block(2)
+	case atom.Ul, atom.Ol:
+		c.list(n)
+	case atom.Li:
+		c.listItem(n)
+	case atom.Blockquote:
+		w.block(2)
+		c.openBlock(Block{Kind: BlockParagraph}, false)
+		w.pushPrefix("> ")
+		c.children(n)
+		w.popPrefix()
+		c.closeBlock()
+		w.block(2)
+	case atom.Pre:
+		w.block(2)
+		c.openBlock(Block{Kind: BlockParagraph}, false)
+		w.raw("```")
+		w.block(1)
+		w.pre += 1
+		c.children(n)
+		w.pre -= 1
+		w.block(1)
+		w.raw("```")
+		c.closeBlock()
+		w.block(2)
+	case atom.Code, atom.Kbd, atom.Samp:
+		if w.pre > 0 {
+			c.children(n)
+			return
+		}
+		text := c.inlineText(n)
+		if text != "" {
+			w.raw("`" + text + "`")
+		}
+	case atom.A:
+		c.link(n)
+	case atom.Img:
+		c.image(n)
+	case atom.Table:
+		c.table(n)
+	default:
+		if htmlLineElements[n.DataAtom] {
+			w.block(1)
+			c.children(n)
+			w.block(1)
+		} else {
+			c.children(n)
+		}
+	}
+}
+
+// Hidden elements are not visible for the user
+func htmlHidden(n *html.Node) bool {
+	for _, attr := range n.Attr {
+		switch strings.ToLower(attr.Key) {
+		case "hidden":
+			return true
+		case "aria-hidden":
+			if strings.EqualFold(attr.Val, "true") {
+				return true
+			}
+		case "role":
+			if role := strings.ToLower(attr.Val); role == "navigation" || role == "contentinfo" {
+				return true
+			}
+		case "style":
+			style := strings.ToLower(strings.ReplaceAll(attr.Val, " ", ""))
+			if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *htmlConverter) list(n *html.Node) {
+	list := htmlList{ordered: n.DataAtom == atom.Ol, index: 1}
+	if start, err := strconv.Atoi(htmlAttr(n, "start")); err == nil {
+		list.index = start
+	}
+
+	// Nested lists are separated by single line break
+	newlines := 2
+	if len(c.lists) != 0 {
+		newlines = 1
+	}
+
+	c.writer.block(newlines)
+	c.lists = append(c.lists, list)
+	c.children(n)
+	c.lists = c.lists[:len(c.lists)-1]
+	c.writer.block(newlines)
+}
+
+func (c *htmlConverter) listItem(n *html.Node) {
+	marker := "- "
+	if len(c.lists) != 0 {
+		list := &c.lists[len(c.lists)-1]
+		if list.ordered {
+			marker = fmt.Sprintf("%d. ", list.index)
+			list.index += 1
+		}
+	}
+
+	w := &c.writer
+	w.block(1)
+	c.openBlock(Block{Kind: BlockListItem, Level: max(len(c.lists)-1, 0), Marker: strings.TrimSuffix(marker, " ")}, false)
+	w.marker(marker)
+	w.pushPrefix(strings.Repeat(" ", len(marker)))
+	c.children(n)
+	w.popPrefix()
+	c.closeBlock()
+	w.block(1)
+}
+
+func (c *htmlConverter) link(n *html.Node) {
+	text := c.inlineText(n)
+	if text == "" {
+		return
+	}
+
+	href := strings.TrimSpace(htmlAttr(n, "href"))
+	lowerHref := strings.ToLower(href)
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(lowerHref, "javascript:") || strings.HasPrefix(lowerHref, "data:") ||
+		text == href || "mailto:"+text == href || "tel:"+text == href {
+		c.writer.raw(text)
+		return
+	}
+
+	c.writer.raw(fmt.Sprintf("[%s](%s)", text, href))
+}
+
+func (c *htmlConverter) image(n *html.Node) {
+	src := strings.TrimSpace(htmlAttr(n, "src"))
+	alt := strings.Join(strings.Fields(htmlAttr(n, "alt")), " ")
+
+	if !strings.HasPrefix(strings.ToLower(src), "data:") {
+		if alt != "" {
+			if src != "" {
+				c.writer.raw(fmt.Sprintf("![%s](%s)", alt, src))
+			} else {
+				c.writer.raw(fmt.Sprintf("![%s]", alt))
+			}
+		}
+		return
+	}
+
+	if c.parseImage == nil {
+		return
+	}
+
+	mediaType, data, ok := parseDataURL(src)
+	if !ok || len(data) == 0 {
+		return
+	}
+
+	name := fmt.Sprintf("image_%d%s", *c.imageIndex, htmlImageExtensions[mediaType])
+	*c.imageIndex += 1
+
+	imageResult := c.parseImage(name, data)
+	text := ""
+	if imageResult.Error() == nil {
+		text = strings.TrimSpace(imageResult.String())
+	}
+	if text == "" {
+		if alt != "" {
+			c.writer.raw(fmt.Sprintf("![%s]", alt))
+		}
+	} else {
+		w := &c.writer
+		w.block(2)
+		c.openBlock(Block{Kind: BlockImage, Path: imageResult.Path()}, false)
+		w.raw(fmt.Sprintf("--- Image %s ---", imageResult.Path()))
+		for _, line := range strings.Split(text, "\n") {
+			w.block(1)
+			w.raw(line)
+		}
+		c.closeBlock()
+		w.block(2)
+	}
+}
+
+// Decodes `data:[<mediatype>][;base64],<data>` URL
+func parseDataURL(src string) (string, []byte, bool) {
+	header, payload, ok := strings.Cut(src[len("data:"):], ",")
+	if !ok {
+		return "", nil, false
+	}
+
+	params := strings.Split(header, ";")
+	mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+	isBase64 := false
+	for _, param := range params[1:] {
+		if strings.EqualFold(strings.TrimSpace(param), "base64") {
+			isBase64 = true
+		}
+	}
+
+	if !isBase64 {
+		data, err := url.PathUnescape(payload)
+		if err != nil {
+			return "", nil, false
+		}
+		return mediaType, []byte(data), true
+	}
+
+	payload = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\n' || r == '\r' || r == '\t' {
+			return -1
+		}
+		return r
+	}, payload)
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		data, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(payload, "="))
+		if err != nil {
+			return "", nil, false
+		}
+	}
+	return mediaType, data, true
+}
+
+// Renders table as Markdown table. Layout tables with single column or nested tables are rendered as plain blocks.
+func (c *htmlConverter) table(n *html.Node) {
+	var rows [][]*html.Node
+	layout := false
+
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode || htmlHidden(child) {
+				continue
+			}
+			switch child.DataAtom {
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				collect(child)
+			case atom.Tr:
+				var cells []*html.Node
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+						cells = append(cells, cell)
+						if htmlContainsTable(cell) {
+							layout = true
+						}
+					}
+				}
+				if len(cells) != 0 {
+					rows = append(rows, cells)
+				}
+			}
+		}
+	}
+	collect(n)
+
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+
+	w := &c.writer
+	if layout || columns < 2 {
+		w.block(1)
+		c.children(n)
+		w.block(1)
+		return
+	}
+
+	var lines []string
+	var tableRows [][]string
+	for _, row := range rows {
+		cells := make([]string, columns)
+		escaped := make([]string, columns)
+		empty := true
+		for i, cell := range row {
+			cells[i] = c.inlineText(cell)
+			escaped[i] = strings.ReplaceAll(cells[i], "|", "\\|")
+			if cells[i] != "" {
+				empty = false
+			}
+		}
+		if empty {
+			continue
+		}
+		tableRows = append(tableRows, cells)
+		lines = append(lines, "| "+strings.Join(escaped, " | ")+" |")
+		if len(lines) == 1 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+
+	w.block(2)
+	c.openBlock(Block{Kind: BlockTable, Rows: tableRows}, false)
+	for _, line := range lines {
+		w.block(1)
+		w.raw(strings.ReplaceAll(line, "  ", " "))
+	}
+	c.closeBlock()
+	w.block(2)
+}
+
+func htmlContainsTable(n *html.Node) bool {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && (child.DataAtom == atom.Table || htmlContainsTable(child)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Writes text collapsing whitespaces and keeping requested line breaks between blocks
+type htmlTextWriter struct {
+	out strings.Builder
+	// Prefixes of every line, for example blockquote marker or list item indentation
+	prefixes []string
+	// Number of line breaks to write before next text
+	newlines int
+	// Prefix of the empty lines between blocks
+	breakPrefix string
+	// Whitespace to write before next text
+	space     bool
+	lineStart bool
+	// List or heading marker was just written and next block must stay on the same line
+	afterMarker bool
+	// Inside of preformatted text
+	pre int
+	// Blocks that start with the next written text
+	starts []*htmlBlockSpan
+}
+
+func (w *htmlTextWriter) block(newlines int) {
+	if w.out.Len() == 0 || w.afterMarker {
+		return
+	}
+	if w.newlines == 0 {
+		w.breakPrefix = strings.Join(w.prefixes, "")
+	}
+	w.newlines = max(w.newlines, newlines)
+	w.space = false
+}
+
+func (w *htmlTextWriter) pushPrefix(prefix string) {
+	w.prefixes = append(w.prefixes, prefix)
+}
+
+// Pending empty lines after the block are written without its prefix
+func (w *htmlTextWriter) popPrefix() {
+	w.prefixes = w.prefixes[:len(w.prefixes)-1]
+	w.breakPrefix = strings.Join(w.prefixes, "")
+}
+
+func (w *htmlTextWriter) lineBreak() {
+	if w.out.Len() == 0 {
+		return
+	}
+	if w.newlines == 0 {
+		w.breakPrefix = strings.Join(w.prefixes, "")
+	}
+	w.newlines = min(w.newlines+1, 2)
+	w.space = false
+	w.afterMarker = false
+}
+
+func (w *htmlTextWriter) marker(marker string) {
+	w.raw(marker)
+	w.afterMarker = true
+}
+
+func (w *htmlTextWriter) text(text string) {
+	if w.pre > 0 {
+		for i, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+			if i > 0 {
+				w.out.WriteString("\n")
+				w.lineStart = true
+			}
+			if line != "" {
+				w.raw(line)
+			}
+		}
+		return
+	}
+
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		if text != "" {
+			w.space = true
+		}
+		return
+	}
+
+	if first, _ := utf8.DecodeRuneInString(text); isHTMLSpace(first) {
+		w.space = true
+	}
+	w.raw(strings.Join(fields, " "))
+	if last, _ := utf8.DecodeLastRuneInString(text); isHTMLSpace(last) {
+		w.space = true
+	}
+}
+
+// Block without text does not start anymore
+func (w *htmlTextWriter) dropStart(span *htmlBlockSpan) {
+	w.starts = slices.DeleteFunc(w.starts, func(start *htmlBlockSpan) bool {
+		return start == span
+	})
+}
+
+func isHTMLSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f'
+}
+
+// Writes text as it is
+func (w *htmlTextWriter) raw(text string) {
+	prefix := strings.Join(w.prefixes, "")
+	if w.newlines > 0 {
+		if w.lineStart {
+			w.newlines -= 1
+		}
+		for i := 0; i < w.newlines; i++ {
+			if i > 0 {
+				w.out.WriteString(strings.TrimRight(w.breakPrefix, " "))
+			}
+			w.out.WriteString("\n")
+		}
+		w.newlines = 0
+		w.lineStart = true
+	}
+
+	lineOffset := -1
+	if w.lineStart {
+		lineOffset = w.out.Len()
+		w.out.WriteString(prefix)
+	} else if w.space && !w.afterMarker {
+		w.out.WriteString(" ")
+	}
+	// Block starts after the prefix it was opened with, prefixes of the block itself are part of its text
+	for _, span := range w.starts {
+		span.start = w.out.Len()
+		if lineOffset >= 0 {
+			span.start = lineOffset + min(len(span.prefix), len(prefix))
+		}
+	}
+	w.starts = w.starts[:0]
+
+	w.out.WriteString(text)
+	w.space = false
+	w.lineStart = false
+	w.afterMarker = false
+}
+
+// Detects XHTML documents that are recognized as generic XML
+func xhtmlMimeDetector(data []byte) bool {
+	return bytes.Contains(data, []byte("http://www.w3.org/1999/xhtml")) || bytes.Contains(bytes.ToLower(data), []byte("<html"))
+}
+
+type HTMLParserResult struct {
+	FullPath string `json:"path"`
+	// Page title and meta tags like description
+	Metadata map[string]string `json:"metadata"`
+	Text     string            `json:"text"`
+	// Headings, paragraphs, lists, tables and images of the text
+	Blocks []Block `json:"blocks"`
+	// Inline images that failed to parse
+	Images []Result `json:"images"`
+	Err    error    `json:"error"`
+}
+
+func (r *HTMLParserResult) Path() string {
+	return r.FullPath
+}
+
+func (r *HTMLParserResult) String() string {
+	var result strings.Builder
+
+	if len(r.Metadata) != 0 {
+		result.WriteString(metadataString(r.MetadataFields()))
+	}
+	result.WriteString(r.Document().String())
+	result.WriteString("\n")
+
+	return result.String()
+}
+
+func (r *HTMLParserResult) MetadataFields() []MetadataField {
+	return metadataFields(r.Metadata, htmlMetadataFields)
+}
+
+// Blocks of the converted text. Offsets are in the `Text`.
+func (r *HTMLParserResult) Document() *Document {
+	if r.Blocks == nil && r.Text != "" {
+		return &Document{Blocks: textBlocks(r.Text, Location{})}
+	}
+	return &Document{Blocks: r.Blocks}
+}
+
+func (r *HTMLParserResult) Error() error {
+	return r.Err
+}
+
+func (r *HTMLParserResult) Subfiles() []Result {
+	return r.Images
+}
+
+type HTMLParserStreamResult struct {
+	FullPath        string             `json:"path"`
+	CurrentStage    ParseProgressStage `json:"stage"`
+	CurrentProgress uint8              `json:"progress"`
+	Text            string             `json:"text"`
+	Err             error              `json:"error"`
+}
+
+func (r *HTMLParserStreamResult) Path() string {
+	return r.FullPath
+}
+
+func (r *HTMLParserStreamResult) Stage() ParseProgressStage {
+	return r.CurrentStage
+}
+
+func (r *HTMLParserStreamResult) Progress() uint8 {
+	return r.CurrentProgress
+}
+
+func (r *HTMLParserStreamResult) SubResult() StreamResult {
+	return nil
+}
+
+func (r *HTMLParserStreamResult) String() string {
+	return r.Text
+}
+
+func (r *HTMLParserStreamResult) Error() error {
+	return r.Err
+}
@@ -0,0 +1,120 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gabriel-vasile/mimetype"
+	testdata "github.com/opengs/file2llm/test_data"
+)
+
+func TestHTML(t *testing.T) {
+	htmlParser := NewCompositeParser(NewHTMLParser(NewCompositeParser()))
+	result := htmlParser.Parse(context.Background(), bytes.NewReader(testdata.HTML), "page.html")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+
+	htmlResult := result.(*CompositeParserResult).Inner.(*HTMLParserResult)
+	if htmlResult.Metadata["Title"] != "Café Quarterly Report" || htmlResult.Metadata["Description"] != "Results of the second quarter" {
+		t.Errorf("unexpected metadata: %v", htmlResult.Metadata)
+	}
+
+	expected := []string{
+		"# Quarterly Report\n\nRevenue of the café grew by 12% compared to the previous quarter. See [full details](https://example.com/details).\n\n## Highlights\n",
+		"- New office opened\n- Two products launched\n  1. Starter plan\n  2. Enterprise plan\n",
+		"| Region | Revenue |\n| --- | --- |\n| North | 120 |\n| South | 95 |\n",
+		"> Best quarter so far.\n",
+		"```\ntotal = north + south\nprint(total)\n```\n",
+		"Chart:\n![Revenue chart]",
+	}
+	for _, text := range expected {
+		if !strings.Contains(htmlResult.Text, text) {
+			t.Errorf("missing %q in:\n%s", text, htmlResult.Text)
+		}
+	}
+	for _, text := range []string{"Home", "tracking", "color", "Hidden text", "Copyright"} {
+		if strings.Contains(htmlResult.Text, text) {
+			t.Errorf("unexpected %q in:\n%s", text, htmlResult.Text)
+		}
+	}
+
+	// Without OCR inline image can not be parsed and is reported as subfile
+	if len(result.Subfiles()) != 1 || result.Subfiles()[0].Path() != "page.html/image_0.png" {
+		t.Errorf("unexpected subfiles: %v", result.Subfiles())
+	}
+
+	if !strings.HasPrefix(result.String(), "------ Metadata ------\nTitle: Café Quarterly Report\nDescription: Results of the second quarter\nAuthor: Jane Doe\n\n# Quarterly Report") {
+		t.Error(result.String())
+	}
+}
+
+func TestHTMLCharset(t *testing.T) {
+	cases := map[string][]byte{
+		"bom":  append([]byte("\xEF\xBB\xBF"), []byte("<html><body><p>Привет</p></body></html>")...),
+		"meta": []byte("<html><head><meta charset=\"windows-1251\"></head><body><p>\xcf\xf0\xe8\xe2\xe5\xf2</p></body></html>"),
+		"none": []byte("<html><body><p>Привет</p></body></html>"),
+	}
+	for name, data := range cases {
+		result := NewHTMLParser(nil).Parse(context.Background(), bytes.NewReader(data), "page.html").(*HTMLParserResult)
+		if result.Text != "Привет" {
+			t.Errorf("%s: unexpected text %q", name, result.Text)
+		}
+	}
+}
+
+func TestHTMLNestedBlocks(t *testing.T) {
+	data := `<blockquote><p>First</p><p>Second</p></blockquote><ul><li><p>Item</p><p>More</p></li></ul><p>(<a href="#top">back</a>)</p>`
+	result := NewHTMLParser(nil).Parse(context.Background(), strings.NewReader(data), "page.html").(*HTMLParserResult)
+	expected := "> First\n>\n> Second\n\n- Item\n\n  More\n\n(back)"
+	if result.Text != expected {
+		t.Errorf("expected %q, got %q", expected, result.Text)
+	}
+}
+
+func TestHTMLStream(t *testing.T) {
+	htmlParser := NewHTMLParser(NewCompositeParser())
+	expected := htmlParser.Parse(context.Background(), bytes.NewReader(testdata.HTML), "page.html").String()
+
+	stream := htmlParser.ParseStream(context.Background(), bytes.NewReader(testdata.HTML), "page.html")
+	defer stream.Close()
+
+	var text strings.Builder
+	var last StreamResult
+	for stream.Next(context.Background()) {
+		last = stream.Current()
+		if last.Error() != nil {
+			t.Fatal(last.Error())
+		}
+		text.WriteString(last.String())
+	}
+
+	if last == nil || last.Stage() != ProgressCompleted {
+		t.Errorf("stream is not completed")
+	}
+	if text.String() != expected {
+		t.Errorf("stream text differs from the parse result:\n%s", text.String())
+	}
+}
+
+func TestXHTMLDetection(t *testing.T) {
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml"><head><title>Chapter</title></head><body><h2>One</h2><p>Text</p></body></html>`
+	result := NewCompositeParser(NewHTMLParser(nil)).Parse(context.Background(), strings.NewReader(data), "chapter.xhtml")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+	if !strings.Contains(result.String(), "## One\n\nText") {
+		t.Error(result.String())
+	}
+
+	if mime := magicMimeType(mimetype.Detect([]byte(data)), []byte(data)); mime != "application/xhtml+xml" {
+		t.Errorf("expected application/xhtml+xml, got %s", mime)
+	}
+	// Global mime type tree of the library is not changed
+	if mime := mimetype.Detect([]byte(data)).String(); !strings.HasPrefix(mime, "text/xml") {
+		t.Errorf("expected text/xml from the library, got %s", mime)
+	}
+}
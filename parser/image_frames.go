@@ -0,0 +1,803 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"strings"
+
+	"github.com/opengs/file2llm/ocr"
+	"golang.org/x/image/tiff"
+	"golang.org/x/image/webp"
+)
+
+type imageConfig struct {
+	maxFrames       int
+	frameSimilarity float64
+}
+
+// Configures parsers of the images that can contain several frames or pages ([GIFParser], [TiffParser], [WebPParser])
+type ImageOption func(c *imageConfig)
+
+// Maximum number of frames or pages decoded from the image. Near identical frames skipped before OCR count to the limit. Default is 100.
+func WithImageMaxFrames(frames int) ImageOption {
+	return func(c *imageConfig) {
+		c.maxFrames = frames
+	}
+}
+
+// Animation frames that differ from the previous recognized frame by less than `threshold` are skipped. Difference is the share of
+// the image area (from 0 to 1) that visibly changed its brightness. Default is 0.02. Zero keeps all the frames. Pages of the documents like TIFF are never skipped.
+func WithImageFrameSimilarity(threshold float64) ImageOption {
+	return func(c *imageConfig) {
+		c.frameSimilarity = threshold
+	}
+}
+
+func newImageConfig(options []ImageOption) imageConfig {
+	config := imageConfig{
+		maxFrames:       100,
+		frameSimilarity: 0.02,
+	}
+	for _, option := range options {
+		option(&config)
+	}
+	return config
+}
+
+// Frames of the animation or pages of the multi page image
+type imageFrames interface {
+	Len() int
+	// Decodes frame. Frames are composed from the previous ones, so they must be requested in order.
+	Frame(index int) (image.Image, error)
+	// True for animations, where frames are usually near identical
+	Animated() bool
+	// True if the file has more frames than can be decoded
+	Truncated() bool
+}
+
+// Page of the multi page image or frame of the animation
+type ImagePage struct {
+	// Number of the frame starting from 1
+	Number int    `json:"number"`
+	Text   string `json:"text"`
+}
+
+// Text of the page with the page marker
+func imagePageString(page ImagePage, animated bool) string {
+	return blocksString(imagePageBlocks(page, animated))
+}
+
+// Page break followed by the recognized text of the page
+func imagePageBlocks(page ImagePage, animated bool) []Block {
+	kind := "Page"
+	if animated {
+		kind = "Frame"
+	}
+	location := Location{Page: page.Number, Offset: -1}
+	return []Block{
+		{Kind: BlockPageBreak, Text: fmt.Sprintf("%s %d", kind, page.Number), Location: location, Separator: "\n"},
+		{Kind: BlockImage, Text: strings.TrimRight(page.Text, "\n"), Location: location, Separator: "\n"},
+	}
+}
+
+func imageTruncatedLine(pages int, animated bool) string {
+	if animated {
+		return fmt.Sprintf("------ Image truncated after %d frames ------\n", pages)
+	}
+	return fmt.Sprintf("------ Image truncated after %d pages ------\n", pages)
+}
+
+// Animations are composed on the canvas that takes 4 bytes per pixel. 40 megapixels take 160 MB.
+const imageMaxCanvasPixels = 40_000_000
+
+var errImageCanvasTooLarge = fmt.Errorf("image canvas is larger than %d pixels", imageMaxCanvasPixels)
+
+// Returns error for the canvas that would exhaust memory. Such images are not parsed at all.
+func checkImageCanvas(width int, height int) error {
+	if int64(width)*int64(height) > imageMaxCanvasPixels {
+		return errors.Join(ErrBadFile, errImageCanvasTooLarge, fmt.Errorf("canvas size is %dx%d", width, height))
+	}
+	return nil
+}
+
+// Skips near identical frames of the animations
+type imageFrameFilter struct {
+	threshold float64
+	previous  []uint8
+}
+
+// Size of the grayscale thumbnail side used to compare frames
+const imageThumbnailSize = 32
+
+func (f *imageFrameFilter) keep(img image.Image) bool {
+	thumbnail := imageThumbnail(img)
+	if f.previous != nil && f.threshold > 0 && imageDifference(f.previous, thumbnail) < f.threshold {
+		return false
+	}
+	f.previous = thumbnail
+	return true
+}
+
+// Downscales image to the small grayscale thumbnail by averaging pixels
+func imageThumbnail(img image.Image) []uint8 {
+	bounds := img.Bounds()
+	sums := make([]float64, imageThumbnailSize*imageThumbnailSize)
+	counts := make([]float64, imageThumbnailSize*imageThumbnailSize)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		ty := (y - bounds.Min.Y) * imageThumbnailSize / bounds.Dy()
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			tx := (x - bounds.Min.X) * imageThumbnailSize / bounds.Dx()
+			gray := color.GrayModel.Convert(img.At(x, y)).(color.Gray)
+			sums[ty*imageThumbnailSize+tx] += float64(gray.Y)
+			counts[ty*imageThumbnailSize+tx] += 1
+		}
+	}
+
+	thumbnail := make([]uint8, len(sums))
+	for i := range sums {
+		if counts[i] != 0 {
+			thumbnail[i] = uint8(sums[i] / counts[i])
+		}
+	}
+	return thumbnail
+}
+
+// Brightness change of the thumbnail cell that is considered visible
+const imageVisibleChange = 16
+
+// Share of the thumbnail cells that changed visibly, from 0 to 1
+func imageDifference(a []uint8, b []uint8) float64 {
+	changed := 0
+	for i := range a {
+		if max(a[i], b[i])-min(a[i], b[i]) > imageVisibleChange {
+			changed += 1
+		}
+	}
+	return float64(changed) / float64(len(a))
+}
+
+// Encodes frame for the OCR provider
+func encodeImageFrame(img image.Image) (io.Reader, error) {
+	var outBuf bytes.Buffer
+	if err := png.Encode(&outBuf, img); err != nil {
+		return nil, errors.Join(errors.New("failed to transcode image to PNG"), err)
+	}
+	return &outBuf, nil
+}
+
+// Reads the image and splits it into frames. Returns nil frames for single images and for the images that can not be split, so they are
+// parsed as a whole.
+func readImageFrames(file io.Reader, decode func(data []byte) (imageFrames, error)) ([]byte, imageFrames, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, nil, errors.Join(errors.New("failed to read image"), err)
+	}
+
+	frames, err := decode(data)
+	if errors.Is(err, errImageCanvasTooLarge) {
+		return nil, nil, err
+	}
+	if err != nil || frames.Len() == 1 {
+		return data, nil, nil
+	}
+	return data, frames, nil
+}
+
+// Recognizes every frame of the image. Used only for images with more than one frame.
+func parseImageFrames(ctx context.Context, ocrProvider ocr.Provider, config imageConfig, frames imageFrames, metadata *ImageMetadata, path string) Result {
+	filter := imageFrameFilter{threshold: config.frameSimilarity}
+	var pages []ImagePage
+	var text strings.Builder
+	// Frames are counted when they are decoded, so skipped duplicates count to the limit too
+	decoded := min(frames.Len(), config.maxFrames)
+	for index := range decoded {
+		img, err := frames.Frame(index)
+		if err != nil {
+			return &ImageParserResult{Err: errors.Join(ErrBadFile, fmt.Errorf("failed to decode frame %d", index), err), FullPath: path, Pages: pages}
+		}
+		if frames.Animated() && !filter.keep(img) {
+			continue
+		}
+
+		imageData, err := encodeImageFrame(img)
+		if err != nil {
+			return &ImageParserResult{Err: err, FullPath: path, Pages: pages}
+		}
+
+		pageText, err := ocrProvider.OCR(ctx, imageData)
+		if err != nil {
+			return &ImageParserResult{Err: errors.Join(fmt.Errorf("errors while running OCR on frame %d", index), err), FullPath: path, Pages: pages}
+		}
+
+		page := ImagePage{Number: index + 1, Text: pageText}
+		pages = append(pages, page)
+		text.WriteString(imagePageString(page, frames.Animated()))
+	}
+
+	result := &ImageParserResult{Text: text.String(), Metadata: metadata, FullPath: path, Pages: pages, Animated: frames.Animated()}
+	if decoded < frames.Len() || frames.Truncated() {
+		result.Truncated = true
+		result.Text += imageTruncatedLine(decoded, frames.Animated())
+	}
+	return result
+}
+
+// Streams frames of the image one by one. Images with single frame are streamed with `single` iterator.
+type ImageFramesStreamResultIterator struct {
+	path        string
+	mimeType    string
+	ocrProvider ocr.Provider
+	config      imageConfig
+	file        io.Reader
+	decode      func(data []byte) (imageFrames, error)
+	single      func(file io.Reader) StreamResultIterator
+
+	baseContext context.Context
+
+	started     bool
+	readError   error
+	completed   bool
+	frames      imageFrames
+	singleFrame StreamResultIterator
+	filter      imageFrameFilter
+	frameIndex  int
+	currentPage int
+	pageOCR     StreamResultIterator
+
+	current StreamResult
+}
+
+// Progress in percents after `done` frames
+func (i *ImageFramesStreamResultIterator) progress(done float64) uint8 {
+	total := min(i.frames.Len(), i.config.maxFrames)
+	if total == 0 {
+		return 100
+	}
+	return uint8(min(done/float64(total), 1) * 100)
+}
+
+func (i *ImageFramesStreamResultIterator) fail(err error) bool {
+	i.completed = true
+	i.current = &ImageParserStreamResult{
+		FullPath:     i.path,
+		CurrentStage: ProgressCompleted,
+		PageNumber:   i.currentPage,
+		Err:          err,
+	}
+	return true
+}
+
+func (i *ImageFramesStreamResultIterator) Next(ctx context.Context) bool {
+	if i.completed {
+		i.current = nil
+		return false
+	}
+
+	if i.singleFrame != nil {
+		if !i.singleFrame.Next(ctx) {
+			i.current = nil
+			return false
+		}
+		i.current = i.singleFrame.Current()
+		return true
+	}
+
+	if !i.started {
+		i.started = true
+		data, frames, err := readImageFrames(i.file, i.decode)
+		if err != nil {
+			i.readError = err
+			i.current = &ImageParserStreamResult{
+				FullPath:     i.path,
+				CurrentStage: ProgressNew,
+			}
+			return true
+		}
+		if frames == nil {
+			i.singleFrame = i.single(bytes.NewReader(data))
+			return i.Next(ctx)
+		}
+
+		i.frames = frames
+		i.filter = imageFrameFilter{threshold: i.config.frameSimilarity}
+		i.current = &ImageParserStreamResult{
+			FullPath:     i.path,
+			CurrentStage: ProgressNew,
+		}
+		if metadata := readImageMetadata(i.mimeType, data); metadata != nil {
+			i.current = &ImageParserStreamResult{
+				FullPath:     i.path,
+				CurrentStage: ProgressNew,
+				Metadata:     metadata,
+				Text:         metadata.String(),
+			}
+		}
+		return true
+	}
+
+	if i.readError != nil {
+		return i.fail(i.readError)
+	}
+
+	if ctx.Err() != nil {
+		i.current = nil
+		return false
+	}
+
+	if i.pageOCR != nil {
+		if !i.pageOCR.Next(ctx) {
+			i.current = nil
+			return false
+		}
+
+		update := i.pageOCR.Current()
+		if update.Stage() != ProgressCompleted {
+			i.current = &ImageParserStreamResult{
+				FullPath:        i.path,
+				CurrentStage:    ProgressUpdate,
+				CurrentProgress: i.progress(float64(i.frameIndex-1) + float64(update.Progress())/100),
+				PageNumber:      i.currentPage,
+			}
+			return true
+		}
+
+		i.pageOCR.Close()
+		i.pageOCR = nil
+		if update.Error() != nil {
+			return i.fail(errors.Join(fmt.Errorf("errors while running OCR on frame %d", i.currentPage-1), update.Error()))
+		}
+
+		i.current = &ImageParserStreamResult{
+			FullPath:        i.path,
+			CurrentStage:    ProgressUpdate,
+			CurrentProgress: i.progress(float64(i.frameIndex)),
+			PageNumber:      i.currentPage,
+			Text:            imagePageString(ImagePage{Number: i.currentPage, Text: update.String()}, i.frames.Animated()),
+		}
+		return true
+	}
+
+	for i.frameIndex < min(i.frames.Len(), i.config.maxFrames) {
+		index := i.frameIndex
+		i.frameIndex += 1
+		i.currentPage = index + 1
+
+		img, err := i.frames.Frame(index)
+		if err != nil {
+			return i.fail(errors.Join(ErrBadFile, fmt.Errorf("failed to decode frame %d", index), err))
+		}
+		if i.frames.Animated() && !i.filter.keep(img) {
+			continue
+		}
+
+		imageData, err := encodeImageFrame(img)
+		if err != nil {
+			return i.fail(err)
+		}
+
+		i.pageOCR = &ImageStreamResultIterator{
+			path:        i.path,
+			file:        imageData,
+			ocrProvider: i.ocrProvider,
+			baseContext: i.baseContext,
+		}
+		return i.Next(ctx)
+	}
+
+	i.completed = true
+	completed := &ImageParserStreamResult{
+		FullPath:        i.path,
+		CurrentStage:    ProgressCompleted,
+		CurrentProgress: 100,
+	}
+	if i.frameIndex < i.frames.Len() || i.frames.Truncated() {
+		completed.Text = imageTruncatedLine(i.frameIndex, i.frames.Animated())
+	}
+	i.current = completed
+	return true
+}
+
+func (i *ImageFramesStreamResultIterator) Current() StreamResult {
+	return i.current
+}
+
+func (i *ImageFramesStreamResultIterator) Close() {
+	if i.singleFrame != nil {
+		i.singleFrame.Close()
+	}
+	if i.pageOCR != nil {
+		i.pageOCR.Close()
+	}
+}
+
+// Frames of the animated GIF composed on the white canvas
+type gifFrames struct {
+	gif       *gif.GIF
+	truncated bool
+	canvas    *image.RGBA
+	restore   *image.RGBA
+	next      int
+	snapshot  *image.RGBA
+}
+
+// Total pixels of the GIF frames decoded at once. Every pixel takes a byte.
+const gifMaxDecodedPixels = 400_000_000
+
+// Decodes only first `maxFrames` frames of the GIF and stops earlier if they exceed [gifMaxDecodedPixels]
+func decodeGIFFrames(data []byte, maxFrames int) (imageFrames, error) {
+	// Frames are allocated with the size of the image while decoding
+	config, err := gif.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Join(ErrBadFile, errors.New("failed to decode gif image"), err)
+	}
+	if err := checkImageCanvas(config.Width, config.Height); err != nil {
+		return nil, err
+	}
+
+	head, truncated, err := gifHead(data, max(maxFrames, 1), gifMaxDecodedPixels)
+	if err != nil {
+		return nil, errors.Join(ErrBadFile, errors.New("failed to read gif frames"), err)
+	}
+	decoded, err := gif.DecodeAll(bytes.NewReader(head))
+	if err != nil {
+		return nil, errors.Join(ErrBadFile, errors.New("failed to decode gif image"), err)
+	}
+	if len(decoded.Image) == 0 {
+		return nil, errors.Join(ErrBadFile, errors.New("gif image has no frames"))
+	}
+	return &gifFrames{gif: decoded, truncated: truncated}, nil
+}
+
+// Returns the beginning of the GIF with at most `maxFrames` frames and `maxPixels` pixels in them, closed with the trailer.
+// At least one frame is always kept. Second value is true if frames were cut off.
+func gifHead(data []byte, maxFrames int, maxPixels int64) ([]byte, bool, error) {
+	if len(data) < 13 {
+		return nil, false, errors.New("gif header is too short")
+	}
+	pos := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		pos += 3 << ((flags & 0x07) + 1) // Global color table
+	}
+
+	// Data of the extension or image is stored in blocks prefixed with their size and terminated by empty block
+	skipBlocks := func() error {
+		for {
+			if pos >= len(data) {
+				return errors.New("gif data block is truncated")
+			}
+			size := int(data[pos])
+			pos += 1 + size
+			if size == 0 {
+				return nil
+			}
+		}
+	}
+
+	frames := 0
+	var pixels int64
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21: // Extension
+			pos += 2
+			if err := skipBlocks(); err != nil {
+				return nil, false, err
+			}
+		case 0x2C: // Image descriptor
+			if pos+10 > len(data) {
+				return nil, false, errors.New("gif image descriptor is truncated")
+			}
+			width := int64(binary.LittleEndian.Uint16(data[pos+5:]))
+			height := int64(binary.LittleEndian.Uint16(data[pos+7:]))
+			if frames > 0 && (frames >= maxFrames || pixels+width*height > maxPixels) {
+				return append(data[:pos:pos], 0x3B), true, nil
+			}
+			frames += 1
+			pixels += width * height
+
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << ((flags & 0x07) + 1) // Local color table
+			}
+			pos += 1 // LZW code size
+			if err := skipBlocks(); err != nil {
+				return nil, false, err
+			}
+		case 0x3B: // Trailer
+			return data[:pos+1], false, nil
+		default:
+			return nil, false, fmt.Errorf("unknown gif block 0x%02X", data[pos])
+		}
+	}
+	return data, false, nil
+}
+
+func (f *gifFrames) Len() int {
+	return len(f.gif.Image)
+}
+
+func (f *gifFrames) Animated() bool {
+	return true
+}
+
+func (f *gifFrames) Truncated() bool {
+	return f.truncated
+}
+
+func (f *gifFrames) Frame(index int) (image.Image, error) {
+	if f.canvas == nil || index < f.next {
+		bounds := image.Rect(0, 0, f.gif.Config.Width, f.gif.Config.Height)
+		if bounds.Empty() {
+			for _, frame := range f.gif.Image {
+				bounds = bounds.Union(frame.Bounds())
+			}
+		}
+		if err := checkImageCanvas(bounds.Dx(), bounds.Dy()); err != nil {
+			return nil, err
+		}
+		f.canvas = image.NewRGBA(bounds)
+		draw.Draw(f.canvas, bounds, image.White, image.Point{}, draw.Src)
+		f.next = 0
+	}
+
+	for ; f.next <= index; f.next++ {
+		frame := f.gif.Image[f.next]
+		var disposal byte
+		if f.next < len(f.gif.Disposal) {
+			disposal = f.gif.Disposal[f.next]
+		}
+
+		if disposal == gif.DisposalPrevious {
+			f.restore = cloneRGBA(f.canvas)
+		}
+		draw.Draw(f.canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		f.snapshot = cloneRGBA(f.canvas)
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(f.canvas, frame.Bounds(), image.White, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			f.canvas = f.restore
+		}
+	}
+
+	return f.snapshot, nil
+}
+
+func cloneRGBA(img *image.RGBA) *image.RGBA {
+	clone := image.NewRGBA(img.Bounds())
+	copy(clone.Pix, img.Pix)
+	return clone
+}
+
+// Pages of the TIFF file. Every page is decoded by pointing the file header to the page directory.
+type tiffFrames struct {
+	data      []byte
+	byteOrder binary.ByteOrder
+	offsets   []uint32
+}
+
+// Guards against directory loops in the broken files
+const tiffMaxPages = 10000
+
+func decodeTIFFFrames(data []byte) (imageFrames, error) {
+	if len(data) < 8 {
+		return nil, errors.Join(ErrBadFile, errors.New("tiff header is too short"))
+	}
+
+	frames := &tiffFrames{data: data}
+	switch string(data[:4]) {
+	case "II*\x00":
+		frames.byteOrder = binary.LittleEndian
+	case "MM\x00*":
+		frames.byteOrder = binary.BigEndian
+	default:
+		return nil, errors.Join(ErrBadFile, errors.New("unsupported tiff header"))
+	}
+
+	visited := map[uint32]bool{}
+	for offset := frames.byteOrder.Uint32(data[4:8]); offset != 0 && len(frames.offsets) < tiffMaxPages; {
+		if visited[offset] || int(offset)+2 > len(data) {
+			break
+		}
+		visited[offset] = true
+		frames.offsets = append(frames.offsets, offset)
+
+		entries := int(frames.byteOrder.Uint16(data[offset:]))
+		nextOffset := int(offset) + 2 + entries*12
+		if nextOffset+4 > len(data) {
+			break
+		}
+		offset = frames.byteOrder.Uint32(data[nextOffset:])
+	}
+
+	if len(frames.offsets) == 0 {
+		return nil, errors.Join(ErrBadFile, errors.New("tiff image has no pages"))
+	}
+	return frames, nil
+}
+
+func (f *tiffFrames) Len() int {
+	return len(f.offsets)
+}
+
+func (f *tiffFrames) Animated() bool {
+	return false
+}
+
+func (f *tiffFrames) Truncated() bool {
+	return false
+}
+
+func (f *tiffFrames) Frame(index int) (image.Image, error) {
+	header := make([]byte, 8)
+	copy(header, f.data[:4])
+	f.byteOrder.PutUint32(header[4:], f.offsets[index])
+
+	page := &tiffPageReader{data: f.data, header: header}
+	return tiff.Decode(io.NewSectionReader(page, 0, int64(len(f.data))))
+}
+
+// TIFF file with replaced header
+type tiffPageReader struct {
+	data   []byte
+	header []byte
+}
+
+func (r *tiffPageReader) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(r.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, r.data[off:])
+	if off < int64(len(r.header)) {
+		copy(p, r.header[off:])
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Frames of the animated WebP composed on the white canvas
+type webpFrames struct {
+	data     []byte
+	width    int
+	height   int
+	anmf     [][]byte
+	canvas   *image.RGBA
+	next     int
+	snapshot *image.RGBA
+}
+
+func decodeWebPFrames(data []byte) (imageFrames, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errors.Join(ErrBadFile, errors.New("unsupported webp header"))
+	}
+
+	frames := &webpFrames{data: data}
+	animated := false
+	for chunks := data[12:]; len(chunks) >= 8; {
+		fourcc := string(chunks[:4])
+		size := int(binary.LittleEndian.Uint32(chunks[4:8]))
+		if size > len(chunks)-8 {
+			return nil, errors.Join(ErrBadFile, fmt.Errorf("webp chunk %s is truncated", fourcc))
+		}
+		payload := chunks[8 : 8+size]
+
+		switch fourcc {
+		case "VP8X":
+			if len(payload) < 10 {
+				return nil, errors.Join(ErrBadFile, errors.New("webp VP8X chunk is too short"))
+			}
+			animated = payload[0]&0x02 != 0
+			frames.width = webpUint24(payload[4:]) + 1
+			frames.height = webpUint24(payload[7:]) + 1
+		case "ANMF":
+			if len(payload) < 16 {
+				return nil, errors.Join(ErrBadFile, errors.New("webp ANMF chunk is too short"))
+			}
+			frames.anmf = append(frames.anmf, payload)
+		}
+
+		// Chunks are padded to the even size. Missing padding of the last chunk is the end of the file.
+		if 8+size+size%2 > len(chunks) {
+			break
+		}
+		chunks = chunks[8+size+size%2:]
+	}
+
+	if !animated {
+		frames.anmf = nil
+	} else if len(frames.anmf) == 0 {
+		return nil, errors.Join(ErrBadFile, errors.New("animated webp image has no frames"))
+	} else if err := checkImageCanvas(frames.width, frames.height); err != nil {
+		return nil, err
+	}
+	return frames, nil
+}
+
+func webpUint24(b []byte) int {
+	return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+}
+
+func (f *webpFrames) Len() int {
+	if f.anmf == nil {
+		return 1
+	}
+	return len(f.anmf)
+}
+
+func (f *webpFrames) Animated() bool {
+	return f.anmf != nil
+}
+
+func (f *webpFrames) Truncated() bool {
+	return false
+}
+
+func (f *webpFrames) Frame(index int) (image.Image, error) {
+	if f.anmf == nil {
+		return webp.Decode(bytes.NewReader(f.data))
+	}
+
+	if f.canvas == nil || index < f.next {
+		f.canvas = image.NewRGBA(image.Rect(0, 0, f.width, f.height))
+		draw.Draw(f.canvas, f.canvas.Bounds(), image.White, image.Point{}, draw.Src)
+		f.next = 0
+	}
+
+	for ; f.next <= index; f.next++ {
+		payload := f.anmf[f.next]
+		x, y := webpUint24(payload[0:])*2, webpUint24(payload[3:])*2
+		width, height := webpUint24(payload[6:])+1, webpUint24(payload[9:])+1
+		flags := payload[15]
+
+		frame, err := webp.Decode(bytes.NewReader(webpStill(payload[16:], width, height)))
+		if err != nil {
+			return nil, err
+		}
+
+		rect := image.Rect(x, y, x+width, y+height)
+		op := draw.Over
+		if flags&0x02 != 0 {
+			op = draw.Src
+		}
+		draw.Draw(f.canvas, rect, frame, frame.Bounds().Min, op)
+		f.snapshot = cloneRGBA(f.canvas)
+
+		if flags&0x01 != 0 {
+			draw.Draw(f.canvas, rect, image.White, image.Point{}, draw.Src)
+		}
+	}
+
+	return f.snapshot, nil
+}
+
+// Wraps frame data from the ANMF chunk into standalone WebP file
+func webpStill(frameData []byte, width int, height int) []byte {
+	body := []byte("WEBP")
+	if len(frameData) >= 4 && string(frameData[:4]) == "ALPH" {
+		vp8x := make([]byte, 18)
+		copy(vp8x, "VP8X")
+		binary.LittleEndian.PutUint32(vp8x[4:], 10)
+		vp8x[8] = 0x10
+		vp8x[12], vp8x[13], vp8x[14] = byte(width-1), byte((width-1)>>8), byte((width-1)>>16)
+		vp8x[15], vp8x[16], vp8x[17] = byte(height-1), byte((height-1)>>8), byte((height-1)>>16)
+		body = append(body, vp8x...)
+	}
+	body = append(body, frameData...)
+
+	riff := make([]byte, 8, 8+len(body))
+	copy(riff, "RIFF")
+	binary.LittleEndian.PutUint32(riff[4:], uint32(len(body)))
+	return append(riff, body...)
+}
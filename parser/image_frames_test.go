@@ -0,0 +1,238 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/gif"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/opengs/file2llm/ocr"
+	testdata "github.com/opengs/file2llm/test_data"
+)
+
+// Decodes GIF with the default frame limit
+func decodeTestGIFFrames(data []byte) (imageFrames, error) {
+	return decodeGIFFrames(data, 100)
+}
+
+func TestImageFramesDecoding(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     []byte
+		decode   func(data []byte) (imageFrames, error)
+		frames   int
+		animated bool
+	}{
+		{"gif", testdata.GIFAnimated, decodeTestGIFFrames, 3, true},
+		{"tiff", testdata.TIFFMultipage, decodeTIFFFrames, 2, false},
+		{"webp", testdata.WEBPAnimated, decodeWebPFrames, 2, true},
+		{"gif single", testdata.GIF, decodeTestGIFFrames, 1, true},
+		{"webp single", testdata.WEBP, decodeWebPFrames, 1, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			frames, err := c.decode(c.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if frames.Len() != c.frames || frames.Animated() != c.animated || frames.Truncated() {
+				t.Fatalf("got %d frames (animated %v)", frames.Len(), frames.Animated())
+			}
+			if c.frames == 1 {
+				return
+			}
+			for index := range frames.Len() {
+				img, err := frames.Frame(index)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if img.Bounds() != image.Rect(0, 0, 800, 600) {
+					t.Errorf("frame %d has bounds %v", index, img.Bounds())
+				}
+			}
+		})
+	}
+}
+
+func TestImageFramesDeduplication(t *testing.T) {
+	frames, err := decodeTestGIFFrames(testdata.GIFAnimated)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filter := imageFrameFilter{threshold: 0.02}
+	var kept []int
+	for index := range frames.Len() {
+		img, err := frames.Frame(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filter.keep(img) {
+			kept = append(kept, index+1)
+		}
+	}
+	if !slices.Equal(kept, []int{1, 3}) {
+		t.Errorf("kept frames %v", kept)
+	}
+
+	if _, err := decodeTIFFFrames([]byte("not a tiff")); err == nil {
+		t.Error("expected error for broken tiff")
+	}
+}
+
+func TestImageFrames(t *testing.T) {
+	cfg := ocr.DefaultTesseractConfig()
+	cfg.SupportedImageFormats = []string{"image/png"}
+	ocrProvider := ocr.NewTestingOCRProvider(t, cfg)
+
+	parsers := []struct {
+		name   string
+		parser Parser
+		data   []byte
+		pages  []int
+	}{
+		{"gif", NewGIFParser(ocrProvider), testdata.GIFAnimated, []int{1, 3}},
+		{"tiff", NewTiffParser(ocrProvider), testdata.TIFFMultipage, []int{1, 2}},
+		{"webp", NewWebPParser(ocrProvider), testdata.WEBPAnimated, []int{1}},
+	}
+
+	for _, p := range parsers {
+		t.Run(p.name, func(t *testing.T) {
+			result := p.parser.Parse(context.Background(), bytes.NewReader(p.data), "")
+			if result.Error() != nil {
+				t.Fatal(result.Error())
+			}
+
+			var pages []int
+			for _, page := range result.(*ImageParserResult).Pages {
+				pages = append(pages, page.Number)
+			}
+			if !slices.Equal(pages, p.pages) {
+				t.Errorf("recognized pages %v", pages)
+			}
+
+			resultString := strings.ToLower(result.String())
+			if !strings.Contains(resultString, "hello") {
+				t.Error(resultString)
+			}
+		})
+	}
+}
+
+func TestImageFramesMaxFrames(t *testing.T) {
+	cfg := ocr.DefaultTesseractConfig()
+	cfg.SupportedImageFormats = []string{"image/png"}
+	ocrProvider := ocr.NewTestingOCRProvider(t, cfg)
+	gifParser := NewGIFParser(ocrProvider, WithImageMaxFrames(1), WithImageFrameSimilarity(0))
+	result := gifParser.Parse(context.Background(), bytes.NewReader(testdata.GIFAnimated), "")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+	if pages := result.(*ImageParserResult).Pages; len(pages) != 1 {
+		t.Errorf("expected single page, got %d", len(pages))
+	}
+	if !result.(*ImageParserResult).Truncated || !strings.Contains(result.String(), "------ Image truncated after 1 frames ------") {
+		t.Errorf("result must be marked as truncated:\n%s", result.String())
+	}
+}
+
+func TestImageFramesGIFLimits(t *testing.T) {
+	// Frames after the limit are not decoded
+	frames, err := decodeGIFFrames(testdata.GIFAnimated, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frames.Len() != 2 || !frames.Truncated() {
+		t.Errorf("expected 2 decoded frames of the truncated image, got %d (truncated %v)", frames.Len(), frames.Truncated())
+	}
+
+	// First frame is kept even if it exceeds the pixel budget
+	head, truncated, err := gifHead(testdata.GIFAnimated, 100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := gif.DecodeAll(bytes.NewReader(head))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Image) != 1 || !truncated {
+		t.Errorf("expected single frame, got %d (truncated %v)", len(decoded.Image), truncated)
+	}
+
+	if _, _, err := gifHead([]byte("GIF89a\x01\x00\x01\x00\x00\x00\x00,\x00\x00"), 100, gifMaxDecodedPixels); err == nil {
+		t.Error("expected error for truncated image descriptor")
+	}
+}
+
+func TestImageFramesMalformed(t *testing.T) {
+	// Last chunk has odd size and no padding byte
+	webp := []byte("RIFF\x00\x00\x00\x00WEBPVP8X\x0a\x00\x00\x00\x02\x00\x00\x00\x00\x00\x00\x00\x00\x00EXIF\x01\x00\x00\x00\x00")
+	if _, err := decodeWebPFrames(webp); err == nil {
+		t.Error("expected error for webp without frames")
+	}
+
+	// Canvas of 16777216x16777216 pixels
+	webp = []byte("RIFF\x00\x00\x00\x00WEBPVP8X\x0a\x00\x00\x00\x02\x00\x00\x00\xff\xff\xff\xff\xff\xff")
+	webp = append(webp, "ANMF\x10\x00\x00\x00"...)
+	webp = append(webp, make([]byte, 16)...)
+	if _, err := decodeWebPFrames(webp); !errors.Is(err, errImageCanvasTooLarge) {
+		t.Errorf("expected canvas error for webp, got %v", err)
+	}
+
+	// Logical screen of 65535x65535 pixels
+	gif := []byte("GIF89a\xff\xff\xff\xff\x00\x00\x00;")
+	if _, err := decodeTestGIFFrames(gif); !errors.Is(err, errImageCanvasTooLarge) {
+		t.Errorf("expected canvas error for gif, got %v", err)
+	}
+	if _, _, err := readImageFrames(bytes.NewReader(gif), decodeTestGIFFrames); !errors.Is(err, errImageCanvasTooLarge) {
+		t.Errorf("huge image must not be parsed as a whole, got %v", err)
+	}
+}
+
+func TestImageFramesStream(t *testing.T) {
+	cfg := ocr.DefaultTesseractConfig()
+	cfg.SupportedImageFormats = []string{"image/png"}
+	ocrProvider := ocr.NewTestingOCRProvider(t, cfg)
+	tiffParser := NewTiffParser(ocrProvider)
+
+	hasNewStage := false
+	hasCompletedStage := false
+	var text strings.Builder
+	var pages []int
+	var lastProgress uint8
+
+	parseProgress := tiffParser.ParseStream(context.Background(), bytes.NewReader(testdata.TIFFMultipage), "")
+	defer parseProgress.Close()
+	for parseProgress.Next(t.Context()) {
+		progress := parseProgress.Current()
+		if progress.Error() != nil {
+			t.Fatal(progress.Error())
+		}
+		hasNewStage = hasNewStage || (progress.Stage() == ProgressNew)
+		hasCompletedStage = hasCompletedStage || (progress.Stage() == ProgressCompleted)
+		if progress.Progress() < lastProgress {
+			t.Errorf("progress decreased from %d to %d", lastProgress, progress.Progress())
+		}
+		lastProgress = progress.Progress()
+		if progress.String() != "" {
+			pages = append(pages, progress.(*ImageParserStreamResult).PageNumber)
+		}
+		text.WriteString(progress.String())
+	}
+	if !hasNewStage || !hasCompletedStage {
+		t.Fail()
+	}
+	if !slices.Equal(pages, []int{1, 2}) {
+		t.Errorf("streamed pages %v", pages)
+	}
+
+	resultString := strings.ToLower(text.String())
+	if !strings.Contains(resultString, "hello") || !strings.Contains(resultString, "world") {
+		t.Error(resultString)
+	}
+}
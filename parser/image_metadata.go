@@ -0,0 +1,889 @@
+package parser
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opengs/file2llm/ocr"
+)
+
+// Metadata of the photo collected from EXIF, IPTC and XMP. EXIF values have priority, missing ones are taken from IPTC and then from XMP.
+type ImageMetadata struct {
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Author      string    `json:"author"`
+	Copyright   string    `json:"copyright"`
+	Keywords    []string  `json:"keywords"`
+	CaptureTime time.Time `json:"captureTime"`
+	CameraMake  string    `json:"cameraMake"`
+	CameraModel string    `json:"cameraModel"`
+	Software    string    `json:"software"`
+	// EXIF orientation from 1 to 8. Zero if unknown. Images are rotated to the normal orientation before OCR.
+	Orientation int            `json:"orientation"`
+	Location    *ImageLocation `json:"location"`
+	City        string         `json:"city"`
+	Country     string         `json:"country"`
+}
+
+// GPS position where the photo was taken
+type ImageLocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	// Altitude in meters above sea level. Zero if unknown.
+	Altitude float64 `json:"altitude"`
+}
+
+func (m *ImageMetadata) IsZero() bool {
+	return m.Title == "" && m.Description == "" && m.Author == "" && m.Copyright == "" && len(m.Keywords) == 0 && m.CaptureTime.IsZero() &&
+		m.CameraMake == "" && m.CameraModel == "" && m.Software == "" && m.Orientation == 0 && m.Location == nil && m.City == "" && m.Country == ""
+}
+
+func (m *ImageMetadata) String() string {
+	return metadataString(m.fields())
+}
+
+func (m *ImageMetadata) fields() []MetadataField {
+	camera := m.CameraModel
+	if m.CameraMake != "" && !strings.HasPrefix(strings.ToLower(m.CameraModel), strings.ToLower(m.CameraMake)) {
+		camera = strings.TrimSpace(m.CameraMake + " " + m.CameraModel)
+	}
+	place := strings.Join(slices.DeleteFunc([]string{m.City, m.Country}, func(s string) bool { return s == "" }), ", ")
+
+	var fields []MetadataField
+	for _, field := range []MetadataField{
+		{"Title", m.Title},
+		{"Description", m.Description},
+		{"Author", m.Author},
+		{"Copyright", m.Copyright},
+		{"Keywords", strings.Join(m.Keywords, ", ")},
+		{"Camera", camera},
+		{"Software", m.Software},
+		{"Place", place},
+	} {
+		if field.Value != "" {
+			fields = append(fields, field)
+		}
+	}
+	if !m.CaptureTime.IsZero() {
+		fields = append(fields, MetadataField{"Captured", m.CaptureTime.Format(time.RFC3339)})
+	}
+	if m.Location != nil {
+		location := fmt.Sprintf("%.6f, %.6f", m.Location.Latitude, m.Location.Longitude)
+		if m.Location.Altitude != 0 {
+			location += fmt.Sprintf(" (altitude %.0f m)", m.Location.Altitude)
+		}
+		fields = append(fields, MetadataField{"Location", location})
+	}
+	if m.Orientation > 1 {
+		fields = append(fields, MetadataField{"Orientation", strconv.Itoa(m.Orientation)})
+	}
+	return fields
+}
+
+// Fills empty fields with the values from `other`
+func (m *ImageMetadata) merge(other *ImageMetadata) {
+	if other == nil {
+		return
+	}
+	for _, field := range []struct{ dst, src *string }{
+		{&m.Title, &other.Title},
+		{&m.Description, &other.Description},
+		{&m.Author, &other.Author},
+		{&m.Copyright, &other.Copyright},
+		{&m.CameraMake, &other.CameraMake},
+		{&m.CameraModel, &other.CameraModel},
+		{&m.Software, &other.Software},
+		{&m.City, &other.City},
+		{&m.Country, &other.Country},
+	} {
+		if *field.dst == "" {
+			*field.dst = *field.src
+		}
+	}
+	if m.CaptureTime.IsZero() {
+		m.CaptureTime = other.CaptureTime
+	}
+	if m.Orientation == 0 {
+		m.Orientation = other.Orientation
+	}
+	if m.Location == nil {
+		m.Location = other.Location
+	}
+	for _, keyword := range other.Keywords {
+		if !slices.Contains(m.Keywords, keyword) {
+			m.Keywords = append(m.Keywords, keyword)
+		}
+	}
+}
+
+// Raw metadata blocks found in the image file
+type imageMetadataBlocks struct {
+	exif []byte
+	iptc []byte
+	xmp  []byte
+	// Textual PNG chunks
+	text map[string]string
+}
+
+// Reads EXIF, IPTC and XMP metadata of the image. Returns nil if image has no metadata or its format is not supported.
+func readImageMetadata(mimeType string, data []byte) *ImageMetadata {
+	var blocks imageMetadataBlocks
+	switch mimeType {
+	case "image/jpeg":
+		blocks = jpegMetadataBlocks(data)
+	case "image/tiff":
+		blocks = tiffMetadataBlocks(data)
+	case "image/webp":
+		blocks = webpMetadataBlocks(data)
+	case "image/png":
+		blocks = pngMetadataBlocks(data)
+	default:
+		return nil
+	}
+
+	metadata := parseEXIF(blocks.exif)
+	metadata.merge(parseIPTC(blocks.iptc))
+	metadata.merge(parseXMP(blocks.xmp))
+	metadata.merge(pngTextMetadata(blocks.text))
+	if metadata.IsZero() {
+		return nil
+	}
+	return metadata
+}
+
+func jpegMetadataBlocks(data []byte) imageMetadataBlocks {
+	var blocks imageMetadataBlocks
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return blocks
+	}
+
+	for offset := 2; offset+4 <= len(data); {
+		if data[offset] != 0xFF {
+			break
+		}
+		marker := data[offset+1]
+		if marker == 0xD8 || marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) || marker == 0xFF {
+			offset += 1
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			// Image data starts, metadata segments are located before it
+			break
+		}
+
+		size := int(binary.BigEndian.Uint16(data[offset+2:]))
+		if size < 2 || offset+2+size > len(data) {
+			break
+		}
+		segment := data[offset+4 : offset+2+size]
+		offset += 2 + size
+
+		switch {
+		case marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")):
+			blocks.exif = segment[6:]
+		case marker == 0xE1 && bytes.HasPrefix(segment, []byte("http://ns.adobe.com/xap/1.0/\x00")):
+			blocks.xmp = segment[len("http://ns.adobe.com/xap/1.0/\x00"):]
+		case marker == 0xED && bytes.HasPrefix(segment, []byte("Photoshop 3.0\x00")):
+			blocks.iptc = photoshopIPTC(segment[len("Photoshop 3.0\x00"):])
+		}
+	}
+
+	return blocks
+}
+
+// Extracts IPTC record from the Photoshop image resources
+func photoshopIPTC(data []byte) []byte {
+	for offset := 0; offset+12 <= len(data); {
+		if string(data[offset:offset+4]) != "8BIM" {
+			break
+		}
+		id := binary.BigEndian.Uint16(data[offset+4:])
+		nameSize := int(data[offset+6])
+		offset += 6 + 1 + nameSize
+		if (1+nameSize)%2 == 1 {
+			offset += 1
+		}
+		if offset+4 > len(data) {
+			break
+		}
+		size := int(binary.BigEndian.Uint32(data[offset:]))
+		offset += 4
+		if size < 0 || offset+size > len(data) {
+			break
+		}
+		if id == 0x0404 {
+			return data[offset : offset+size]
+		}
+		offset += size + size%2
+	}
+	return nil
+}
+
+func tiffMetadataBlocks(data []byte) imageMetadataBlocks {
+	blocks := imageMetadataBlocks{exif: data}
+	reader, ok := newEXIFReader(data)
+	if !ok {
+		return imageMetadataBlocks{}
+	}
+	for _, entry := range reader.ifd(reader.firstIFD()) {
+		switch entry.tag {
+		case 0x83BB:
+			blocks.iptc = entry.value
+		case 0x02BC:
+			blocks.xmp = entry.value
+		}
+	}
+	return blocks
+}
+
+func webpMetadataBlocks(data []byte) imageMetadataBlocks {
+	var blocks imageMetadataBlocks
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return blocks
+	}
+
+	for chunks := data[12:]; len(chunks) >= 8; {
+		size := int(binary.LittleEndian.Uint32(chunks[4:8]))
+		if size > len(chunks)-8 {
+			break
+		}
+		payload := chunks[8 : 8+size]
+		switch string(chunks[:4]) {
+		case "EXIF":
+			blocks.exif = bytes.TrimPrefix(payload, []byte("Exif\x00\x00"))
+		case "XMP ":
+			blocks.xmp = payload
+		}
+		if 8+size+size%2 > len(chunks) {
+			break
+		}
+		chunks = chunks[8+size+size%2:]
+	}
+
+	return blocks
+}
+
+func pngMetadataBlocks(data []byte) imageMetadataBlocks {
+	blocks := imageMetadataBlocks{text: map[string]string{}}
+	if len(data) < 8 || string(data[:8]) != "\x89PNG\r\n\x1a\n" {
+		return blocks
+	}
+
+	for offset := 8; offset+12 <= len(data); {
+		size := int(binary.BigEndian.Uint32(data[offset:]))
+		chunkType := string(data[offset+4 : offset+8])
+		if size < 0 || offset+12+size > len(data) {
+			break
+		}
+		payload := data[offset+8 : offset+8+size]
+		offset += 12 + size
+
+		switch chunkType {
+		case "eXIf":
+			blocks.exif = payload
+		case "tEXt":
+			if keyword, text, ok := bytes.Cut(payload, []byte{0}); ok {
+				blocks.text[string(keyword)] = string(text)
+			}
+		case "zTXt":
+			if keyword, compressed, ok := bytes.Cut(payload, []byte{0}); ok && len(compressed) > 0 {
+				if text, err := pngInflate(compressed[1:]); err == nil {
+					blocks.text[string(keyword)] = string(text)
+				}
+			}
+		case "iTXt":
+			keyword, text, ok := pngInternationalText(payload)
+			if !ok {
+				continue
+			}
+			if keyword == "XML:com.adobe.xmp" {
+				blocks.xmp = text
+			} else {
+				blocks.text[keyword] = string(text)
+			}
+		case "IDAT":
+			// Metadata written after the image data is rare, and scanning all the data chunks is not worth it
+			offset = len(data)
+		}
+	}
+
+	// Tools like ImageMagick store EXIF and IPTC as hex encoded text chunks
+	if blocks.exif == nil {
+		if raw := pngRawProfile(blocks.text["Raw profile type exif"]); raw != nil {
+			blocks.exif = bytes.TrimPrefix(raw, []byte("Exif\x00\x00"))
+		}
+	}
+	if raw := pngRawProfile(blocks.text["Raw profile type iptc"]); raw != nil {
+		if iptc := photoshopIPTC(raw); iptc != nil {
+			blocks.iptc = iptc
+		} else {
+			blocks.iptc = raw
+		}
+	}
+
+	return blocks
+}
+
+func pngInternationalText(payload []byte) (string, []byte, bool) {
+	keyword, rest, ok := bytes.Cut(payload, []byte{0})
+	if !ok || len(rest) < 2 {
+		return "", nil, false
+	}
+	compressed := rest[0] == 1
+	rest = rest[2:]
+	// Language tag and translated keyword
+	for range 2 {
+		if _, rest, ok = bytes.Cut(rest, []byte{0}); !ok {
+			return "", nil, false
+		}
+	}
+	if compressed {
+		text, err := pngInflate(rest)
+		if err != nil {
+			return "", nil, false
+		}
+		return string(keyword), text, true
+	}
+	return string(keyword), rest, true
+}
+
+func pngInflate(data []byte) ([]byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(io.LimitReader(reader, 16<<20))
+}
+
+// Decodes "\nexif\n    1234\n<hex data>" profiles
+func pngRawProfile(text string) []byte {
+	fields := strings.Fields(text)
+	if len(fields) < 3 {
+		return nil
+	}
+	raw, err := hex.DecodeString(strings.Join(fields[2:], ""))
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
+func pngTextMetadata(text map[string]string) *ImageMetadata {
+	if len(text) == 0 {
+		return nil
+	}
+	metadata := &ImageMetadata{
+		Title:       text["Title"],
+		Description: text["Description"],
+		Author:      text["Author"],
+		Copyright:   text["Copyright"],
+		Software:    text["Software"],
+	}
+	if metadata.Description == "" {
+		metadata.Description = text["Comment"]
+	}
+	if created, err := time.Parse(time.RFC1123Z, text["Creation Time"]); err == nil {
+		metadata.CaptureTime = created
+	}
+	return metadata
+}
+
+// Reads TIFF structured EXIF data
+type exifReader struct {
+	data      []byte
+	byteOrder binary.ByteOrder
+}
+
+type exifEntry struct {
+	tag      uint16
+	dataType uint16
+	count    uint32
+	value    []byte
+}
+
+func newEXIFReader(data []byte) (*exifReader, bool) {
+	if len(data) < 8 {
+		return nil, false
+	}
+	switch string(data[:4]) {
+	case "II*\x00":
+		return &exifReader{data: data, byteOrder: binary.LittleEndian}, true
+	case "MM\x00*":
+		return &exifReader{data: data, byteOrder: binary.BigEndian}, true
+	}
+	return nil, false
+}
+
+func (r *exifReader) firstIFD() uint32 {
+	return r.byteOrder.Uint32(r.data[4:8])
+}
+
+// Sizes of the EXIF data types in bytes
+var exifTypeSizes = map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+func (r *exifReader) ifd(offset uint32) []exifEntry {
+	if offset == 0 || int(offset)+2 > len(r.data) {
+		return nil
+	}
+	count := int(r.byteOrder.Uint16(r.data[offset:]))
+	var entries []exifEntry
+	for i := range count {
+		start := int(offset) + 2 + i*12
+		if start+12 > len(r.data) {
+			break
+		}
+		entry := exifEntry{
+			tag:      r.byteOrder.Uint16(r.data[start:]),
+			dataType: r.byteOrder.Uint16(r.data[start+2:]),
+			count:    r.byteOrder.Uint32(r.data[start+4:]),
+		}
+		typeSize, ok := exifTypeSizes[entry.dataType]
+		if !ok {
+			continue
+		}
+		size := int64(typeSize) * int64(entry.count)
+		if size <= 4 {
+			entry.value = r.data[start+8 : start+8+int(size)]
+		} else {
+			valueOffset := int64(r.byteOrder.Uint32(r.data[start+8:]))
+			if valueOffset+size > int64(len(r.data)) {
+				continue
+			}
+			entry.value = r.data[valueOffset : valueOffset+size]
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func (r *exifReader) string(entry exifEntry) string {
+	value := string(bytes.TrimRight(entry.value, "\x00 "))
+	if entry.dataType == 7 {
+		// User comments start with the 8 byte character code
+		if len(value) >= 8 && (strings.HasPrefix(value, "ASCII") || strings.HasPrefix(value, "UNICODE") || value[:8] == "\x00\x00\x00\x00\x00\x00\x00\x00") {
+			value = strings.TrimRight(value[8:], "\x00 ")
+		}
+	}
+	return strings.TrimSpace(value)
+}
+
+func (r *exifReader) uint(entry exifEntry) (uint32, bool) {
+	switch {
+	case entry.dataType == 3 && len(entry.value) >= 2:
+		return uint32(r.byteOrder.Uint16(entry.value)), true
+	case entry.dataType == 4 && len(entry.value) >= 4:
+		return r.byteOrder.Uint32(entry.value), true
+	case entry.dataType == 1 && len(entry.value) >= 1:
+		return uint32(entry.value[0]), true
+	}
+	return 0, false
+}
+
+func (r *exifReader) rationals(entry exifEntry) []float64 {
+	if entry.dataType != 5 && entry.dataType != 10 {
+		return nil
+	}
+	values := make([]float64, 0, entry.count)
+	for i := 0; i+8 <= len(entry.value); i += 8 {
+		numerator, denominator := r.byteOrder.Uint32(entry.value[i:]), r.byteOrder.Uint32(entry.value[i+4:])
+		if denominator == 0 {
+			values = append(values, 0)
+		} else if entry.dataType == 10 {
+			values = append(values, float64(int32(numerator))/float64(int32(denominator)))
+		} else {
+			values = append(values, float64(numerator)/float64(denominator))
+		}
+	}
+	return values
+}
+
+// Parses EXIF data in TIFF format. Never returns nil, so result can be merged with other metadata.
+func parseEXIF(data []byte) *ImageMetadata {
+	metadata := &ImageMetadata{}
+	reader, ok := newEXIFReader(data)
+	if !ok {
+		return metadata
+	}
+
+	var exifIFD, gpsIFD uint32
+	var modified string
+	for _, entry := range reader.ifd(reader.firstIFD()) {
+		switch entry.tag {
+		case 0x010E:
+			metadata.Description = reader.string(entry)
+		case 0x010F:
+			metadata.CameraMake = reader.string(entry)
+		case 0x0110:
+			metadata.CameraModel = reader.string(entry)
+		case 0x0112:
+			if orientation, ok := reader.uint(entry); ok && orientation >= 1 && orientation <= 8 {
+				metadata.Orientation = int(orientation)
+			}
+		case 0x0131:
+			metadata.Software = reader.string(entry)
+		case 0x0132:
+			modified = reader.string(entry)
+		case 0x013B:
+			metadata.Author = reader.string(entry)
+		case 0x8298:
+			metadata.Copyright = reader.string(entry)
+		case 0x8769:
+			exifIFD, _ = reader.uint(entry)
+		case 0x8825:
+			gpsIFD, _ = reader.uint(entry)
+		}
+	}
+
+	var original, offset string
+	for _, entry := range reader.ifd(exifIFD) {
+		switch entry.tag {
+		case 0x9003:
+			original = reader.string(entry)
+		case 0x9011:
+			offset = reader.string(entry)
+		case 0x9286:
+			if metadata.Description == "" {
+				metadata.Description = reader.string(entry)
+			}
+		}
+	}
+	if original == "" {
+		original = modified
+	}
+	metadata.CaptureTime = exifTime(original, offset)
+
+	var latitude, longitude []float64
+	var latitudeRef, longitudeRef string
+	var altitude float64
+	var belowSeaLevel bool
+	for _, entry := range reader.ifd(gpsIFD) {
+		switch entry.tag {
+		case 0x0001:
+			latitudeRef = reader.string(entry)
+		case 0x0002:
+			latitude = reader.rationals(entry)
+		case 0x0003:
+			longitudeRef = reader.string(entry)
+		case 0x0004:
+			longitude = reader.rationals(entry)
+		case 0x0005:
+			belowSeaLevel = len(entry.value) > 0 && entry.value[0] == 1
+		case 0x0006:
+			if values := reader.rationals(entry); len(values) > 0 {
+				altitude = values[0]
+			}
+		}
+	}
+	if len(latitude) == 3 && len(longitude) == 3 {
+		location := &ImageLocation{
+			Latitude:  latitude[0] + latitude[1]/60 + latitude[2]/3600,
+			Longitude: longitude[0] + longitude[1]/60 + longitude[2]/3600,
+			Altitude:  altitude,
+		}
+		if latitudeRef == "S" {
+			location.Latitude = -location.Latitude
+		}
+		if longitudeRef == "W" {
+			location.Longitude = -location.Longitude
+		}
+		if belowSeaLevel {
+			location.Altitude = -location.Altitude
+		}
+		metadata.Location = location
+	}
+
+	return metadata
+}
+
+// EXIF dates have no time zone unless offset tag is present. Such dates are returned in UTC.
+func exifTime(value string, offset string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	if offset != "" {
+		if t, err := time.Parse("2006:01:02 15:04:05-07:00", value+offset); err == nil {
+			return t
+		}
+	}
+	t, err := time.Parse("2006:01:02 15:04:05", value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// Parses IPTC-IIM application record
+func parseIPTC(data []byte) *ImageMetadata {
+	if len(data) == 0 {
+		return nil
+	}
+
+	metadata := &ImageMetadata{}
+	var date, clock string
+	for offset := 0; offset+5 <= len(data); {
+		if data[offset] != 0x1C {
+			break
+		}
+		record, dataset := data[offset+1], data[offset+2]
+		size := int(binary.BigEndian.Uint16(data[offset+3:]))
+		offset += 5
+		if size&0x8000 != 0 || offset+size > len(data) {
+			// Extended datasets are not used by the fields we read
+			break
+		}
+		value := strings.TrimSpace(string(data[offset : offset+size]))
+		offset += size
+		if record != 2 {
+			continue
+		}
+
+		switch dataset {
+		case 5:
+			metadata.Title = value
+		case 25:
+			if value != "" && !slices.Contains(metadata.Keywords, value) {
+				metadata.Keywords = append(metadata.Keywords, value)
+			}
+		case 55:
+			date = value
+		case 60:
+			clock = value
+		case 80:
+			metadata.Author = value
+		case 90:
+			metadata.City = value
+		case 101:
+			metadata.Country = value
+		case 116:
+			metadata.Copyright = value
+		case 120:
+			metadata.Description = value
+		}
+	}
+
+	if date != "" {
+		if t, err := time.Parse("20060102150405-0700", date+clock); err == nil {
+			metadata.CaptureTime = t
+		} else if t, err := time.Parse("20060102", date); err == nil {
+			metadata.CaptureTime = t
+		}
+	}
+
+	return metadata
+}
+
+// XMP namespaces of the properties we read
+const (
+	xmpNamespaceRDF       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmpNamespaceDC        = "http://purl.org/dc/elements/1.1/"
+	xmpNamespaceXMP       = "http://ns.adobe.com/xap/1.0/"
+	xmpNamespacePhotoshop = "http://ns.adobe.com/photoshop/1.0/"
+	xmpNamespaceEXIF      = "http://ns.adobe.com/exif/1.0/"
+	xmpNamespaceTIFF      = "http://ns.adobe.com/tiff/1.0/"
+	xmpNamespaceIPTC      = "http://iptc.org/std/Iptc4xmpCore/1.0/xmlns/"
+)
+
+// Collects values of the XMP properties. Values of the arrays are joined into single list.
+func xmpProperties(data []byte) map[xml.Name][]string {
+	properties := map[xml.Name][]string{}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+
+	var stack []xml.Name
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			if token.Name.Space == xmpNamespaceRDF && token.Name.Local == "Description" {
+				for _, attr := range token.Attr {
+					if attr.Name.Space != xmpNamespaceRDF && attr.Name.Space != "xmlns" && attr.Name.Space != "" {
+						properties[attr.Name] = append(properties[attr.Name], attr.Value)
+					}
+				}
+			}
+			stack = append(stack, token.Name)
+			text.Reset()
+		case xml.CharData:
+			text.Write(token)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				break
+			}
+			stack = stack[:len(stack)-1]
+			value := strings.TrimSpace(text.String())
+			text.Reset()
+			if value == "" {
+				break
+			}
+
+			name := token.Name
+			if name.Space == xmpNamespaceRDF {
+				if name.Local != "li" {
+					break
+				}
+				// Array item belongs to the closest property outside of the RDF namespace
+				name = xml.Name{}
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i].Space != xmpNamespaceRDF {
+						name = stack[i]
+						break
+					}
+				}
+				if name.Local == "" {
+					break
+				}
+			}
+			properties[name] = append(properties[name], value)
+		}
+	}
+
+	return properties
+}
+
+func parseXMP(data []byte) *ImageMetadata {
+	if len(data) == 0 {
+		return nil
+	}
+
+	properties := xmpProperties(data)
+	first := func(space string, local string) string {
+		if values := properties[xml.Name{Space: space, Local: local}]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	metadata := &ImageMetadata{
+		Title:       first(xmpNamespaceDC, "title"),
+		Description: first(xmpNamespaceDC, "description"),
+		Author:      strings.Join(properties[xml.Name{Space: xmpNamespaceDC, Local: "creator"}], ", "),
+		Copyright:   first(xmpNamespaceDC, "rights"),
+		CameraMake:  first(xmpNamespaceTIFF, "Make"),
+		CameraModel: first(xmpNamespaceTIFF, "Model"),
+		Software:    first(xmpNamespaceXMP, "CreatorTool"),
+		City:        first(xmpNamespacePhotoshop, "City"),
+		Country:     first(xmpNamespacePhotoshop, "Country"),
+	}
+	for _, keyword := range properties[xml.Name{Space: xmpNamespaceDC, Local: "subject"}] {
+		if !slices.Contains(metadata.Keywords, keyword) {
+			metadata.Keywords = append(metadata.Keywords, keyword)
+		}
+	}
+	if metadata.City == "" {
+		metadata.City = first(xmpNamespaceIPTC, "Location")
+	}
+	if orientation, err := strconv.Atoi(first(xmpNamespaceTIFF, "Orientation")); err == nil && orientation >= 1 && orientation <= 8 {
+		metadata.Orientation = orientation
+	}
+	for _, date := range []string{
+		first(xmpNamespaceEXIF, "DateTimeOriginal"),
+		first(xmpNamespacePhotoshop, "DateCreated"),
+		first(xmpNamespaceXMP, "CreateDate"),
+	} {
+		if t, ok := xmpDate(date); ok {
+			metadata.CaptureTime = t
+			break
+		}
+	}
+
+	return metadata
+}
+
+func xmpDate(value string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04Z07:00", "2006-01-02T15:04", "2006-01-02", "2006-01", "2006"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Rotates and mirrors image according to the EXIF orientation, so text is upright
+func orientImage(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	source := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(source, source.Bounds(), img, bounds.Min, draw.Src)
+
+	outW, outH := w, h
+	if orientation >= 5 {
+		outW, outH = h, w
+	}
+	result := image.NewRGBA(image.Rect(0, 0, outW, outH))
+	for y := range h {
+		for x := range w {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			copy(result.Pix[result.PixOffset(dx, dy):][:4], source.Pix[source.PixOffset(x, y):][:4])
+		}
+	}
+	return result
+}
+
+// Reads metadata of the image and prepares it for the OCR. Rotated images are turned upright and images in the formats not supported
+// by OCR provider are transcoded to PNG.
+func prepareImageWithMetadata(ocrProvider ocr.Provider, mimeType string, file io.Reader, decode func(io.Reader) (image.Image, error)) (io.Reader, *ImageMetadata, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, nil, errors.Join(errors.New("failed to read image"), err)
+	}
+
+	metadata := readImageMetadata(mimeType, data)
+	rotate := metadata != nil && metadata.Orientation > 1
+	if !rotate && ocrProvider.IsMimeTypeSupported(mimeType) {
+		return bytes.NewReader(data), metadata, nil
+	}
+
+	format := strings.TrimPrefix(mimeType, "image/")
+	img, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, errors.Join(ErrBadFile, fmt.Errorf("failed to decode %s image for transcoding", format), err)
+	}
+	if rotate {
+		img = orientImage(img, metadata.Orientation)
+	}
+
+	var outBuf bytes.Buffer
+	if err := png.Encode(&outBuf, img); err != nil {
+		return nil, nil, errors.Join(errors.New("failed to transcode image to PNG"), err)
+	}
+	return &outBuf, metadata, nil
+}
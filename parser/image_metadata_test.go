@@ -0,0 +1,139 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opengs/file2llm/ocr"
+	testdata "github.com/opengs/file2llm/test_data"
+)
+
+func TestImageMetadata(t *testing.T) {
+	cases := []struct {
+		name     string
+		mimeType string
+		data     []byte
+	}{
+		{"jpeg", "image/jpeg", testdata.JPEGMetadata},
+		{"png", "image/png", testdata.PNGMetadata},
+		{"webp", "image/webp", testdata.WEBPMetadata},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			metadata := readImageMetadata(c.mimeType, c.data)
+			if metadata == nil {
+				t.Fatal("metadata not found")
+			}
+			if metadata.CameraMake != "Canon" || metadata.CameraModel != "Canon EOS R5" || metadata.Orientation != 6 {
+				t.Errorf("wrong camera: %+v", metadata)
+			}
+			if !metadata.CaptureTime.Equal(time.Date(2024, 5, 6, 5, 8, 9, 0, time.UTC)) {
+				t.Errorf("wrong capture time %v", metadata.CaptureTime)
+			}
+			if metadata.Location == nil || int(metadata.Location.Latitude*1000) != 50450 || int(metadata.Location.Longitude*1000) != 30523 ||
+				metadata.Location.Altitude != 179 {
+				t.Errorf("wrong location %+v", metadata.Location)
+			}
+			if metadata.Title != "Planning meeting" || metadata.City != "Kyiv" {
+				t.Errorf("XMP is not merged: %+v", metadata)
+			}
+
+			text := metadata.String()
+			for _, expected := range []string{"Camera: Canon EOS R5\n", "Captured: 2024-05-06T07:08:09+02:00\n", "Location: 50.450", "Orientation: 6\n"} {
+				if !strings.Contains(text, expected) {
+					t.Errorf("%q not found in %q", expected, text)
+				}
+			}
+		})
+	}
+
+	metadata := readImageMetadata("image/jpeg", testdata.JPEGMetadata)
+	if metadata.Description != "Whiteboard after sprint planning" || metadata.Author != "Jane Doe" || metadata.Country != "Ukraine" {
+		t.Errorf("IPTC is not merged: %+v", metadata)
+	}
+	if !slices.Equal(metadata.Keywords, []string{"whiteboard", "planning", "meeting"}) {
+		t.Errorf("wrong keywords %v", metadata.Keywords)
+	}
+
+	for _, data := range [][]byte{testdata.JPEG, testdata.PNG, testdata.WEBP} {
+		if metadata := readImageMetadata("image/jpeg", data); metadata != nil {
+			t.Errorf("unexpected metadata %+v", metadata)
+		}
+	}
+}
+
+func TestImageOrientation(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	img.Set(0, 0, color.RGBA{255, 0, 0, 255})
+
+	cases := []struct {
+		orientation int
+		x, y        int
+	}{
+		{1, 0, 0}, {2, 2, 0}, {3, 2, 1}, {4, 0, 1}, {5, 0, 0}, {6, 1, 0}, {7, 1, 2}, {8, 0, 2},
+	}
+	for _, c := range cases {
+		oriented := orientImage(img, c.orientation)
+		if r, _, _, _ := oriented.At(c.x, c.y).RGBA(); r == 0 {
+			t.Errorf("orientation %d: marked pixel is not at (%d, %d)", c.orientation, c.x, c.y)
+		}
+		if c.orientation >= 5 && oriented.Bounds().Dx() != 2 {
+			t.Errorf("orientation %d: image is not rotated", c.orientation)
+		}
+	}
+}
+
+func TestJPEGMetadata(t *testing.T) {
+	cfg := ocr.DefaultTesseractConfig()
+	cfg.SupportedImageFormats = []string{"image/jpeg", "image/png"}
+	ocrProvider := ocr.NewTestingOCRProvider(t, cfg)
+	jpegParser := NewJPEGParser(ocrProvider)
+	result := jpegParser.Parse(context.Background(), bytes.NewReader(testdata.JPEGMetadata), "")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+
+	if result.(*ImageParserResult).Metadata == nil {
+		t.Fatal("metadata not found")
+	}
+	resultString := strings.ToLower(result.String())
+	if !strings.HasPrefix(resultString, "------ metadata ------\n") || !strings.Contains(resultString, "hello") {
+		t.Error(resultString)
+	}
+}
+
+func TestJPEGMetadataStream(t *testing.T) {
+	cfg := ocr.DefaultTesseractConfig()
+	cfg.SupportedImageFormats = []string{"image/jpeg", "image/png"}
+	ocrProvider := ocr.NewTestingOCRProvider(t, cfg)
+	jpegParser := NewJPEGParser(ocrProvider)
+
+	var metadata *ImageMetadata
+	var text strings.Builder
+	parseProgress := jpegParser.ParseStream(context.Background(), bytes.NewReader(testdata.JPEGMetadata), "")
+	defer parseProgress.Close()
+	for parseProgress.Next(t.Context()) {
+		progress := parseProgress.Current().(*ImageParserStreamResult)
+		if progress.Error() != nil {
+			t.Fatal(progress.Error())
+		}
+		if progress.Metadata != nil {
+			metadata = progress.Metadata
+		}
+		text.WriteString(progress.String())
+	}
+
+	if metadata == nil || metadata.Orientation != 6 {
+		t.Errorf("wrong metadata %+v", metadata)
+	}
+	if !strings.Contains(strings.ToLower(text.String()), "hello") {
+		t.Error(text.String())
+	}
+}
@@ -3,14 +3,23 @@ package parser
 import (
 	"context"
 	"io"
+	"strings"
 
 	"github.com/opengs/file2llm/ocr"
 )
 
 type ImageParserResult struct {
 	FullPath string `json:"path"`
-	Text     string `json:"text"`
-	Err      error  `json:"error"`
+	// EXIF, IPTC and XMP metadata. Nil if image has no metadata.
+	Metadata *ImageMetadata `json:"metadata"`
+	Text     string         `json:"text"`
+	// Recognized frames of the animation or pages of the multi page image. Empty for single images.
+	Pages []ImagePage `json:"pages"`
+	// Pages are frames of the animation
+	Animated bool `json:"animated"`
+	// Image has more frames than allowed by [WithImageMaxFrames]
+	Truncated bool  `json:"truncated"`
+	Err       error `json:"error"`
 }
 
 func (r *ImageParserResult) Path() string {
@@ -18,7 +27,33 @@ func (r *ImageParserResult) Path() string {
 }
 
 func (r *ImageParserResult) String() string {
-	return r.Text
+	if r.Metadata == nil {
+		return r.Document().String()
+	}
+	return r.Metadata.String() + r.Document().String()
+}
+
+// Recognized text of the image. Every page of the multi page image begins with page break.
+func (r *ImageParserResult) Document() *Document {
+	if len(r.Pages) == 0 || r.Err != nil {
+		return &Document{Blocks: []Block{{Kind: BlockImage, Text: r.Text, Location: noLocation}}}
+	}
+
+	document := &Document{}
+	for _, page := range r.Pages {
+		document.Blocks = append(document.Blocks, imagePageBlocks(page, r.Animated)...)
+	}
+	if r.Truncated {
+		document.Blocks = append(document.Blocks, Block{Kind: BlockParagraph, Text: strings.TrimSuffix(imageTruncatedLine(len(r.Pages), r.Animated), "\n"), Location: noLocation, Separator: "\n"})
+	}
+	return document
+}
+
+func (r *ImageParserResult) MetadataFields() []MetadataField {
+	if r.Metadata == nil {
+		return nil
+	}
+	return r.Metadata.fields()
 }
 
 func (r *ImageParserResult) Error() error {
@@ -34,7 +69,11 @@ type ImageParserStreamResult struct {
 	Text            string             `json:"text"`
 	CurrentStage    ParseProgressStage `json:"stage"`
 	CurrentProgress uint8              `json:"progress"`
-	Err             error              `json:"error"`
+	// Sent once with the first update. Its text is part of the update text.
+	Metadata *ImageMetadata `json:"metadata"`
+	// Number of the frame or page of the multi frame image which is processed now. Zero for single images.
+	PageNumber int   `json:"page"`
+	Err        error `json:"error"`
 }
 
 func (r *ImageParserStreamResult) Path() string {
@@ -65,7 +104,7 @@ type ImageStreamResultIterator struct {
 	path                  string
 	ocrProvider           ocr.Provider
 	file                  io.Reader
-	imagePreparation      func(file io.Reader) (io.Reader, error)
+	imagePreparation      func(file io.Reader) (io.Reader, *ImageMetadata, error)
 	imagePreparationError error
 	imagePrepared         bool
 
@@ -97,19 +136,29 @@ func (i *ImageStreamResultIterator) Next(ctx context.Context) bool {
 
 	if !i.imagePrepared && i.imagePreparation != nil {
 		i.imagePrepared = true
-		i.file, i.imagePreparationError = i.imagePreparation(i.file)
+		var metadata *ImageMetadata
+		i.file, metadata, i.imagePreparationError = i.imagePreparation(i.file)
 		i.current = &ImageParserStreamResult{
 			FullPath:     i.path,
 			CurrentStage: ProgressNew,
 		}
-		if i.imagePreparationError != nil {
-			return true
+		if metadata != nil {
+			i.current = &ImageParserStreamResult{
+				FullPath:     i.path,
+				CurrentStage: ProgressNew,
+				Metadata:     metadata,
+				Text:         metadata.String(),
+			}
 		}
+		if i.imagePreparationError == nil {
+			// Result with metadata is the first result of the stream
+			i.startOCR()
+		}
+		return true
 	}
 
 	if i.ocrProgress == nil {
-		i.ocrContext, i.ocrCancel = context.WithCancel(i.baseContext)
-		i.ocrProgress = i.ocrProvider.OCRWithProgress(i.ocrContext, i.file)
+		i.startOCR()
 		i.current = &ImageParserStreamResult{
 			FullPath:     i.path,
 			CurrentStage: ProgressNew,
@@ -143,6 +192,11 @@ func (i *ImageStreamResultIterator) Next(ctx context.Context) bool {
 	}
 }
 
+func (i *ImageStreamResultIterator) startOCR() {
+	i.ocrContext, i.ocrCancel = context.WithCancel(i.baseContext)
+	i.ocrProgress = i.ocrProvider.OCRWithProgress(i.ocrContext, i.file)
+}
+
 func (i *ImageStreamResultIterator) Current() StreamResult {
 	return i.current
 }
@@ -1,14 +1,11 @@
 package parser
 
 import (
-	"bytes"
 	"context"
 	"errors"
-	"image"
 	"io"
 
-	_ "image/jpeg"
-	"image/png"
+	"image/jpeg"
 
 	"github.com/opengs/file2llm/ocr"
 )
@@ -24,33 +21,21 @@ func NewJPEGParser(ocrProvider ocr.Provider) *JPEGParser {
 	}
 }
 
+// Configuration that changes the result. Used in the cache keys.
+func (p *JPEGParser) CacheFingerprint() string {
+	return "ocr=" + cacheFingerprint(p.ocrProvider)
+}
+
 func (p *JPEGParser) SupportedMimeTypes() []string {
 	return []string{"image/jpeg"}
 }
 
-func (p *JPEGParser) prepareData(file io.Reader) (io.Reader, error) {
-	var imageData io.Reader
-
-	if p.ocrProvider.IsMimeTypeSupported("image/jpeg") {
-		imageData = file
-	} else {
-		img, _, err := image.Decode(file)
-		if err != nil {
-			return nil, errors.Join(ErrBadFile, errors.New("failed to decode jpeg image for transcoding"), err)
-		}
-
-		var outBuf bytes.Buffer
-		if err := png.Encode(&outBuf, img); err != nil {
-			return nil, errors.Join(errors.New("failed to transcode image to PNG"), err)
-		}
-		imageData = &outBuf
-	}
-
-	return imageData, nil
+func (p *JPEGParser) prepareData(file io.Reader) (io.Reader, *ImageMetadata, error) {
+	return prepareImageWithMetadata(p.ocrProvider, "image/jpeg", file, jpeg.Decode)
 }
 
 func (p *JPEGParser) Parse(ctx context.Context, file io.Reader, path string) Result {
-	imageData, err := p.prepareData(file)
+	imageData, metadata, err := p.prepareData(file)
 	if err != nil {
 		return &ImageParserResult{Err: errors.Join(errors.New("failed to prepare image data"), err), FullPath: path}
 	}
@@ -60,7 +45,7 @@ func (p *JPEGParser) Parse(ctx context.Context, file io.Reader, path string) Res
 		return &ImageParserResult{Err: errors.Join(errors.New("errors while running OCR"), err), FullPath: path}
 	}
 
-	return &ImageParserResult{Text: text, FullPath: path}
+	return &ImageParserResult{Text: text, Metadata: metadata, FullPath: path}
 }
 
 func (p *JPEGParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
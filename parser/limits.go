@@ -0,0 +1,229 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Limits protect from archive bombs and deeply nested files. Zero value of the field disables the limit.
+type Limits struct {
+	// Maximum nesting depth of the subfiles. Top level file has depth 0, files inside of the archive have depth 1 and so on.
+	MaxDepth int
+	// Maximum total size of all the subfiles of the top level file in bytes, after decompression
+	MaxTotalBytes int64
+	// Maximum number of entries in the single archive, email or other container
+	MaxEntries int
+	// Maximum size of the single subfile in bytes, after decompression
+	MaxEntrySize int64
+	// Maximum time to parse top level file with all its subfiles
+	Timeout time.Duration
+}
+
+// Limits used by [CompositeParser] if other are not configured
+var DefaultLimits = Limits{
+	MaxDepth:      16,
+	MaxTotalBytes: 4 << 30,
+	MaxEntries:    100000,
+	MaxEntrySize:  1 << 30,
+}
+
+// Sets limits that are enforced for every top level file and all its subfiles
+func WithCompositeLimits(limits Limits) CompositeOption {
+	return func(c *compositeConfig) {
+		c.limits = limits
+	}
+}
+
+type LimitKind string
+
+const (
+	LimitDepth      LimitKind = "depth"
+	LimitTotalBytes LimitKind = "total bytes"
+	LimitEntries    LimitKind = "entries"
+	LimitEntrySize  LimitKind = "entry size"
+	LimitTimeout    LimitKind = "timeout"
+)
+
+// One of the [Limits] was exceeded. Use `errors.As` with [LimitError] to find out which one.
+var ErrLimitExceeded = errors.New("parsing limit exceeded")
+
+// File exceeded one of the [Limits]
+type LimitError struct {
+	Limit LimitKind `json:"limit"`
+	// Configured value of the limit. Nanoseconds for the timeout.
+	Max int64 `json:"max"`
+	// File that exceeded the limit
+	Path string `json:"path"`
+}
+
+func (e *LimitError) Error() string {
+	max := fmt.Sprint(e.Max)
+	if e.Limit == LimitTimeout {
+		max = time.Duration(e.Max).String()
+	}
+	return fmt.Sprintf("%s limit %s exceeded by %s", e.Limit, max, e.Path)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+type parseStateContextKey struct{}
+
+// Marks subfiles created by the parser itself, like rendered PDF pages
+type generatedSubfileContextKey struct{}
+
+// Limits state of the top level file shared with all its subfiles
+type parseState struct {
+	limits Limits
+	depth  int
+	// Bytes of the subfiles read so far
+	totalBytes *atomic.Int64
+	// Zero if there is no timeout
+	deadline time.Time
+	// File is generated by the parent parser and not expanded from the top level file. Its size is not limited.
+	generated bool
+}
+
+func parseStateFromContext(ctx context.Context) *parseState {
+	state, _ := ctx.Value(parseStateContextKey{}).(*parseState)
+	return state
+}
+
+// Limits of the file that is parsed with the context. Default limits are returned outside of the [CompositeParser].
+func limitsFromContext(ctx context.Context) Limits {
+	if state := parseStateFromContext(ctx); state != nil {
+		return state.limits
+	}
+	return DefaultLimits
+}
+
+// Context for the subfiles that parser generates itself, for example page images rendered for OCR.
+// Size limits are not applied to them, because they do not come from the parsed file.
+func generatedSubfileContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, generatedSubfileContextKey{}, parseStateFromContext(ctx))
+}
+
+// Returns [LimitError] if container at `path` has more than allowed entries
+func checkEntryLimit(ctx context.Context, entries int, path string) error {
+	limits := limitsFromContext(ctx)
+	if limits.MaxEntries > 0 && entries > limits.MaxEntries {
+		return &LimitError{Limit: LimitEntries, Max: int64(limits.MaxEntries), Path: path}
+	}
+	return nil
+}
+
+// Returns state for the file. Top level file starts new state, subfiles inherit it with increased depth.
+func newParseState(ctx context.Context, limits Limits, path string) (*parseState, error) {
+	parent := parseStateFromContext(ctx)
+	if parent == nil {
+		state := &parseState{limits: limits, totalBytes: new(atomic.Int64)}
+		if limits.Timeout > 0 {
+			state.deadline = time.Now().Add(limits.Timeout)
+		}
+		return state, nil
+	}
+
+	state := *parent
+	state.depth += 1
+	state.generated = ctx.Value(generatedSubfileContextKey{}) == parent
+	if state.limits.MaxDepth > 0 && state.depth > state.limits.MaxDepth {
+		return nil, &LimitError{Limit: LimitDepth, Max: int64(state.limits.MaxDepth), Path: path}
+	}
+	return &state, nil
+}
+
+// State of the nested file that parser handles itself instead of passing it to the [CompositeParser], like email attached to the email.
+// Nested file counts to the depth and size limits as any other subfile. Parsers used without [CompositeParser] get [DefaultLimits].
+func nestedParseState(ctx context.Context, path string) (*parseState, error) {
+	if parseStateFromContext(ctx) == nil {
+		ctx = context.WithValue(ctx, parseStateContextKey{}, &parseState{limits: DefaultLimits, totalBytes: new(atomic.Int64)})
+	}
+	return newParseState(ctx, Limits{}, path)
+}
+
+// State for the parts of the file, like entries of the zip package. Parts count to the size limits as subfiles, but not to the depth.
+func partParseState(ctx context.Context) *parseState {
+	parent := parseStateFromContext(ctx)
+	if parent == nil {
+		parent = &parseState{limits: DefaultLimits, totalBytes: new(atomic.Int64)}
+	}
+	state := *parent
+	state.depth += 1
+	state.generated = false
+	return &state
+}
+
+// Context for the parsers of the file. Context is canceled when timeout of the top level file is reached.
+func (s *parseState) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = context.WithValue(ctx, parseStateContextKey{}, s)
+	if s.deadline.IsZero() {
+		return ctx, func() {}
+	}
+	return context.WithDeadline(ctx, s.deadline)
+}
+
+// Returns [LimitError] if timeout of the top level file is reached
+func (s *parseState) timeoutError(path string) error {
+	if !s.deadline.IsZero() && !time.Now().Before(s.deadline) {
+		return &LimitError{Limit: LimitTimeout, Max: int64(s.limits.Timeout), Path: path}
+	}
+	return nil
+}
+
+// Wraps file so reading it fails when it exceeds size limits or timeout
+func (s *parseState) reader(file io.Reader, path string) *limitedReader {
+	return &limitedReader{reader: file, state: s, path: path}
+}
+
+type limitedReader struct {
+	reader io.Reader
+	state  *parseState
+	path   string
+	read   int64
+	// Limit error returned by the last read
+	limitErr error
+}
+
+// Adds limit error of the reader to the parser error. Some libraries do not wrap errors of the reader.
+func (r *limitedReader) wrapError(err error) error {
+	if err == nil || r.limitErr == nil || errors.Is(err, ErrLimitExceeded) {
+		return err
+	}
+	return errors.Join(r.limitErr, err)
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if err := r.state.timeoutError(r.path); err != nil {
+		return 0, err
+	}
+
+	n, err := r.reader.Read(p)
+	if r.state.depth == 0 || r.state.generated {
+		// Size of the top level file is not limited
+		return n, err
+	}
+
+	// Bytes over the limit are not returned, so parser can not finish the file without seeing the error
+	r.read += int64(n)
+	limits := r.state.limits
+	var limitErr error
+	if limits.MaxEntrySize > 0 && r.read > limits.MaxEntrySize {
+		n -= int(min(r.read-limits.MaxEntrySize, int64(n)))
+		limitErr = &LimitError{Limit: LimitEntrySize, Max: limits.MaxEntrySize, Path: r.path}
+	}
+	// Both limits can be exceeded by the same read. The one that allows less bytes is reported.
+	if total := r.state.totalBytes.Add(int64(n)); limits.MaxTotalBytes > 0 && total > limits.MaxTotalBytes {
+		n -= int(min(total-limits.MaxTotalBytes, int64(n)))
+		limitErr = &LimitError{Limit: LimitTotalBytes, Max: limits.MaxTotalBytes, Path: r.path}
+	}
+	if limitErr != nil {
+		r.limitErr = limitErr
+		return n, limitErr
+	}
+	return n, err
+}
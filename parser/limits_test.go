@@ -0,0 +1,279 @@
+package parser
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	testdata "github.com/opengs/file2llm/test_data"
+)
+
+type tarTestFile struct {
+	name string
+	data []byte
+}
+
+func buildTar(t *testing.T, files ...tarTestFile) []byte {
+	var buffer bytes.Buffer
+	writer := tar.NewWriter(&buffer)
+	for _, file := range files {
+		if err := writer.WriteHeader(&tar.Header{Name: file.name, Mode: 0644, Size: int64(len(file.data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := writer.Write(file.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func newLimitsTestParser(limits Limits) *CompositeParser {
+	composite := NewCompositeParser(NewTextParser())
+	composite.AddParsers(NewTARParser(composite))
+	composite.Configure(WithCompositeLimits(limits))
+	return composite
+}
+
+func expectLimitError(t *testing.T, err error, kind LimitKind) {
+	t.Helper()
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("expected %s limit error, got %v", kind, err)
+	}
+	if limitErr.Limit != kind {
+		t.Errorf("expected %s limit, got %s", kind, limitErr.Limit)
+	}
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("limit error must wrap ErrLimitExceeded")
+	}
+}
+
+// Collects errors of all the stream results and their subresults
+func collectStreamErrors(ctx context.Context, iterator StreamResultIterator) []error {
+	defer iterator.Close()
+	var errs []error
+	for iterator.Next(ctx) {
+		for current := iterator.Current(); current != nil; current = current.SubResult() {
+			if current.Error() != nil {
+				errs = append(errs, current.Error())
+				break
+			}
+		}
+	}
+	return errs
+}
+
+func TestLimitsDepth(t *testing.T) {
+	inner := buildTar(t, tarTestFile{name: "file.txt", data: []byte("nested text file")})
+	outer := buildTar(t, tarTestFile{name: "inner.tar", data: inner})
+
+	result := newLimitsTestParser(DefaultLimits).Parse(context.Background(), bytes.NewReader(outer), "outer.tar")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+	if !strings.Contains(result.String(), "nested text file") {
+		t.Errorf("expected nested file with default limits, got %q", result.String())
+	}
+
+	result = newLimitsTestParser(Limits{MaxDepth: 1}).Parse(context.Background(), bytes.NewReader(outer), "outer.tar")
+	innerResult := result.Subfiles()[0]
+	if innerResult.Error() != nil {
+		t.Fatal(innerResult.Error())
+	}
+	expectLimitError(t, innerResult.Subfiles()[0].Error(), LimitDepth)
+
+	errs := collectStreamErrors(context.Background(), newLimitsTestParser(Limits{MaxDepth: 1}).ParseStream(context.Background(), bytes.NewReader(outer), "outer.tar"))
+	if len(errs) != 1 {
+		t.Fatalf("expected single error in stream, got %v", errs)
+	}
+	expectLimitError(t, errs[0], LimitDepth)
+}
+
+func TestLimitsEntries(t *testing.T) {
+	data := buildTar(t,
+		tarTestFile{name: "1.txt", data: []byte("first")},
+		tarTestFile{name: "2.txt", data: []byte("second")},
+		tarTestFile{name: "3.txt", data: []byte("third")},
+	)
+
+	result := newLimitsTestParser(Limits{MaxEntries: 2}).Parse(context.Background(), bytes.NewReader(data), "file.tar")
+	expectLimitError(t, result.Error(), LimitEntries)
+	if len(result.Subfiles()) != 2 {
+		t.Errorf("expected 2 parsed entries before the limit, got %d", len(result.Subfiles()))
+	}
+
+	errs := collectStreamErrors(context.Background(), newLimitsTestParser(Limits{MaxEntries: 2}).ParseStream(context.Background(), bytes.NewReader(data), "file.tar"))
+	if len(errs) != 1 {
+		t.Fatalf("expected single error in stream, got %v", errs)
+	}
+	expectLimitError(t, errs[0], LimitEntries)
+}
+
+func TestLimitsEntrySize(t *testing.T) {
+	data := buildTar(t,
+		tarTestFile{name: "small.txt", data: []byte("small")},
+		tarTestFile{name: "large.txt", data: bytes.Repeat([]byte("large "), 100)},
+	)
+
+	result := newLimitsTestParser(Limits{MaxEntrySize: 100}).Parse(context.Background(), bytes.NewReader(data), "file.tar")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+	if result.Subfiles()[0].Error() != nil {
+		t.Errorf("small file must be parsed, got %v", result.Subfiles()[0].Error())
+	}
+	expectLimitError(t, result.Subfiles()[1].Error(), LimitEntrySize)
+}
+
+func TestLimitsTotalBytes(t *testing.T) {
+	data := buildTar(t,
+		tarTestFile{name: "1.txt", data: bytes.Repeat([]byte("a"), 100)},
+		tarTestFile{name: "2.txt", data: bytes.Repeat([]byte("b"), 100)},
+		tarTestFile{name: "3.txt", data: bytes.Repeat([]byte("c"), 100)},
+	)
+
+	result := newLimitsTestParser(Limits{MaxTotalBytes: 250}).Parse(context.Background(), bytes.NewReader(data), "file.tar")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+	for _, subfile := range result.Subfiles()[:2] {
+		if subfile.Error() != nil {
+			t.Errorf("%s must be parsed, got %v", subfile.Path(), subfile.Error())
+		}
+	}
+	expectLimitError(t, result.Subfiles()[2].Error(), LimitTotalBytes)
+
+	// Every top level file has its own budget
+	result = newLimitsTestParser(Limits{MaxTotalBytes: 250}).Parse(context.Background(), bytes.NewReader(data[:1024]), "file.tar")
+	if result.Subfiles()[0].Error() != nil {
+		t.Errorf("budget must not be shared between top level files, got %v", result.Subfiles()[0].Error())
+	}
+}
+
+func TestLimitsEntryAndTotalBytes(t *testing.T) {
+	// Single read exceeds both limits, total limit is reached first
+	data := buildTar(t,
+		tarTestFile{name: "1.txt", data: bytes.Repeat([]byte("a"), 60)},
+		tarTestFile{name: "2.txt", data: bytes.Repeat([]byte("b"), 300)},
+	)
+
+	result := newLimitsTestParser(Limits{MaxEntrySize: 200, MaxTotalBytes: 100}).Parse(context.Background(), bytes.NewReader(data), "file.tar")
+	if result.Subfiles()[0].Error() != nil {
+		t.Errorf("first file must be parsed, got %v", result.Subfiles()[0].Error())
+	}
+	expectLimitError(t, result.Subfiles()[1].Error(), LimitTotalBytes)
+}
+
+// Returns data in small chunks with delay between them
+type slowReader struct {
+	reader io.Reader
+	delay  time.Duration
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+	time.Sleep(r.delay)
+	return r.reader.Read(p[:min(len(p), 512)])
+}
+
+func TestLimitsTimeout(t *testing.T) {
+	var files []tarTestFile
+	for i := range 20 {
+		files = append(files, tarTestFile{name: strings.Repeat("x", i+1) + ".txt", data: []byte("text")})
+	}
+	data := buildTar(t, files...)
+	limits := Limits{Timeout: 20 * time.Millisecond}
+
+	result := newLimitsTestParser(limits).Parse(context.Background(), &slowReader{reader: bytes.NewReader(data), delay: 5 * time.Millisecond}, "file.tar")
+	expectLimitError(t, result.Error(), LimitTimeout)
+
+	iterator := newLimitsTestParser(limits).ParseStream(context.Background(), &slowReader{reader: bytes.NewReader(data), delay: 5 * time.Millisecond}, "file.tar")
+	defer iterator.Close()
+	var last StreamResult
+	for iterator.Next(context.Background()) {
+		last = iterator.Current()
+	}
+	if last == nil || last.Stage() != ProgressCompleted {
+		t.Fatalf("expected completed stream result, got %v", last)
+	}
+	expectLimitError(t, last.Error(), LimitTimeout)
+}
+
+// Email with the attached email `depth` times
+func buildNestedEmail(depth int) []byte {
+	message := "From: a@example.com\r\nSubject: level 0\r\nContent-Type: text/plain\r\n\r\ninnermost text\r\n"
+	for level := 1; level <= depth; level++ {
+		message = fmt.Sprintf("From: a@example.com\r\nSubject: level %d\r\nContent-Type: multipart/mixed; boundary=\"b%d\"\r\n\r\n"+
+			"--b%d\r\nContent-Type: text/plain\r\n\r\nbody\r\n"+
+			"--b%d\r\nContent-Type: message/rfc822\r\nContent-Disposition: attachment\r\n\r\n%s\r\n--b%d--\r\n",
+			level, level, level, level, message, level)
+	}
+	return []byte(message)
+}
+
+func TestLimitsNestedEmail(t *testing.T) {
+	composite := NewCompositeParser(NewTextParser())
+	composite.AddParsers(NewEMLParser(composite))
+	composite.Configure(WithCompositeLimits(Limits{MaxDepth: 4}))
+	data := buildNestedEmail(10)
+
+	result := composite.Parse(context.Background(), bytes.NewReader(data), "mail.eml")
+	depth := 0
+	for len(result.Subfiles()) != 0 {
+		result = result.Subfiles()[0]
+		depth += 1
+	}
+	if depth != 5 || strings.Contains(result.String(), "innermost text") {
+		t.Fatalf("expected parsing to stop at depth 5, got %d", depth)
+	}
+	expectLimitError(t, result.Error(), LimitDepth)
+
+	errs := collectStreamErrors(context.Background(), composite.ParseStream(context.Background(), bytes.NewReader(data), "mail.eml"))
+	if len(errs) == 0 {
+		t.Fatal("expected depth limit error in stream")
+	}
+	expectLimitError(t, errs[0], LimitDepth)
+
+	// Attached email counts to the size limits
+	composite.Configure(WithCompositeLimits(Limits{MaxEntrySize: 100}))
+	result = composite.Parse(context.Background(), bytes.NewReader(buildNestedEmail(2)), "mail.eml")
+	expectLimitError(t, result.Subfiles()[0].Error(), LimitEntrySize)
+}
+
+func TestLimitsPackageParts(t *testing.T) {
+	parsers := map[string]Parser{"book.xlsx": NewXLSXParser(), "book.epub": NewEPUBParser(), "slides.pptx": NewPPTXParser(nil)}
+	data := map[string][]byte{"book.xlsx": testdata.XLSX, "book.epub": testdata.EPUB, "slides.pptx": testdata.PPTX}
+	for name, parser := range parsers {
+		composite := NewCompositeParser(parser)
+		composite.Configure(WithCompositeLimits(Limits{MaxTotalBytes: 1000}))
+		result := composite.Parse(context.Background(), bytes.NewReader(data[name]), name)
+		expectLimitError(t, result.Error(), LimitTotalBytes)
+
+		errs := collectStreamErrors(context.Background(), composite.ParseStream(context.Background(), bytes.NewReader(data[name]), name))
+		if len(errs) == 0 {
+			t.Fatalf("expected total size limit error in stream of %s", name)
+		}
+		expectLimitError(t, errs[0], LimitTotalBytes)
+	}
+}
+
+func TestLimitsPackageSize(t *testing.T) {
+	// Package is read into the memory, so it is limited even at the top level
+	composite := NewCompositeParser(NewXLSXParser())
+	composite.Configure(WithCompositeLimits(Limits{MaxEntrySize: int64(len(testdata.XLSX)) - 1}))
+	result := composite.Parse(context.Background(), bytes.NewReader(testdata.XLSX), "book.xlsx")
+	expectLimitError(t, result.Error(), LimitEntrySize)
+
+	composite.Configure(WithCompositeLimits(Limits{MaxEntrySize: int64(len(testdata.XLSX))}))
+	if result := composite.Parse(context.Background(), bytes.NewReader(testdata.XLSX), "book.xlsx"); result.Error() != nil {
+		t.Error(result.Error())
+	}
+}
@@ -0,0 +1,598 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	pathlib "path"
+	"slices"
+	"strings"
+)
+
+// Parses `application/mbox` mailboxes. Every message is parsed with email parser as `<mailbox>/<index>-<message-id>.eml` subfile.
+type MBOXParser struct {
+	emlParser Parser
+}
+
+// Messages are parsed with `emlParser`, usually [EMLParser]
+func NewMBOXParser(emlParser Parser) *MBOXParser {
+	return &MBOXParser{
+		emlParser: emlParser,
+	}
+}
+
+func (p *MBOXParser) SupportedMimeTypes() []string {
+	return []string{"application/mbox"}
+}
+
+func (p *MBOXParser) Parse(ctx context.Context, file io.Reader, path string) Result {
+	return parseMailbox(ctx, p.emlParser, newMBOXReader(file), path)
+}
+
+func (p *MBOXParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
+	return &MailboxStreamResultIterator{
+		path:      path,
+		emlParser: p.emlParser,
+		size:      readerSize(file),
+		source:    newMBOXReader(file),
+	}
+}
+
+// Splits mbox file into messages. Separator is the "From " line at the start of the file or after empty line.
+// Escaped lines like ">From " and ">>From " lose one ">" (mboxrd), which also restores the lines escaped by mboxo writers.
+type mboxReader struct {
+	reader *bufio.Reader
+	// Bytes read from the file, including separators
+	consumed int64
+	started  bool
+	// Separator of the next message that was already read
+	nextSeparator bool
+}
+
+func newMBOXReader(file io.Reader) *mboxReader {
+	return &mboxReader{reader: bufio.NewReaderSize(file, 64*1024)}
+}
+
+func isMBOXSeparator(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("From "))
+}
+
+func (r *mboxReader) readLine() ([]byte, error) {
+	line, err := r.reader.ReadBytes('\n')
+	r.consumed += int64(len(line))
+	if err == io.EOF && len(line) != 0 {
+		err = nil
+	}
+	return line, err
+}
+
+// Returns next message without separator line
+func (r *mboxReader) next() ([]byte, error) {
+	if !r.started {
+		r.started = true
+		for {
+			line, err := r.readLine()
+			if err == io.EOF {
+				return nil, io.EOF
+			} else if err != nil {
+				return nil, err
+			}
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			if !isMBOXSeparator(line) {
+				return nil, errors.New("mbox file must start with the \"From \" line")
+			}
+			r.nextSeparator = true
+			break
+		}
+	}
+
+	if !r.nextSeparator {
+		return nil, io.EOF
+	}
+	r.nextSeparator = false
+
+	var message bytes.Buffer
+	previousEmpty := false
+	for {
+		line, err := r.readLine()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if previousEmpty && isMBOXSeparator(line) {
+			r.nextSeparator = true
+			break
+		}
+		previousEmpty = len(bytes.TrimRight(line, "\r\n")) == 0
+
+		if unquoted := bytes.TrimLeft(line, ">"); len(unquoted) != len(line) && isMBOXSeparator(unquoted) {
+			line = line[1:]
+		}
+		message.Write(line)
+	}
+
+	// Empty line before the separator belongs to the mbox format, not to the message
+	data := message.Bytes()
+	if r.nextSeparator {
+		if bytes.HasSuffix(data, []byte("\r\n")) {
+			data = data[:len(data)-2]
+		} else if bytes.HasSuffix(data, []byte("\n")) {
+			data = data[:len(data)-1]
+		}
+	}
+	return data, nil
+}
+
+// Name of the message subfile: `<index>-<message-id>.eml` or `<index>.eml` if message has no ID
+func mailboxMessageName(index int, message []byte) string {
+	id := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < ' ' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, strings.Trim(emlHeaderValue(message, "Message-ID"), "<> \t"))
+	if id == "" {
+		return fmt.Sprintf("%d.eml", index)
+	}
+	return fmt.Sprintf("%d-%s.eml", index, id)
+}
+
+// Reads the raw value of the header without parsing entire message
+func emlHeaderValue(message []byte, name string) string {
+	var value strings.Builder
+	found := false
+	for len(message) != 0 {
+		line, rest, _ := bytes.Cut(message, []byte("\n"))
+		message = rest
+		line = bytes.TrimRight(line, "\r")
+		if len(line) == 0 {
+			break
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if found {
+				value.WriteString(strings.TrimSpace(string(line)))
+			}
+			continue
+		}
+		if found {
+			break
+		}
+		key, val, ok := bytes.Cut(line, []byte(":"))
+		if ok && strings.EqualFold(string(key), name) {
+			found = true
+			value.WriteString(strings.TrimSpace(string(val)))
+		}
+	}
+	return value.String()
+}
+
+// Size of the file if reader knows it, otherwise -1
+func readerSize(file io.Reader) int64 {
+	switch file := file.(type) {
+	case interface{ Size() int64 }:
+		return file.Size()
+	case interface{ Stat() (fs.FileInfo, error) }:
+		if info, err := file.Stat(); err == nil && info.Mode().IsRegular() {
+			return info.Size()
+		}
+	}
+	return -1
+}
+
+// Parses Maildir mailboxes from the file system. Messages from `cur` and `new` directories of the mailbox and its Maildir++ folders
+// (`.Sent`, `.Archive.2024` and others) are parsed with email parser in the order of their file names.
+type MaildirParser struct {
+	emlParser Parser
+}
+
+// Messages are parsed with `emlParser`, usually [EMLParser]
+func NewMaildirParser(emlParser Parser) *MaildirParser {
+	return &MaildirParser{
+		emlParser: emlParser,
+	}
+}
+
+// Parses mailbox located at `root` directory of the `fsys`
+func (p *MaildirParser) Parse(ctx context.Context, fsys fs.FS, root string) Result {
+	messages, err := maildirMessages(fsys, root)
+	if err != nil {
+		return &MailboxParserResult{Err: err, FullPath: root}
+	}
+	return parseMailbox(ctx, p.emlParser, &maildirReader{fsys: fsys, messages: messages}, root)
+}
+
+func (p *MaildirParser) ParseStream(ctx context.Context, fsys fs.FS, root string) StreamResultIterator {
+	source := &maildirReader{fsys: fsys}
+	messages, err := maildirMessages(fsys, root)
+	if err != nil {
+		source.err = err
+	}
+	source.messages = messages
+
+	var size int64
+	for _, message := range messages {
+		size += message.size
+	}
+	return &MailboxStreamResultIterator{
+		path:      root,
+		emlParser: p.emlParser,
+		size:      size,
+		source:    source,
+	}
+}
+
+type maildirMessage struct {
+	// Path of the message file in the file system
+	file string
+	// Maildir++ folder of the message relative to the mailbox root
+	folder string
+	size   int64
+}
+
+// Lists messages of the mailbox and its Maildir++ folders
+func maildirMessages(fsys fs.FS, root string) ([]maildirMessage, error) {
+	folders := []string{""}
+	entries, err := fs.ReadDir(fsys, root)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to read maildir"), err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), ".") && entry.Name() != "." && entry.Name() != ".." {
+			folders = append(folders, entry.Name())
+		}
+	}
+
+	var messages []maildirMessage
+	for _, folder := range folders {
+		var folderMessages []maildirMessage
+		for _, dir := range []string{"cur", "new"} {
+			dirPath := pathlib.Join(root, folder, dir)
+			entries, err := fs.ReadDir(fsys, dirPath)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			} else if err != nil {
+				return nil, errors.Join(fmt.Errorf("failed to read maildir directory %s", dirPath), err)
+			}
+
+			for _, entry := range entries {
+				if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+					continue
+				}
+				info, err := entry.Info()
+				if err != nil {
+					return nil, errors.Join(fmt.Errorf("failed to read info of %s", entry.Name()), err)
+				}
+				folderMessages = append(folderMessages, maildirMessage{
+					file:   pathlib.Join(dirPath, entry.Name()),
+					folder: folder,
+					size:   info.Size(),
+				})
+			}
+		}
+
+		// Names start with the delivery time, so sorting by name keeps messages in the order they arrived
+		slices.SortFunc(folderMessages, func(a, b maildirMessage) int {
+			return strings.Compare(pathlib.Base(a.file), pathlib.Base(b.file))
+		})
+		messages = append(messages, folderMessages...)
+	}
+
+	if len(folders) == 1 && len(messages) == 0 {
+		if _, err := fs.Stat(fsys, pathlib.Join(root, "cur")); err != nil {
+			return nil, errors.Join(ErrBadFile, fmt.Errorf("%s is not a maildir", root))
+		}
+	}
+	return messages, nil
+}
+
+// Reads messages of the Maildir one by one
+type maildirReader struct {
+	fsys     fs.FS
+	messages []maildirMessage
+	index    int
+	consumed int64
+	err      error
+}
+
+// Parses all the messages of the mailbox
+func parseMailbox(ctx context.Context, emlParser Parser, source mailboxSource, path string) Result {
+	result := &MailboxParserResult{FullPath: path}
+	for index := 1; ; index++ {
+		if ctx.Err() != nil {
+			result.Err = ctx.Err()
+			return result
+		}
+
+		name, message, err := source.nextMessage(index)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			result.Err = err
+			return result
+		}
+		if err := checkEntryLimit(ctx, index, path); err != nil {
+			result.Err = err
+			return result
+		}
+
+		result.Messages = append(result.Messages, emlParser.Parse(ctx, bytes.NewReader(message), pathlib.Join(path, name)))
+	}
+	return result
+}
+
+// Source of the messages of the mailbox
+type mailboxSource interface {
+	// Returns next message and its path relative to the mailbox. Returns [io.EOF] after the last message.
+	nextMessage(index int) (string, []byte, error)
+	// Bytes of the mailbox that were read
+	bytesConsumed() int64
+}
+
+func (r *maildirReader) nextMessage(index int) (string, []byte, error) {
+	if r.err != nil {
+		return "", nil, r.err
+	}
+	if r.index >= len(r.messages) {
+		return "", nil, io.EOF
+	}
+
+	message := r.messages[r.index]
+	r.index += 1
+	data, err := fs.ReadFile(r.fsys, message.file)
+	if err != nil {
+		return "", nil, errors.Join(fmt.Errorf("failed to read message %s", message.file), err)
+	}
+	r.consumed += message.size
+	return pathlib.Join(message.folder, mailboxMessageName(index, data)), data, nil
+}
+
+func (r *maildirReader) bytesConsumed() int64 {
+	return r.consumed
+}
+
+func (r *mboxReader) nextMessage(index int) (string, []byte, error) {
+	message, err := r.next()
+	if err != nil {
+		if err != io.EOF {
+			err = errors.Join(ErrBadFile, err)
+		}
+		return "", nil, err
+	}
+	return mailboxMessageName(index, message), message, nil
+}
+
+func (r *mboxReader) bytesConsumed() int64 {
+	return r.consumed
+}
+
+// Streams messages of the mailbox one by one
+type MailboxStreamResultIterator struct {
+	path      string
+	emlParser Parser
+	// Total size of the mailbox in bytes. Negative if unknown.
+	size   int64
+	source mailboxSource
+
+	started      bool
+	completed    bool
+	messageIndex int
+	messageParse StreamResultIterator
+
+	current StreamResult
+}
+
+// Progress in percents. Zero if size of the mailbox is unknown.
+func (i *MailboxStreamResultIterator) progress() uint8 {
+	if i.size <= 0 {
+		return 0
+	}
+	return uint8(min(float64(i.source.bytesConsumed())/float64(i.size), 1) * 100)
+}
+
+func (i *MailboxStreamResultIterator) Next(ctx context.Context) bool {
+	if i.completed {
+		i.current = nil
+		return false
+	}
+
+	if !i.started {
+		i.started = true
+		i.current = &MailboxParserStreamResult{
+			FullPath:     i.path,
+			CurrentStage: ProgressNew,
+		}
+		return true
+	}
+
+	if i.messageParse != nil {
+		if i.messageParse.Next(ctx) {
+			i.current = &MailboxParserStreamResult{
+				FullPath:        i.path,
+				CurrentStage:    ProgressUpdate,
+				CurrentProgress: i.progress(),
+				BytesConsumed:   i.source.bytesConsumed(),
+				CurrentMessage:  i.messageParse.Current(),
+			}
+			return true
+		}
+		if ctx.Err() != nil {
+			i.current = nil
+			return false
+		}
+		i.messageParse.Close()
+		i.messageParse = nil
+	}
+
+	i.messageIndex += 1
+	name, message, err := i.source.nextMessage(i.messageIndex)
+	if err != nil {
+		i.completed = true
+		i.current = &MailboxParserStreamResult{
+			FullPath:        i.path,
+			CurrentStage:    ProgressCompleted,
+			CurrentProgress: 100,
+			BytesConsumed:   i.source.bytesConsumed(),
+		}
+		if err != io.EOF {
+			i.current = &MailboxParserStreamResult{
+				FullPath:      i.path,
+				CurrentStage:  ProgressCompleted,
+				BytesConsumed: i.source.bytesConsumed(),
+				Err:           err,
+			}
+		}
+		return true
+	}
+	if err := checkEntryLimit(ctx, i.messageIndex, i.path); err != nil {
+		i.completed = true
+		i.current = &MailboxParserStreamResult{
+			FullPath:      i.path,
+			CurrentStage:  ProgressCompleted,
+			BytesConsumed: i.source.bytesConsumed(),
+			Err:           err,
+		}
+		return true
+	}
+
+	i.messageParse = i.emlParser.ParseStream(ctx, bytes.NewReader(message), pathlib.Join(i.path, name))
+	return i.Next(ctx)
+}
+
+func (i *MailboxStreamResultIterator) Current() StreamResult {
+	return i.current
+}
+
+func (i *MailboxStreamResultIterator) Close() {
+	if i.messageParse != nil {
+		i.messageParse.Close()
+		i.messageParse = nil
+	}
+}
+
+type MailboxParserResult struct {
+	FullPath string   `json:"path"`
+	Messages []Result `json:"messages"`
+	Err      error    `json:"error"`
+}
+
+func (r *MailboxParserResult) Path() string {
+	return r.FullPath
+}
+
+func (r *MailboxParserResult) String() string {
+	var result strings.Builder
+
+	for _, message := range r.Messages {
+		if message.Error() != nil {
+			continue
+		}
+
+		result.WriteString(fmt.Sprintf("------ Message %s ------\n", message.Path()))
+		result.WriteString(message.String())
+		result.WriteString("\n")
+	}
+
+	return result.String()
+}
+
+func (r *MailboxParserResult) Error() error {
+	return r.Err
+}
+
+func (r *MailboxParserResult) Subfiles() []Result {
+	return r.Messages
+}
+
+// Copy of the result with other subfiles in the same order, so they are rendered in its text
+func (r *MailboxParserResult) withSubfiles(subfiles []Result) Result {
+	result := *r
+	result.Messages = subfiles
+	return &result
+}
+
+type MailboxParserStreamResult struct {
+	FullPath        string             `json:"path"`
+	CurrentStage    ParseProgressStage `json:"stage"`
+	CurrentProgress uint8              `json:"progress"`
+	// Bytes of the mailbox that were read
+	BytesConsumed  int64        `json:"bytesConsumed"`
+	CurrentMessage StreamResult `json:"subResult"`
+	Err            error        `json:"error"`
+}
+
+func (r *MailboxParserStreamResult) Path() string {
+	return r.FullPath
+}
+
+func (r *MailboxParserStreamResult) Stage() ParseProgressStage {
+	return r.CurrentStage
+}
+
+func (r *MailboxParserStreamResult) Progress() uint8 {
+	return r.CurrentProgress
+}
+
+func (r *MailboxParserStreamResult) SubResult() StreamResult {
+	return r.CurrentMessage
+}
+
+func (r *MailboxParserStreamResult) String() string {
+	return ""
+}
+
+func (r *MailboxParserStreamResult) Error() error {
+	return r.Err
+}
+
+// Detects mbox files, that are recognized as plain text
+func mboxMimeDetector(data []byte) bool {
+	line, rest, ok := bytes.Cut(data, []byte("\n"))
+	if !ok || !isMBOXSeparator(line) {
+		return false
+	}
+	return emlMimeDetector(rest)
+}
+
+// Header fields that emails usually start with
+var emlFirstHeaders = []string{"received", "return-path", "delivered-to", "from", "to", "subject", "date", "message-id", "mime-version",
+	"x-mozilla-status", "x-gm-thrid", "reply-to", "sender", "envelope-to", "dkim-signature", "arc-seal", "authentication-results"}
+
+// Detects emails, that are recognized as plain text. At least three of the first header lines must be well known email headers,
+// and one of them must be `From` or `Received`.
+func emlMimeDetector(data []byte) bool {
+	known := 0
+	hasSender := false
+	for lineIndex := 0; lineIndex < 8 && len(data) != 0; {
+		line, rest, _ := bytes.Cut(data, []byte("\n"))
+		data = rest
+		line = bytes.TrimRight(line, "\r")
+		if len(line) == 0 {
+			break
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		lineIndex += 1
+
+		key, _, ok := bytes.Cut(line, []byte(":"))
+		if !ok || bytes.ContainsAny(key, " \t") {
+			return false
+		}
+		name := strings.ToLower(string(key))
+		if slices.Contains(emlFirstHeaders, name) || strings.HasPrefix(name, "x-") {
+			known += 1
+		}
+		if name == "from" || name == "received" {
+			hasSender = true
+		}
+	}
+	return known >= 3 && hasSender
+}
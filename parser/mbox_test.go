@@ -0,0 +1,150 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/gabriel-vasile/mimetype"
+	testdata "github.com/opengs/file2llm/test_data"
+)
+
+func TestMBOX(t *testing.T) {
+	mboxParser := NewMBOXParser(NewEMLParser(NewCompositeParser()))
+	result := mboxParser.Parse(context.Background(), bytes.NewReader(testdata.MBOX), "archive.mbox")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+
+	expectedPaths := []string{
+		"archive.mbox/1-report-1@example.com.eml",
+		"archive.mbox/2-reply-2@example.org.eml",
+		"archive.mbox/3.eml",
+	}
+	if len(result.Subfiles()) != len(expectedPaths) {
+		t.Fatalf("expected %d messages, got %d", len(expectedPaths), len(result.Subfiles()))
+	}
+	for i, message := range result.Subfiles() {
+		if message.Path() != expectedPaths[i] {
+			t.Errorf("expected path %s, got %s", expectedPaths[i], message.Path())
+		}
+		if message.Error() != nil {
+			t.Error(message.Error())
+		}
+	}
+
+	first := result.Subfiles()[0].String()
+	if !strings.Contains(first, "\nFrom the north region we got 120.\n>From is quoted twice.\n") {
+		t.Errorf("escaped From lines are not unescaped:\n%q", first)
+	}
+	if strings.HasSuffix(first, "\n\n") {
+		t.Errorf("blank line before the separator belongs to the message:\n%q", first)
+	}
+	if !strings.Contains(result.String(), "------ Message archive.mbox/3.eml ------\n") || !strings.Contains(result.String(), "Lunch at noon?") {
+		t.Error(result.String())
+	}
+}
+
+func TestMBOXStream(t *testing.T) {
+	mboxParser := NewMBOXParser(NewEMLParser(NewCompositeParser()))
+	stream := mboxParser.ParseStream(context.Background(), bytes.NewReader(testdata.MBOX), "archive.mbox")
+	defer stream.Close()
+
+	var paths []string
+	var lastProgress uint8
+	for stream.Next(context.Background()) {
+		current := stream.Current()
+		if current.Error() != nil {
+			t.Fatal(current.Error())
+		}
+		if current.Progress() < lastProgress {
+			t.Errorf("progress goes back from %d to %d", lastProgress, current.Progress())
+		}
+		lastProgress = current.Progress()
+
+		if current.Stage() != ProgressUpdate {
+			continue
+		}
+		mailboxResult := current.(*MailboxParserStreamResult)
+		if mailboxResult.BytesConsumed <= 0 || mailboxResult.BytesConsumed > int64(len(testdata.MBOX)) {
+			t.Errorf("unexpected consumed bytes %d", mailboxResult.BytesConsumed)
+		}
+		if current.SubResult() != nil && current.SubResult().Stage() == ProgressNew {
+			paths = append(paths, current.SubResult().Path())
+		}
+	}
+
+	if lastProgress != 100 {
+		t.Errorf("expected progress 100, got %d", lastProgress)
+	}
+	if len(paths) != 3 || paths[2] != "archive.mbox/3.eml" {
+		t.Errorf("unexpected messages %v", paths)
+	}
+}
+
+func TestMaildir(t *testing.T) {
+	fsys, err := fs.Sub(testdata.Maildir, "maildir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	maildirParser := NewMaildirParser(NewEMLParser(NewCompositeParser()))
+	result := maildirParser.Parse(context.Background(), fsys, ".")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+
+	expected := []struct {
+		path string
+		body string
+	}{
+		{"1-old-1@example.com.eml", "Read message body."},
+		{"2-new-2@example.org.eml", "Unread message body."},
+		{".Sent/3-sent-3@example.org.eml", "Sent message body."},
+	}
+	if len(result.Subfiles()) != len(expected) {
+		t.Fatalf("expected %d messages, got %d", len(expected), len(result.Subfiles()))
+	}
+	for i, message := range result.Subfiles() {
+		if message.Path() != expected[i].path {
+			t.Errorf("expected path %s, got %s", expected[i].path, message.Path())
+		}
+		if !strings.Contains(message.String(), expected[i].body) {
+			t.Errorf("message %s doesnt contain body: %s", message.Path(), message.String())
+		}
+	}
+
+	if result := maildirParser.Parse(context.Background(), fsys, "cur"); result.Error() == nil {
+		t.Error("expected error for directory that is not a maildir")
+	}
+}
+
+func TestMailboxMimeDetection(t *testing.T) {
+	detect := func(data []byte) string {
+		return magicMimeType(mimetype.Detect(data), data)
+	}
+	if mime := detect(testdata.MBOX); mime != "application/mbox" {
+		t.Errorf("expected application/mbox, got %s", mime)
+	}
+	if mime := detect(testdata.EML); mime != "message/rfc822" {
+		t.Errorf("expected message/rfc822, got %s", mime)
+	}
+	if mime := detect(testdata.HTML); !strings.HasPrefix(mime, "text/html") {
+		t.Errorf("expected text/html, got %s", mime)
+	}
+	for _, text := range []string{
+		"From the beginning.\nTo: the end\n",
+		// Notes with the header like lines are not emails
+		"To: team\nSubject: plans\nDate: Monday\n\nBring the slides.\n",
+		"From: Anna\nTo: Bob\n\nHello\n",
+	} {
+		if mime := detect([]byte(text)); !strings.HasPrefix(mime, "text/plain") {
+			t.Errorf("expected text/plain for %q, got %s", text, mime)
+		}
+	}
+	if mime := mimetype.Detect(testdata.EML).String(); !strings.HasPrefix(mime, "text/plain") {
+		t.Errorf("global mime type tree of the library must not be changed, got %s", mime)
+	}
+}
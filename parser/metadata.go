@@ -0,0 +1,38 @@
+package parser
+
+import "strings"
+
+// Named property of the file like title or author
+type MetadataField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Result that knows metadata of the file
+type MetadataResult interface {
+	Result
+	// Metadata fields in the display order. Empty if file has no metadata.
+	MetadataFields() []MetadataField
+}
+
+// Metadata section of the plain text result
+func metadataString(fields []MetadataField) string {
+	var result strings.Builder
+	result.WriteString("------ Metadata ------\n")
+	for _, field := range fields {
+		result.WriteString(field.Name + ": " + field.Value + "\n")
+	}
+	result.WriteString("\n")
+	return result.String()
+}
+
+// Fields of the metadata map in the given order. Missing fields are skipped.
+func metadataFields(metadata map[string]string, order []string) []MetadataField {
+	var fields []MetadataField
+	for _, name := range order {
+		if value, ok := metadata[name]; ok {
+			fields = append(fields, MetadataField{Name: name, Value: value})
+		}
+	}
+	return fields
+}
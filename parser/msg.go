@@ -0,0 +1,158 @@
+package parser
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Parses Outlook `.msg` files. Attached Outlook messages are parsed recursively, other attachments with inner parser.
+// Pass nil inner parser to ignore them.
+type MSGParser struct {
+	innerParser Parser
+}
+
+func NewMSGParser(innerParser Parser) *MSGParser {
+	return &MSGParser{
+		innerParser: innerParser,
+	}
+}
+
+func (p *MSGParser) SupportedMimeTypes() []string {
+	return []string{"application/vnd.ms-outlook"}
+}
+
+func (p *MSGParser) Parse(ctx context.Context, file io.Reader, path string) Result {
+	message, err := readMSG(file)
+	if err != nil {
+		return &EMLParserResult{Err: err, FullPath: path}
+	}
+	return parseOutlookMessage(ctx, p.innerParser, message, path)
+}
+
+func (p *MSGParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
+	return newOutlookStreamResultIterator(p.innerParser, path, func() (*outlookMessage, error) {
+		return readMSG(file)
+	})
+}
+
+func readMSG(file io.Reader) (*outlookMessage, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to read data to the bytes buffer"), err)
+	}
+	cfb, err := openCFB(data)
+	if err != nil {
+		return nil, errors.Join(ErrBadFile, err)
+	}
+	message, err := readMSGStorage(cfb, cfb.root, 32)
+	if err != nil {
+		return nil, errors.Join(ErrBadFile, err)
+	}
+	return message, nil
+}
+
+// Reads message from the storage. Size of the properties stream header is 32 bytes for the top level message and 24 bytes for attached messages.
+func readMSGStorage(cfb *cfbFile, storage *cfbEntry, headerSize int) (*outlookMessage, error) {
+	properties, err := readMSGProperties(cfb, storage, headerSize)
+	if err != nil {
+		return nil, err
+	}
+	message := &outlookMessage{properties: properties}
+
+	for _, child := range storage.children {
+		switch {
+		case strings.HasPrefix(child.name, "__recip_version1.0_"):
+			recipient, err := readMSGProperties(cfb, child, 8)
+			if err != nil {
+				return nil, err
+			}
+			message.recipients = append(message.recipients, recipient)
+		case strings.HasPrefix(child.name, "__attach_version1.0_"):
+			attachment, err := readMSGAttachment(cfb, child, len(message.attachments))
+			if err != nil {
+				return nil, err
+			}
+			if attachment != nil {
+				message.attachments = append(message.attachments, *attachment)
+			}
+		}
+	}
+	return message, nil
+}
+
+func readMSGAttachment(cfb *cfbFile, storage *cfbEntry, index int) (*outlookAttachment, error) {
+	properties, err := readMSGProperties(cfb, storage, 8)
+	if err != nil {
+		return nil, err
+	}
+	attachment := &outlookAttachment{name: outlookAttachmentName(properties, index)}
+
+	if method, _ := properties.integer(mapiAttachMethod); method == mapiAttachEmbeddedMessage {
+		embedded := storage.child(msgPropertyName(mapiAttachData, mapiTypeObject))
+		if embedded == nil || !embedded.isStorage() {
+			return nil, nil
+		}
+		attachment.message, err = readMSGStorage(cfb, embedded, 24)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.Contains(attachment.name, ".") {
+			attachment.name += ".msg"
+		}
+		return attachment, nil
+	}
+
+	attachment.data = properties.binary(mapiAttachData)
+	if len(attachment.data) == 0 {
+		// Attached OLE objects and links to the files are not supported
+		return nil, nil
+	}
+	return attachment, nil
+}
+
+func msgPropertyName(id uint16, kind uint16) string {
+	return fmt.Sprintf("__substg1.0_%04X%04X", id, kind)
+}
+
+// Reads variable length properties from the `__substg1.0_` streams and fixed length properties from the `__properties_version1.0` stream
+func readMSGProperties(cfb *cfbFile, storage *cfbEntry, headerSize int) (mapiProperties, error) {
+	properties := make(mapiProperties)
+	for _, child := range storage.children {
+		tagName, isProperty := strings.CutPrefix(child.name, "__substg1.0_")
+		if !isProperty || child.kind != cfbTypeStream || len(tagName) != 8 {
+			continue
+		}
+		tag, err := strconv.ParseUint(tagName, 16, 32)
+		if err != nil {
+			continue
+		}
+		data, err := cfb.read(child)
+		if err != nil {
+			return nil, err
+		}
+		properties[uint16(tag>>16)] = mapiProperty{kind: uint16(tag), data: data}
+	}
+
+	fixed := storage.child("__properties_version1.0")
+	if fixed == nil {
+		return properties, nil
+	}
+	data, err := cfb.read(fixed)
+	if err != nil {
+		return nil, err
+	}
+	for offset := headerSize; offset+16 <= len(data); offset += 16 {
+		tag := binary.LittleEndian.Uint32(data[offset:])
+		id, kind := uint16(tag>>16), uint16(tag)
+		if _, ok := properties[id]; ok {
+			continue
+		}
+		properties[id] = mapiProperty{kind: kind, data: data[offset+8 : offset+16]}
+	}
+	return properties, nil
+}
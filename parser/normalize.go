@@ -0,0 +1,516 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Unicode normalization form applied to the text
+type UnicodeForm string
+
+const (
+	// Text is not normalized
+	UnicodeFormNone UnicodeForm = "none"
+	// Canonical composition. Keeps ligatures, full width letters and other compatibility characters.
+	UnicodeFormNFC UnicodeForm = "NFC"
+	// Compatibility composition. Also folds ligatures, full width letters, superscripts and similar characters.
+	UnicodeFormNFKC UnicodeForm = "NFKC"
+)
+
+// Number of non empty lines at the top and bottom of the page that are checked for headers and footers
+const pageHeaderLines = 2
+
+// Header or footer has to be repeated at least on this number of pages
+const pageHeaderMinPages = 3
+
+type normalizeConfig struct {
+	form               UnicodeForm
+	joinHyphens        bool
+	foldLigatures      bool
+	foldQuotes         bool
+	collapseWhitespace bool
+	removePageHeaders  bool
+}
+
+// Configures [NormalizingParser]
+type NormalizeOption func(c *normalizeConfig)
+
+// Unicode normalization form. Default is NFC.
+func WithNormalizeForm(form UnicodeForm) NormalizeOption {
+	return func(c *normalizeConfig) {
+		c.form = form
+	}
+}
+
+// Joins words split with hyphen at the end of the line, like `exam-\nple`. Enabled by default.
+// Hyphen is removed only when the first part is lowercase and the text does not use the joined word with hyphen elsewhere,
+// so compounds like `well-\nknown` are kept. Words split with soft hyphen are always joined.
+func WithNormalizeHyphenation(join bool) NormalizeOption {
+	return func(c *normalizeConfig) {
+		c.joinHyphens = join
+	}
+}
+
+// Replaces ligatures like `ﬁ` with separate letters. Disabled by default. NFKC form folds them too.
+func WithNormalizeLigatures(fold bool) NormalizeOption {
+	return func(c *normalizeConfig) {
+		c.foldLigatures = fold
+	}
+}
+
+// Replaces typographic quotes with `'` and `"`. Disabled by default.
+func WithNormalizeQuotes(fold bool) NormalizeOption {
+	return func(c *normalizeConfig) {
+		c.foldQuotes = fold
+	}
+}
+
+// Collapses runs of spaces inside the lines, removes trailing spaces and keeps at most one empty line between paragraphs.
+// Indentation at the beginning of the lines is kept. Enabled by default.
+func WithNormalizeWhitespace(collapse bool) NormalizeOption {
+	return func(c *normalizeConfig) {
+		c.collapseWhitespace = collapse
+	}
+}
+
+// Removes headers and footers repeated on the pages of PDF documents. Numbers in them may differ, like in `Page 3 of 10`. Enabled by default.
+func WithNormalizePageHeaders(remove bool) NormalizeOption {
+	return func(c *normalizeConfig) {
+		c.removePageHeaders = remove
+	}
+}
+
+var (
+	normalizeLigatures = strings.NewReplacer("ﬀ", "ff", "ﬁ", "fi", "ﬂ", "fl", "ﬃ", "ffi", "ﬄ", "ffl", "ﬅ", "st", "ﬆ", "st")
+	normalizeQuotes    = strings.NewReplacer("‘", "'", "’", "'", "‚", "'", "‛", "'", "“", `"`, "”", `"`, "„", `"`, "‟", `"`)
+	// Word, hyphen or soft hyphen at the end of the line and lowercase word at the beginning of the next one
+	normalizeHyphenation = regexp.MustCompile(`(\p{L}+)([-\x{2010}\x{00AD}])[ \t]*\n[ \t]*(\p{Ll}\p{L}*)`)
+	// Words with hyphen inside of the line
+	normalizeHyphenated = regexp.MustCompile(`\p{L}+[-\x{2010}]\p{L}+`)
+	normalizeDigits     = regexp.MustCompile(`\d+`)
+)
+
+// Normalizes text of the results created by the inner parser: Unicode form, hyphenation, ligatures, quotes, whitespace and repeated page headers.
+// Structure of the results is kept, so files parsed by [CompositeParser] still have [CompositeParserResult] with mime type.
+type NormalizingParser struct {
+	inner  Parser
+	config normalizeConfig
+}
+
+func NewNormalizingParser(inner Parser, options ...NormalizeOption) *NormalizingParser {
+	parser := &NormalizingParser{
+		inner: inner,
+		config: normalizeConfig{
+			form:               UnicodeFormNFC,
+			joinHyphens:        true,
+			collapseWhitespace: true,
+			removePageHeaders:  true,
+		},
+	}
+
+	for _, option := range options {
+		option(&parser.config)
+	}
+
+	return parser
+}
+
+// Configuration that changes the result. Used in the cache keys.
+func (p *NormalizingParser) CacheFingerprint() string {
+	return fmt.Sprintf("%+v inner=%s", p.config, cacheFingerprint(p.inner))
+}
+
+func (p *NormalizingParser) SupportedMimeTypes() []string {
+	return p.inner.SupportedMimeTypes()
+}
+
+func (p *NormalizingParser) Parse(ctx context.Context, file io.Reader, path string) Result {
+	return p.normalizeResult(p.inner.Parse(ctx, file, path))
+}
+
+// Stream with normalized text of every update. Words split between updates are not joined.
+// Repeated PDF headers and footers are removed from the third page, after they were seen on two pages before.
+func (p *NormalizingParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
+	return &NormalizingStreamResultIterator{
+		parser:   p,
+		inner:    p.inner.ParseStream(ctx, file, path),
+		trackers: make(map[string]map[string]int),
+	}
+}
+
+// Applies configured normalization to the text
+func (p *NormalizingParser) NormalizeText(text string) string {
+	return p.normalizeText(text, hyphenatedWords(text))
+}
+
+// Normalizes part of the document. Words with hyphen used in the document are not joined when they are split between lines.
+func (p *NormalizingParser) normalizeText(text string, hyphenated map[string]bool) string {
+	switch p.config.form {
+	case UnicodeFormNFC:
+		text = norm.NFC.String(text)
+	case UnicodeFormNFKC:
+		text = norm.NFKC.String(text)
+	}
+	if p.config.foldLigatures {
+		text = normalizeLigatures.Replace(text)
+	}
+	if p.config.foldQuotes {
+		text = normalizeQuotes.Replace(text)
+	}
+	if p.config.joinHyphens {
+		text = joinHyphens(text, hyphenated)
+		text = strings.ReplaceAll(text, "\u00AD", "")
+	}
+	if p.config.collapseWhitespace {
+		text = collapseWhitespace(text)
+	}
+	return text
+}
+
+// Lowercase words with hyphen used in the text
+func hyphenatedWords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range normalizeHyphenated.FindAllString(text, -1) {
+		words[strings.ToLower(strings.ReplaceAll(word, "\u2010", "-"))] = true
+	}
+	return words
+}
+
+// Joins words split between lines. Compounds and words that start with capital letter keep the hyphen and the line break.
+func joinHyphens(text string, hyphenated map[string]bool) string {
+	var result strings.Builder
+	last := 0
+	for _, match := range normalizeHyphenation.FindAllStringSubmatchIndex(text, -1) {
+		before, hyphen, after := text[match[2]:match[3]], text[match[4]:match[5]], text[match[6]:match[7]]
+		if hyphen != "\u00AD" && (strings.ToLower(before) != before || hyphenated[strings.ToLower(before+"-"+after)]) {
+			continue
+		}
+		result.WriteString(text[last:match[0]])
+		result.WriteString(before)
+		result.WriteString(after)
+		last = match[1]
+	}
+	if last == 0 {
+		return text
+	}
+	result.WriteString(text[last:])
+	return result.String()
+}
+
+// Collapses spaces inside the lines and runs of empty lines
+func collapseWhitespace(text string) string {
+	lines := strings.Split(text, "\n")
+	result := make([]string, 0, len(lines))
+	for i, line := range lines {
+		indentEnd := len(line) - len(strings.TrimLeft(line, " \t"))
+		fields := strings.FieldsFunc(line[indentEnd:], unicode.IsSpace)
+		line = line[:indentEnd] + strings.Join(fields, " ")
+		if len(fields) == 0 {
+			line = ""
+		}
+
+		// Empty line is kept only after the non empty one. Last line is kept to preserve final line break.
+		if line == "" && len(result) != 0 && result[len(result)-1] == "" && i != len(lines)-1 {
+			continue
+		}
+		result = append(result, line)
+	}
+	return strings.Join(result, "\n")
+}
+
+// Key of the header line. Page numbers and dates differ between the pages.
+func pageLineKey(line string) string {
+	return normalizeDigits.ReplaceAllString(strings.Join(strings.Fields(line), " "), "#")
+}
+
+// Indexes of the first and last non empty lines of the page
+func pageEdgeLines(lines []string) []int {
+	var nonEmpty []int
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			nonEmpty = append(nonEmpty, i)
+		}
+	}
+	// At least one line in the middle is never treated as header or footer
+	count := min(pageHeaderLines, (len(nonEmpty)-1)/2)
+	if count <= 0 {
+		return nil
+	}
+	return append(nonEmpty[:count:count], nonEmpty[len(nonEmpty)-count:]...)
+}
+
+// Keys of the edge lines of the page without duplicates
+func pageEdgeKeys(lines []string) []string {
+	var keys []string
+	for _, index := range pageEdgeLines(lines) {
+		key := pageLineKey(lines[index])
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Removes edge lines for which `remove` returns true.
+// Empty lines around the text of the page are kept, empty lines left after removed header or footer are not.
+func removePageLines(text string, remove func(key string) bool) string {
+	lines := strings.Split(text, "\n")
+	edges := pageEdgeLines(lines)
+	removedIndexes := make(map[int]bool)
+	for _, index := range edges {
+		if remove(pageLineKey(lines[index])) {
+			removedIndexes[index] = true
+		}
+	}
+	if len(removedIndexes) == 0 {
+		return text
+	}
+
+	first, last := edges[0], edges[len(edges)-1]
+	var content []string
+	for i := first; i <= last; i++ {
+		if !removedIndexes[i] {
+			content = append(content, lines[i])
+		}
+	}
+	for len(content) != 0 && strings.TrimSpace(content[0]) == "" {
+		content = content[1:]
+	}
+	for len(content) != 0 && strings.TrimSpace(content[len(content)-1]) == "" {
+		content = content[:len(content)-1]
+	}
+
+	result := slices.Concat(lines[:first], content, lines[last+1:])
+	return strings.Join(result, "\n")
+}
+
+// Copy of the document with normalized text of the pages and without repeated headers and footers
+func (p *NormalizingParser) normalizePDF(result *PDFParserResult) *PDFParserResult {
+	stripped := *result
+	stripped.Pages = slices.Clone(result.Pages)
+	if p.config.removePageHeaders && len(result.Pages) >= pageHeaderMinPages {
+		counts := make(map[string]int)
+		for _, page := range result.Pages {
+			for _, key := range pageEdgeKeys(strings.Split(page.Text, "\n")) {
+				counts[key] += 1
+			}
+		}
+		repeated := func(key string) bool {
+			return counts[key] >= pageHeaderMinPages && counts[key]*2 >= len(result.Pages)
+		}
+		for i := range stripped.Pages {
+			stripped.Pages[i].Text = removePageLines(stripped.Pages[i].Text, repeated)
+		}
+	}
+
+	normalized := stripped
+	normalized.Pages = make([]PDFPage, len(stripped.Pages))
+	hyphenated := hyphenatedWords(stripped.String())
+	for i, page := range stripped.Pages {
+		page.Text = p.normalizeText(page.Text, hyphenated)
+		normalized.Pages[i] = page
+	}
+	return &normalized
+}
+
+// Implemented by the container results that render text of their subfiles
+type subfilesReplacer interface {
+	withSubfiles(subfiles []Result) Result
+}
+
+// Normalizes result and its subfiles. Subfiles are normalized first, so containers render their normalized text.
+func (p *NormalizingParser) normalizeResult(result Result) Result {
+	switch typed := result.(type) {
+	case *CompositeParserResult:
+		if typed.Inner == nil {
+			return typed
+		}
+		normalized := *typed
+		normalized.Inner = p.normalizeResult(typed.Inner)
+		return &normalized
+	case *TextParserResult:
+		normalized := *typed
+		normalized.Text = p.NormalizeText(typed.Text)
+		normalized.Blocks = textBlocks(normalized.Text, Location{})
+		return &normalized
+	}
+
+	var subfiles []Result
+	for _, subfile := range result.Subfiles() {
+		subfiles = append(subfiles, p.normalizeResult(subfile))
+	}
+	if replacer, ok := result.(subfilesReplacer); ok && len(subfiles) != 0 {
+		result = replacer.withSubfiles(subfiles)
+	}
+	if pdf, ok := result.(*PDFParserResult); ok {
+		result = p.normalizePDF(pdf)
+	}
+	return &NormalizedResult{Inner: result, parser: p, subfiles: subfiles}
+}
+
+// Result with normalized text. Containers render normalized text of their subfiles.
+type NormalizedResult struct {
+	Inner    Result
+	parser   *NormalizingParser
+	subfiles []Result
+}
+
+func (r *NormalizedResult) Path() string {
+	return r.Inner.Path()
+}
+
+func (r *NormalizedResult) String() string {
+	return r.parser.NormalizeText(r.Inner.String())
+}
+
+func (r *NormalizedResult) Error() error {
+	return r.Inner.Error()
+}
+
+func (r *NormalizedResult) Subfiles() []Result {
+	return r.subfiles
+}
+
+func (r *NormalizedResult) MetadataFields() []MetadataField {
+	return resultMetadata(r.Inner)
+}
+
+// Document of the inner result with normalized text of the blocks
+func (r *NormalizedResult) Document() *Document {
+	document := resultDocument(r.Inner)
+	if document == nil {
+		return nil
+	}
+	return &Document{Blocks: r.parser.normalizeBlocks(document.Blocks, hyphenatedWords(document.String()))}
+}
+
+func (p *NormalizingParser) normalizeBlocks(blocks []Block, hyphenated map[string]bool) []Block {
+	if len(blocks) == 0 {
+		return nil
+	}
+	normalized := make([]Block, len(blocks))
+	for i, block := range blocks {
+		block.Text = p.normalizeText(block.Text, hyphenated)
+		if block.Rows != nil {
+			rows := make([][]string, len(block.Rows))
+			for rowIndex, row := range block.Rows {
+				rows[rowIndex] = make([]string, len(row))
+				for cellIndex, cell := range row {
+					rows[rowIndex][cellIndex] = p.normalizeText(cell, hyphenated)
+				}
+			}
+			block.Rows = rows
+		}
+		block.Children = p.normalizeBlocks(block.Children, hyphenated)
+		if p.config.collapseWhitespace && strings.Count(block.Separator, "\n") > 2 && strings.TrimSpace(block.Separator) == "" {
+			block.Separator = "\n\n"
+		}
+		normalized[i] = block
+	}
+	return normalized
+}
+
+type NormalizingStreamResultIterator struct {
+	parser *NormalizingParser
+	inner  StreamResultIterator
+	// Counts of the edge line keys on the already streamed pages of every PDF document
+	trackers map[string]map[string]int
+	current  StreamResult
+}
+
+func (i *NormalizingStreamResultIterator) Next(ctx context.Context) bool {
+	if !i.inner.Next(ctx) {
+		i.current = nil
+		return false
+	}
+	i.current = i.normalizeStreamResult(i.inner.Current())
+	return true
+}
+
+func (i *NormalizingStreamResultIterator) Current() StreamResult {
+	return i.current
+}
+
+func (i *NormalizingStreamResultIterator) Close() {
+	i.inner.Close()
+}
+
+// Normalizes text of the stream result and its subresults
+func (i *NormalizingStreamResultIterator) normalizeStreamResult(result StreamResult) StreamResult {
+	if result == nil {
+		return nil
+	}
+
+	normalized := &NormalizedStreamResult{Inner: result, Text: i.parser.NormalizeText(result.String())}
+	if page, ok := pdfStreamPage(result); ok && i.parser.config.removePageHeaders {
+		counts, ok := i.trackers[page.FullPath]
+		if !ok {
+			counts = make(map[string]int)
+			i.trackers[page.FullPath] = counts
+		}
+		keys := pageEdgeKeys(strings.Split(normalized.Text, "\n"))
+		normalized.Text = removePageLines(normalized.Text, func(key string) bool {
+			return counts[key] >= pageHeaderMinPages-1
+		})
+		for _, key := range keys {
+			counts[key] += 1
+		}
+	}
+	if subResult := i.normalizeStreamResult(result.SubResult()); subResult != nil {
+		normalized.CurrentSubResult = subResult
+	}
+	return normalized
+}
+
+// Returns PDF stream result if it is an update with the text of the page
+func pdfStreamPage(result StreamResult) (*PDFParserStreamResult, bool) {
+	for {
+		composite, ok := result.(*CompositeParserStreamResult)
+		if !ok || composite.Inner == nil {
+			break
+		}
+		result = composite.Inner
+	}
+	page, ok := result.(*PDFParserStreamResult)
+	if !ok || page.PageNumber == 0 || page.Attachment != nil {
+		return nil, false
+	}
+	return page, true
+}
+
+type NormalizedStreamResult struct {
+	Inner            StreamResult `json:"inner"`
+	Text             string       `json:"text"`
+	CurrentSubResult StreamResult `json:"subResult"`
+}
+
+func (r *NormalizedStreamResult) Path() string {
+	return r.Inner.Path()
+}
+
+func (r *NormalizedStreamResult) Stage() ParseProgressStage {
+	return r.Inner.Stage()
+}
+
+func (r *NormalizedStreamResult) Progress() uint8 {
+	return r.Inner.Progress()
+}
+
+func (r *NormalizedStreamResult) SubResult() StreamResult {
+	return r.CurrentSubResult
+}
+
+func (r *NormalizedStreamResult) String() string {
+	return r.Text
+}
+
+func (r *NormalizedStreamResult) Error() error {
+	return r.Inner.Error()
+}
@@ -0,0 +1,183 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Returns the same result and stream for every file
+type staticParser struct {
+	result Result
+	stream []StreamResult
+}
+
+func (p *staticParser) SupportedMimeTypes() []string {
+	return []string{"application/pdf"}
+}
+
+func (p *staticParser) Parse(ctx context.Context, file io.Reader, path string) Result {
+	return p.result
+}
+
+func (p *staticParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
+	return &staticStreamIterator{results: p.stream}
+}
+
+type staticStreamIterator struct {
+	results []StreamResult
+	current StreamResult
+}
+
+func (i *staticStreamIterator) Next(ctx context.Context) bool {
+	if len(i.results) == 0 {
+		return false
+	}
+	i.current, i.results = i.results[0], i.results[1:]
+	return true
+}
+
+func (i *staticStreamIterator) Current() StreamResult {
+	return i.current
+}
+
+func (i *staticStreamIterator) Close() {}
+
+func TestNormalizeText(t *testing.T) {
+	parser := NewNormalizingParser(NewTextParser())
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"The exam-\nple shows hyphen-\n  ation.", "The example shows hyphenation."},
+		{"Well-known\n- list item", "Well-known\n- list item"},
+		// Compound used in the text and first part with capital letter keep the hyphen
+		{"A well-known fact is well-\nknown.", "A well-known fact is well-\nknown."},
+		{"Hello Covid-\nnineteen", "Hello Covid-\nnineteen"},
+		{"Stra\u00ADße and Wei\u00AD\nter", "Straße and Weiter"},
+		{"Café de­sign", "Café design"},
+		{"first   line  \t\n\n\n\nsecond  line\n", "first line\n\nsecond line\n"},
+		{"  - nested  item", "  - nested item"},
+		{"ﬁnal “quoted”", "ﬁnal “quoted”"},
+	}
+	for _, test := range tests {
+		if normalized := parser.NormalizeText(test.input); normalized != test.expected {
+			t.Errorf("normalized %q to %q, expected %q", test.input, normalized, test.expected)
+		}
+	}
+
+	folding := NewNormalizingParser(NewTextParser(), WithNormalizeLigatures(true), WithNormalizeQuotes(true))
+	if normalized := folding.NormalizeText("ﬁnal “quoted” ‘text’"); normalized != `final "quoted" 'text'` {
+		t.Errorf("unexpected folded text: %q", normalized)
+	}
+	nfkc := NewNormalizingParser(NewTextParser(), WithNormalizeForm(UnicodeFormNFKC))
+	if normalized := nfkc.NormalizeText("ﬂow Ａ1"); normalized != "flow A1" {
+		t.Errorf("unexpected NFKC text: %q", normalized)
+	}
+	disabled := NewNormalizingParser(NewTextParser(), WithNormalizeForm(UnicodeFormNone), WithNormalizeHyphenation(false), WithNormalizeWhitespace(false))
+	if text := "exam-\nple  \n\n\n"; disabled.NormalizeText(text) != text {
+		t.Errorf("disabled normalization changed text: %q", disabled.NormalizeText(text))
+	}
+
+	composite := NewCompositeParser(NewTextParser())
+	result := NewNormalizingParser(composite).Parse(context.Background(), bytes.NewReader([]byte("exam-\nple   text\n\n\n\nend")), "notes.txt")
+	if _, ok := result.(*CompositeParserResult); !ok || result.String() != "example text\n\nend" {
+		t.Errorf("unexpected normalized result %T: %q", result, result.String())
+	}
+}
+
+func normalizeTestPDF(pages int) *PDFParserResult {
+	result := &PDFParserResult{FullPath: "report.pdf", Metadata: PDFMetadata{Pages: pages}}
+	for i := 1; i <= pages; i++ {
+		result.Pages = append(result.Pages, PDFPage{
+			Number: i,
+			Text:   fmt.Sprintf("ACME   Annual Report\n\nBody of the page %d con-\ntinues here.\n\nPage %d of %d\n", i, i, pages),
+		})
+	}
+	return result
+}
+
+func TestNormalizePageHeaders(t *testing.T) {
+	pdf := normalizeTestPDF(4)
+	result := NewNormalizingParser(&staticParser{result: pdf}).Parse(context.Background(), nil, "report.pdf")
+
+	text := result.String()
+	if strings.Contains(text, "ACME") || strings.Contains(text, "Page 2 of 4") {
+		t.Errorf("headers and footers must be removed:\n%s", text)
+	}
+	if !strings.Contains(text, "Body of the page 3 continues here.") {
+		t.Errorf("page text must be kept:\n%s", text)
+	}
+	if !strings.Contains(pdf.String(), "ACME   Annual Report") {
+		t.Errorf("original result must not be changed")
+	}
+
+	document := result.(DocumentResult).Document()
+	for _, block := range document.Blocks {
+		if strings.Contains(block.Text, "ACME") || strings.Contains(block.Text, "of 4") {
+			t.Errorf("unexpected block: %+v", block)
+		}
+	}
+
+	// Headers of the documents inside containers are removed from the container text too, but same lines of other files are kept
+	notes := &TextParserResult{FullPath: "docs.tar/notes.txt", Text: "ACME Annual Report\nPage 1 of 3\n"}
+	archive := &TARParserResult{FullPath: "docs.tar", SubfilesResults: []Result{notes, &CompositeParserResult{Inner: normalizeTestPDF(3), MimeType: "application/pdf"}, notes}}
+	text = NewNormalizingParser(&staticParser{result: archive}).Parse(context.Background(), nil, "docs.tar").String()
+	if strings.Count(text, "ACME Annual Report") != 2 || strings.Count(text, "Page 1 of 3") != 2 || !strings.Contains(text, "Body of the page 2 continues here.") {
+		t.Errorf("unexpected container text:\n%s", text)
+	}
+
+	// Containers inside containers render normalized text of the same documents
+	pdf = normalizeTestPDF(3)
+	message := &EMLParserResult{FullPath: "inbox.mbox/1", Text: "See attached", Attachments: []Result{
+		&CompositeParserResult{Inner: pdf, MimeType: "application/pdf"},
+		&CompositeParserResult{Inner: pdf, MimeType: "application/pdf"},
+	}}
+	mailbox := &MailboxParserResult{FullPath: "inbox.mbox", Messages: []Result{&CompositeParserResult{Inner: message, MimeType: "message/rfc822"}}}
+	text = NewNormalizingParser(&staticParser{result: mailbox}).Parse(context.Background(), nil, "inbox.mbox").String()
+	if strings.Contains(text, "ACME") || strings.Contains(text, "of 3") || strings.Count(text, "Body of the page 2 continues here.") != 2 {
+		t.Errorf("unexpected mailbox text:\n%s", text)
+	}
+	if strings.Count(mailbox.String(), "ACME   Annual Report") != 6 {
+		t.Errorf("original results must not be changed")
+	}
+
+	// Two pages are not enough to tell header from the text
+	text = NewNormalizingParser(&staticParser{result: normalizeTestPDF(2)}).Parse(context.Background(), nil, "report.pdf").String()
+	if !strings.Contains(text, "ACME Annual Report") {
+		t.Errorf("header of the short document must be kept:\n%s", text)
+	}
+}
+
+func TestNormalizeStream(t *testing.T) {
+	pdf := normalizeTestPDF(4)
+	stream := []StreamResult{&PDFParserStreamResult{FullPath: "report.pdf", CurrentStage: ProgressNew}}
+	for _, page := range pdf.Pages {
+		stream = append(stream, &PDFParserStreamResult{FullPath: "report.pdf", CurrentStage: ProgressUpdate, PageNumber: page.Number, Text: page.String()})
+	}
+	stream = append(stream, &PDFParserStreamResult{FullPath: "report.pdf", CurrentStage: ProgressCompleted})
+
+	iterator := NewNormalizingParser(&staticParser{stream: stream}).ParseStream(context.Background(), nil, "report.pdf")
+	defer iterator.Close()
+	var pages []string
+	for iterator.Next(context.Background()) {
+		if iterator.Current().Stage() == ProgressUpdate {
+			pages = append(pages, iterator.Current().String())
+		}
+	}
+
+	if len(pages) != 4 {
+		t.Fatalf("unexpected pages: %q", pages)
+	}
+	for i, page := range pages {
+		if !strings.Contains(page, fmt.Sprintf("Body of the page %d continues here.", i+1)) {
+			t.Errorf("unexpected text of the page %d: %q", i+1, page)
+		}
+		if hasHeader := strings.Contains(page, "ACME Annual Report"); hasHeader != (i < 2) {
+			t.Errorf("header of the page %d: expected %v", i+1, i < 2)
+		}
+	}
+}
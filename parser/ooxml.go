@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	pathlib "path"
+	"strings"
+)
+
+// Office Open XML package (pptx, xlsx, docx). It is a zip archive where parts reference each other through relationships.
+type ooxmlPackage struct {
+	files map[string]*zip.File
+	// Parts are read with the size limits of the subfiles
+	state *parseState
+	path  string
+}
+
+// Package is read into the memory, so its size is limited by the entry size limit even for the top level file
+func openOOXMLPackage(ctx context.Context, file io.Reader, path string) (*ooxmlPackage, error) {
+	state := partParseState(ctx)
+	maxSize := state.limits.MaxEntrySize
+	if maxSize > 0 {
+		file = io.LimitReader(file, maxSize+1)
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to read data to the bytes buffer"), err)
+	}
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		return nil, &LimitError{Limit: LimitEntrySize, Max: maxSize, Path: path}
+	}
+
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errors.Join(ErrBadFile, errors.New("failed to open zip container"), err)
+	}
+
+	files := make(map[string]*zip.File, len(zipReader.File))
+	for _, f := range zipReader.File {
+		files[strings.TrimPrefix(f.Name, "/")] = f
+	}
+
+	return &ooxmlPackage{files: files, state: state, path: path}, nil
+}
+
+func (p *ooxmlPackage) has(name string) bool {
+	_, ok := p.files[name]
+	return ok
+}
+
+func (p *ooxmlPackage) open(name string) (io.ReadCloser, error) {
+	f, ok := p.files[name]
+	if !ok {
+		return nil, fmt.Errorf("part %s not found in the package", name)
+	}
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{p.state.reader(r, pathlib.Join(p.path, name)), r}, nil
+}
+
+func (p *ooxmlPackage) readXML(name string, v any) error {
+	r, err := p.open(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if err := xml.NewDecoder(r).Decode(v); err != nil {
+		return errors.Join(fmt.Errorf("failed to decode part %s", name), err)
+	}
+	return nil
+}
+
+type ooxmlRelationship struct {
+	ID         string `xml:"Id,attr"`
+	Type       string `xml:"Type,attr"`
+	Target     string `xml:"Target,attr"`
+	TargetMode string `xml:"TargetMode,attr"`
+}
+
+// Returns relationships of the part indexed by relationship ID. Internal targets are resolved to the absolute part names inside package.
+// Use empty part name to get package level relationships. Missing relationships part is not an error.
+func (p *ooxmlPackage) relationships(partName string) (map[string]ooxmlRelationship, error) {
+	relsName := "_rels/.rels"
+	if partName != "" {
+		relsName = pathlib.Join(pathlib.Dir(partName), "_rels", pathlib.Base(partName)+".rels")
+	}
+	if !p.has(relsName) {
+		return map[string]ooxmlRelationship{}, nil
+	}
+
+	var rels struct {
+		Relationships []ooxmlRelationship `xml:"Relationship"`
+	}
+	if err := p.readXML(relsName, &rels); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]ooxmlRelationship, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		if rel.TargetMode != "External" {
+			if strings.HasPrefix(rel.Target, "/") {
+				rel.Target = strings.TrimPrefix(rel.Target, "/")
+			} else {
+				rel.Target = pathlib.Join(pathlib.Dir(partName), rel.Target)
+			}
+		}
+		result[rel.ID] = rel
+	}
+
+	return result, nil
+}
+
+// Returns first relationship which type ends with specified suffix. Relationship types differ between transitional and strict schemas only by prefix.
+func ooxmlRelationshipByType(rels map[string]ooxmlRelationship, typeSuffix string) (ooxmlRelationship, bool) {
+	for _, rel := range rels {
+		if strings.HasSuffix(rel.Type, typeSuffix) {
+			return rel, true
+		}
+	}
+	return ooxmlRelationship{}, false
+}
+
+func xmlAttr(element xml.StartElement, local string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
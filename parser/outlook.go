@@ -0,0 +1,552 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	pathlib "path"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"golang.org/x/net/html"
+)
+
+// MAPI property identifiers used by Outlook messages and TNEF
+const (
+	mapiSubject                 = 0x0037
+	mapiClientSubmitTime        = 0x0039
+	mapiSentRepresentingName    = 0x0042
+	mapiSentRepresentingAddress = 0x0065
+	mapiSenderName              = 0x0C1A
+	mapiSenderAddress           = 0x0C1F
+	mapiRecipientType           = 0x0C15
+	mapiDisplayBcc              = 0x0E02
+	mapiDisplayCc               = 0x0E03
+	mapiDisplayTo               = 0x0E04
+	mapiDeliveryTime            = 0x0E06
+	mapiBody                    = 0x1000
+	mapiRTFCompressed           = 0x1009
+	mapiHTML                    = 0x1013
+	mapiInternetMessageID       = 0x1035
+	mapiInternetReferences      = 0x1039
+	mapiInReplyTo               = 0x1042
+	mapiDisplayName             = 0x3001
+	mapiEmailAddress            = 0x3003
+	mapiAttachData              = 0x3701
+	mapiAttachFilename          = 0x3704
+	mapiAttachMethod            = 0x3705
+	mapiAttachLongFilename      = 0x3707
+	mapiSMTPAddress             = 0x39FE
+	mapiInternetCodepage        = 0x3FDE
+	mapiMessageCodepage         = 0x3FFD
+	mapiSenderSMTPAddress       = 0x5D01
+)
+
+// MAPI property types
+const (
+	mapiTypeShort   = 0x0002
+	mapiTypeLong    = 0x0003
+	mapiTypeBoolean = 0x000B
+	mapiTypeObject  = 0x000D
+	mapiTypeString8 = 0x001E
+	mapiTypeUnicode = 0x001F
+	mapiTypeTime    = 0x0040
+	mapiTypeBinary  = 0x0102
+)
+
+// Attachment is an embedded message
+const mapiAttachEmbeddedMessage = 5
+
+type mapiProperty struct {
+	kind uint16
+	data []byte
+}
+
+// MAPI properties of the message, recipient or attachment by property identifier
+type mapiProperties map[uint16]mapiProperty
+
+// Codepage of the 8 bit strings
+func (p mapiProperties) codepage() int {
+	if codepage, ok := p.integer(mapiMessageCodepage); ok {
+		return codepage
+	}
+	if codepage, ok := p.integer(mapiInternetCodepage); ok {
+		return codepage
+	}
+	return 1252
+}
+
+func (p mapiProperties) string(id uint16) string {
+	property, ok := p[id]
+	if !ok {
+		return ""
+	}
+	switch property.kind {
+	case mapiTypeUnicode:
+		return strings.TrimRight(decodeUTF16LE(property.data), "\x00")
+	case mapiTypeString8:
+		return strings.TrimRight(decodeCodepage(property.data, p.codepage()), "\x00")
+	}
+	return ""
+}
+
+func (p mapiProperties) binary(id uint16) []byte {
+	property, ok := p[id]
+	if !ok {
+		return nil
+	}
+	if property.kind == mapiTypeString8 || property.kind == mapiTypeUnicode {
+		return []byte(p.string(id))
+	}
+	return property.data
+}
+
+func (p mapiProperties) integer(id uint16) (int, bool) {
+	property, ok := p[id]
+	if !ok {
+		return 0, false
+	}
+	switch {
+	case (property.kind == mapiTypeLong || property.kind == mapiTypeBoolean) && len(property.data) >= 4:
+		return int(int32(binary.LittleEndian.Uint32(property.data))), true
+	case property.kind == mapiTypeShort && len(property.data) >= 2:
+		return int(int16(binary.LittleEndian.Uint16(property.data))), true
+	}
+	return 0, false
+}
+
+func (p mapiProperties) time(id uint16) time.Time {
+	property, ok := p[id]
+	if !ok || property.kind != mapiTypeTime || len(property.data) < 8 {
+		return time.Time{}
+	}
+	return filetimeToTime(binary.LittleEndian.Uint64(property.data))
+}
+
+// Converts Windows FILETIME (100 nanosecond intervals since 1601) to the time
+func filetimeToTime(filetime uint64) time.Time {
+	if filetime == 0 {
+		return time.Time{}
+	}
+	const unixEpoch = 116444736000000000
+	return time.Unix(0, 0).UTC().Add(time.Duration(int64(filetime)-unixEpoch) * 100)
+}
+
+func decodeUTF16LE(data []byte) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(data[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
+
+func encodeUTF16LE(text string) []byte {
+	var result []byte
+	for _, unit := range utf16.Encode([]rune(text)) {
+		result = binary.LittleEndian.AppendUint16(result, unit)
+	}
+	return result
+}
+
+// Email in the Outlook formats
+type outlookMessage struct {
+	properties  mapiProperties
+	recipients  []mapiProperties
+	attachments []outlookAttachment
+
+	// Values from the TNEF attributes. Used when MAPI properties are missing.
+	from     string
+	subject  string
+	body     string
+	sent     time.Time
+	received time.Time
+}
+
+type outlookAttachment struct {
+	name string
+	data []byte
+	// Not nil for the attached Outlook messages
+	message *outlookMessage
+}
+
+// Returns `Name <address>`. Exchange addresses are replaced with SMTP address if it is known.
+func outlookAddress(name string, address string, smtpAddress string) string {
+	if smtpAddress != "" {
+		address = smtpAddress
+	}
+	if strings.HasPrefix(address, "/") {
+		address = ""
+	}
+	if address == "" || name == address {
+		return name
+	}
+	if name == "" {
+		return address
+	}
+	return fmt.Sprintf("%s <%s>", name, address)
+}
+
+func (m *outlookMessage) sender() string {
+	sender := outlookAddress(m.properties.string(mapiSenderName), m.properties.string(mapiSenderAddress), m.properties.string(mapiSenderSMTPAddress))
+	if sender == "" {
+		sender = outlookAddress(m.properties.string(mapiSentRepresentingName), m.properties.string(mapiSentRepresentingAddress), "")
+	}
+	if sender == "" {
+		sender = m.from
+	}
+	return sender
+}
+
+// Recipients of the given type: 1 for To, 2 for Cc and 3 for Bcc. Falls back to display lists if there is no recipient table.
+func (m *outlookMessage) recipientsOf(recipientType int, displayID uint16) []string {
+	var result []string
+	for _, recipient := range m.recipients {
+		if kind, ok := recipient.integer(mapiRecipientType); !ok || kind&0x3 != recipientType {
+			continue
+		}
+		address := outlookAddress(recipient.string(mapiDisplayName), recipient.string(mapiEmailAddress), recipient.string(mapiSMTPAddress))
+		if address != "" {
+			result = append(result, address)
+		}
+	}
+	if len(m.recipients) != 0 {
+		return result
+	}
+
+	for _, name := range strings.Split(m.properties.string(displayID), ";") {
+		if name = strings.TrimSpace(name); name != "" {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+func (m *outlookMessage) headers() []EMLHeader {
+	sent := m.properties.time(mapiClientSubmitTime)
+	if sent.IsZero() {
+		sent = m.sent
+	}
+	received := m.properties.time(mapiDeliveryTime)
+	if received.IsZero() {
+		received = m.received
+	}
+	subject := m.properties.string(mapiSubject)
+	if subject == "" {
+		subject = m.subject
+	}
+
+	var headers []EMLHeader
+	add := func(name string, values ...string) {
+		if len(values) != 0 && values[0] != "" {
+			headers = append(headers, EMLHeader{Name: name, Values: values})
+		}
+	}
+	add("From", m.sender())
+	add("To", m.recipientsOf(1, mapiDisplayTo)...)
+	add("Cc", m.recipientsOf(2, mapiDisplayCc)...)
+	add("Bcc", m.recipientsOf(3, mapiDisplayBcc)...)
+	if !sent.IsZero() {
+		add("Date", sent.Format(emlDateLayout))
+	}
+	if !received.IsZero() {
+		add("Delivery-Date", received.Format(emlDateLayout))
+	}
+	add("Subject", subject)
+	return headers
+}
+
+// Date format of the email headers
+const emlDateLayout = "Mon, 2 Jan 2006 15:04:05 -0700"
+
+func (m *outlookMessage) thread() EMLThread {
+	ids := func(value string) []string {
+		var result []string
+		for _, id := range strings.Fields(strings.ReplaceAll(value, ",", " ")) {
+			if id = strings.Trim(id, "<>"); id != "" {
+				result = append(result, id)
+			}
+		}
+		return result
+	}
+	return EMLThread{
+		MessageID:  strings.Trim(strings.TrimSpace(m.properties.string(mapiInternetMessageID)), "<>"),
+		InReplyTo:  ids(m.properties.string(mapiInReplyTo)),
+		References: ids(m.properties.string(mapiInternetReferences)),
+	}
+}
+
+// Blocks of the plain text body. If message has no plain text body, blocks are extracted from the RTF or HTML body.
+func (m *outlookMessage) blocks() ([]Block, error) {
+	body := m.properties.string(mapiBody)
+	if body == "" {
+		body = m.body
+	}
+	if strings.TrimSpace(body) != "" {
+		return textBlocks(strings.ReplaceAll(body, "\r\n", "\n"), Location{}), nil
+	}
+
+	if compressed := m.properties.binary(mapiRTFCompressed); len(compressed) != 0 {
+		rtf, err := decompressRTF(compressed)
+		if err != nil {
+			return nil, errors.Join(errors.New("failed to decompress RTF body"), err)
+		}
+		doc, err := parseRTF(rtf)
+		if err != nil {
+			return nil, errors.Join(errors.New("failed to parse RTF body"), err)
+		}
+		if blocks := doc.blocks(); len(blocks) != 0 {
+			return blocks, nil
+		}
+	}
+
+	if htmlBody := m.properties.binary(mapiHTML); len(htmlBody) != 0 {
+		root, err := html.Parse(bytes.NewReader(decodeHTML(htmlBody, "")))
+		if err != nil {
+			return nil, errors.Join(errors.New("failed to parse HTML body"), err)
+		}
+		blocks := newHTMLConverter().convert(root)
+		appendSeparator(blocks, "\n")
+		return blocks, nil
+	}
+	return nil, nil
+}
+
+// Name of the attachment subfile
+func outlookAttachmentName(properties mapiProperties, index int) string {
+	name := properties.string(mapiAttachLongFilename)
+	if name == "" {
+		name = properties.string(mapiAttachFilename)
+	}
+	if name == "" {
+		name = properties.string(mapiDisplayName)
+	}
+	name = strings.NewReplacer("/", "_", "\\", "_").Replace(strings.TrimSpace(name))
+	if name == "" {
+		name = fmt.Sprintf("attachment_%d", index)
+	}
+	return name
+}
+
+// Parses message and its attachments. Attached Outlook messages are parsed recursively, other attachments with inner parser.
+func parseOutlookMessage(ctx context.Context, innerParser Parser, message *outlookMessage, path string) *EMLParserResult {
+	blocks, err := message.blocks()
+	if err != nil {
+		return &EMLParserResult{Err: errors.Join(ErrBadFile, err), FullPath: path}
+	}
+
+	result := &EMLParserResult{
+		FullPath: path,
+		Headers:  message.headers(),
+		Thread:   message.thread(),
+	}
+	result.appendBlocks(blocks)
+	for index, attachment := range message.attachments {
+		if err := checkEntryLimit(ctx, index+1, path); err != nil {
+			result.Err = err
+			break
+		}
+		attachmentPath := pathlib.Join(path, attachment.name)
+		if attachment.message != nil {
+			result.Attachments = append(result.Attachments, parseNestedOutlookMessage(ctx, innerParser, attachment.message, attachmentPath))
+		} else if innerParser != nil {
+			result.Attachments = append(result.Attachments, innerParser.Parse(ctx, bytes.NewReader(attachment.data), attachmentPath))
+		}
+	}
+	return result
+}
+
+// Parses attached Outlook message with the limits of the subfile
+func parseNestedOutlookMessage(ctx context.Context, innerParser Parser, message *outlookMessage, path string) *EMLParserResult {
+	state, err := nestedParseState(ctx, path)
+	if err != nil {
+		return &EMLParserResult{Err: err, FullPath: path}
+	}
+	ctx, cancel := state.context(ctx)
+	defer cancel()
+	return parseOutlookMessage(ctx, innerParser, message, path)
+}
+
+// Streams Outlook message: headers, body and then attachments one by one
+type OutlookStreamResultIterator struct {
+	innerParser Parser
+	// Reads the message. Nil if message was already read.
+	read func() (*outlookMessage, error)
+	path string
+
+	message         *outlookMessage
+	completed       bool
+	bodySent        bool
+	attachmentIndex int
+	attachmentParse StreamResultIterator
+	// Limits state of the attached message. Nil for the top level message.
+	state *parseState
+
+	current StreamResult
+}
+
+func newOutlookStreamResultIterator(innerParser Parser, path string, read func() (*outlookMessage, error)) *OutlookStreamResultIterator {
+	return &OutlookStreamResultIterator{
+		innerParser: innerParser,
+		read:        read,
+		path:        path,
+	}
+}
+
+func (i *OutlookStreamResultIterator) Next(ctx context.Context) bool {
+	if i.state != nil {
+		ctx = context.WithValue(ctx, parseStateContextKey{}, i.state)
+	}
+	if i.completed {
+		i.current = nil
+		return false
+	}
+
+	if i.message == nil {
+		message, err := i.read()
+		if err != nil {
+			i.completed = true
+			i.current = &EMLParserStreamResult{FullPath: i.path, CurrentStage: ProgressCompleted, Err: err}
+			return true
+		}
+		i.message = message
+		thread := message.thread()
+		i.current = &EMLParserStreamResult{
+			FullPath:     i.path,
+			CurrentStage: ProgressNew,
+			Headers:      message.headers(),
+			Thread:       &thread,
+		}
+		return true
+	}
+
+	if !i.bodySent {
+		i.bodySent = true
+		blocks, err := i.message.blocks()
+		if err != nil {
+			i.completed = true
+			i.current = &EMLParserStreamResult{FullPath: i.path, CurrentStage: ProgressCompleted, Err: errors.Join(ErrBadFile, err)}
+			return true
+		}
+		if len(blocks) != 0 {
+			i.current = &EMLParserStreamResult{FullPath: i.path, CurrentStage: ProgressUpdate, Text: blocksString(blocks)}
+			return true
+		}
+	}
+
+	if ctx.Err() != nil {
+		i.completed = true
+		i.current = &EMLParserStreamResult{FullPath: i.path, CurrentStage: ProgressCompleted, Err: ctx.Err()}
+		return true
+	}
+
+	if i.attachmentParse == nil {
+		for i.attachmentIndex < len(i.message.attachments) && i.attachmentParse == nil {
+			attachment := i.message.attachments[i.attachmentIndex]
+			i.attachmentIndex += 1
+			if err := checkEntryLimit(ctx, i.attachmentIndex, i.path); err != nil {
+				i.completed = true
+				i.current = &EMLParserStreamResult{FullPath: i.path, CurrentStage: ProgressCompleted, Err: err}
+				return true
+			}
+			attachmentPath := pathlib.Join(i.path, attachment.name)
+			if attachment.message != nil {
+				state, err := nestedParseState(ctx, attachmentPath)
+				iterator := newOutlookStreamResultIterator(i.innerParser, attachmentPath, func() (*outlookMessage, error) {
+					return attachment.message, err
+				})
+				iterator.state = state
+				i.attachmentParse = iterator
+			} else if i.innerParser != nil {
+				i.attachmentParse = i.innerParser.ParseStream(ctx, bytes.NewReader(attachment.data), attachmentPath)
+			}
+		}
+		if i.attachmentParse == nil {
+			i.completed = true
+			i.current = &EMLParserStreamResult{FullPath: i.path, CurrentStage: ProgressCompleted}
+			return true
+		}
+	}
+
+	if i.attachmentParse.Next(ctx) {
+		i.current = &EMLParserStreamResult{
+			FullPath:     i.path,
+			CurrentStage: ProgressUpdate,
+			CurrentPart:  i.attachmentParse.Current(),
+		}
+		return true
+	}
+	i.attachmentParse.Close()
+	i.attachmentParse = nil
+	return i.Next(ctx)
+}
+
+func (i *OutlookStreamResultIterator) Current() StreamResult {
+	return i.current
+}
+
+func (i *OutlookStreamResultIterator) Close() {
+	if i.attachmentParse != nil {
+		i.attachmentParse.Close()
+		i.attachmentParse = nil
+	}
+}
+
+// Dictionary of the compressed RTF is initialized with this text
+const rtfCompressionPrebuffer = "{\\rtf1\\ansi\\mac\\deff0\\deftab720{\\fonttbl;}{\\f0\\fnil \\froman \\fswiss \\fmodern \\fscript \\fdecor MS Sans SerifSymbolArialTimes New RomanCourier{\\colortbl\\red0\\green0\\blue0\r\n\\par \\pard\\plain\\f0\\fs20\\b\\i\\u\\tab\\tx"
+
+// Decompresses RTF body of the Outlook message ([MS-OXRTFCP])
+func decompressRTF(data []byte) ([]byte, error) {
+	if len(data) < 16 {
+		return nil, errors.New("compressed RTF header is too short")
+	}
+	rawSize := int(binary.LittleEndian.Uint32(data[4:]))
+	compression := string(data[8:12])
+	data = data[16:]
+
+	switch compression {
+	case "MELA":
+		return data[:min(rawSize, len(data))], nil
+	case "LZFu":
+	default:
+		return nil, fmt.Errorf("unknown RTF compression %q", compression)
+	}
+
+	var dictionary [4096]byte
+	writePosition := copy(dictionary[:], rtfCompressionPrebuffer)
+	// Declared size comes from the file. Two bytes of the reference expand to at most 17 bytes.
+	result := make([]byte, 0, min(rawSize, len(data)*9))
+	for position := 0; position < len(data); {
+		control := data[position]
+		position += 1
+		for bit := 0; bit < 8 && position < len(data); bit++ {
+			if control&(1<<bit) == 0 {
+				dictionary[writePosition] = data[position]
+				writePosition = (writePosition + 1) % len(dictionary)
+				result = append(result, data[position])
+				position += 1
+				continue
+			}
+
+			if position+2 > len(data) {
+				return result, nil
+			}
+			reference := int(binary.BigEndian.Uint16(data[position:]))
+			position += 2
+			offset := reference >> 4
+			if offset == writePosition {
+				return result, nil
+			}
+			for range reference&0xF + 2 {
+				c := dictionary[offset]
+				offset = (offset + 1) % len(dictionary)
+				dictionary[writePosition] = c
+				writePosition = (writePosition + 1) % len(dictionary)
+				result = append(result, c)
+			}
+		}
+	}
+	return result, nil
+}
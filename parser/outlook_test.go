@@ -0,0 +1,205 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/gabriel-vasile/mimetype"
+	testdata "github.com/opengs/file2llm/test_data"
+)
+
+func TestMSG(t *testing.T) {
+	msgParser := NewMSGParser(NewCompositeParser(NewCSVParser()))
+	result := msgParser.Parse(context.Background(), bytes.NewReader(testdata.MSG), "mail.msg")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+
+	expected := "----- Headers -----\n" +
+		"From: Jane Doe <jane@example.com>\n" +
+		"To: John Smith <john@example.org>\n" +
+		"Cc: Anna Brown <anna@example.org>\n" +
+		"Date: Tue, 2 Jan 2024 10:00:00 +0000\n" +
+		"Delivery-Date: Tue, 2 Jan 2024 10:00:05 +0000\n" +
+		"Subject: Quarterly report\n" +
+		"----- Body -----\n" +
+		"Hello team,\nrevenue grew by 12% this quarter.\n"
+	if !strings.HasPrefix(result.String(), expected) {
+		t.Errorf("unexpected result:\n%q", result.String())
+	}
+
+	emlResult := result.(*EMLParserResult)
+	if emlResult.Thread.MessageID != "report-1@example.com" || emlResult.Thread.ThreadID() != "previous-0@example.com" {
+		t.Errorf("unexpected thread %+v", emlResult.Thread)
+	}
+
+	if len(result.Subfiles()) != 2 {
+		t.Fatalf("expected 2 attachments, got %d", len(result.Subfiles()))
+	}
+	csv := result.Subfiles()[0]
+	if csv.Path() != "mail.msg/numbers.csv" || csv.Error() != nil || !strings.Contains(csv.String(), "north") {
+		t.Errorf("unexpected attachment %s: %s %v", csv.Path(), csv.String(), csv.Error())
+	}
+
+	embedded := result.Subfiles()[1]
+	if embedded.Path() != "mail.msg/Lunch.msg" || embedded.Error() != nil {
+		t.Fatalf("unexpected attached message %s: %v", embedded.Path(), embedded.Error())
+	}
+	if !strings.Contains(embedded.String(), "From: Anna Brown <anna@example.org>\n") || !strings.Contains(embedded.String(), "Subject: Café plan\n") {
+		t.Errorf("unexpected attached message headers:\n%s", embedded.String())
+	}
+	if !strings.Contains(embedded.String(), "Lunch moved to noon.") {
+		t.Errorf("RTF body of the attached message is missing:\n%s", embedded.String())
+	}
+}
+
+func TestMSGStream(t *testing.T) {
+	msgParser := NewMSGParser(NewCompositeParser(NewCSVParser()))
+	stream := msgParser.ParseStream(context.Background(), bytes.NewReader(testdata.MSG), "mail.msg")
+	defer stream.Close()
+
+	var text strings.Builder
+	var attachments []string
+	completed := false
+	for stream.Next(context.Background()) {
+		current := stream.Current()
+		if current.Error() != nil {
+			t.Fatal(current.Error())
+		}
+		text.WriteString(current.String())
+		if current.SubResult() != nil && current.SubResult().Stage() == ProgressNew {
+			attachments = append(attachments, current.SubResult().Path())
+		}
+		completed = current.Stage() == ProgressCompleted
+	}
+
+	if !completed {
+		t.Error("stream is not completed")
+	}
+	if !strings.Contains(text.String(), "Subject: Quarterly report\n") || !strings.Contains(text.String(), "revenue grew by 12%") {
+		t.Errorf("unexpected text:\n%s", text.String())
+	}
+	if len(attachments) != 2 || attachments[0] != "mail.msg/numbers.csv" || attachments[1] != "mail.msg/Lunch.msg" {
+		t.Errorf("unexpected attachments %v", attachments)
+	}
+}
+
+func TestTNEF(t *testing.T) {
+	tnefParser := NewTNEFParser(NewCompositeParser(NewCSVParser()))
+	result := tnefParser.Parse(context.Background(), bytes.NewReader(testdata.TNEF), "winmail.dat")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+
+	expected := "----- Headers -----\n" +
+		"From: Jane Doe <jane@example.com>\n" +
+		"To: John Smith <john@example.org>\n" +
+		"Date: Tue, 2 Jan 2024 10:00:00 +0000\n" +
+		"Subject: Budget for café\n" +
+		"----- Body -----\n"
+	if !strings.HasPrefix(result.String(), expected) || !strings.Contains(result.String(), "The budget is approved.") {
+		t.Errorf("unexpected result:\n%q", result.String())
+	}
+	if strings.Contains(result.String(), "secret") {
+		t.Error("named property is included into the result")
+	}
+	if result.(*EMLParserResult).Thread.MessageID != "budget-7@example.com" {
+		t.Errorf("unexpected thread %+v", result.(*EMLParserResult).Thread)
+	}
+
+	if len(result.Subfiles()) != 1 || result.Subfiles()[0].Path() != "winmail.dat/numbers.csv" || !strings.Contains(result.Subfiles()[0].String(), "south") {
+		t.Errorf("unexpected attachments %v", result.Subfiles())
+	}
+}
+
+func TestEMLWithTNEF(t *testing.T) {
+	composite := NewCompositeParser(NewCSVParser())
+	composite.AddParsers(NewTNEFParser(composite))
+	emlParser := NewEMLParser(composite)
+	result := emlParser.Parse(context.Background(), bytes.NewReader(testdata.EMLTNEF), "mail.eml")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+
+	if len(result.Subfiles()) != 1 {
+		t.Fatalf("expected TNEF attachment, got %v", result.Subfiles())
+	}
+	tnef := result.Subfiles()[0]
+	if tnef.Error() != nil {
+		t.Fatal(tnef.Error())
+	}
+	if tnef.Path() != "mail.eml/winmail.dat" || !strings.Contains(tnef.String(), "The budget is approved.") || len(tnef.Subfiles()) != 1 {
+		t.Errorf("TNEF attachment is not parsed:\n%s", tnef.String())
+	}
+}
+
+func TestOutlookMimeDetection(t *testing.T) {
+	if mime := mimetype.Detect(testdata.MSG).String(); mime != "application/vnd.ms-outlook" {
+		t.Errorf("expected application/vnd.ms-outlook, got %s", mime)
+	}
+	if mime := magicMimeType(mimetype.Detect(testdata.TNEF), testdata.TNEF); mime != "application/ms-tnef" {
+		t.Errorf("expected application/ms-tnef, got %s", mime)
+	}
+	if mime := mimetype.Detect(testdata.TNEF).String(); mime != "application/octet-stream" {
+		t.Errorf("global mime type tree of the library must not be changed, got %s", mime)
+	}
+}
+
+func TestDecompressRTF(t *testing.T) {
+	// Example from the [MS-OXRTFCP] specification
+	compressed := []byte{
+		0x2d, 0x00, 0x00, 0x00, 0x2b, 0x00, 0x00, 0x00, 0x4c, 0x5a, 0x46, 0x75, 0xf1, 0xc5, 0xc7, 0xa7,
+		0x03, 0x00, 0x0a, 0x00, 0x72, 0x63, 0x70, 0x67, 0x31, 0x32, 0x35, 0x42, 0x32, 0x0a, 0xf3, 0x20,
+		0x68, 0x65, 0x6c, 0x09, 0x00, 0x20, 0x62, 0x77, 0x05, 0xb0, 0x6c, 0x64, 0x7d, 0x0a, 0x80, 0x0f,
+		0xa0,
+	}
+	rtf, err := decompressRTF(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rtf) != "{\\rtf1\\ansi\\ansicpg1252\\pard hello world}\r\n" {
+		t.Errorf("unexpected RTF %q", rtf)
+	}
+
+	// Declared size must not be trusted
+	binary.LittleEndian.PutUint32(compressed[4:], 0xffffffff)
+	if rtf, err := decompressRTF(compressed); err != nil || cap(rtf) > len(compressed)*9 {
+		t.Errorf("unexpected result for the huge declared size: %v, capacity %d", err, cap(rtf))
+	}
+}
+
+func TestTNEFPropertiesCount(t *testing.T) {
+	if _, _, err := decodeTNEFProperties([]byte{0xff, 0xff, 0xff, 0x7f}); err == nil {
+		t.Error("expected error for the truncated property list")
+	}
+}
+
+func TestCFBTruncatedSectors(t *testing.T) {
+	header := func(size int, difat uint32, fat uint32) []byte {
+		data := make([]byte, size)
+		copy(data, cfbSignature)
+		binary.LittleEndian.PutUint16(data[30:], 9)
+		binary.LittleEndian.PutUint16(data[32:], 6)
+		binary.LittleEndian.PutUint32(data[68:], difat)
+		for i := range 109 {
+			binary.LittleEndian.PutUint32(data[76+i*4:], 0xFFFFFFFF)
+		}
+		binary.LittleEndian.PutUint32(data[76:], fat)
+		return data
+	}
+	tests := map[string][]byte{
+		"DIFAT": header(612, 0, 0xFFFFFFFF),
+		"FAT":   header(613, cfbEndOfChain, 0),
+	}
+	for name, data := range tests {
+		if _, err := openCFB(data); err == nil {
+			t.Errorf("expected error for the truncated %s sector", name)
+		}
+		if mimeType := oleMimeType(data); mimeType != "" {
+			t.Errorf("unexpected mime type for the truncated %s sector: %s", name, mimeType)
+		}
+	}
+}
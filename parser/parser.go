@@ -13,11 +13,17 @@ import (
 var ErrBadFile = errors.New("bad file or corrupted")
 var ErrParserDisabled = errors.New("parser disabled")
 
+// File is encrypted and none of the passwords from the [PasswordProvider] opened it
+var ErrEncrypted = errors.New("file is encrypted and password is missing or wrong")
+
 type ErrMimeTypeNotSupported struct {
 	MimeType *mimetype.MIME
 }
 
 func (e *ErrMimeTypeNotSupported) Error() string {
+	if e.MimeType == nil {
+		return "mime type of the file is not supported"
+	}
 	return fmt.Sprintf("mime type of the file is not supported: %s", e.MimeType)
 }
 
@@ -76,9 +82,11 @@ type StreamResult interface {
 	Error() error
 }
 
-// Build parser with all possible file types included
-func New(ocrProvider ocr.Provider) Parser {
+// Build parser with all possible file types included. Options configure mime type detection.
+// Returned parser is [*CompositeParser]; use type assertion to access `ParseAs` and `ParseStreamAs`.
+func New(ocrProvider ocr.Provider, options ...CompositeOption) Parser {
 	composite := NewCompositeParser()
+	composite.Configure(options...)
 	if ocrProvider != nil {
 		composite.AddParsers(
 			NewPNGParser(ocrProvider),
@@ -93,6 +101,14 @@ func New(ocrProvider ocr.Provider) Parser {
 
 	composite.AddParsers(NewPDFParser(composite, 300))
 	composite.AddParsers(NewTARParser(composite))
-	composite.AddParsers(NewEMLParser(composite))
+	emlParser := NewEMLParser(composite)
+	composite.AddParsers(emlParser, NewMBOXParser(emlParser))
+	composite.AddParsers(NewMSGParser(composite), NewTNEFParser(composite))
+	composite.AddParsers(NewPPTXParser(composite))
+	composite.AddParsers(NewXLSXParser(), NewCSVParser())
+	composite.AddParsers(NewRTFParser(composite))
+	composite.AddParsers(NewHTMLParser(composite))
+	composite.AddParsers(NewEPUBParser())
+	composite.AddParsers(NewTextParser())
 	return composite
 }
@@ -0,0 +1,48 @@
+package parser
+
+import (
+	"context"
+	"maps"
+	pathlib "path"
+	"slices"
+)
+
+// Provides passwords for the encrypted files
+type PasswordProvider interface {
+	// Returns passwords to try for the file at `path`. Passwords are tried in order until one of them opens the file.
+	Passwords(ctx context.Context, path string) []string
+}
+
+// Tries the same list of passwords for every encrypted file
+type PasswordList []string
+
+func NewPasswordList(passwords ...string) PasswordList {
+	return PasswordList(passwords)
+}
+
+func (l PasswordList) Passwords(ctx context.Context, path string) []string {
+	return l
+}
+
+// Passwords keyed by the file path. Keys are `path.Match` patterns, so `*.pdf` or `reports/*` can be used. Exact path is tried first, then matching patterns in sorted order.
+type PathPasswords map[string]string
+
+func NewPathPasswords(passwords map[string]string) PathPasswords {
+	return PathPasswords(passwords)
+}
+
+func (p PathPasswords) Passwords(ctx context.Context, path string) []string {
+	var passwords []string
+	if password, ok := p[path]; ok {
+		passwords = append(passwords, password)
+	}
+	for _, pattern := range slices.Sorted(maps.Keys(p)) {
+		if pattern == path {
+			continue
+		}
+		if matched, _ := pathlib.Match(pattern, path); matched {
+			passwords = append(passwords, p[pattern])
+		}
+	}
+	return passwords
+}
@@ -0,0 +1,34 @@
+package parser
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestPasswordList(t *testing.T) {
+	provider := NewPasswordList("first", "second")
+	if !slices.Equal(provider.Passwords(context.Background(), "any.pdf"), []string{"first", "second"}) {
+		t.Error(provider.Passwords(context.Background(), "any.pdf"))
+	}
+}
+
+func TestPathPasswords(t *testing.T) {
+	provider := NewPathPasswords(map[string]string{
+		"reports/q2.pdf": "exact",
+		"reports/*.pdf":  "pattern",
+		"reports/q*.pdf": "quarter",
+		"*.pdf":          "other",
+	})
+
+	passwords := provider.Passwords(context.Background(), "reports/q2.pdf")
+	if !slices.Equal(passwords, []string{"exact", "pattern", "quarter"}) {
+		t.Errorf("unexpected passwords: %v", passwords)
+	}
+	if passwords := provider.Passwords(context.Background(), "reports/q3.pdf"); !slices.Equal(passwords, []string{"pattern", "quarter"}) {
+		t.Errorf("patterns must be tried in sorted order: %v", passwords)
+	}
+	if len(provider.Passwords(context.Background(), "archive.zip/report.docx")) != 0 {
+		t.Error("passwords must not be returned for unmatched path")
+	}
+}
@@ -1,14 +1,512 @@
 package parser
 
 import (
+	"context"
+	"fmt"
+	"math"
+	pathlib "path"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 )
 
+// How text of the PDF page was extracted
+type PDFPageMethod string
+
+// Text was taken from the text layer of the page
+const PDFPageMethodTextLayer PDFPageMethod = "TEXT_LAYER"
+
+// Page was rendered to the image and recognized with OCR
+const PDFPageMethodOCR PDFPageMethod = "OCR"
+
+type pdfConfig struct {
+	textLayer         bool
+	minTextLayerChars int
+	parallelPages     int
+	passwordProvider  PasswordProvider
+	xmpMetadata       bool
+	inMemoryLimit     int64
+	maxPagePixels     int
+	maxPages          int
+	selection         PDFPageSelection
+}
+
+// Documents up to this size are held in memory
+const pdfDefaultInMemoryLimit = 32 * 1024 * 1024
+
+// 40 megapixels fit A2 page at 300 DPI and take 160 MB as BGRA image
+const pdfDefaultMaxPagePixels = 40_000_000
+
+// Configures [PDFParser]
+type PDFOption func(c *pdfConfig)
+
+// Extract text from the text layer of the page first. Page is rendered and OCRed only if its text layer has less than `minChars` non whitespace characters.
+// Pages are never OCRed if inner parser cant parse rendered images, in that case text layer is used as it is.
+func WithPDFTextLayer(minChars int) PDFOption {
+	return func(c *pdfConfig) {
+		c.textLayer = true
+		c.minTextLayerChars = minChars
+	}
+}
+
+// Number of pages processed at the same time. Pages are rendered one by one and OCRed in parallel, so set it to the size of the OCR pool (for example `TesseractPool`).
+// At most `pages` rendered images are held in memory. Stream results are still emitted in page order, but without progress inside of the page. Default is 1.
+func WithPDFParallelPages(pages int) PDFOption {
+	return func(c *pdfConfig) {
+		c.parallelPages = pages
+	}
+}
+
+// Passwords used to open encrypted documents. Without provider encrypted documents fail with [ErrEncrypted].
+func WithPDFPasswords(provider PasswordProvider) PDFOption {
+	return func(c *pdfConfig) {
+		c.passwordProvider = provider
+	}
+}
+
+// Include full XMP metadata into the text after the document information. Disabled by default because XMP is verbose XML that mostly repeats document information.
+func WithPDFXMPMetadata(include bool) PDFOption {
+	return func(c *pdfConfig) {
+		c.xmpMetadata = include
+	}
+}
+
+// Documents larger than `bytes` are written to the temporary file and read by poppler from disk instead of being held in memory. Default is 32 MB.
+// Negative value means no limit: documents are always held in memory.
+func WithPDFInMemoryLimit(bytes int64) PDFOption {
+	return func(c *pdfConfig) {
+		c.inMemoryLimit = bytes
+	}
+}
+
+// Maximum number of pixels in the rendered page. DPI is lowered for the pages that would be larger, so huge pages dont exhaust memory. Default is 40 megapixels.
+func WithPDFMaxPagePixels(pixels int) PDFOption {
+	return func(c *pdfConfig) {
+		c.maxPagePixels = pixels
+	}
+}
+
+// Parse only first `pages` pages of the document. Text ends with truncation marker if document has more pages. Zero means no limit.
+func WithPDFMaxPages(pages int) PDFOption {
+	return func(c *pdfConfig) {
+		c.maxPages = pages
+	}
+}
+
+// Parse only selected pages of the document, for example first and last pages or every 10th page for sampling
+func WithPDFPages(selection PDFPageSelection) PDFOption {
+	return func(c *pdfConfig) {
+		c.selection = selection
+	}
+}
+
+// Range of pages with numbers starting from 1. Zero `To` means until the last page.
+type PDFPageRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// Parses comma separated page ranges like `1-3,7,10-`
+func ParsePDFPageRanges(ranges string) ([]PDFPageRange, error) {
+	var result []PDFPageRange
+	for _, part := range strings.Split(ranges, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		from, to, isRange := strings.Cut(part, "-")
+		pageRange := PDFPageRange{}
+		var err error
+		if pageRange.From, err = strconv.Atoi(strings.TrimSpace(from)); err != nil || pageRange.From < 1 {
+			return nil, fmt.Errorf("invalid page range %q", part)
+		}
+		switch {
+		case !isRange:
+			pageRange.To = pageRange.From
+		case strings.TrimSpace(to) != "":
+			if pageRange.To, err = strconv.Atoi(strings.TrimSpace(to)); err != nil || pageRange.To < pageRange.From {
+				return nil, fmt.Errorf("invalid page range %q", part)
+			}
+		}
+		result = append(result, pageRange)
+	}
+	return result, nil
+}
+
+// Pages of the document to parse. Pages from ranges, first and last pages are combined. All pages are selected if none of them is set.
+type PDFPageSelection struct {
+	Ranges []PDFPageRange `json:"ranges"`
+	// Number of pages from the beginning of the document
+	First int `json:"first"`
+	// Number of pages from the end of the document
+	Last int `json:"last"`
+	// Keep only every k-th of the selected pages starting from the first one
+	Every int `json:"every"`
+}
+
+func (s PDFPageSelection) IsZero() bool {
+	return len(s.Ranges) == 0 && s.First <= 0 && s.Last <= 0 && s.Every <= 1
+}
+
+// Human readable description, for example `pages 1-3, 7, first 5, one of every 10 pages`
+func (s PDFPageSelection) String() string {
+	var parts []string
+	if len(s.Ranges) != 0 {
+		var ranges []string
+		for _, pageRange := range s.Ranges {
+			switch {
+			case pageRange.To == 0:
+				ranges = append(ranges, fmt.Sprintf("%d-", pageRange.From))
+			case pageRange.To == pageRange.From:
+				ranges = append(ranges, strconv.Itoa(pageRange.From))
+			default:
+				ranges = append(ranges, fmt.Sprintf("%d-%d", pageRange.From, pageRange.To))
+			}
+		}
+		parts = append(parts, "pages "+strings.Join(ranges, ", "))
+	}
+	if s.First > 0 {
+		parts = append(parts, fmt.Sprintf("first %d", s.First))
+	}
+	if s.Last > 0 {
+		parts = append(parts, fmt.Sprintf("last %d", s.Last))
+	}
+	if s.Every > 1 {
+		parts = append(parts, fmt.Sprintf("one of every %d pages", s.Every))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Indexes of the selected pages in the document with `total` pages
+func (s PDFPageSelection) pages(total int) []int {
+	all := len(s.Ranges) == 0 && s.First <= 0 && s.Last <= 0
+	selected := make([]bool, total)
+	for _, pageRange := range s.Ranges {
+		to := pageRange.To
+		if to == 0 || to > total {
+			to = total
+		}
+		for number := max(pageRange.From, 1); number <= to; number++ {
+			selected[number-1] = true
+		}
+	}
+	for index := 0; index < min(s.First, total); index++ {
+		selected[index] = true
+	}
+	for index := max(total-s.Last, 0); index < total && s.Last > 0; index++ {
+		selected[index] = true
+	}
+
+	var pages []int
+	for index := 0; index < total; index++ {
+		if all || selected[index] {
+			pages = append(pages, index)
+		}
+	}
+
+	if s.Every > 1 {
+		sampled := pages[:0]
+		for position, index := range pages {
+			if position%s.Every == 0 {
+				sampled = append(sampled, index)
+			}
+		}
+		pages = sampled
+	}
+	return pages
+}
+
+// Indexes of the pages to parse and number of selected pages before max pages limit is applied
+func (c *pdfConfig) pagesToParse(total int) ([]int, int) {
+	pages := c.selection.pages(total)
+	selected := len(pages)
+	if c.maxPages > 0 && len(pages) > c.maxPages {
+		pages = pages[:c.maxPages]
+	}
+	return pages, selected
+}
+
+// Describes which pages were selected. Empty if all pages are parsed.
+func pdfSelectionString(selection PDFPageSelection, selected int, total int) string {
+	if selection.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("------ Selected pages: %s (%d of %d pages) ------\n\n", selection, selected, total)
+}
+
+// Marks that not all selected pages of the document were parsed
+func pdfTruncatedString(parsed int, total int) string {
+	return fmt.Sprintf("------ Truncated: parsed %d of %d pages ------\n", parsed, total)
+}
+
+// DPI to render page of `width` x `height` points without exceeding `maxPixels`
+func pdfRenderDPI(width float64, height float64, dpi uint32, maxPixels int) uint32 {
+	scale := float64(dpi) / 72.0
+	pixels := width * scale * height * scale
+	if maxPixels <= 0 || pixels <= float64(maxPixels) {
+		return dpi
+	}
+	return max(uint32(float64(dpi)*math.Sqrt(float64(maxPixels)/pixels)), 1)
+}
+
+// Text layer is good enough if it is not empty and has at least `minChars` non whitespace characters
+func pdfTextLayerSufficient(text string, minChars int) bool {
+	chars := 0
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			chars += 1
+		}
+	}
+	return chars > 0 && chars >= minChars
+}
+
+// Rendered pages are passed to the inner parser as raw BGRA images. Without OCR inner parser doesnt support them.
+func pdfOCRAvailable(innerParser Parser) bool {
+	return slices.Contains(innerParser.SupportedMimeTypes(), "image/file2llm-raw-bgra")
+}
+
+// Text layer of the page terminated with line break, so pages are not glued together
+func pdfTextLayerString(text string) string {
+	text = strings.TrimRight(text, " \t\r\n")
+	if text == "" {
+		return ""
+	}
+	return text + "\n"
+}
+
+type pdfPageOutcome struct {
+	page PDFPage
+	err  error
+}
+
+// Keeps limited number of pages in flight and returns them in page order
+type pdfPageQueue struct {
+	size    int
+	pending []chan pdfPageOutcome
+}
+
+func newPDFPageQueue(size int) *pdfPageQueue {
+	return &pdfPageQueue{size: max(size, 1)}
+}
+
+func (q *pdfPageQueue) full() bool {
+	return len(q.pending) >= q.size
+}
+
+func (q *pdfPageQueue) empty() bool {
+	return len(q.pending) == 0
+}
+
+// Adds page that doesnt require processing
+func (q *pdfPageQueue) done(page PDFPage, err error) {
+	outcome := make(chan pdfPageOutcome, 1)
+	outcome <- pdfPageOutcome{page: page, err: err}
+	q.pending = append(q.pending, outcome)
+}
+
+// Processes page in the background
+func (q *pdfPageQueue) start(process func() (PDFPage, error)) {
+	outcome := make(chan pdfPageOutcome, 1)
+	go func() {
+		page, err := process()
+		outcome <- pdfPageOutcome{page: page, err: err}
+	}()
+	q.pending = append(q.pending, outcome)
+}
+
+// Waits for the oldest page in the queue
+func (q *pdfPageQueue) next(ctx context.Context) (PDFPage, error) {
+	select {
+	case outcome := <-q.pending[0]:
+		q.pending = q.pending[1:]
+		return outcome.page, outcome.err
+	case <-ctx.Done():
+		return PDFPage{}, ctx.Err()
+	}
+}
+
+// Document information of the PDF
+type PDFMetadata struct {
+	Title    string `json:"title"`
+	Author   string `json:"author"`
+	Subject  string `json:"subject"`
+	Keywords string `json:"keywords"`
+	// Application that created the original document
+	Creator string `json:"creator"`
+	// Application that converted document to PDF
+	Producer         string    `json:"producer"`
+	CreationDate     time.Time `json:"creationDate"`
+	ModificationDate time.Time `json:"modificationDate"`
+	Pages            int       `json:"pages"`
+	// Raw XMP metadata. Set only with [WithPDFXMPMetadata].
+	XMP string `json:"xmp"`
+}
+
+// Concise header with non empty fields of the document information
+func (m *PDFMetadata) String() string {
+	var result strings.Builder
+	result.WriteString(metadataString(m.fields()))
+
+	if m.XMP != "" {
+		result.WriteString("------ XMP ------\n")
+		result.WriteString(strings.TrimSpace(m.XMP))
+		result.WriteString("\n\n")
+	}
+
+	return result.String()
+}
+
+// Document information without XMP packet
+func (m *PDFMetadata) fields() []MetadataField {
+	var fields []MetadataField
+	for _, field := range []MetadataField{
+		{"Title", m.Title},
+		{"Author", m.Author},
+		{"Subject", m.Subject},
+		{"Keywords", m.Keywords},
+		{"Creator", m.Creator},
+		{"Producer", m.Producer},
+	} {
+		if field.Value != "" {
+			fields = append(fields, field)
+		}
+	}
+	if !m.CreationDate.IsZero() {
+		fields = append(fields, MetadataField{"Created", m.CreationDate.Format(time.RFC3339)})
+	}
+	if !m.ModificationDate.IsZero() {
+		fields = append(fields, MetadataField{"Modified", m.ModificationDate.Format(time.RFC3339)})
+	}
+	return append(fields, MetadataField{"Pages", strconv.Itoa(m.Pages)})
+}
+
+// Poppler returns unix time or -1 if date is missing
+func pdfDate(unix int64) time.Time {
+	if unix <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(unix, 0).UTC()
+}
+
+// Entry of the document outline (bookmarks)
+type PDFOutlineItem struct {
+	Title string `json:"title"`
+	// Page number starting from 1. Zero if entry doesnt point to the page of the document.
+	Page int `json:"page"`
+	// Nesting level starting from 0
+	Level int `json:"level"`
+}
+
+// Comment, note or markup annotation of the page
+type PDFAnnotation struct {
+	// Kind of annotation, for example `Note` or `Highlight`
+	Type   string `json:"type"`
+	Author string `json:"author"`
+	Text   string `json:"text"`
+}
+
+// Filled field of the interactive (AcroForm) form
+type PDFFormField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Renders outline as indented list of titles with page numbers
+func pdfOutlineString(outline []PDFOutlineItem) string {
+	if len(outline) == 0 {
+		return ""
+	}
+
+	var result strings.Builder
+	result.WriteString("------ Table of contents ------\n")
+	for _, item := range outline {
+		result.WriteString(strings.Repeat("  ", item.Level))
+		result.WriteString("- ")
+		result.WriteString(item.Title)
+		if item.Page > 0 {
+			result.WriteString(fmt.Sprintf(" (page %d)", item.Page))
+		}
+		result.WriteString("\n")
+	}
+	result.WriteString("\n")
+	return result.String()
+}
+
+// Name under which attachment is passed to the inner parser. Attachment names may contain directories of the machine where they were attached.
+func pdfAttachmentName(name string, index int) string {
+	name = pathlib.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == ".." {
+		return fmt.Sprintf("attachment_%d", index)
+	}
+	return name
+}
+
+type PDFPage struct {
+	// Page number starting from 1
+	Number int           `json:"number"`
+	Method PDFPageMethod `json:"method"`
+	Text   string        `json:"text"`
+	// Comments and notes left on the page
+	Annotations []PDFAnnotation `json:"annotations"`
+	// Filled form fields placed on the page
+	FormFields []PDFFormField `json:"formFields"`
+}
+
+// Text of the page followed by its annotations and filled form fields
+func (p *PDFPage) String() string {
+	return blocksString(p.blocks()) + pdfPageExtrasString(p.Annotations, p.FormFields)
+}
+
+// Page break followed by the paragraphs of the page text
+func (p *PDFPage) blocks() []Block {
+	location := Location{Page: p.Number, Offset: -1}
+	return append([]Block{{Kind: BlockPageBreak, Location: location}}, textBlocks(p.Text, location)...)
+}
+
+func pdfPageExtrasString(annotations []PDFAnnotation, formFields []PDFFormField) string {
+	var result strings.Builder
+
+	if len(annotations) != 0 {
+		result.WriteString("--- Annotations ---\n")
+		for _, annotation := range annotations {
+			result.WriteString(fmt.Sprintf("[%s] ", annotation.Type))
+			if annotation.Author != "" {
+				result.WriteString(annotation.Author)
+				result.WriteString(": ")
+			}
+			result.WriteString(annotation.Text)
+			result.WriteString("\n")
+		}
+	}
+
+	if len(formFields) != 0 {
+		result.WriteString("--- Form fields ---\n")
+		for _, field := range formFields {
+			result.WriteString(fmt.Sprintf("%s: %s\n", field.Name, field.Value))
+		}
+	}
+
+	return result.String()
+}
+
 type PDFParserResult struct {
-	FullPath string   `json:"path"`
-	Metadata string   `json:"metadata"`
-	Pages    []string `json:"pages"`
-	Err      error    `json:"error"`
+	FullPath string      `json:"path"`
+	Metadata PDFMetadata `json:"metadata"`
+	// Bookmarks of the document in the order they appear
+	Outline []PDFOutlineItem `json:"outline"`
+	Pages   []PDFPage        `json:"pages"`
+	// Pages chosen with [WithPDFPages]
+	Selection PDFPageSelection `json:"selection"`
+	// Number of pages chosen by selection. All pages of the document if selection is empty.
+	SelectedPages int `json:"selectedPages"`
+	// More pages were selected than parsed. See [WithPDFMaxPages].
+	Truncated bool `json:"truncated"`
+	// Parsed files attached to the document
+	Attachments []Result `json:"attachments"`
+	Err         error    `json:"error"`
 }
 
 func (r *PDFParserResult) Path() string {
@@ -18,36 +516,88 @@ func (r *PDFParserResult) Path() string {
 func (r *PDFParserResult) String() string {
 	var result strings.Builder
 
-	if r.Metadata != "" {
-		result.WriteString("------ Metadata ------\n\n")
-		result.WriteString(r.Metadata)
-		result.WriteString("\n\n")
+	if r.Metadata.Pages != 0 {
+		result.WriteString(r.Metadata.String())
 	}
 
+	result.WriteString(pdfOutlineString(r.Outline))
+	result.WriteString(pdfSelectionString(r.Selection, r.SelectedPages, r.Metadata.Pages))
+
 	result.WriteString("------ Pages ------\n\n")
 
 	for _, page := range r.Pages {
-		result.WriteString(page)
+		result.WriteString(page.String())
 		result.WriteString("\n")
 	}
 
+	if r.Truncated {
+		result.WriteString(pdfTruncatedString(len(r.Pages), r.SelectedPages))
+	}
+
+	for _, attachment := range r.Attachments {
+		if attachment.Error() != nil {
+			continue
+		}
+		if text := attachment.String(); text != "" {
+			result.WriteString(fmt.Sprintf("------ Attachment %s ------\n", attachment.Path()))
+			result.WriteString(text)
+			result.WriteString("\n")
+		}
+	}
+
 	return result.String()
 }
 
+func (r *PDFParserResult) MetadataFields() []MetadataField {
+	if r.Metadata.Pages == 0 {
+		return nil
+	}
+	return r.Metadata.fields()
+}
+
+// Text of the parsed pages. Every page begins with page break.
+func (r *PDFParserResult) Document() *Document {
+	document := &Document{}
+	for i := range r.Pages {
+		blocks := r.Pages[i].blocks()
+		appendSeparator(blocks, "\n")
+		document.Blocks = append(document.Blocks, blocks...)
+	}
+	return document
+}
+
 func (r *PDFParserResult) Error() error {
 	return r.Err
 }
 
 func (r *PDFParserResult) Subfiles() []Result {
-	return nil
+	return r.Attachments
+}
+
+// Copy of the result with other subfiles in the same order, so they are rendered in its text
+func (r *PDFParserResult) withSubfiles(subfiles []Result) Result {
+	result := *r
+	result.Attachments = subfiles
+	return &result
 }
 
 type PDFParserStreamResult struct {
 	FullPath        string             `json:"path"`
 	CurrentStage    ParseProgressStage `json:"stage"`
 	CurrentProgress uint8              `json:"progress"`
-	Text            string             `json:"text"`
-	Err             error              `json:"error"`
+	// Number of the page this update belongs to starting from 1. Zero for document level updates.
+	PageNumber int `json:"page"`
+	// How text of the page was extracted
+	PageMethod PDFPageMethod `json:"pageMethod"`
+	// Document information. Set only for the first update after document is opened.
+	Metadata *PDFMetadata `json:"metadata"`
+	// Pages chosen with [WithPDFPages] and their number. Set together with metadata.
+	Selection     *PDFPageSelection `json:"selection"`
+	SelectedPages int               `json:"selectedPages"`
+	Text          string            `json:"text"`
+	// Update of the attachment parsing. Set only for attachment updates.
+	Attachment StreamResult `json:"attachment"`
+	Err        error        `json:"error"`
 }
 
 func (r *PDFParserStreamResult) Path() string {
@@ -63,7 +613,7 @@ func (r *PDFParserStreamResult) Progress() uint8 {
 }
 
 func (r *PDFParserStreamResult) SubResult() StreamResult {
-	return nil
+	return r.Attachment
 }
 
 func (r *PDFParserStreamResult) String() string {
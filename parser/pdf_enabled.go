@@ -8,6 +8,35 @@ package parser
  #include <cairo.h>
  #include <cairo-pdf.h>
  #include <stdlib.h>
+
+ static const char *file2llm_action_title(PopplerAction *action) {
+ 	return action->any.title;
+ }
+
+ // Page number of the outline entry starting from 1 or 0 if entry doesnt point to the page
+ static int file2llm_action_page(PopplerDocument *doc, PopplerAction *action) {
+ 	if (action->type != POPPLER_ACTION_GOTO_DEST || action->goto_dest.dest == NULL) {
+ 		return 0;
+ 	}
+ 	PopplerDest *dest = action->goto_dest.dest;
+ 	if (dest->type != POPPLER_DEST_NAMED) {
+ 		return dest->page_num;
+ 	}
+ 	PopplerDest *named = poppler_document_find_dest(doc, dest->named_dest);
+ 	if (named == NULL) {
+ 		return 0;
+ 	}
+ 	int page = named->page_num;
+ 	poppler_dest_free(named);
+ 	return page;
+ }
+
+ static gchar *file2llm_annot_author(PopplerAnnot *annot) {
+ 	if (!POPPLER_IS_ANNOT_MARKUP(annot)) {
+ 		return NULL;
+ 	}
+ 	return poppler_annot_markup_get_label(POPPLER_ANNOT_MARKUP(annot));
+ }
 */
 import "C"
 import (
@@ -17,6 +46,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	pathlib "path"
+	"strings"
 	"unsafe"
 
 	"github.com/opengs/file2llm/parser/bgra"
@@ -28,15 +60,33 @@ const FeaturePDFEnabled = true
 type PDFParser struct {
 	innerParser Parser
 
-	dpi uint32
+	dpi    uint32
+	config pdfConfig
 }
 
-func NewPDFParser(innerParser Parser, dpi uint32) *PDFParser {
-	return &PDFParser{
+func NewPDFParser(innerParser Parser, dpi uint32, options ...PDFOption) *PDFParser {
+	parser := &PDFParser{
 		innerParser: innerParser,
 
 		dpi: dpi, // Ideal for ocr
+		config: pdfConfig{
+			inMemoryLimit: pdfDefaultInMemoryLimit,
+			maxPagePixels: pdfDefaultMaxPagePixels,
+		},
 	}
+
+	for _, option := range options {
+		option(&parser.config)
+	}
+
+	return parser
+}
+
+// Configuration that changes the result. Used in the cache keys.
+func (p *PDFParser) CacheFingerprint() string {
+	// Passwords, parallelism and memory limits do not change the text
+	c := p.config
+	return fmt.Sprintf("dpi=%d textLayer=%t minChars=%d xmp=%t pixels=%d pages=%d selection=%+v", p.dpi, c.textLayer, c.minTextLayerChars, c.xmpMetadata, c.maxPagePixels, c.maxPages, c.selection)
 }
 
 func (p *PDFParser) SupportedMimeTypes() []string {
@@ -44,75 +94,273 @@ func (p *PDFParser) SupportedMimeTypes() []string {
 }
 
 func (p *PDFParser) Parse(ctx context.Context, file io.Reader, path string) Result {
-	pdfData, err := io.ReadAll(file)
+	source, err := p.readSource(file)
 	if err != nil {
-		return &PDFParserResult{Err: errors.Join(errors.New("failed to read data to the bytes buffer"), err)}
+		return &PDFParserResult{FullPath: path, Err: err}
 	}
+	defer source.close()
 
-	gbytes := C.g_bytes_new(C.gconstpointer(unsafe.Pointer(&pdfData[0])), C.size_t(len(pdfData)))
-	if gbytes == nil {
-		return &PDFParserResult{Err: errors.New("failed to create GBytes")}
-	}
-	defer C.g_bytes_unref(gbytes)
-
-	var gErr *C.GError
-	doc := C.poppler_document_new_from_bytes(gbytes, nil, &gErr)
-	if doc == nil {
-		if gErr != nil {
-			defer C.g_error_free(gErr)
-			return &PDFParserResult{
-				Err: errors.Join(ErrBadFile, errors.New(C.GoString((*C.char)(gErr.message)))),
-			}
-		}
-		return &PDFParserResult{
-			Err: errors.New("unknown error while reading PDF document"),
-		}
+	doc, err := p.openDocument(ctx, source, path)
+	if err != nil {
+		return &PDFParserResult{FullPath: path, Err: err}
 	}
 	defer C.g_object_unref(C.gpointer(doc))
 
-	// Get the metadata
-	var meta string
-	metaCStr := C.poppler_document_get_metadata(doc)
-	if metaCStr != nil {
-		meta = C.GoString(metaCStr)
-		C.g_free(C.gpointer(metaCStr))
-	}
+	meta := p.getMetadata(doc)
+	outline := getOutline(doc)
 
-	var pages []string
+	var pages []PDFPage
+	ocrAvailable := pdfOCRAvailable(p.innerParser)
 
-	n_pages := int(C.poppler_document_get_n_pages(doc))
-	for pageIndex := range n_pages {
-		page := C.poppler_document_get_page(doc, C.int(pageIndex))
-		if page == nil {
-			return &PDFParserResult{
-				FullPath: path,
-				Metadata: meta,
-				Err:      errors.Join(ErrBadFile, fmt.Errorf("failed to get page %d from the document", pageIndex)),
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	queue := newPDFPageQueue(p.config.parallelPages)
+
+	pagesToParse, selectedPages := p.config.pagesToParse(meta.Pages)
+	for next := 0; next < len(pagesToParse) || !queue.empty(); {
+		if next < len(pagesToParse) && !queue.full() {
+			pageIndex := pagesToParse[next]
+			page := C.poppler_document_get_page(doc, C.int(pageIndex))
+			if page == nil {
+				return &PDFParserResult{
+					FullPath: path,
+					Metadata: meta,
+					Outline:  outline,
+					Err:      errors.Join(ErrBadFile, fmt.Errorf("failed to get page %d from the document", pageIndex)),
+				}
 			}
+
+			pdfPage, rendered, err := p.preparePage(page, pageIndex, ocrAvailable)
+			C.g_object_unref(C.gpointer(page))
+			if err != nil || rendered == nil {
+				queue.done(pdfPage, err)
+			} else {
+				queue.start(func() (PDFPage, error) {
+					return p.ocrPage(ctx, pdfPage, rendered)
+				})
+			}
+			next += 1
+			continue
 		}
 
-		pageImage, err := p.getPageImage(page)
-		C.g_object_unref(C.gpointer(page))
+		pdfPage, err := queue.next(ctx)
 		if err != nil {
 			return &PDFParserResult{
 				FullPath: path,
 				Metadata: meta,
-				Err:      errors.Join(fmt.Errorf("failed to render page %d", pageIndex), err),
+				Outline:  outline,
+				Err:      err,
 			}
 		}
+		pages = append(pages, pdfPage)
+	}
+
+	attachments, err := getAttachments(doc)
+	if err != nil {
+		return &PDFParserResult{
+			FullPath: path,
+			Metadata: meta,
+			Outline:  outline,
+			Pages:    pages,
+			Err:      err,
+		}
+	}
 
-		imageResult := p.innerParser.Parse(context.WithValue(ctx, "file2llm_DPI", p.dpi), pageImage, "")
-		if imageResult.Error() != nil {
+	var parsedAttachments []Result
+	for index, attachment := range attachments {
+		if err := checkEntryLimit(ctx, index+1, path); err != nil {
 			return &PDFParserResult{
-				FullPath: path,
-				Metadata: meta,
-				Err:      errors.Join(fmt.Errorf("failed to parse page %d", pageIndex), imageResult.Error()),
+				FullPath:      path,
+				Pages:         pages,
+				Selection:     p.config.selection,
+				SelectedPages: selectedPages,
+				Truncated:     len(pagesToParse) < selectedPages,
+				Metadata:      meta,
+				Outline:       outline,
+				Attachments:   parsedAttachments,
+				Err:           err,
 			}
 		}
-		pages = append(pages, imageResult.String())
+		parsedAttachments = append(parsedAttachments, p.innerParser.Parse(ctx, bytes.NewReader(attachment.data), pathlib.Join(path, attachment.name)))
 	}
 
-	return &PDFParserResult{Pages: pages, Metadata: meta}
+	return &PDFParserResult{
+		FullPath:      path,
+		Pages:         pages,
+		Selection:     p.config.selection,
+		SelectedPages: selectedPages,
+		Truncated:     len(pagesToParse) < selectedPages,
+		Metadata:      meta,
+		Outline:       outline,
+		Attachments:   parsedAttachments,
+	}
+}
+
+// Memory or temporary file with the document data. Poppler reads document lazily, so source must live as long as the document.
+type pdfSource struct {
+	data    *C.GBytes
+	tmpPath string
+	uri     *C.char
+}
+
+// Reads document to the memory. Documents larger than in memory limit are written to the temporary file.
+func (p *PDFParser) readSource(file io.Reader) (*pdfSource, error) {
+	limit := p.config.inMemoryLimit
+	reader := file
+	if limit >= 0 {
+		reader = io.LimitReader(file, limit+1)
+	}
+	head, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to read PDF data"), err)
+	}
+	if len(head) == 0 {
+		return nil, errors.Join(ErrBadFile, errors.New("file is empty"))
+	}
+
+	if limit < 0 || int64(len(head)) <= limit {
+		data := C.g_bytes_new(C.gconstpointer(unsafe.Pointer(&head[0])), C.size_t(len(head)))
+		if data == nil {
+			return nil, errors.New("failed to create GBytes")
+		}
+		return &pdfSource{data: data}, nil
+	}
+
+	tmpFile, err := os.CreateTemp("", "file2llm-pdf-*.pdf")
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to create temporary file"), err)
+	}
+	source := &pdfSource{tmpPath: tmpFile.Name()}
+
+	_, err = tmpFile.Write(head)
+	if err == nil {
+		_, err = io.Copy(tmpFile, file)
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		source.close()
+		return nil, errors.Join(errors.New("failed to write PDF data to the temporary file"), err)
+	}
+
+	cPath := C.CString(source.tmpPath)
+	defer C.free(unsafe.Pointer(cPath))
+	var gErr *C.GError
+	source.uri = (*C.char)(C.g_filename_to_uri((*C.gchar)(cPath), nil, &gErr))
+	if source.uri == nil {
+		source.close()
+		if gErr != nil {
+			defer C.g_error_free(gErr)
+			return nil, errors.New(C.GoString((*C.char)(gErr.message)))
+		}
+		return nil, errors.New("failed to build URI of the temporary file")
+	}
+
+	return source, nil
+}
+
+func (s *pdfSource) open(password *C.char, gErr **C.GError) *C.PopplerDocument {
+	if s.data != nil {
+		return C.poppler_document_new_from_bytes(s.data, password, gErr)
+	}
+	return C.poppler_document_new_from_file(s.uri, password, gErr)
+}
+
+func (s *pdfSource) close() {
+	if s.data != nil {
+		C.g_bytes_unref(s.data)
+	}
+	if s.uri != nil {
+		C.g_free(C.gpointer(s.uri))
+	}
+	if s.tmpPath != "" {
+		os.Remove(s.tmpPath)
+	}
+}
+
+// Opens the document. Encrypted documents are opened with passwords from the provider.
+func (p *PDFParser) openDocument(ctx context.Context, source *pdfSource, path string) (*C.PopplerDocument, error) {
+	doc, encrypted, err := newPDFDocument(source, nil)
+	if !encrypted {
+		return doc, err
+	}
+
+	if p.config.passwordProvider != nil {
+		for _, password := range p.config.passwordProvider.Passwords(ctx, path) {
+			cPassword := C.CString(password)
+			doc, encrypted, err = newPDFDocument(source, cPassword)
+			C.free(unsafe.Pointer(cPassword))
+			if !encrypted {
+				return doc, err
+			}
+		}
+	}
+
+	return nil, errors.Join(ErrEncrypted, err)
+}
+
+// Returns true if document can not be opened without password or password is wrong
+func newPDFDocument(source *pdfSource, password *C.char) (*C.PopplerDocument, bool, error) {
+	var gErr *C.GError
+	doc := source.open(password, &gErr)
+	if doc != nil {
+		return doc, false, nil
+	}
+	if gErr == nil {
+		return nil, false, errors.New("unknown error while reading PDF document")
+	}
+	defer C.g_error_free(gErr)
+
+	err := errors.New(C.GoString((*C.char)(gErr.message)))
+	if gErr.domain == C.POPPLER_ERROR && gErr.code == C.POPPLER_ERROR_ENCRYPTED {
+		return nil, true, err
+	}
+	return nil, false, errors.Join(ErrBadFile, err)
+}
+
+// Page rendered for OCR
+type pdfRenderedPage struct {
+	image io.Reader
+	// Resolution of the image. Can be lower than configured for the oversized pages.
+	dpi uint32
+}
+
+// Takes text of the page from the text layer or renders page for OCR. Returns nil rendered page if page doesnt need OCR.
+func (p *PDFParser) preparePage(page *C.PopplerPage, pageIndex int, ocrAvailable bool) (PDFPage, *pdfRenderedPage, error) {
+	pdfPage := PDFPage{
+		Number:      pageIndex + 1,
+		Annotations: getPageAnnotations(page),
+		FormFields:  getPageFormFields(page),
+	}
+
+	if p.config.textLayer {
+		text := getPageText(page)
+		if !ocrAvailable || pdfTextLayerSufficient(text, p.config.minTextLayerChars) {
+			pdfPage.Method = PDFPageMethodTextLayer
+			pdfPage.Text = pdfTextLayerString(text)
+			return pdfPage, nil, nil
+		}
+	}
+
+	rendered, err := p.getPageImage(page)
+	if err != nil {
+		return pdfPage, nil, errors.Join(fmt.Errorf("failed to render page %d", pageIndex), err)
+	}
+
+	pdfPage.Method = PDFPageMethodOCR
+	return pdfPage, rendered, nil
+}
+
+// Recognizes text of the rendered page
+func (p *PDFParser) ocrPage(ctx context.Context, pdfPage PDFPage, rendered *pdfRenderedPage) (PDFPage, error) {
+	imageResult := p.innerParser.Parse(generatedSubfileContext(context.WithValue(ctx, "file2llm_DPI", rendered.dpi)), rendered.image, "")
+	if imageResult.Error() != nil {
+		return pdfPage, errors.Join(fmt.Errorf("failed to parse page %d", pdfPage.Number-1), imageResult.Error())
+	}
+
+	pdfPage.Text = imageResult.String()
+	return pdfPage, nil
 }
 
 func (p *PDFParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
@@ -124,11 +372,248 @@ func (p *PDFParser) ParseStream(ctx context.Context, file io.Reader, path string
 	}
 }
 
-func (p *PDFParser) getPageImage(page *C.PopplerPage) (io.Reader, error) {
-	scale := float64(p.dpi) / 72.0
+// Returns text from the text layer of the page
+func getPageText(page *C.PopplerPage) string {
+	textCStr := C.poppler_page_get_text(page)
+	if textCStr == nil {
+		return ""
+	}
+	defer C.g_free(C.gpointer(textCStr))
+
+	return C.GoString(textCStr)
+}
+
+// Reads document information dictionary. XMP metadata is read only if it was requested with [WithPDFXMPMetadata].
+func (p *PDFParser) getMetadata(doc *C.PopplerDocument) PDFMetadata {
+	meta := PDFMetadata{
+		Title:            strings.TrimSpace(takeGString(C.poppler_document_get_title(doc))),
+		Author:           strings.TrimSpace(takeGString(C.poppler_document_get_author(doc))),
+		Subject:          strings.TrimSpace(takeGString(C.poppler_document_get_subject(doc))),
+		Keywords:         strings.TrimSpace(takeGString(C.poppler_document_get_keywords(doc))),
+		Creator:          strings.TrimSpace(takeGString(C.poppler_document_get_creator(doc))),
+		Producer:         strings.TrimSpace(takeGString(C.poppler_document_get_producer(doc))),
+		CreationDate:     pdfDate(int64(C.poppler_document_get_creation_date(doc))),
+		ModificationDate: pdfDate(int64(C.poppler_document_get_modification_date(doc))),
+		Pages:            int(C.poppler_document_get_n_pages(doc)),
+	}
+
+	if p.config.xmpMetadata {
+		meta.XMP = takeGString(C.poppler_document_get_metadata(doc))
+	}
+
+	return meta
+}
+
+// Returns bookmarks of the document in the order they appear
+func getOutline(doc *C.PopplerDocument) []PDFOutlineItem {
+	iter := C.poppler_index_iter_new(doc)
+	if iter == nil {
+		return nil
+	}
+	defer C.poppler_index_iter_free(iter)
+
+	var outline []PDFOutlineItem
+	walkOutline(doc, iter, 0, &outline)
+	return outline
+}
+
+func walkOutline(doc *C.PopplerDocument, iter *C.PopplerIndexIter, level int, outline *[]PDFOutlineItem) {
+	for {
+		action := C.poppler_index_iter_get_action(iter)
+		if action != nil {
+			title := strings.TrimSpace(C.GoString(C.file2llm_action_title(action)))
+			page := int(C.file2llm_action_page(doc, action))
+			C.poppler_action_free(action)
+			if title != "" {
+				*outline = append(*outline, PDFOutlineItem{Title: title, Page: page, Level: level})
+			}
+		}
+
+		child := C.poppler_index_iter_get_child(iter)
+		if child != nil {
+			walkOutline(doc, child, level+1, outline)
+			C.poppler_index_iter_free(child)
+		}
+
+		if C.poppler_index_iter_next(iter) == 0 {
+			return
+		}
+	}
+}
 
+// Converts string owned by the caller and frees it
+func takeGString(str *C.gchar) string {
+	if str == nil {
+		return ""
+	}
+	defer C.g_free(C.gpointer(str))
+	return C.GoString((*C.char)(str))
+}
+
+// Names of the annotation types that carry user comments. Links, popups and form widgets are skipped.
+var pdfAnnotationTypes = map[C.PopplerAnnotType]string{
+	C.POPPLER_ANNOT_TEXT:            "Note",
+	C.POPPLER_ANNOT_FREE_TEXT:       "Text box",
+	C.POPPLER_ANNOT_LINE:            "Line",
+	C.POPPLER_ANNOT_SQUARE:          "Square",
+	C.POPPLER_ANNOT_CIRCLE:          "Circle",
+	C.POPPLER_ANNOT_POLYGON:         "Polygon",
+	C.POPPLER_ANNOT_POLY_LINE:       "Polyline",
+	C.POPPLER_ANNOT_HIGHLIGHT:       "Highlight",
+	C.POPPLER_ANNOT_UNDERLINE:       "Underline",
+	C.POPPLER_ANNOT_SQUIGGLY:        "Squiggly",
+	C.POPPLER_ANNOT_STRIKE_OUT:      "Strikeout",
+	C.POPPLER_ANNOT_STAMP:           "Stamp",
+	C.POPPLER_ANNOT_CARET:           "Caret",
+	C.POPPLER_ANNOT_INK:             "Ink",
+	C.POPPLER_ANNOT_FILE_ATTACHMENT: "File",
+}
+
+// Returns comments and notes of the page. Annotations without text are skipped.
+func getPageAnnotations(page *C.PopplerPage) []PDFAnnotation {
+	mapping := C.poppler_page_get_annot_mapping(page)
+	if mapping == nil {
+		return nil
+	}
+	defer C.poppler_page_free_annot_mapping(mapping)
+
+	var annotations []PDFAnnotation
+	for item := mapping; item != nil; item = item.next {
+		annot := (*C.PopplerAnnotMapping)(unsafe.Pointer(item.data)).annot
+		annotType, ok := pdfAnnotationTypes[C.poppler_annot_get_annot_type(annot)]
+		if !ok {
+			continue
+		}
+
+		text := strings.TrimSpace(takeGString(C.poppler_annot_get_contents(annot)))
+		if text == "" {
+			continue
+		}
+
+		annotations = append(annotations, PDFAnnotation{
+			Type:   annotType,
+			Author: strings.TrimSpace(takeGString(C.file2llm_annot_author(annot))),
+			Text:   text,
+		})
+	}
+	return annotations
+}
+
+// Returns form fields of the page that have a value
+func getPageFormFields(page *C.PopplerPage) []PDFFormField {
+	mapping := C.poppler_page_get_form_field_mapping(page)
+	if mapping == nil {
+		return nil
+	}
+	defer C.poppler_page_free_form_field_mapping(mapping)
+
+	var fields []PDFFormField
+	for item := mapping; item != nil; item = item.next {
+		field := (*C.PopplerFormFieldMapping)(unsafe.Pointer(item.data)).field
+
+		var value string
+		switch C.poppler_form_field_get_field_type(field) {
+		case C.POPPLER_FORM_FIELD_TEXT:
+			value = takeGString(C.poppler_form_field_text_get_text(field))
+		case C.POPPLER_FORM_FIELD_BUTTON:
+			if C.poppler_form_field_button_get_button_type(field) != C.POPPLER_FORM_BUTTON_PUSH && C.poppler_form_field_button_get_state(field) != 0 {
+				value = "Yes"
+			}
+		case C.POPPLER_FORM_FIELD_CHOICE:
+			var selected []string
+			for index := C.gint(0); index < C.poppler_form_field_choice_get_n_items(field); index++ {
+				if C.poppler_form_field_choice_is_item_selected(field, index) != 0 {
+					selected = append(selected, takeGString(C.poppler_form_field_choice_get_item(field, index)))
+				}
+			}
+			value = strings.Join(selected, ", ")
+			if value == "" {
+				value = takeGString(C.poppler_form_field_choice_get_text(field))
+			}
+		}
+
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		name := takeGString(C.poppler_form_field_get_name(field))
+		if name == "" {
+			name = takeGString(C.poppler_form_field_get_partial_name(field))
+		}
+		fields = append(fields, PDFFormField{Name: name, Value: value})
+	}
+	return fields
+}
+
+type pdfAttachment struct {
+	name string
+	data []byte
+}
+
+// Returns files embedded into the document
+func getAttachments(doc *C.PopplerDocument) ([]pdfAttachment, error) {
+	list := C.poppler_document_get_attachments(doc)
+	if list == nil {
+		return nil, nil
+	}
+	defer func() {
+		for item := list; item != nil; item = item.next {
+			C.g_object_unref(item.data)
+		}
+		C.g_list_free(list)
+	}()
+
+	var attachments []pdfAttachment
+	index := 0
+	for item := list; item != nil; item = item.next {
+		attachment := (*C.PopplerAttachment)(unsafe.Pointer(item.data))
+		data, err := saveAttachment(attachment)
+		if err != nil {
+			return attachments, errors.Join(fmt.Errorf("failed to extract attachment %d", index), err)
+		}
+
+		attachments = append(attachments, pdfAttachment{
+			name: pdfAttachmentName(C.GoString((*C.char)(attachment.name)), index),
+			data: data,
+		})
+		index += 1
+	}
+	return attachments, nil
+}
+
+// Poppler can only save attachment to the file, so it is saved to the temporary file and read back
+func saveAttachment(attachment *C.PopplerAttachment) ([]byte, error) {
+	tmpFile, err := os.CreateTemp("", "file2llm-pdf-attachment-*")
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to create temporary file"), err)
+	}
+	tmpPath := tmpFile.Name()
+	tmpFile.Close()
+	defer os.Remove(tmpPath)
+
+	cPath := C.CString(tmpPath)
+	defer C.free(unsafe.Pointer(cPath))
+
+	var gErr *C.GError
+	if C.poppler_attachment_save(attachment, cPath, &gErr) == 0 {
+		if gErr != nil {
+			defer C.g_error_free(gErr)
+			return nil, errors.Join(ErrBadFile, errors.New(C.GoString((*C.char)(gErr.message))))
+		}
+		return nil, errors.Join(ErrBadFile, errors.New("failed to save attachment"))
+	}
+
+	return os.ReadFile(tmpPath)
+}
+
+// Renders page at configured DPI. DPI is lowered if page has more pixels than allowed.
+func (p *PDFParser) getPageImage(page *C.PopplerPage) (*pdfRenderedPage, error) {
 	var w, h C.double
 	C.poppler_page_get_size(page, &w, &h)
+	dpi := pdfRenderDPI(float64(w), float64(h), p.dpi, p.config.maxPagePixels)
+	scale := float64(dpi) / 72.0
+
 	width := int(float64(w) * scale)
 	if width <= 0 {
 		return nil, fmt.Errorf("page width is: %d", width)
@@ -178,7 +663,7 @@ func (p *PDFParser) getPageImage(page *C.PopplerPage) (io.Reader, error) {
 		bytes.NewBuffer(buf),
 	)
 
-	return bgraStream, nil
+	return &pdfRenderedPage{image: bgraStream, dpi: dpi}, nil
 }
 
 type PDFStreamResultIterator struct {
@@ -189,14 +674,33 @@ type PDFStreamResultIterator struct {
 	started   bool
 	completed bool
 
-	docBuffer        *C.GBytes
-	doc              *C.PopplerDocument
-	nPages           int
-	currentPage      *C.PopplerPage
-	currentPageIndex int
+	source *pdfSource
+	doc    *C.PopplerDocument
+	// Indexes of the pages to parse and number of selected pages before max pages limit
+	pages         []int
+	selectedPages int
+	// Position of the next page in `pages`
+	nextPage int
+	// Number of pages emitted when pages are processed in parallel
+	emittedPages      int
+	ocrAvailable      bool
+	currentPage       *C.PopplerPage
+	currentPageNumber int
+	// Annotations and form fields of the current page emitted after its text
+	currentPageExtras string
 
 	pageProcessing StreamResultIterator
-	current        StreamResult
+	// Pages in flight when pages are processed in parallel
+	pageQueue       *pdfPageQueue
+	pageQueueCancel context.CancelFunc
+
+	truncationReported bool
+	attachmentsLoaded  bool
+	attachments        []pdfAttachment
+	attachmentIndex    int
+	attachmentParse    StreamResultIterator
+
+	current StreamResult
 }
 
 func (i *PDFStreamResultIterator) Current() StreamResult {
@@ -219,66 +723,51 @@ func (i *PDFStreamResultIterator) Next(ctx context.Context) bool {
 	}
 
 	if i.doc == nil {
-		pdfData, err := io.ReadAll(i.file)
+		source, err := i.pdfParser.readSource(i.file)
 		if err != nil {
 			i.completed = true
 			i.current = &PDFParserStreamResult{
 				FullPath:     i.path,
-				Err:          errors.Join(errors.New("failed to read data to the bytes buffer"), err),
+				Err:          err,
 				CurrentStage: ProgressCompleted,
 			}
 			return true
 		}
 
-		i.docBuffer = C.g_bytes_new(C.gconstpointer(unsafe.Pointer(&pdfData[0])), C.size_t(len(pdfData)))
-		if i.docBuffer == nil {
+		i.doc, err = i.pdfParser.openDocument(i.ctx, source, i.path)
+		if err != nil {
+			source.close()
 			i.completed = true
 			i.current = &PDFParserStreamResult{
 				FullPath:     i.path,
-				Err:          errors.New("failed to create GBytes"),
+				Err:          err,
 				CurrentStage: ProgressCompleted,
 			}
 			return true
 		}
 
-		var gErr *C.GError
-		i.doc = C.poppler_document_new_from_bytes(i.docBuffer, nil, &gErr)
-		if i.doc == nil {
-			C.g_bytes_unref(i.docBuffer)
-			i.docBuffer = nil
-			if gErr != nil {
-				defer C.g_error_free(gErr)
-				i.current = &PDFParserStreamResult{
-					FullPath:     i.path,
-					Err:          errors.Join(ErrBadFile, errors.New(C.GoString((*C.char)(gErr.message)))),
-					CurrentStage: ProgressCompleted,
-				}
-				return true
-			}
-			i.current = &PDFParserStreamResult{
-				FullPath:     i.path,
-				Err:          errors.New("unknown error while reading PDF document"),
-				CurrentStage: ProgressCompleted,
-			}
-			return true
-		}
+		i.source = source
 
-		var meta string
-		metaCStr := C.poppler_document_get_metadata(i.doc)
-		if metaCStr != nil {
-			meta = C.GoString(metaCStr)
-			C.g_free(C.gpointer(metaCStr))
-		}
-		i.nPages = int(C.poppler_document_get_n_pages(i.doc))
+		meta := i.pdfParser.getMetadata(i.doc)
+		i.pages, i.selectedPages = i.pdfParser.config.pagesToParse(meta.Pages)
+		i.ocrAvailable = pdfOCRAvailable(i.pdfParser.innerParser)
 
+		selection := i.pdfParser.config.selection
 		i.current = &PDFParserStreamResult{
-			FullPath:     i.path,
-			CurrentStage: ProgressUpdate,
-			Text:         fmt.Sprintf("------METADATA START------\n\n%s------METADATA END------\n\n", meta),
+			FullPath:      i.path,
+			CurrentStage:  ProgressUpdate,
+			Metadata:      &meta,
+			Selection:     &selection,
+			SelectedPages: i.selectedPages,
+			Text:          meta.String() + pdfOutlineString(getOutline(i.doc)) + pdfSelectionString(selection, i.selectedPages, meta.Pages),
 		}
 		return true
 	}
 
+	if i.pdfParser.config.parallelPages > 1 {
+		return i.nextParallel(ctx)
+	}
+
 	if i.pageProcessing != nil {
 		if i.pageProcessing.Next(ctx) {
 			nextPageUpdate := i.pageProcessing.Current()
@@ -287,20 +776,20 @@ func (i *PDFStreamResultIterator) Next(ctx context.Context) bool {
 				i.current = &PDFParserStreamResult{
 					FullPath:     i.path,
 					CurrentStage: ProgressCompleted,
-					Err:          errors.Join(fmt.Errorf("failed to process page %d of the file", i.currentPageIndex-1), err),
+					Err:          errors.Join(fmt.Errorf("failed to process page %d of the file", i.currentPageNumber-1), err),
 				}
 				return true
 			}
 
 			if nextPageUpdate.Stage() == ProgressNew || nextPageUpdate.Stage() == ProgressUpdate {
-				progressPerPage := 1.0 / float64(i.nPages)
 				progressInsidePage := float64(nextPageUpdate.Progress()) / 100
-				curentProgress := progressPerPage * (float64(i.currentPageIndex-1) + progressInsidePage) * 100
 
 				i.current = &PDFParserStreamResult{
 					FullPath:        i.path,
 					CurrentStage:    ProgressUpdate,
-					CurrentProgress: uint8(curentProgress),
+					CurrentProgress: i.progress(float64(i.nextPage-1) + progressInsidePage),
+					PageNumber:      i.currentPageNumber,
+					PageMethod:      PDFPageMethodOCR,
 					Text:            nextPageUpdate.String(),
 				}
 				return true
@@ -313,8 +802,10 @@ func (i *PDFStreamResultIterator) Next(ctx context.Context) bool {
 				i.current = &PDFParserStreamResult{
 					FullPath:        i.path,
 					CurrentStage:    ProgressUpdate,
-					CurrentProgress: uint8(1.0 / float64(i.nPages) * float64(i.currentPageIndex) * 100),
-					Text:            text,
+					CurrentProgress: i.progress(float64(i.nextPage)),
+					PageNumber:      i.currentPageNumber,
+					PageMethod:      PDFPageMethodOCR,
+					Text:            text + i.currentPageExtras,
 				}
 				return true
 			}
@@ -324,62 +815,239 @@ func (i *PDFStreamResultIterator) Next(ctx context.Context) bool {
 		}
 	}
 
-	if i.currentPageIndex < i.nPages {
+	if i.nextPage < len(i.pages) {
 		if i.currentPage != nil {
 			C.g_object_unref(C.gpointer(i.currentPage))
 		}
 
-		i.currentPage = C.poppler_document_get_page(i.doc, C.int(i.currentPageIndex))
+		pageIndex := i.pages[i.nextPage]
+		i.currentPage = C.poppler_document_get_page(i.doc, C.int(pageIndex))
 		if i.currentPage == nil {
 			i.completed = true
 			i.current = &PDFParserStreamResult{
 				FullPath:     i.path,
 				CurrentStage: ProgressCompleted,
-				Err:          fmt.Errorf("failed to load page %d of the file", i.currentPageIndex),
+				Err:          fmt.Errorf("failed to load page %d of the file", pageIndex),
 			}
 			return true
 		}
-		i.currentPageIndex += 1
+		i.nextPage += 1
+		i.currentPageNumber = pageIndex + 1
+		i.currentPageExtras = pdfPageExtrasString(getPageAnnotations(i.currentPage), getPageFormFields(i.currentPage))
 
-		imageData, err := i.pdfParser.getPageImage(i.currentPage)
+		if i.pdfParser.config.textLayer {
+			text := getPageText(i.currentPage)
+			if !i.ocrAvailable || pdfTextLayerSufficient(text, i.pdfParser.config.minTextLayerChars) {
+				i.current = &PDFParserStreamResult{
+					FullPath:        i.path,
+					CurrentStage:    ProgressUpdate,
+					CurrentProgress: i.progress(float64(i.nextPage)),
+					PageNumber:      i.currentPageNumber,
+					PageMethod:      PDFPageMethodTextLayer,
+					Text:            pdfTextLayerString(text) + i.currentPageExtras,
+				}
+				return true
+			}
+		}
+
+		rendered, err := i.pdfParser.getPageImage(i.currentPage)
 		if err != nil {
 			i.completed = true
 			i.current = &PDFParserStreamResult{
 				FullPath:     i.path,
 				CurrentStage: ProgressCompleted,
-				Err:          fmt.Errorf("failed to render page %d of the file", i.currentPageIndex-1),
+				Err:          fmt.Errorf("failed to render page %d of the file", pageIndex),
 			}
 			return true
 		}
-		i.pageProcessing = i.pdfParser.innerParser.ParseStream(context.WithValue(i.ctx, "file2llm_DPI", i.pdfParser.dpi), imageData, "")
+		i.pageProcessing = i.pdfParser.innerParser.ParseStream(generatedSubfileContext(context.WithValue(i.ctx, "file2llm_DPI", rendered.dpi)), rendered.image, "")
 
 		return i.Next(ctx)
 	}
 
-	if !i.completed {
+	return i.nextAttachment(ctx)
+}
+
+// Progress in percents after `pages` of the selected pages were processed
+func (i *PDFStreamResultIterator) progress(pages float64) uint8 {
+	if len(i.pages) == 0 {
+		return 100
+	}
+	return uint8(pages / float64(len(i.pages)) * 100)
+}
+
+// Renders next pages while previous are OCRed and emits them in page order
+func (i *PDFStreamResultIterator) nextParallel(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		i.current = nil
+		return false
+	}
+
+	if i.pageQueue == nil {
+		i.pageQueue = newPDFPageQueue(i.pdfParser.config.parallelPages)
+		var queueCtx context.Context
+		queueCtx, i.pageQueueCancel = context.WithCancel(i.ctx)
+		i.ctx = queueCtx
+	}
+
+	for i.nextPage < len(i.pages) && !i.pageQueue.full() {
+		pageIndex := i.pages[i.nextPage]
+		i.nextPage += 1
+		page := C.poppler_document_get_page(i.doc, C.int(pageIndex))
+		if page == nil {
+			i.pageQueue.done(PDFPage{Number: pageIndex + 1}, fmt.Errorf("failed to load page %d of the file", pageIndex))
+			break
+		}
+
+		pdfPage, rendered, err := i.pdfParser.preparePage(page, pageIndex, i.ocrAvailable)
+		C.g_object_unref(C.gpointer(page))
+		if err != nil || rendered == nil {
+			i.pageQueue.done(pdfPage, err)
+		} else {
+			pageCtx := i.ctx
+			i.pageQueue.start(func() (PDFPage, error) {
+				return i.pdfParser.ocrPage(pageCtx, pdfPage, rendered)
+			})
+		}
+	}
+
+	if i.pageQueue.empty() {
+		return i.nextAttachment(ctx)
+	}
+
+	pdfPage, err := i.pageQueue.next(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			i.current = nil
+			return false
+		}
 		i.completed = true
 		i.current = &PDFParserStreamResult{
 			FullPath:     i.path,
 			CurrentStage: ProgressCompleted,
+			PageNumber:   pdfPage.Number,
+			Err:          errors.Join(fmt.Errorf("failed to process page %d of the file", pdfPage.Number-1), err),
 		}
 		return true
 	}
 
-	i.current = nil
-	return false
+	i.emittedPages += 1
+	i.current = &PDFParserStreamResult{
+		FullPath:        i.path,
+		CurrentStage:    ProgressUpdate,
+		CurrentProgress: i.progress(float64(i.emittedPages)),
+		PageNumber:      pdfPage.Number,
+		PageMethod:      pdfPage.Method,
+		Text:            pdfPage.String(),
+	}
+	return true
 }
 
+// Streams files attached to the document after all pages and completes the stream
+func (i *PDFStreamResultIterator) nextAttachment(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		i.current = nil
+		return false
+	}
+
+	if !i.truncationReported {
+		i.truncationReported = true
+		if len(i.pages) < i.selectedPages {
+			i.current = &PDFParserStreamResult{
+				FullPath:        i.path,
+				CurrentStage:    ProgressUpdate,
+				CurrentProgress: 100,
+				Text:            pdfTruncatedString(len(i.pages), i.selectedPages),
+			}
+			return true
+		}
+	}
+
+	if !i.attachmentsLoaded {
+		i.attachmentsLoaded = true
+		attachments, err := getAttachments(i.doc)
+		if err != nil {
+			i.completed = true
+			i.current = &PDFParserStreamResult{
+				FullPath:     i.path,
+				CurrentStage: ProgressCompleted,
+				Err:          err,
+			}
+			return true
+		}
+		i.attachments = attachments
+	}
+
+	for {
+		if i.attachmentParse != nil {
+			if i.attachmentParse.Next(ctx) {
+				i.current = &PDFParserStreamResult{
+					FullPath:        i.path,
+					CurrentStage:    ProgressUpdate,
+					CurrentProgress: 100,
+					Attachment:      i.attachmentParse.Current(),
+				}
+				return true
+			}
+			i.attachmentParse.Close()
+			i.attachmentParse = nil
+		}
+
+		if i.attachmentIndex >= len(i.attachments) {
+			break
+		}
+		attachment := i.attachments[i.attachmentIndex]
+		i.attachments[i.attachmentIndex] = pdfAttachment{}
+		i.attachmentIndex += 1
+		if err := checkEntryLimit(ctx, i.attachmentIndex, i.path); err != nil {
+			i.completed = true
+			i.current = &PDFParserStreamResult{
+				FullPath:     i.path,
+				CurrentStage: ProgressCompleted,
+				Err:          err,
+			}
+			return true
+		}
+		i.attachmentParse = i.pdfParser.innerParser.ParseStream(i.ctx, bytes.NewReader(attachment.data), pathlib.Join(i.path, attachment.name))
+	}
+
+	i.completed = true
+	i.current = &PDFParserStreamResult{
+		FullPath:        i.path,
+		CurrentStage:    ProgressCompleted,
+		CurrentProgress: 100,
+	}
+	return true
+}
+
+// Releases the document. It is safe to call Close more than once; stream is completed after it.
 func (i *PDFStreamResultIterator) Close() {
+	i.completed = true
+
 	if i.pageProcessing != nil {
 		i.pageProcessing.Close()
+		i.pageProcessing = nil
+	}
+
+	if i.attachmentParse != nil {
+		i.attachmentParse.Close()
+		i.attachmentParse = nil
+	}
+
+	if i.pageQueueCancel != nil {
+		i.pageQueueCancel()
+		i.pageQueueCancel = nil
 	}
 
 	if i.currentPage != nil {
 		C.g_object_unref(C.gpointer(i.currentPage))
+		i.currentPage = nil
 	}
 
-	if i.docBuffer != nil {
-		C.g_bytes_unref(i.docBuffer)
+	if i.source != nil {
 		C.g_object_unref(C.gpointer(i.doc))
+		i.doc = nil
+		i.source.close()
+		i.source = nil
 	}
 }
@@ -3,8 +3,12 @@ package parser
 import (
 	"bytes"
 	"context"
+	"errors"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/opengs/file2llm/ocr"
 	testdata "github.com/opengs/file2llm/test_data"
@@ -55,3 +59,353 @@ func TestPDFStream(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestPDFTextLayer(t *testing.T) {
+	// Without OCR every page is taken from the text layer
+	pdfParser := NewPDFParser(NewCompositeParser(), 300, WithPDFTextLayer(10))
+	result := pdfParser.Parse(context.Background(), bytes.NewReader(testdata.PDF), "")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+
+	pdfResult := result.(*PDFParserResult)
+	if len(pdfResult.Pages) == 0 || pdfResult.Pages[0].Method != PDFPageMethodTextLayer || pdfResult.Pages[0].Number != 1 {
+		t.Fatalf("unexpected pages: %+v", pdfResult.Pages)
+	}
+	if !strings.Contains(result.String(), "TITLE") || !strings.Contains(result.String(), "normal text") {
+		t.Error(result.String())
+	}
+}
+
+func TestPDFTextLayerFallbackToOCR(t *testing.T) {
+	ocrProvider := ocr.NewTestingOCRProvider(t)
+	pdfParser := NewPDFParser(New(ocrProvider), 300, WithPDFTextLayer(1000))
+
+	methods := map[PDFPageMethod]bool{}
+	var resultString string
+	stream := pdfParser.ParseStream(context.Background(), bytes.NewReader(testdata.PDF), "")
+	defer stream.Close()
+	for stream.Next(t.Context()) {
+		progress := stream.Current().(*PDFParserStreamResult)
+		if progress.Err != nil {
+			t.Fatal(progress.Err)
+		}
+		if progress.PageNumber != 0 {
+			methods[progress.PageMethod] = true
+		}
+		resultString += progress.String()
+	}
+
+	// Text layer is too short, so page is OCRed together with the embedded image
+	if !methods[PDFPageMethodOCR] || methods[PDFPageMethodTextLayer] || !strings.Contains(strings.ToLower(resultString), "hello") {
+		t.Errorf("unexpected methods %v: %s", methods, resultString)
+	}
+}
+
+func TestPDFTextLayerSufficient(t *testing.T) {
+	if pdfTextLayerSufficient(" \n\t ", 0) {
+		t.Error("empty text layer must not be sufficient")
+	}
+	if !pdfTextLayerSufficient("a b c", 3) || pdfTextLayerSufficient("a b c", 4) {
+		t.Error("whitespaces must not be counted")
+	}
+	if pdfTextLayerString("text \n\n") != "text\n" || pdfTextLayerString(" \n") != "" {
+		t.Error("unexpected text layer string")
+	}
+}
+
+func TestPDFParallelPages(t *testing.T) {
+	ocrProvider := ocr.NewTestingOCRProvider(t)
+	sequential := NewPDFParser(New(ocrProvider), 300).Parse(context.Background(), bytes.NewReader(testdata.PDF), "")
+	parallel := NewPDFParser(New(ocrProvider), 300, WithPDFParallelPages(4)).Parse(context.Background(), bytes.NewReader(testdata.PDF), "")
+	if sequential.Error() != nil || parallel.Error() != nil {
+		t.Fatal(sequential.Error(), parallel.Error())
+	}
+	if sequential.String() != parallel.String() {
+		t.Errorf("parallel result differs from sequential:\n%s", parallel.String())
+	}
+}
+
+func TestPDFPageQueue(t *testing.T) {
+	queue := newPDFPageQueue(3)
+
+	var inFlight, maxInFlight atomic.Int32
+	var pages []int
+	for pageIndex := 0; pageIndex < 10 || !queue.empty(); {
+		if pageIndex < 10 && !queue.full() {
+			number := pageIndex + 1
+			if number%4 == 0 {
+				queue.done(PDFPage{Number: number, Method: PDFPageMethodTextLayer}, nil)
+			} else {
+				queue.start(func() (PDFPage, error) {
+					current := inFlight.Add(1)
+					defer inFlight.Add(-1)
+					for {
+						observed := maxInFlight.Load()
+						if current <= observed || maxInFlight.CompareAndSwap(observed, current) {
+							break
+						}
+					}
+					// Earlier pages finish later
+					time.Sleep(time.Duration(10-number) * time.Millisecond)
+					return PDFPage{Number: number, Method: PDFPageMethodOCR}, nil
+				})
+			}
+			pageIndex += 1
+			continue
+		}
+
+		page, err := queue.next(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, page.Number)
+	}
+
+	for i, number := range pages {
+		if number != i+1 {
+			t.Fatalf("pages are out of order: %v", pages)
+		}
+	}
+	if len(pages) != 10 || maxInFlight.Load() > 3 {
+		t.Errorf("unexpected pages %v or too many pages in flight %d", pages, maxInFlight.Load())
+	}
+}
+
+func TestPDFExtras(t *testing.T) {
+	pdfParser := NewPDFParser(NewCompositeParser(NewCSVParser()), 300, WithPDFTextLayer(10))
+	result := pdfParser.Parse(context.Background(), bytes.NewReader(testdata.PDFExtras), "report.pdf")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+
+	pdfResult := result.(*PDFParserResult)
+	meta := pdfResult.Metadata
+	if meta.Title != "Quarterly report" || meta.Author != "Jane Doe" || meta.Pages != 2 || !meta.CreationDate.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) || meta.XMP != "" {
+		t.Errorf("unexpected metadata: %+v", meta)
+	}
+
+	expectedOutline := []PDFOutlineItem{{Title: "Introduction", Page: 1}, {Title: "Scope", Page: 1, Level: 1}, {Title: "Details", Page: 2}}
+	if !slices.Equal(pdfResult.Outline, expectedOutline) {
+		t.Errorf("unexpected outline: %+v", pdfResult.Outline)
+	}
+
+	if len(pdfResult.Pages) != 2 {
+		t.Fatalf("unexpected pages: %+v", pdfResult.Pages)
+	}
+	if !slices.Equal(pdfResult.Pages[0].Annotations, []PDFAnnotation{{Type: "Note", Author: "Jane Doe", Text: "Please verify these numbers"}}) {
+		t.Errorf("unexpected annotations: %+v", pdfResult.Pages[0].Annotations)
+	}
+	// Empty fields are skipped
+	if !slices.Equal(pdfResult.Pages[0].FormFields, []PDFFormField{{Name: "CustomerName", Value: "John Smith"}}) {
+		t.Errorf("unexpected form fields: %+v", pdfResult.Pages[0].FormFields)
+	}
+
+	if len(result.Subfiles()) != 1 || result.Subfiles()[0].Path() != "report.pdf/report.csv" || result.Subfiles()[0].Error() != nil {
+		t.Fatalf("unexpected attachments: %v", result.Subfiles())
+	}
+
+	resultString := result.String()
+	for _, text := range []string{
+		"------ Metadata ------\nTitle: Quarterly report\nAuthor: Jane Doe\nKeywords: finance, q2\nProducer: file2llm tests\nCreated: 2024-01-02T03:04:05Z\nPages: 2\n\n",
+		"------ Table of contents ------\n- Introduction (page 1)\n  - Scope (page 1)\n- Details (page 2)\n",
+		"--- Annotations ---\n[Note] Jane Doe: Please verify these numbers\n--- Form fields ---\nCustomerName: John Smith\n",
+		"------ Attachment report.pdf/report.csv ------\n",
+		"North",
+	} {
+		if !strings.Contains(resultString, text) {
+			t.Errorf("missing %q in:\n%s", text, resultString)
+		}
+	}
+}
+
+func TestPDFExtrasStream(t *testing.T) {
+	pdfParser := NewPDFParser(NewCompositeParser(NewCSVParser()), 300, WithPDFTextLayer(10))
+	stream := pdfParser.ParseStream(context.Background(), bytes.NewReader(testdata.PDFExtras), "report.pdf")
+	defer stream.Close()
+
+	var text strings.Builder
+	var meta *PDFMetadata
+	attachmentPaths := map[string]bool{}
+	for stream.Next(t.Context()) {
+		progress := stream.Current()
+		if progress.Error() != nil {
+			t.Fatal(progress.Error())
+		}
+		if progress.(*PDFParserStreamResult).Metadata != nil {
+			meta = progress.(*PDFParserStreamResult).Metadata
+		}
+		if progress.SubResult() != nil {
+			attachmentPaths[progress.SubResult().Path()] = true
+			continue
+		}
+		text.WriteString(progress.String())
+	}
+
+	if meta == nil || meta.Title != "Quarterly report" || meta.Pages != 2 {
+		t.Errorf("unexpected metadata: %+v", meta)
+	}
+	if !attachmentPaths["report.pdf/report.csv"] {
+		t.Errorf("attachment is not streamed: %v", attachmentPaths)
+	}
+	for _, expected := range []string{"- Details (page 2)", "[Note] Jane Doe: Please verify these numbers", "CustomerName: John Smith"} {
+		if !strings.Contains(text.String(), expected) {
+			t.Errorf("missing %q in:\n%s", expected, text.String())
+		}
+	}
+}
+
+func TestPDFExtrasString(t *testing.T) {
+	outline := []PDFOutlineItem{{Title: "One", Page: 1}, {Title: "Nested", Page: 3, Level: 1}, {Title: "External"}}
+	if pdfOutlineString(outline) != "------ Table of contents ------\n- One (page 1)\n  - Nested (page 3)\n- External\n\n" || pdfOutlineString(nil) != "" {
+		t.Errorf("unexpected outline string: %q", pdfOutlineString(outline))
+	}
+
+	page := PDFPage{
+		Text:        "Text\n",
+		Annotations: []PDFAnnotation{{Type: "Highlight", Text: "Important"}},
+		FormFields:  []PDFFormField{{Name: "Agree", Value: "Yes"}},
+	}
+	if page.String() != "Text\n--- Annotations ---\n[Highlight] Important\n--- Form fields ---\nAgree: Yes\n" {
+		t.Errorf("unexpected page string: %q", page.String())
+	}
+
+	meta := PDFMetadata{Title: "Report", ModificationDate: pdfDate(1700000000), CreationDate: pdfDate(-1), Pages: 3, XMP: "<x:xmpmeta/>\n"}
+	if meta.String() != "------ Metadata ------\nTitle: Report\nModified: 2023-11-14T22:13:20Z\nPages: 3\n\n------ XMP ------\n<x:xmpmeta/>\n\n" {
+		t.Errorf("unexpected metadata string: %q", meta.String())
+	}
+
+	if pdfAttachmentName(`C:\Users\jane\data.xlsx`, 0) != "data.xlsx" || pdfAttachmentName("", 2) != "attachment_2" {
+		t.Error("unexpected attachment name")
+	}
+}
+
+func TestPDFEncrypted(t *testing.T) {
+	result := NewPDFParser(NewCompositeParser(), 300, WithPDFTextLayer(1)).Parse(context.Background(), bytes.NewReader(testdata.PDFEncrypted), "secret.pdf")
+	if !errors.Is(result.Error(), ErrEncrypted) || errors.Is(result.Error(), ErrBadFile) {
+		t.Fatalf("expected encrypted error, got %v", result.Error())
+	}
+
+	pdfParser := NewPDFParser(NewCompositeParser(), 300, WithPDFTextLayer(1), WithPDFPasswords(NewPasswordList("wrong", "secret")))
+	result = pdfParser.Parse(context.Background(), bytes.NewReader(testdata.PDFEncrypted), "secret.pdf")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+	if !strings.Contains(result.String(), "Confidential report") {
+		t.Error(result.String())
+	}
+
+	stream := NewPDFParser(NewCompositeParser(), 300, WithPDFPasswords(NewPasswordList("wrong"))).ParseStream(context.Background(), bytes.NewReader(testdata.PDFEncrypted), "secret.pdf")
+	defer stream.Close()
+	var last StreamResult
+	for stream.Next(t.Context()) {
+		last = stream.Current()
+	}
+	if last == nil || !errors.Is(last.Error(), ErrEncrypted) {
+		t.Errorf("expected encrypted error from the stream, got %v", last)
+	}
+}
+
+func TestPDFMemoryLimits(t *testing.T) {
+	// Small in memory limit forces parser to read document from the temporary file
+	pdfParser := NewPDFParser(NewCompositeParser(), 300, WithPDFTextLayer(1), WithPDFInMemoryLimit(128), WithPDFMaxPages(1))
+	result := pdfParser.Parse(context.Background(), bytes.NewReader(testdata.PDFExtras), "report.pdf")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+
+	pdfResult := result.(*PDFParserResult)
+	if len(pdfResult.Pages) != 1 || !pdfResult.Truncated || pdfResult.Metadata.Pages != 2 {
+		t.Errorf("unexpected pages: %+v", pdfResult.Pages)
+	}
+	if !strings.Contains(result.String(), "First page text") || strings.Contains(result.String(), "Second page text") || !strings.Contains(result.String(), "------ Truncated: parsed 1 of 2 pages ------\n") {
+		t.Error(result.String())
+	}
+}
+
+func TestPDFRenderDPI(t *testing.T) {
+	// A4 page at 300 DPI has about 8.7 megapixels
+	if pdfRenderDPI(595, 842, 300, 40_000_000) != 300 || pdfRenderDPI(595, 842, 300, 0) != 300 {
+		t.Error("DPI must not be changed for the page that fits the limit")
+	}
+	dpi := pdfRenderDPI(595, 842, 300, 2_000_000)
+	width, height := 595*float64(dpi)/72, 842*float64(dpi)/72
+	if dpi >= 300 || width*height > 2_000_000 || width*height < 1_900_000 {
+		t.Errorf("unexpected DPI %d for the limited page", dpi)
+	}
+}
+
+func TestPDFPageSelection(t *testing.T) {
+	cases := []struct {
+		selection PDFPageSelection
+		maxPages  int
+		pages     []int
+		selected  int
+	}{
+		{PDFPageSelection{}, 0, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, 10},
+		{PDFPageSelection{First: 2, Last: 2}, 0, []int{0, 1, 8, 9}, 4},
+		{PDFPageSelection{Ranges: []PDFPageRange{{From: 3, To: 4}, {From: 9}}}, 0, []int{2, 3, 8, 9}, 4},
+		{PDFPageSelection{Ranges: []PDFPageRange{{From: 8, To: 20}}, First: 1}, 0, []int{0, 7, 8, 9}, 4},
+		{PDFPageSelection{Every: 3}, 0, []int{0, 3, 6, 9}, 4},
+		{PDFPageSelection{Last: 20, Every: 4}, 2, []int{0, 4}, 3},
+		{PDFPageSelection{}, 3, []int{0, 1, 2}, 10},
+	}
+	for _, c := range cases {
+		config := pdfConfig{selection: c.selection, maxPages: c.maxPages}
+		pages, selected := config.pagesToParse(10)
+		if !slices.Equal(pages, c.pages) || selected != c.selected {
+			t.Errorf("%+v: unexpected pages %v of %d", c.selection, pages, selected)
+		}
+	}
+
+	ranges, err := ParsePDFPageRanges("1-3, 7,10-")
+	if err != nil || !slices.Equal(ranges, []PDFPageRange{{From: 1, To: 3}, {From: 7, To: 7}, {From: 10}}) {
+		t.Errorf("unexpected ranges %v: %v", ranges, err)
+	}
+	for _, invalid := range []string{"0-2", "5-3", "a", "-4"} {
+		if _, err := ParsePDFPageRanges(invalid); err == nil {
+			t.Errorf("%q must be invalid", invalid)
+		}
+	}
+
+	selection := PDFPageSelection{Ranges: ranges, First: 5, Every: 10}
+	if pdfSelectionString(selection, 12, 2000) != "------ Selected pages: pages 1-3, 7, 10-, first 5, one of every 10 pages (12 of 2000 pages) ------\n\n" {
+		t.Error(pdfSelectionString(selection, 12, 2000))
+	}
+	if pdfSelectionString(PDFPageSelection{}, 10, 10) != "" {
+		t.Error("all pages must not be marked")
+	}
+}
+
+func TestPDFPageSelectionStream(t *testing.T) {
+	pdfParser := NewPDFParser(NewCompositeParser(), 300, WithPDFTextLayer(1), WithPDFPages(PDFPageSelection{Last: 1}))
+	stream := pdfParser.ParseStream(context.Background(), bytes.NewReader(testdata.PDFExtras), "report.pdf")
+	defer stream.Close()
+
+	var text strings.Builder
+	var pages []int
+	var selection *PDFPageSelection
+	for stream.Next(t.Context()) {
+		progress := stream.Current().(*PDFParserStreamResult)
+		if progress.Err != nil {
+			t.Fatal(progress.Err)
+		}
+		if progress.Selection != nil {
+			selection = progress.Selection
+		}
+		if progress.PageNumber != 0 {
+			pages = append(pages, progress.PageNumber)
+			if progress.CurrentProgress != 100 {
+				t.Errorf("progress must be computed against selected pages, got %d", progress.CurrentProgress)
+			}
+		}
+		text.WriteString(progress.String())
+	}
+
+	if selection == nil || selection.Last != 1 || !slices.Equal(pages, []int{2}) {
+		t.Errorf("unexpected selection %v or pages %v", selection, pages)
+	}
+	if !strings.Contains(text.String(), "------ Selected pages: last 1 (1 of 2 pages) ------") || strings.Contains(text.String(), "First page text") {
+		t.Error(text.String())
+	}
+}
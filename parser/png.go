@@ -3,6 +3,7 @@ package parser
 import (
 	"context"
 	"errors"
+	"image/png"
 	"io"
 
 	"github.com/opengs/file2llm/ocr"
@@ -19,24 +20,39 @@ func NewPNGParser(ocrProvider ocr.Provider) *PNGParser {
 	}
 }
 
+// Configuration that changes the result. Used in the cache keys.
+func (p *PNGParser) CacheFingerprint() string {
+	return "ocr=" + cacheFingerprint(p.ocrProvider)
+}
+
 func (p *PNGParser) SupportedMimeTypes() []string {
 	return []string{"image/png"}
 }
 
+func (p *PNGParser) prepareData(file io.Reader) (io.Reader, *ImageMetadata, error) {
+	return prepareImageWithMetadata(p.ocrProvider, "image/png", file, png.Decode)
+}
+
 func (p *PNGParser) Parse(ctx context.Context, file io.Reader, path string) Result {
-	text, err := p.ocrProvider.OCR(ctx, file)
+	imageData, metadata, err := p.prepareData(file)
+	if err != nil {
+		return &ImageParserResult{Err: errors.Join(errors.New("failed to prepare image data"), err), FullPath: path}
+	}
+
+	text, err := p.ocrProvider.OCR(ctx, imageData)
 	if err != nil {
 		return &ImageParserResult{Err: errors.Join(errors.New("errors while running OCR"), err), FullPath: path}
 	}
 
-	return &ImageParserResult{Text: text, FullPath: path}
+	return &ImageParserResult{Text: text, Metadata: metadata, FullPath: path}
 }
 
 func (p *PNGParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
 	return &ImageStreamResultIterator{
-		path:        path,
-		file:        file,
-		ocrProvider: p.ocrProvider,
-		baseContext: ctx,
+		path:             path,
+		file:             file,
+		imagePreparation: p.prepareData,
+		ocrProvider:      p.ocrProvider,
+		baseContext:      ctx,
 	}
 }
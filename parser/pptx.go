@@ -0,0 +1,545 @@
+package parser
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	pathlib "path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Parses `application/vnd.openxmlformats-officedocument.presentationml.presentation` files (.pptx)
+type PPTXParser struct {
+	innerParser Parser
+}
+
+func NewPPTXParser(innerParser Parser) *PPTXParser {
+	return &PPTXParser{
+		innerParser: innerParser,
+	}
+}
+
+func (p *PPTXParser) SupportedMimeTypes() []string {
+	return []string{"application/vnd.openxmlformats-officedocument.presentationml.presentation"}
+}
+
+func (p *PPTXParser) Parse(ctx context.Context, file io.Reader, path string) Result {
+	presentation, err := p.openPresentation(ctx, file, path)
+	if err != nil {
+		return &PPTXParserResult{Err: err, FullPath: path}
+	}
+
+	result := &PPTXParserResult{
+		FullPath: path,
+	}
+	for slideIndex := range presentation.slides {
+		slide, images, err := p.parseSlide(ctx, presentation, slideIndex, path)
+		result.Images = append(result.Images, images...)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		result.Slides = append(result.Slides, slide)
+	}
+
+	return result
+}
+
+func (p *PPTXParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
+	return &PPTXStreamResultIterator{
+		pptxParser: p,
+		file:       file,
+		path:       path,
+	}
+}
+
+type pptxPresentation struct {
+	pkg *ooxmlPackage
+	// Slide part names in presentation order
+	slides []string
+}
+
+func (p *PPTXParser) openPresentation(ctx context.Context, file io.Reader, path string) (*pptxPresentation, error) {
+	pkg, err := openOOXMLPackage(ctx, file, path)
+	if err != nil {
+		return nil, err
+	}
+
+	rootRels, err := pkg.relationships("")
+	if err != nil {
+		return nil, errors.Join(ErrBadFile, err)
+	}
+	mainPart := "ppt/presentation.xml"
+	if rel, ok := ooxmlRelationshipByType(rootRels, "/officeDocument"); ok {
+		mainPart = rel.Target
+	}
+
+	presentationRels, err := pkg.relationships(mainPart)
+	if err != nil {
+		return nil, errors.Join(ErrBadFile, err)
+	}
+
+	r, err := pkg.open(mainPart)
+	if err != nil {
+		return nil, errors.Join(ErrBadFile, err)
+	}
+	defer r.Close()
+
+	presentation := &pptxPresentation{pkg: pkg}
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Join(ErrBadFile, errors.New("failed to decode presentation part"), err)
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok || element.Name.Local != "sldId" {
+			continue
+		}
+		for _, attr := range element.Attr {
+			// Relationship ID is namespaced attribute, while plain `id` is the slide identifier
+			if attr.Name.Local == "id" && attr.Name.Space != "" {
+				if rel, ok := presentationRels[attr.Value]; ok {
+					presentation.slides = append(presentation.slides, rel.Target)
+				}
+			}
+		}
+	}
+
+	return presentation, nil
+}
+
+// Adds block to the slide body
+func (s *PPTXSlide) addBlock(block Block) {
+	block.Location = Location{Page: s.Number, Offset: -1}
+	block.Separator = "\n\n"
+	s.body = append(s.body, block)
+	s.Body = append(s.Body, block.String())
+}
+
+func (p *PPTXParser) parseSlide(ctx context.Context, presentation *pptxPresentation, slideIndex int, path string) (PPTXSlide, []Result, error) {
+	slidePart := presentation.slides[slideIndex]
+	slide := PPTXSlide{Number: slideIndex + 1}
+
+	shapes, err := p.readShapes(presentation.pkg, slidePart)
+	if err != nil {
+		return slide, nil, errors.Join(ErrBadFile, fmt.Errorf("failed to read slide %d", slide.Number), err)
+	}
+	rels, err := presentation.pkg.relationships(slidePart)
+	if err != nil {
+		return slide, nil, errors.Join(ErrBadFile, fmt.Errorf("failed to read relationships of slide %d", slide.Number), err)
+	}
+
+	var images []Result
+	for _, shape := range shapes {
+		switch {
+		case shape.placeholder == "title" || shape.placeholder == "ctrTitle":
+			slide.Title = strings.TrimSpace(strings.Join(append([]string{slide.Title}, shape.paragraphs...), " "))
+		case pptxBoilerplatePlaceholders[shape.placeholder]:
+			continue
+		case shape.imageRelID != "":
+			rel, ok := rels[shape.imageRelID]
+			if !ok || rel.TargetMode == "External" || p.innerParser == nil {
+				continue
+			}
+			imageResult := p.parseImage(ctx, presentation.pkg, rel.Target, pathlib.Join(path, rel.Target))
+			if imageResult.Error() != nil {
+				images = append(images, imageResult)
+				continue
+			}
+			if text := strings.TrimSpace(imageResult.String()); text != "" {
+				slide.addBlock(Block{Kind: BlockImage, Path: imageResult.Path(), Text: text})
+			}
+		case len(shape.table) != 0:
+			rows := make([]string, 0, len(shape.table))
+			for _, row := range shape.table {
+				rows = append(rows, strings.Join(row, " | "))
+			}
+			slide.addBlock(Block{Kind: BlockTable, Text: strings.Join(rows, "\n"), Rows: shape.table})
+		case len(shape.paragraphs) != 0:
+			slide.addBlock(Block{Kind: BlockParagraph, Text: strings.Join(shape.paragraphs, "\n")})
+		}
+	}
+
+	if notesRel, ok := ooxmlRelationshipByType(rels, "/notesSlide"); ok {
+		notesShapes, err := p.readShapes(presentation.pkg, notesRel.Target)
+		if err != nil {
+			return slide, images, errors.Join(ErrBadFile, fmt.Errorf("failed to read notes of slide %d", slide.Number), err)
+		}
+		var notes []string
+		for _, shape := range notesShapes {
+			if shape.placeholder == "body" {
+				notes = append(notes, shape.paragraphs...)
+			}
+		}
+		slide.Notes = strings.Join(notes, "\n")
+	}
+
+	return slide, images, nil
+}
+
+func (p *PPTXParser) parseImage(ctx context.Context, pkg *ooxmlPackage, partName string, path string) Result {
+	r, err := pkg.open(partName)
+	if err != nil {
+		return &CompositeParserResult{Err: errors.Join(ErrBadFile, err), FullPath: path}
+	}
+	defer r.Close()
+
+	return p.innerParser.Parse(ctx, r, path)
+}
+
+// Placeholders that repeat on every slide and doesnt carry slide content
+var pptxBoilerplatePlaceholders = map[string]bool{
+	"sldNum": true,
+	"dt":     true,
+	"ftr":    true,
+	"hdr":    true,
+	"sldImg": true,
+}
+
+type pptxShape struct {
+	placeholder string
+	hasPosition bool
+	x, y        int64
+	paragraphs  []string
+	table       [][]string
+	imageRelID  string
+}
+
+// Reads shapes (text frames, tables and pictures) of the slide or notes part in reading order
+func (p *PPTXParser) readShapes(pkg *ooxmlPackage, partName string) ([]*pptxShape, error) {
+	r, err := pkg.open(partName)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var shapes []*pptxShape
+	var shapeStack []*pptxShape
+	var paragraph *strings.Builder
+	var inText bool
+	var row []string
+	var cell []string
+	var inCell bool
+
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		var current *pptxShape
+		if len(shapeStack) != 0 {
+			current = shapeStack[len(shapeStack)-1]
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "sp", "pic", "graphicFrame":
+				shapeStack = append(shapeStack, &pptxShape{})
+			case "ph":
+				if current != nil {
+					current.placeholder = xmlAttr(element, "type")
+					if current.placeholder == "" {
+						current.placeholder = "body"
+					}
+				}
+			case "off":
+				if current != nil && !current.hasPosition {
+					current.x, _ = strconv.ParseInt(xmlAttr(element, "x"), 10, 64)
+					current.y, _ = strconv.ParseInt(xmlAttr(element, "y"), 10, 64)
+					current.hasPosition = true
+				}
+			case "blip":
+				if current != nil {
+					current.imageRelID = xmlAttr(element, "embed")
+				}
+			case "tr":
+				row = nil
+			case "tc":
+				inCell = true
+				cell = nil
+			case "p":
+				paragraph = &strings.Builder{}
+			case "t":
+				inText = true
+			case "br":
+				if paragraph != nil {
+					paragraph.WriteString("\n")
+				}
+			}
+		case xml.CharData:
+			if inText && paragraph != nil {
+				paragraph.Write(element)
+			}
+		case xml.EndElement:
+			switch element.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if paragraph == nil {
+					continue
+				}
+				text := strings.TrimSpace(paragraph.String())
+				paragraph = nil
+				if text == "" {
+					continue
+				}
+				if inCell {
+					cell = append(cell, text)
+				} else if current != nil {
+					current.paragraphs = append(current.paragraphs, text)
+				}
+			case "tc":
+				inCell = false
+				row = append(row, strings.Join(cell, " "))
+			case "tr":
+				if current != nil {
+					current.table = append(current.table, row)
+				}
+			case "sp", "pic", "graphicFrame":
+				if current != nil {
+					shapeStack = shapeStack[:len(shapeStack)-1]
+					shapes = append(shapes, current)
+				}
+			}
+		}
+	}
+
+	// Placeholders without explicit position inherit it from the layout. In that case document order is the best guess for reading order.
+	allPositioned := true
+	for _, shape := range shapes {
+		allPositioned = allPositioned && shape.hasPosition
+	}
+	if allPositioned {
+		sort.SliceStable(shapes, func(i, j int) bool {
+			if shapes[i].y != shapes[j].y {
+				return shapes[i].y < shapes[j].y
+			}
+			return shapes[i].x < shapes[j].x
+		})
+	}
+
+	return shapes, nil
+}
+
+type PPTXStreamResultIterator struct {
+	pptxParser *PPTXParser
+	file       io.Reader
+	path       string
+
+	started           bool
+	completed         bool
+	presentation      *pptxPresentation
+	currentSlideIndex int
+
+	current StreamResult
+}
+
+func (i *PPTXStreamResultIterator) Next(ctx context.Context) bool {
+	if i.completed {
+		i.current = nil
+		return false
+	}
+
+	if !i.started {
+		i.started = true
+		i.current = &PPTXParserStreamResult{
+			FullPath:     i.path,
+			CurrentStage: ProgressNew,
+		}
+		return true
+	}
+
+	if ctx.Err() != nil {
+		i.current = nil
+		return false
+	}
+
+	if i.presentation == nil {
+		presentation, err := i.pptxParser.openPresentation(ctx, i.file, i.path)
+		if err != nil {
+			i.completed = true
+			i.current = &PPTXParserStreamResult{
+				FullPath:     i.path,
+				CurrentStage: ProgressCompleted,
+				Err:          err,
+			}
+			return true
+		}
+		i.presentation = presentation
+	}
+
+	if i.currentSlideIndex >= len(i.presentation.slides) {
+		i.completed = true
+		i.current = &PPTXParserStreamResult{
+			FullPath:        i.path,
+			CurrentStage:    ProgressCompleted,
+			CurrentProgress: 100,
+		}
+		return true
+	}
+
+	slide, images, err := i.pptxParser.parseSlide(ctx, i.presentation, i.currentSlideIndex, i.path)
+	i.currentSlideIndex += 1
+	// Only images that failed are returned. They are reported as the error of the slide.
+	imageErrors := make([]error, 0, len(images))
+	for _, image := range images {
+		imageErrors = append(imageErrors, fmt.Errorf("failed to parse image %s: %w", image.Path(), image.Error()))
+	}
+	if err != nil {
+		i.completed = true
+		i.current = &PPTXParserStreamResult{
+			FullPath:     i.path,
+			CurrentStage: ProgressCompleted,
+			Err:          errors.Join(append(imageErrors, err)...),
+		}
+		return true
+	}
+
+	i.current = &PPTXParserStreamResult{
+		FullPath:        i.path,
+		CurrentStage:    ProgressUpdate,
+		CurrentProgress: uint8(float64(i.currentSlideIndex) / float64(len(i.presentation.slides)) * 100),
+		Slide:           &slide,
+		Err:             errors.Join(imageErrors...),
+	}
+	return true
+}
+
+func (i *PPTXStreamResultIterator) Current() StreamResult {
+	return i.current
+}
+
+func (i *PPTXStreamResultIterator) Close() {
+	i.presentation = nil
+}
+
+type PPTXSlide struct {
+	// Slide number starting from 1
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	// Text frames, tables and recognized images in reading order
+	Body []string `json:"body"`
+	// Speaker notes
+	Notes string `json:"notes"`
+
+	// Blocks of the body with their types
+	body []Block
+}
+
+func (s *PPTXSlide) String() string {
+	var result strings.Builder
+
+	result.WriteString(blocksString(s.blocks()))
+	if s.Notes != "" {
+		result.WriteString("--- Notes ---\n")
+		result.WriteString(s.Notes)
+		result.WriteString("\n\n")
+	}
+
+	return result.String()
+}
+
+// Slide label, title and body of the slide. Speaker notes are not part of the slide content.
+func (s *PPTXSlide) blocks() []Block {
+	location := Location{Page: s.Number, Offset: -1}
+	blocks := []Block{{Kind: BlockPageBreak, Text: fmt.Sprintf("Slide %d", s.Number), Location: location, Separator: "\n"}}
+	if s.Title != "" {
+		blocks = append(blocks, Block{Kind: BlockHeading, Level: 1, Text: s.Title, Location: location, Separator: "\n\n"})
+	}
+	if len(s.body) == len(s.Body) {
+		return append(blocks, s.body...)
+	}
+
+	// Slide was not created by the parser, so types of the body blocks are unknown
+	for _, text := range s.Body {
+		blocks = append(blocks, Block{Kind: BlockParagraph, Text: text, Location: location, Separator: "\n\n"})
+	}
+	return blocks
+}
+
+type PPTXParserResult struct {
+	FullPath string      `json:"path"`
+	Slides   []PPTXSlide `json:"slides"`
+	// Embedded images that failed to parse
+	Images []Result `json:"images"`
+	Err    error    `json:"error"`
+}
+
+func (r *PPTXParserResult) Path() string {
+	return r.FullPath
+}
+
+func (r *PPTXParserResult) String() string {
+	var result strings.Builder
+
+	for _, slide := range r.Slides {
+		result.WriteString(slide.String())
+	}
+
+	return result.String()
+}
+
+// Slides of the presentation. Every slide begins with page break.
+func (r *PPTXParserResult) Document() *Document {
+	document := &Document{}
+	for i := range r.Slides {
+		document.Blocks = append(document.Blocks, r.Slides[i].blocks()...)
+	}
+	return document
+}
+
+func (r *PPTXParserResult) Error() error {
+	return r.Err
+}
+
+func (r *PPTXParserResult) Subfiles() []Result {
+	return r.Images
+}
+
+type PPTXParserStreamResult struct {
+	FullPath        string             `json:"path"`
+	CurrentStage    ParseProgressStage `json:"stage"`
+	CurrentProgress uint8              `json:"progress"`
+	Slide           *PPTXSlide         `json:"slide"`
+	Err             error              `json:"error"`
+}
+
+func (r *PPTXParserStreamResult) Path() string {
+	return r.FullPath
+}
+
+func (r *PPTXParserStreamResult) Stage() ParseProgressStage {
+	return r.CurrentStage
+}
+
+func (r *PPTXParserStreamResult) Progress() uint8 {
+	return r.CurrentProgress
+}
+
+func (r *PPTXParserStreamResult) SubResult() StreamResult {
+	return nil
+}
+
+func (r *PPTXParserStreamResult) String() string {
+	if r.Slide != nil {
+		return r.Slide.String()
+	}
+	return ""
+}
+
+func (r *PPTXParserStreamResult) Error() error {
+	return r.Err
+}
@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	testdata "github.com/opengs/file2llm/test_data"
+)
+
+func TestPPTX(t *testing.T) {
+	pptxParser := NewPPTXParser(NewCompositeParser())
+	result := pptxParser.Parse(context.Background(), bytes.NewReader(testdata.PPTX), "deck.pptx")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+
+	pptxResult := result.(*PPTXParserResult)
+	if len(pptxResult.Slides) != 2 {
+		t.Fatalf("expected 2 slides, got %d", len(pptxResult.Slides))
+	}
+
+	first := pptxResult.Slides[0]
+	if first.Title != "Quarterly Review" || first.Notes != "Mention the new office opening" {
+		t.Errorf("unexpected first slide: %+v", first)
+	}
+	if len(first.Body) != 2 || first.Body[0] != "Revenue grew in every region\nCosts stayed flat" || first.Body[1] != "Second paragraph below" {
+		t.Errorf("unexpected reading order of the first slide: %q", first.Body)
+	}
+
+	second := pptxResult.Slides[1]
+	if second.Title != "Regional Numbers" || len(second.Body) == 0 || second.Body[0] != "Region | Revenue\nNorth | 120" {
+		t.Errorf("unexpected second slide: %+v", second)
+	}
+
+	// Without OCR image can not be parsed and is reported as subfile
+	if len(result.Subfiles()) != 1 || result.Subfiles()[0].Path() != "deck.pptx/ppt/media/image1.png" {
+		t.Errorf("unexpected subfiles: %v", result.Subfiles())
+	}
+
+	resultString := result.String()
+	if !strings.Contains(resultString, "------ Slide 1 ------") || strings.Index(resultString, "Quarterly Review") > strings.Index(resultString, "Regional Numbers") {
+		t.Error(resultString)
+	}
+}
+
+func TestPPTXStream(t *testing.T) {
+	pptxParser := NewPPTXParser(NewCompositeParser())
+
+	hasNewStage := false
+	hasCompletedStage := false
+	var lastResult StreamResult
+
+	var resultString string
+	var slideUpdates int
+	var imageErrors []error
+	parseProgress := pptxParser.ParseStream(context.Background(), bytes.NewReader(testdata.PPTX), "")
+	defer parseProgress.Close()
+	for parseProgress.Next(t.Context()) {
+		progress := parseProgress.Current()
+		resultString += progress.String()
+		hasNewStage = hasNewStage || (progress.Stage() == ProgressNew)
+		hasCompletedStage = hasCompletedStage || (progress.Stage() == ProgressCompleted)
+		if progress.Stage() == ProgressUpdate {
+			slideUpdates += 1
+			if progress.Error() != nil {
+				imageErrors = append(imageErrors, progress.Error())
+			}
+		}
+		lastResult = progress
+	}
+	if !hasNewStage || !hasCompletedStage || slideUpdates != 2 {
+		t.Fail()
+	}
+	if lastResult.Error() != nil {
+		t.Fatal(lastResult.Error())
+	}
+	// Without OCR image can not be parsed and is reported with its slide
+	if len(imageErrors) != 1 || !strings.Contains(imageErrors[0].Error(), "ppt/media/image1.png") {
+		t.Errorf("expected error of the image, got %v", imageErrors)
+	}
+	if lastResult.Progress() != 100 {
+		t.Errorf("expected progress 100, got %d", lastResult.Progress())
+	}
+
+	if !strings.Contains(resultString, "Quarterly Review") || !strings.Contains(resultString, "Mention the new office opening") || !strings.Contains(resultString, "North | 120") {
+		t.Error(resultString)
+	}
+}
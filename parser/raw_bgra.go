@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"image/png"
 	"io"
 
@@ -24,32 +25,37 @@ func NewRAWBGRAParser(ocrProvider ocr.Provider) *RAWBGRAParser {
 	}
 }
 
+// Configuration that changes the result. Used in the cache keys.
+func (p *RAWBGRAParser) CacheFingerprint() string {
+	return fmt.Sprintf("ocr=%s png=%t", cacheFingerprint(p.ocrProvider), p.convertToPNG)
+}
+
 func (p *RAWBGRAParser) SupportedMimeTypes() []string {
 	return []string{"image/file2llm-raw-bgra"}
 }
 
-func (p *RAWBGRAParser) prepareData(file io.Reader) (io.Reader, error) {
+func (p *RAWBGRAParser) prepareData(file io.Reader) (io.Reader, *ImageMetadata, error) {
 	if p.convertToPNG {
 		img, err := bgra.ReadRAWBGRAImageFromReader(file)
 		if err != nil {
-			return nil, errors.Join(errors.New("failed to read raw BGRA image"), err)
+			return nil, nil, errors.Join(errors.New("failed to read raw BGRA image"), err)
 		}
 
 		rgbaIMG := img.ConvertBGRAtoRGBAInplace()
 
 		var outPNGImgBuf bytes.Buffer
 		if err := png.Encode(&outPNGImgBuf, rgbaIMG); err != nil {
-			return nil, errors.Join(errors.New("failed to convert image to PNG"), err)
+			return nil, nil, errors.Join(errors.New("failed to convert image to PNG"), err)
 		}
 
-		return &outPNGImgBuf, nil
+		return &outPNGImgBuf, nil, nil
 	}
 
-	return file, nil
+	return file, nil, nil
 }
 
 func (p *RAWBGRAParser) Parse(ctx context.Context, file io.Reader, path string) Result {
-	imageData, err := p.prepareData(file)
+	imageData, _, err := p.prepareData(file)
 	if err != nil {
 		return &ImageParserResult{Err: errors.Join(errors.New("failed to prepare image data"), err), FullPath: path}
 	}
@@ -0,0 +1,324 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Writes [Result] with all its subfiles in some output format
+type Renderer interface {
+	Render(w io.Writer, result Result) error
+}
+
+// Renders result to string
+func RenderString(renderer Renderer, result Result) (string, error) {
+	var builder strings.Builder
+	if err := renderer.Render(&builder, result); err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
+
+// Returns result created by the format parser and mime type of the file. Composite results are unwrapped.
+func unwrapResult(result Result) (Result, string) {
+	var mimeType string
+	for {
+		composite, ok := result.(*CompositeParserResult)
+		if !ok || composite.Inner == nil {
+			return result, mimeType
+		}
+		if mimeType == "" {
+			mimeType = composite.MimeType
+		}
+		result = composite.Inner
+	}
+}
+
+// Document of the file without subfiles. Nil if result has no known structure.
+func resultDocument(result Result) *Document {
+	if documentResult, ok := result.(DocumentResult); ok {
+		return documentResult.Document()
+	}
+	return nil
+}
+
+// Text of the file without subfiles. Containers without document have no own text.
+func resultText(result Result) string {
+	if document := resultDocument(result); document != nil {
+		return document.String()
+	}
+	if len(result.Subfiles()) == 0 {
+		return result.String()
+	}
+	return ""
+}
+
+func resultMetadata(result Result) []MetadataField {
+	if metadataResult, ok := result.(MetadataResult); ok {
+		return metadataResult.MetadataFields()
+	}
+	return nil
+}
+
+// Renders result with `String()`. Output is the same as before renderers were added.
+type LegacyRenderer struct{}
+
+func NewLegacyRenderer() *LegacyRenderer {
+	return &LegacyRenderer{}
+}
+
+func (r *LegacyRenderer) Render(w io.Writer, result Result) error {
+	_, err := io.WriteString(w, result.String())
+	return err
+}
+
+// Renders every file as a section with heading. Nested files get deeper headings, plain text and source code are fenced.
+type MarkdownRenderer struct{}
+
+func NewMarkdownRenderer() *MarkdownRenderer {
+	return &MarkdownRenderer{}
+}
+
+func (r *MarkdownRenderer) Render(w io.Writer, result Result) error {
+	var builder strings.Builder
+	markdownFile(&builder, result, 1)
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+// Markdown headings have at most 6 levels
+func markdownHeadingMarker(level int) string {
+	return strings.Repeat("#", min(max(level, 1), 6))
+}
+
+func markdownFile(builder *strings.Builder, result Result, level int) {
+	inner, mimeType := unwrapResult(result)
+
+	builder.WriteString(markdownHeadingMarker(level) + " " + result.Path() + "\n\n")
+
+	var metadata []MetadataField
+	if mimeType != "" {
+		metadata = append(metadata, MetadataField{Name: "MIME type", Value: mimeType})
+	}
+	metadata = append(metadata, resultMetadata(inner)...)
+	for _, field := range metadata {
+		builder.WriteString("- **" + field.Name + ":** " + strings.ReplaceAll(field.Value, "\n", " ") + "\n")
+	}
+	if len(metadata) != 0 {
+		builder.WriteString("\n")
+	}
+
+	if err := result.Error(); err != nil {
+		builder.WriteString("> **Error:** " + strings.ReplaceAll(err.Error(), "\n", "\n> ") + "\n\n")
+	}
+
+	if text, ok := inner.(*TextParserResult); ok {
+		if text.Text != "" {
+			builder.WriteString(markdownFence(text.Text, markdownLanguage(mimeType)))
+		}
+	} else if document := resultDocument(inner); document != nil {
+		markdownBlocksString(builder, document.Blocks, level)
+	} else if text := resultText(inner); strings.TrimSpace(text) != "" {
+		builder.WriteString(markdownFence(text, ""))
+	}
+
+	for _, subfile := range inner.Subfiles() {
+		markdownFile(builder, subfile, level+1)
+	}
+}
+
+// Headings of the document are placed below the heading of the file
+func markdownBlocksString(builder *strings.Builder, blocks []Block, level int) {
+	for i := range blocks {
+		block := &blocks[i]
+		var text string
+		switch block.Kind {
+		case BlockHeading:
+			text = markdownHeadingMarker(level+block.Level) + " " + block.Text
+		case BlockPageBreak:
+			label := block.Text
+			if label == "" && block.Location.Page != 0 {
+				label = fmt.Sprintf("Page %d", block.Location.Page)
+			}
+			if label == "" {
+				text = "---"
+			} else {
+				text = markdownHeadingMarker(level+1) + " " + label
+			}
+		case BlockTable:
+			text = markdownTable(block)
+		case BlockImage:
+			text = strings.TrimSpace(block.Text)
+			if block.Path != "" {
+				text = strings.TrimSpace("**Image:** `" + block.Path + "`\n\n" + text)
+			}
+		default:
+			text = strings.TrimSpace(block.String())
+		}
+
+		if text != "" {
+			builder.WriteString(text + "\n\n")
+		}
+	}
+}
+
+func markdownTable(block *Block) string {
+	if len(block.Rows) == 0 {
+		return markdownFence(block.Text, "")
+	}
+
+	columns := 0
+	for _, row := range block.Rows {
+		columns = max(columns, len(row))
+	}
+
+	var result strings.Builder
+	for i, row := range block.Rows {
+		result.WriteString("|")
+		for column := range columns {
+			cell := ""
+			if column < len(row) {
+				cell = strings.ReplaceAll(strings.ReplaceAll(row[column], "|", "\\|"), "\n", "<br>")
+			}
+			result.WriteString(" " + cell + " |")
+		}
+		result.WriteString("\n")
+		if i == 0 {
+			result.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+		}
+	}
+	return strings.TrimSuffix(result.String(), "\n")
+}
+
+// Code block that is longer than any backtick sequence in the text
+func markdownFence(text string, language string) string {
+	longest, current := 0, 0
+	for _, c := range text {
+		if c == '`' {
+			current += 1
+			longest = max(longest, current)
+		} else {
+			current = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	return fence + language + "\n" + strings.TrimSuffix(text, "\n") + "\n" + fence + "\n\n"
+}
+
+// Language hints of the fenced blocks for the mime types supported by [TextParser]
+var markdownLanguages = map[string]string{
+	"text/markdown":        "markdown",
+	"text/x-markdown":      "markdown",
+	"text/x-python":        "python",
+	"text/x-php":           "php",
+	"text/javascript":      "javascript",
+	"text/x-lua":           "lua",
+	"text/x-perl":          "perl",
+	"text/x-tcl":           "tcl",
+	"text/x-shellscript":   "sh",
+	"text/x-c":             "c",
+	"text/x-c++":           "cpp",
+	"text/x-csharp":        "csharp",
+	"text/x-go":            "go",
+	"text/x-java":          "java",
+	"text/x-rust":          "rust",
+	"text/x-ruby":          "ruby",
+	"text/x-sql":           "sql",
+	"text/x-kotlin":        "kotlin",
+	"text/x-swift":         "swift",
+	"text/x-typescript":    "typescript",
+	"text/css":             "css",
+	"text/x-yaml":          "yaml",
+	"text/xml":             "xml",
+	"application/json":     "json",
+	"application/x-ndjson": "json",
+	"application/yaml":     "yaml",
+	"application/toml":     "toml",
+	"application/sql":      "sql",
+}
+
+func markdownLanguage(mimeType string) string {
+	return markdownLanguages[mimeBaseType(mimeType)]
+}
+
+type jsonRendererConfig struct {
+	indent string
+	blocks bool
+}
+
+// Configures [JSONRenderer]
+type JSONOption func(c *jsonRendererConfig)
+
+// Indents nested JSON values. Output is compact by default.
+func WithJSONIndent(indent string) JSONOption {
+	return func(c *jsonRendererConfig) {
+		c.indent = indent
+	}
+}
+
+// Adds blocks of the document to every file. Disabled by default.
+func WithJSONBlocks(include bool) JSONOption {
+	return func(c *jsonRendererConfig) {
+		c.blocks = include
+	}
+}
+
+// Renders result as a single JSON object of type [RenderedFile]
+type JSONRenderer struct {
+	config jsonRendererConfig
+}
+
+func NewJSONRenderer(options ...JSONOption) *JSONRenderer {
+	renderer := &JSONRenderer{}
+
+	for _, option := range options {
+		option(&renderer.config)
+	}
+
+	return renderer
+}
+
+// File in the output of the [JSONRenderer]
+type RenderedFile struct {
+	Path string `json:"path"`
+	// Empty if file was not parsed by [CompositeParser]
+	MimeType string          `json:"mimeType,omitempty"`
+	Metadata []MetadataField `json:"metadata,omitempty"`
+	// Text of the file without subfiles
+	Text     string         `json:"text"`
+	Error    string         `json:"error,omitempty"`
+	Blocks   []Block        `json:"blocks,omitempty"`
+	Subfiles []RenderedFile `json:"subfiles,omitempty"`
+}
+
+func (r *JSONRenderer) Render(w io.Writer, result Result) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", r.config.indent)
+	return encoder.Encode(r.file(result))
+}
+
+func (r *JSONRenderer) file(result Result) RenderedFile {
+	inner, mimeType := unwrapResult(result)
+
+	file := RenderedFile{
+		Path:     result.Path(),
+		MimeType: mimeType,
+		Metadata: resultMetadata(inner),
+		Text:     resultText(inner),
+	}
+	if err := result.Error(); err != nil {
+		file.Error = err.Error()
+	}
+	if r.config.blocks {
+		if document := resultDocument(inner); document != nil {
+			file.Blocks = document.Blocks
+		}
+	}
+	for _, subfile := range inner.Subfiles() {
+		file.Subfiles = append(file.Subfiles, r.file(subfile))
+	}
+
+	return file
+}
@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	testdata "github.com/opengs/file2llm/test_data"
+)
+
+const renderTestPage = `<html><head><title>Report</title></head><body>
+<h1>Plan</h1><p>Intro</p>
+<table><tr><th>Name</th><th>Value</th></tr><tr><td>a|b</td><td>1</td></tr></table>
+</body></html>`
+
+const renderTestSource = "package main\n\n// Prints ```quoted``` text\nfunc main() {}\n"
+
+func renderTestResult(t *testing.T) Result {
+	data := buildTar(t,
+		tarTestFile{name: "docs/page.html", data: []byte(renderTestPage)},
+		tarTestFile{name: "main.go", data: []byte(renderTestSource)},
+		tarTestFile{name: "blob.bin", data: []byte{0x00, 0x01, 0x02, 0xff, 0xfe}},
+	)
+
+	composite := NewCompositeParser(NewTextParser())
+	composite.AddParsers(NewTARParser(composite), NewHTMLParser(composite))
+	return composite.Parse(context.Background(), bytes.NewReader(data), "archive.tar")
+}
+
+func TestRenderLegacy(t *testing.T) {
+	result := renderTestResult(t)
+	text, err := RenderString(NewLegacyRenderer(), result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != result.String() {
+		t.Errorf("legacy renderer must return String() of the result")
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	text, err := RenderString(NewMarkdownRenderer(), renderTestResult(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"# archive.tar\n\n- **MIME type:** application/x-tar\n",
+		"## archive.tar/docs/page.html\n\n- **MIME type:** text/html; charset=utf-8\n- **Title:** Report\n\n",
+		"### Plan\n\nIntro\n\n| Name | Value |\n| --- | --- |\n| a\\|b | 1 |\n\n",
+		"## archive.tar/main.go\n\n- **MIME type:** text/x-go\n\n````go\n" + renderTestSource + "````\n\n",
+		"## archive.tar/blob.bin\n\n",
+		"> **Error:** mime type of the file is not supported",
+	}
+	for _, part := range expected {
+		if !strings.Contains(text, part) {
+			t.Errorf("expected %q in the output:\n%s", part, text)
+		}
+	}
+	if strings.Contains(text, "------ File") {
+		t.Errorf("markdown must not contain legacy markers:\n%s", text)
+	}
+
+	email, err := RenderString(NewMarkdownRenderer(), New(nil).Parse(context.Background(), bytes.NewReader(testdata.EML), "mail.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(email, "# mail.eml\n\n- **MIME type:** message/rfc822\n- **") || strings.Contains(email, "----- Headers -----") {
+		t.Errorf("unexpected email output:\n%s", email)
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	var output bytes.Buffer
+	if err := NewJSONRenderer(WithJSONBlocks(true), WithJSONIndent("  ")).Render(&output, renderTestResult(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	var file RenderedFile
+	if err := json.Unmarshal(output.Bytes(), &file); err != nil {
+		t.Fatal(err)
+	}
+	if file.Path != "archive.tar" || file.MimeType != "application/x-tar" || file.Text != "" || file.Error != "" || len(file.Subfiles) != 3 {
+		t.Fatalf("unexpected archive: %+v", file)
+	}
+
+	page := file.Subfiles[0]
+	if page.Path != "archive.tar/docs/page.html" || len(page.Metadata) != 1 || page.Metadata[0] != (MetadataField{Name: "Title", Value: "Report"}) {
+		t.Errorf("unexpected page: %+v", page)
+	}
+	if !strings.Contains(page.Text, "# Plan") || len(page.Blocks) != 3 || page.Blocks[2].Kind != BlockTable {
+		t.Errorf("unexpected page content: %+v", page)
+	}
+
+	source := file.Subfiles[1]
+	if source.MimeType != "text/x-go" || source.Text != renderTestSource {
+		t.Errorf("unexpected source file: %+v", source)
+	}
+	if file.Subfiles[2].Error == "" || file.Subfiles[2].Text != "" {
+		t.Errorf("unsupported file must have error: %+v", file.Subfiles[2])
+	}
+
+	output.Reset()
+	if err := NewJSONRenderer().Render(&output, renderTestResult(t)); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(output.String(), `"blocks"`) || strings.Count(output.String(), "\n") != 1 {
+		t.Errorf("blocks and indentation must be disabled by default: %s", output.String())
+	}
+}
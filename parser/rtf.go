@@ -0,0 +1,874 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	pathlib "path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Parses `application/rtf` files. Embedded pictures and objects are parsed with inner parser. Pass nil inner parser to ignore them.
+type RTFParser struct {
+	innerParser Parser
+}
+
+func NewRTFParser(innerParser Parser) *RTFParser {
+	return &RTFParser{
+		innerParser: innerParser,
+	}
+}
+
+func (p *RTFParser) SupportedMimeTypes() []string {
+	return []string{"application/rtf", "text/rtf"}
+}
+
+func (p *RTFParser) Parse(ctx context.Context, file io.Reader, path string) Result {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return &RTFParserResult{Err: errors.Join(errors.New("failed to read data to the bytes buffer"), err), FullPath: path}
+	}
+
+	doc, err := parseRTF(data)
+	if err != nil {
+		return &RTFParserResult{Err: err, FullPath: path}
+	}
+
+	result := &RTFParserResult{
+		FullPath: path,
+		Metadata: doc.metadata,
+		Blocks:   doc.blocks(),
+	}
+	result.Text = blocksString(result.Blocks)
+
+	if p.innerParser != nil {
+		for index, embedded := range doc.embedded {
+			if err := checkEntryLimit(ctx, index+1, path); err != nil {
+				result.Err = err
+				break
+			}
+			name, data := embedded.payload(index)
+			if len(data) == 0 {
+				continue
+			}
+			result.Embedded = append(result.Embedded, p.innerParser.Parse(ctx, bytes.NewReader(data), pathlib.Join(path, name)))
+		}
+	}
+
+	return result
+}
+
+func (p *RTFParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
+	return &RTFStreamResultIterator{
+		rtfParser: p,
+		ctx:       ctx,
+		file:      file,
+		path:      path,
+	}
+}
+
+type RTFStreamResultIterator struct {
+	rtfParser *RTFParser
+	ctx       context.Context
+	file      io.Reader
+	path      string
+
+	started   bool
+	completed bool
+	result    *RTFParserResult
+	current   StreamResult
+}
+
+func (i *RTFStreamResultIterator) Next(ctx context.Context) bool {
+	if i.completed {
+		i.current = nil
+		return false
+	}
+
+	if !i.started {
+		i.started = true
+		i.current = &RTFParserStreamResult{
+			FullPath:     i.path,
+			CurrentStage: ProgressNew,
+		}
+		return true
+	}
+
+	if i.result == nil {
+		i.result = i.rtfParser.Parse(i.ctx, i.file, i.path).(*RTFParserResult)
+		if i.result.Err == nil {
+			i.current = &RTFParserStreamResult{
+				FullPath:        i.path,
+				CurrentStage:    ProgressUpdate,
+				CurrentProgress: 100,
+				Text:            i.result.String(),
+			}
+			return true
+		}
+	}
+
+	i.completed = true
+	i.current = &RTFParserStreamResult{
+		FullPath:        i.path,
+		CurrentStage:    ProgressCompleted,
+		CurrentProgress: 100,
+		Err:             i.result.Err,
+	}
+	return true
+}
+
+func (i *RTFStreamResultIterator) Current() StreamResult {
+	return i.current
+}
+
+func (i *RTFStreamResultIterator) Close() {
+}
+
+// Document information fields in the order they are printed
+var rtfInfoFields = []struct {
+	control string
+	name    string
+}{
+	{"title", "Title"},
+	{"subject", "Subject"},
+	{"author", "Author"},
+	{"manager", "Manager"},
+	{"company", "Company"},
+	{"operator", "Operator"},
+	{"category", "Category"},
+	{"keywords", "Keywords"},
+	{"comment", "Comment"},
+	{"doccomm", "Comments"},
+	{"creatim", "Created"},
+	{"revtim", "Modified"},
+}
+
+// Destinations that doesnt contain visible text
+var rtfSkippedDestinations = map[string]bool{
+	"colortbl": true, "stylesheet": true, "listtable": true, "listoverridetable": true, "revtbl": true,
+	"rsidtbl": true, "generator": true, "xmlnstbl": true, "themedata": true, "colorschememapping": true,
+	"datastore": true, "latentstyles": true, "pgdsctbl": true, "filetbl": true, "fldinst": true,
+	"bkmkstart": true, "bkmkend": true, "nonshppict": true, "private": true, "pntxta": true, "pntxtb": true,
+	"header": true, "headerl": true, "headerr": true, "headerf": true,
+	"footer": true, "footerl": true, "footerr": true, "footerf": true,
+	"xe": true, "tc": true, "template": true, "userprops": true, "docvar": true, "printim": true,
+	"buptim": true, "objclass": true, "objname": true, "mmathPr": true, "wgrffmtfilter": true,
+}
+
+// Maps `\fcharsetN` values to the code pages
+var rtfCharsetCodepages = map[int]int{
+	0: 1252, 77: 10000, 128: 932, 129: 949, 134: 936, 136: 950, 161: 1253, 162: 1254,
+	163: 1258, 177: 1255, 178: 1256, 186: 1257, 204: 1251, 222: 874, 238: 1250,
+}
+
+type rtfEmbedded struct {
+	// `pict` or `object`
+	kind      string
+	extension string
+	hexData   []byte
+}
+
+// Returns subfile name and payload of the embedded picture or object
+func (e *rtfEmbedded) payload(index int) (string, []byte) {
+	data := make([]byte, hex.DecodedLen(len(e.hexData)))
+	n, _ := hex.Decode(data, e.hexData)
+	data = data[:n]
+
+	if e.kind == "pict" {
+		return fmt.Sprintf("pict_%d%s", index, e.extension), data
+	}
+
+	name, native := parseOLE1Object(data)
+	if name == "" {
+		name = fmt.Sprintf("object_%d", index)
+	}
+	return name, native
+}
+
+// Extracts native data from the OLE 1.0 embedded object. For packages returns original file name and file content.
+func parseOLE1Object(data []byte) (string, []byte) {
+	if len(data) < 8 {
+		return "", nil
+	}
+	data = data[8:] // OLE version and format ID
+
+	readString := func() (string, bool) {
+		if len(data) < 4 {
+			return "", false
+		}
+		length := int(binary.LittleEndian.Uint32(data))
+		if length > len(data)-4 {
+			return "", false
+		}
+		value := strings.TrimRight(string(data[4:4+length]), "\x00")
+		data = data[4+length:]
+		return value, true
+	}
+
+	className, ok := readString()
+	if !ok {
+		return "", nil
+	}
+	for range 2 { // topic and item names
+		if _, ok := readString(); !ok {
+			return "", nil
+		}
+	}
+	if len(data) < 4 {
+		return "", nil
+	}
+	size := int(binary.LittleEndian.Uint32(data))
+	if size > len(data)-4 {
+		return "", nil
+	}
+	native := data[4 : 4+size]
+
+	if className != "Package" {
+		return "", native
+	}
+
+	// Package: signature, label, original path, reserved, temporary path, content
+	if len(native) < 2 {
+		return "", native
+	}
+	rest := native[2:]
+	readCString := func() (string, bool) {
+		end := bytes.IndexByte(rest, 0)
+		if end < 0 {
+			return "", false
+		}
+		value := string(rest[:end])
+		rest = rest[end+1:]
+		return value, true
+	}
+	label, ok := readCString()
+	if !ok {
+		return "", native
+	}
+	if _, ok := readCString(); !ok || len(rest) < 8 {
+		return "", native
+	}
+	rest = rest[4:]
+	tempPathLength := int(binary.LittleEndian.Uint32(rest))
+	rest = rest[4:]
+	if tempPathLength > len(rest) || len(rest)-tempPathLength < 4 {
+		return "", native
+	}
+	rest = rest[tempPathLength:]
+	contentLength := int(binary.LittleEndian.Uint32(rest))
+	rest = rest[4:]
+	if contentLength > len(rest) {
+		return "", native
+	}
+
+	return pathlib.Base(strings.ReplaceAll(label, "\\", "/")), rest[:contentLength]
+}
+
+type rtfGroupState struct {
+	// Destination of the visible text. Nil if text of the group must be dropped.
+	out *bytes.Buffer
+	// Destination of the hex encoded binary data of pictures and objects
+	data        *[]byte
+	destination string
+	unicodeSkip int
+	codepage    int
+}
+
+type rtfDocument struct {
+	body bytes.Buffer
+	// Offsets of the line breaks in the body that end paragraphs
+	paragraphEnds map[int]bool
+	rows          []rtfRow
+	metadata      map[string]string
+	embedded      []*rtfEmbedded
+}
+
+// Table row in the body
+type rtfRow struct {
+	start int
+	end   int
+	cells []string
+}
+
+// Paragraphs and tables of the body. Lines are trimmed and empty lines between blocks are collapsed to one.
+func (d *rtfDocument) blocks() []Block {
+	var blocks []Block
+	// Last paragraph continues on the next line
+	open := false
+	// Empty lines after the last block
+	blank := false
+	row, rowAdded := 0, -1
+
+	lineStart := 0
+	for _, line := range strings.SplitAfter(d.body.String(), "\n") {
+		start := lineStart
+		lineStart += len(line)
+		content := strings.TrimRight(strings.TrimSuffix(line, "\n"), " \t")
+		if len(blocks) == 0 {
+			content = strings.TrimLeft(content, " \t")
+		}
+		for row < len(d.rows) && d.rows[row].end < start {
+			row++
+		}
+		inRow := row < len(d.rows) && d.rows[row].start <= start
+
+		if content == "" && !inRow {
+			open = false
+			blank = blank || len(blocks) != 0
+			continue
+		}
+
+		last := len(blocks) - 1
+		if last >= 0 && !blank && (open || (inRow && blocks[last].Kind == BlockTable)) {
+			blocks[last].Text += "\n" + content
+		} else {
+			block := Block{Kind: BlockParagraph, Text: content}
+			if inRow {
+				block.Kind = BlockTable
+			}
+			if last >= 0 {
+				blocks[last].Separator = "\n"
+				if blank {
+					blocks[last].Separator = "\n\n"
+				}
+				block.Location.Offset = blocks[last].Location.Offset + int64(len(blocks[last].Text)+len(blocks[last].Separator))
+			}
+			blocks = append(blocks, block)
+		}
+
+		if inRow && rowAdded != row {
+			blocks[len(blocks)-1].Rows = append(blocks[len(blocks)-1].Rows, d.rows[row].cells)
+			rowAdded = row
+		}
+		open = !inRow && !d.paragraphEnds[lineStart-1]
+		blank = false
+	}
+	return blocks
+}
+
+type rtfTokenizer struct {
+	data []byte
+	pos  int
+
+	doc   *rtfDocument
+	state rtfGroupState
+	stack []rtfGroupState
+
+	defaultCodepage int
+	fontCodepages   map[int]int
+	currentFont     int
+	infoFields      map[string]*bytes.Buffer
+	dateFields      map[string]map[string]int
+
+	// Bytes of multi byte characters that must be decoded together
+	pendingBytes []byte
+	pendingOut   *bytes.Buffer
+	// Number of fallback characters to skip after `\uN`
+	skipChars int
+	// High surrogate of `\uN` that is combined with the low surrogate of the next `\uN`
+	highSurrogate rune
+	// Beginning of the current table row and its cell in the body. Negative outside of the row.
+	rowStart  int
+	cellStart int
+	cells     []string
+	// Next control word is destination that may be ignored if unknown
+	ignorable bool
+}
+
+func parseRTF(data []byte) (*rtfDocument, error) {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	if !bytes.HasPrefix(data, []byte("{\\rtf")) {
+		return nil, errors.Join(ErrBadFile, errors.New("rtf header not found"))
+	}
+
+	t := &rtfTokenizer{
+		data:            data,
+		doc:             &rtfDocument{metadata: map[string]string{}, paragraphEnds: map[int]bool{}},
+		defaultCodepage: 1252,
+		fontCodepages:   map[int]int{},
+		infoFields:      map[string]*bytes.Buffer{},
+		dateFields:      map[string]map[string]int{},
+		rowStart:        -1,
+	}
+	t.state = rtfGroupState{out: &t.doc.body, unicodeSkip: 1, codepage: 1252}
+	t.run()
+
+	for _, field := range rtfInfoFields {
+		if buffer, ok := t.infoFields[field.control]; ok {
+			if value := strings.TrimSpace(buffer.String()); value != "" {
+				t.doc.metadata[field.name] = value
+			}
+		}
+		if date, ok := t.dateFields[field.control]; ok && date["yr"] != 0 {
+			t.doc.metadata[field.name] = time.Date(date["yr"], time.Month(max(date["mo"], 1)), max(date["dy"], 1), date["hr"], date["min"], 0, 0, time.UTC).Format("2006-01-02 15:04")
+		}
+	}
+
+	return t.doc, nil
+}
+
+func (t *rtfTokenizer) run() {
+	for t.pos < len(t.data) {
+		c := t.data[t.pos]
+		switch c {
+		case '{':
+			t.flushBytes()
+			t.stack = append(t.stack, t.state)
+			t.pos++
+		case '}':
+			t.flushBytes()
+			t.skipChars = 0
+			if len(t.stack) == 0 {
+				return
+			}
+			t.state = t.stack[len(t.stack)-1]
+			t.stack = t.stack[:len(t.stack)-1]
+			t.pos++
+		case '\\':
+			t.control()
+		case '\r', '\n':
+			t.pos++
+		default:
+			t.pos++
+			t.char(c)
+		}
+	}
+}
+
+func (t *rtfTokenizer) char(c byte) {
+	if t.skipChars > 0 {
+		t.skipChars--
+		return
+	}
+	if t.state.data != nil {
+		if isHexDigit(c) {
+			*t.state.data = append(*t.state.data, c)
+		}
+		return
+	}
+	if t.state.out == nil {
+		return
+	}
+	if c >= 0x80 {
+		t.writeByte(c)
+		return
+	}
+	t.flushBytes()
+	t.state.out.WriteByte(c)
+}
+
+// Collects byte of the current code page. Bytes are decoded when character sequence ends.
+func (t *rtfTokenizer) writeByte(c byte) {
+	t.flushSurrogate()
+	if t.pendingOut != t.state.out {
+		t.flushBytes()
+		t.pendingOut = t.state.out
+	}
+	t.pendingBytes = append(t.pendingBytes, c)
+}
+
+func (t *rtfTokenizer) flushBytes() {
+	t.flushSurrogate()
+	if len(t.pendingBytes) != 0 && t.pendingOut != nil {
+		t.pendingOut.WriteString(decodeCodepage(t.pendingBytes, t.state.codepage))
+	}
+	t.pendingBytes = t.pendingBytes[:0]
+	t.pendingOut = nil
+}
+
+// Text is written to the document body
+func (t *rtfTokenizer) inBody() bool {
+	return t.state.out == &t.doc.body && t.state.data == nil
+}
+
+// High surrogate without the low surrogate is written as replacement character
+func (t *rtfTokenizer) flushSurrogate() {
+	if t.highSurrogate == 0 {
+		return
+	}
+	t.highSurrogate = 0
+	if t.state.out != nil && t.state.data == nil {
+		t.state.out.WriteRune(utf8.RuneError)
+	}
+}
+
+func (t *rtfTokenizer) write(text string) {
+	t.flushBytes()
+	if t.state.out != nil && t.state.data == nil {
+		t.state.out.WriteString(text)
+	}
+}
+
+func (t *rtfTokenizer) control() {
+	t.pos++ // backslash
+	if t.pos >= len(t.data) {
+		return
+	}
+
+	c := t.data[t.pos]
+	if !isASCIILetter(c) {
+		t.pos++
+		switch c {
+		case '\'':
+			if t.pos+2 > len(t.data) {
+				t.pos = len(t.data)
+				return
+			}
+			value, err := strconv.ParseUint(string(t.data[t.pos:t.pos+2]), 16, 8)
+			t.pos += 2
+			if err != nil || t.state.data != nil || t.state.out == nil {
+				return
+			}
+			if t.skipChars > 0 {
+				t.skipChars--
+				return
+			}
+			t.writeByte(byte(value))
+		case '\\', '{', '}':
+			if t.skipChars > 0 {
+				t.skipChars--
+				return
+			}
+			t.write(string(c))
+		case '~':
+			t.write(" ")
+		case '_':
+			t.write("-")
+		case '*':
+			t.ignorable = true
+		case '\r', '\n':
+			t.write("\n")
+		}
+		return
+	}
+
+	start := t.pos
+	for t.pos < len(t.data) && isASCIILetter(t.data[t.pos]) {
+		t.pos++
+	}
+	name := string(t.data[start:t.pos])
+
+	paramStart := t.pos
+	if t.pos < len(t.data) && t.data[t.pos] == '-' {
+		t.pos++
+	}
+	for t.pos < len(t.data) && t.data[t.pos] >= '0' && t.data[t.pos] <= '9' {
+		t.pos++
+	}
+	param, err := strconv.Atoi(string(t.data[paramStart:t.pos]))
+	hasParam := err == nil
+	if t.pos < len(t.data) && t.data[t.pos] == ' ' {
+		t.pos++
+	}
+
+	ignorable := t.ignorable
+	t.ignorable = false
+	t.controlWord(name, param, hasParam, ignorable)
+}
+
+func (t *rtfTokenizer) controlWord(name string, param int, hasParam bool, ignorable bool) {
+	switch name {
+	case "par", "sect", "page":
+		t.flushBytes()
+		if t.inBody() {
+			t.doc.paragraphEnds[t.doc.body.Len()] = true
+		}
+		t.write("\n")
+	case "line":
+		t.write("\n")
+	case "row":
+		t.flushBytes()
+		if t.state.out != nil {
+			if bytes.HasSuffix(t.state.out.Bytes(), []byte(" | ")) {
+				t.state.out.Truncate(t.state.out.Len() - 3)
+			}
+		}
+		if t.inBody() && t.rowStart >= 0 {
+			t.doc.rows = append(t.doc.rows, rtfRow{start: t.rowStart, end: t.doc.body.Len(), cells: t.cells})
+		}
+		t.rowStart, t.cells = -1, nil
+		t.write("\n")
+	case "cell", "nestcell":
+		t.flushBytes()
+		if t.inBody() {
+			body := t.doc.body.Bytes()
+			if t.rowStart < 0 {
+				t.rowStart = bytes.LastIndexByte(body, '\n') + 1
+				t.cellStart = t.rowStart
+			}
+			t.cells = append(t.cells, strings.TrimSpace(string(body[t.cellStart:])))
+		}
+		t.write(" | ")
+		t.cellStart = t.doc.body.Len()
+	case "tab":
+		t.write("\t")
+	case "emdash":
+		t.write("—")
+	case "endash":
+		t.write("–")
+	case "bullet":
+		t.write("•")
+	case "lquote":
+		t.write("‘")
+	case "rquote":
+		t.write("’")
+	case "ldblquote":
+		t.write("“")
+	case "rdblquote":
+		t.write("”")
+	case "emspace", "enspace", "qmspace":
+		t.write(" ")
+	case "u":
+		if !hasParam {
+			return
+		}
+		if param < 0 {
+			param += 65536
+		}
+		// Characters outside of the BMP are written as two `\uN` with UTF-16 surrogates
+		r := rune(param)
+		if t.highSurrogate != 0 && r >= 0xDC00 && r < 0xE000 {
+			r = utf16.DecodeRune(t.highSurrogate, r)
+			t.highSurrogate = 0
+		}
+		if r >= 0xD800 && r < 0xDC00 {
+			t.flushSurrogate()
+			t.highSurrogate = r
+		} else {
+			t.write(string(r))
+		}
+		t.skipChars = t.state.unicodeSkip
+	case "uc":
+		t.state.unicodeSkip = param
+	case "ansicpg":
+		t.defaultCodepage = param
+		t.state.codepage = param
+	case "mac":
+		t.defaultCodepage = 10000
+		t.state.codepage = 10000
+	case "pc":
+		t.defaultCodepage = 437
+		t.state.codepage = 437
+	case "pca":
+		t.defaultCodepage = 850
+		t.state.codepage = 850
+	case "fonttbl":
+		t.setDestination(name, nil)
+	case "f":
+		if t.state.destination == "fonttbl" {
+			t.currentFont = param
+			return
+		}
+		t.flushBytes()
+		if codepage, ok := t.fontCodepages[param]; ok {
+			t.state.codepage = codepage
+		} else {
+			t.state.codepage = t.defaultCodepage
+		}
+	case "fcharset":
+		if t.state.destination == "fonttbl" {
+			if codepage, ok := rtfCharsetCodepages[param]; ok {
+				t.fontCodepages[t.currentFont] = codepage
+			}
+		}
+	case "cpg":
+		if t.state.destination == "fonttbl" {
+			t.fontCodepages[t.currentFont] = param
+		}
+	case "info":
+		t.setDestination(name, nil)
+	case "creatim", "revtim":
+		t.setDestination(name, nil)
+		t.dateFields[name] = map[string]int{}
+	case "yr", "mo", "dy", "hr", "min":
+		if date, ok := t.dateFields[t.state.destination]; ok {
+			date[name] = param
+		}
+	case "pict":
+		if t.state.out == nil {
+			// Alternative representation in skipped destination like `\nonshppict`
+			t.setDestination(name, nil)
+			return
+		}
+		embedded := &rtfEmbedded{kind: "pict"}
+		t.doc.embedded = append(t.doc.embedded, embedded)
+		t.setDestination(name, nil)
+		t.state.data = &embedded.hexData
+	case "pngblip", "jpegblip", "emfblip", "wmetafile", "macpict", "dibitmap", "wbitmap":
+		if t.state.destination == "pict" && t.state.data != nil {
+			t.doc.embedded[len(t.doc.embedded)-1].extension = map[string]string{
+				"pngblip": ".png", "jpegblip": ".jpg", "emfblip": ".emf", "wmetafile": ".wmf",
+				"macpict": ".pict", "dibitmap": ".bmp", "wbitmap": ".bmp",
+			}[name]
+		}
+	case "shppict":
+		// Picture container that is marked as ignorable but holds the picture itself
+	case "object":
+		t.setDestination(name, nil)
+	case "objdata":
+		embedded := &rtfEmbedded{kind: "object"}
+		t.doc.embedded = append(t.doc.embedded, embedded)
+		t.setDestination(name, nil)
+		t.state.data = &embedded.hexData
+	case "result":
+		// Visible representation of the object
+		t.state.destination = name
+		t.state.out = &t.doc.body
+		t.state.data = nil
+	case "bin":
+		t.binary(param)
+	default:
+		for _, field := range rtfInfoFields {
+			if field.control == name && t.state.destination == "info" {
+				buffer := &bytes.Buffer{}
+				t.infoFields[name] = buffer
+				t.setDestination(name, buffer)
+				return
+			}
+		}
+		if rtfSkippedDestinations[name] || ignorable {
+			t.setDestination(name, nil)
+		}
+	}
+}
+
+func (t *rtfTokenizer) setDestination(name string, out *bytes.Buffer) {
+	t.flushBytes()
+	t.state.destination = name
+	t.state.out = out
+	t.state.data = nil
+}
+
+// Skips `\binN` raw data. Binary picture data is stored in the same way as hex encoded one.
+func (t *rtfTokenizer) binary(length int) {
+	// Length comes from the file, so it is clamped before it is added to the position
+	length = max(length, 0)
+	if length > len(t.data)-t.pos {
+		length = len(t.data) - t.pos
+	}
+	end := t.pos + length
+	if t.state.data != nil {
+		*t.state.data = append(*t.state.data, []byte(hex.EncodeToString(t.data[t.pos:end]))...)
+	}
+	t.pos = end
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+type RTFParserResult struct {
+	FullPath string `json:"path"`
+	// Document information like title and author
+	Metadata map[string]string `json:"metadata"`
+	Text     string            `json:"text"`
+	// Paragraphs and tables of the text
+	Blocks []Block `json:"blocks"`
+	// Parsed embedded pictures and objects
+	Embedded []Result `json:"embedded"`
+	Err      error    `json:"error"`
+}
+
+func (r *RTFParserResult) Path() string {
+	return r.FullPath
+}
+
+func (r *RTFParserResult) String() string {
+	var result strings.Builder
+
+	if len(r.Metadata) != 0 {
+		result.WriteString(metadataString(r.MetadataFields()))
+	}
+	result.WriteString(r.Document().String())
+	result.WriteString("\n")
+
+	for _, embedded := range r.Embedded {
+		if embedded.Error() != nil {
+			continue
+		}
+		if text := embedded.String(); text != "" {
+			result.WriteString(fmt.Sprintf("------ Embedded %s ------\n", embedded.Path()))
+			result.WriteString(text)
+			result.WriteString("\n")
+		}
+	}
+
+	return result.String()
+}
+
+func (r *RTFParserResult) MetadataFields() []MetadataField {
+	var fields []MetadataField
+	for _, field := range rtfInfoFields {
+		if value, ok := r.Metadata[field.name]; ok {
+			fields = append(fields, MetadataField{Name: field.name, Value: value})
+		}
+	}
+	return fields
+}
+
+// Paragraphs and tables of the text. Offsets are in the `Text`.
+func (r *RTFParserResult) Document() *Document {
+	if r.Blocks == nil && r.Text != "" {
+		return &Document{Blocks: textBlocks(r.Text, Location{})}
+	}
+	return &Document{Blocks: r.Blocks}
+}
+
+func (r *RTFParserResult) Error() error {
+	return r.Err
+}
+
+func (r *RTFParserResult) Subfiles() []Result {
+	return r.Embedded
+}
+
+// Copy of the result with other subfiles in the same order, so they are rendered in its text
+func (r *RTFParserResult) withSubfiles(subfiles []Result) Result {
+	result := *r
+	result.Embedded = subfiles
+	return &result
+}
+
+type RTFParserStreamResult struct {
+	FullPath        string             `json:"path"`
+	CurrentStage    ParseProgressStage `json:"stage"`
+	CurrentProgress uint8              `json:"progress"`
+	Text            string             `json:"text"`
+	Err             error              `json:"error"`
+}
+
+func (r *RTFParserStreamResult) Path() string {
+	return r.FullPath
+}
+
+func (r *RTFParserStreamResult) Stage() ParseProgressStage {
+	return r.CurrentStage
+}
+
+func (r *RTFParserStreamResult) Progress() uint8 {
+	return r.CurrentProgress
+}
+
+func (r *RTFParserStreamResult) SubResult() StreamResult {
+	return nil
+}
+
+func (r *RTFParserStreamResult) String() string {
+	return r.Text
+}
+
+func (r *RTFParserStreamResult) Error() error {
+	return r.Err
+}
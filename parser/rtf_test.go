@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	testdata "github.com/opengs/file2llm/test_data"
+)
+
+func TestRTF(t *testing.T) {
+	rtfParser := NewRTFParser(NewCompositeParser())
+	result := rtfParser.Parse(context.Background(), bytes.NewReader(testdata.RTF), "doc.rtf")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+
+	rtfResult := result.(*RTFParserResult)
+	if rtfResult.Metadata["Title"] != "Quarterly Report" || rtfResult.Metadata["Author"] != "Jane Doe" || rtfResult.Metadata["Created"] != "2024-03-05 09:30" {
+		t.Errorf("unexpected metadata: %v", rtfResult.Metadata)
+	}
+
+	expected := []string{
+		"Summary\nCafé revenue grew\t12%.\nПривет and € euro\n",
+		"Region | Revenue\nNorth | 120\n",
+		"Package placeholder",
+		"End of {document}.",
+	}
+	for _, text := range expected {
+		if !strings.Contains(rtfResult.Text, text) {
+			t.Errorf("missing %q in:\n%s", text, rtfResult.Text)
+		}
+	}
+	for _, text := range []string{"Arial", "Confidential header", "Riched20", "results", "0102"} {
+		if strings.Contains(rtfResult.Text, text) {
+			t.Errorf("unexpected %q in:\n%s", text, rtfResult.Text)
+		}
+	}
+
+	subfiles := result.Subfiles()
+	if len(subfiles) != 2 || subfiles[0].Path() != "doc.rtf/pict_0.png" || subfiles[1].Path() != "doc.rtf/notes.txt" {
+		t.Errorf("unexpected subfiles: %v", subfiles)
+	}
+}
+
+func TestRTFSurrogates(t *testing.T) {
+	data := []byte(`{\rtf1\ansi\uc1 Smile \u-10179?\u-8704? and lone \u55357? end}`)
+	result := NewRTFParser(nil).Parse(context.Background(), bytes.NewReader(data), "doc.rtf")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+	if text := result.String(); !strings.Contains(text, "Smile \U0001F600 and lone \uFFFD end") {
+		t.Errorf("unexpected text: %q", text)
+	}
+}
+
+func TestRTFBinaryLength(t *testing.T) {
+	for _, data := range []string{`{\rtf1 \bin9223372036854775807 x}`, `{\rtf1 \bin-5 x}`} {
+		result := NewRTFParser(nil).Parse(context.Background(), strings.NewReader(data), "doc.rtf")
+		if result.Error() != nil {
+			t.Errorf("unexpected error for %s: %v", data, result.Error())
+		}
+	}
+}
+
+func TestRTFEmbeddedPayload(t *testing.T) {
+	doc, err := parseRTF(testdata.RTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, data := doc.embedded[0].payload(0)
+	if name != "pict_0.png" || !bytes.Equal(data, testdata.PNG) {
+		t.Errorf("unexpected picture %s", name)
+	}
+	name, data = doc.embedded[1].payload(1)
+	if name != "notes.txt" || string(data) != "Meeting notes from the package" {
+		t.Errorf("unexpected object %s: %q", name, data)
+	}
+}
+
+func TestRTFStream(t *testing.T) {
+	rtfParser := NewRTFParser(nil)
+
+	hasNewStage := false
+	hasCompletedStage := false
+	var lastResult StreamResult
+
+	var resultString string
+	parseProgress := rtfParser.ParseStream(context.Background(), bytes.NewReader(testdata.RTF), "")
+	defer parseProgress.Close()
+	for parseProgress.Next(t.Context()) {
+		progress := parseProgress.Current()
+		resultString += progress.String()
+		hasNewStage = hasNewStage || (progress.Stage() == ProgressNew)
+		hasCompletedStage = hasCompletedStage || (progress.Stage() == ProgressCompleted)
+		lastResult = progress
+	}
+	if !hasNewStage || !hasCompletedStage {
+		t.Fail()
+	}
+	if lastResult.Error() != nil {
+		t.Fatal(lastResult.Error())
+	}
+
+	if !strings.Contains(resultString, "Title: Quarterly Report\nAuthor: Jane Doe\n") || !strings.Contains(resultString, "Café revenue grew") {
+		t.Error(resultString)
+	}
+}
@@ -0,0 +1,408 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type spreadsheetConfig struct {
+	maxRowsPerSheet int
+}
+
+func defaultSpreadsheetConfig() spreadsheetConfig {
+	return spreadsheetConfig{
+		maxRowsPerSheet: 10000,
+	}
+}
+
+// Configures spreadsheet parsers (XLSX, CSV)
+type SpreadsheetOption func(c *spreadsheetConfig)
+
+// Maximum number of data rows (header is not counted) emitted for every sheet. Rest of the sheet is skipped and marked as truncated. Zero disables limit. Default is 10000.
+func WithMaxRowsPerSheet(maxRows int) SpreadsheetOption {
+	return func(c *spreadsheetConfig) {
+		c.maxRowsPerSheet = maxRows
+	}
+}
+
+// Number of rows sent in one stream update
+const spreadsheetStreamBatchRows = 200
+
+// Reads rows of one sheet. Returns [io.EOF] when there are no more rows.
+type spreadsheetRowReader interface {
+	// Returns row number starting from 1 and cell values. Empty cells in the middle of the row are empty strings.
+	next() (int, []string, error)
+	close()
+}
+
+type spreadsheetSheetSource struct {
+	name string
+	open func() (spreadsheetRowReader, error)
+}
+
+// Reads sheet applying rows limit. First non empty row is used as header.
+func readSpreadsheetSheet(source spreadsheetSheetSource, maxRows int) (SpreadsheetSheet, error) {
+	sheet := SpreadsheetSheet{Name: source.name}
+
+	rows, err := source.open()
+	if err != nil {
+		return sheet, err
+	}
+	defer rows.close()
+
+	for {
+		number, cells, err := rows.next()
+		if err == io.EOF {
+			return sheet, nil
+		} else if err != nil {
+			return sheet, err
+		}
+
+		if !sheet.addRow(number, cells, maxRows) {
+			return sheet, nil
+		}
+	}
+}
+
+type SpreadsheetRow struct {
+	// Row number in the sheet starting from 1
+	Number int      `json:"number"`
+	Cells  []string `json:"cells"`
+}
+
+type SpreadsheetSheet struct {
+	Name   string           `json:"name"`
+	Header []string         `json:"header"`
+	Rows   []SpreadsheetRow `json:"rows"`
+	// Sheet has more rows than allowed by limit
+	Truncated bool `json:"truncated"`
+}
+
+// Adds row to the sheet. Returns false if rows limit is reached and rest of the rows must be skipped.
+func (s *SpreadsheetSheet) addRow(number int, cells []string, maxRows int) bool {
+	cells = trimSpreadsheetRow(cells)
+	if len(cells) == 0 {
+		return true
+	}
+
+	if s.Header == nil {
+		s.Header = spreadsheetHeader(cells)
+		return true
+	}
+
+	if maxRows > 0 && len(s.Rows) >= maxRows {
+		s.Truncated = true
+		return false
+	}
+
+	s.Rows = append(s.Rows, SpreadsheetRow{Number: number, Cells: cells})
+	return true
+}
+
+func (s *SpreadsheetSheet) String() string {
+	return blocksString(s.blocks(0))
+}
+
+// Sheet label followed by the table with header and rows of the sheet
+func (s *SpreadsheetSheet) blocks(page int) []Block {
+	location := Location{Page: page, Offset: -1}
+	blocks := []Block{{Kind: BlockPageBreak, Text: "Sheet " + s.Name, Location: location, Separator: "\n"}}
+
+	if s.Header != nil || len(s.Rows) != 0 {
+		var text strings.Builder
+		table := Block{Kind: BlockTable, Location: location, Separator: "\n"}
+		if s.Header != nil {
+			text.WriteString(spreadsheetColumnsLine(s.Header))
+			table.Rows = append(table.Rows, s.Header)
+		} else {
+			// Table without header starts with the empty header row
+			table.Rows = append(table.Rows, []string{})
+		}
+		for _, row := range s.Rows {
+			text.WriteString(spreadsheetRowLine(s.Header, row))
+			table.Rows = append(table.Rows, row.Cells)
+		}
+		table.Text = strings.TrimSuffix(text.String(), "\n")
+		blocks = append(blocks, table)
+	}
+	if s.Truncated {
+		blocks = append(blocks, Block{Kind: BlockParagraph, Text: strings.TrimSuffix(spreadsheetTruncatedLine(len(s.Rows)), "\n"), Location: location, Separator: "\n"})
+	}
+
+	return blocks
+}
+
+func trimSpreadsheetRow(cells []string) []string {
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	for len(cells) > 0 && cells[len(cells)-1] == "" {
+		cells = cells[:len(cells)-1]
+	}
+	return cells
+}
+
+// Uses column letter for columns without name in the header row
+func spreadsheetHeader(cells []string) []string {
+	header := make([]string, len(cells))
+	for i, cell := range cells {
+		if cell == "" {
+			cell = spreadsheetColumnName(i)
+		}
+		header[i] = cell
+	}
+	return header
+}
+
+// Converts zero based column index to the column letters: 0 -> A, 26 -> AA
+func spreadsheetColumnName(index int) string {
+	var name []byte
+	for index >= 0 {
+		name = append([]byte{byte('A' + index%26)}, name...)
+		index = index/26 - 1
+	}
+	return string(name)
+}
+
+func spreadsheetSheetBegin(name string) string {
+	return fmt.Sprintf("------ Sheet %s ------\n", name)
+}
+
+func spreadsheetColumnsLine(header []string) string {
+	return fmt.Sprintf("Columns: %s\n", strings.Join(header, " | "))
+}
+
+func spreadsheetRowLine(header []string, row SpreadsheetRow) string {
+	var result strings.Builder
+
+	result.WriteString(fmt.Sprintf("Row %d:", row.Number))
+	first := true
+	for i, cell := range row.Cells {
+		if cell == "" {
+			continue
+		}
+		if !first {
+			result.WriteString(" |")
+		}
+		first = false
+
+		column := spreadsheetColumnName(i)
+		if i < len(header) {
+			column = header[i]
+		}
+		result.WriteString(fmt.Sprintf(" %s: %s", column, cell))
+	}
+	result.WriteString("\n")
+
+	return result.String()
+}
+
+func spreadsheetTruncatedLine(rows int) string {
+	return fmt.Sprintf("------ Sheet truncated after %d rows ------\n", rows)
+}
+
+type SpreadsheetStreamResultIterator struct {
+	path      string
+	maxRows   int
+	openBook  func() ([]spreadsheetSheetSource, error)
+	started   bool
+	completed bool
+
+	sheets            []spreadsheetSheetSource
+	currentSheetIndex int
+	currentRows       spreadsheetRowReader
+	currentHeader     []string
+	currentSheetRows  int
+
+	current StreamResult
+}
+
+func (i *SpreadsheetStreamResultIterator) Next(ctx context.Context) bool {
+	if i.completed {
+		i.current = nil
+		return false
+	}
+
+	if !i.started {
+		i.started = true
+		i.current = &SpreadsheetParserStreamResult{
+			FullPath:     i.path,
+			CurrentStage: ProgressNew,
+		}
+		return true
+	}
+
+	if ctx.Err() != nil {
+		i.current = nil
+		return false
+	}
+
+	if i.sheets == nil {
+		sheets, err := i.openBook()
+		if err != nil {
+			return i.fail(err)
+		}
+		i.sheets = sheets
+	}
+
+	var text strings.Builder
+	for text.Len() == 0 {
+		if i.currentRows == nil {
+			if i.currentSheetIndex >= len(i.sheets) {
+				i.completed = true
+				i.current = &SpreadsheetParserStreamResult{
+					FullPath:        i.path,
+					CurrentStage:    ProgressCompleted,
+					CurrentProgress: 100,
+				}
+				return true
+			}
+
+			rows, err := i.sheets[i.currentSheetIndex].open()
+			if err != nil {
+				return i.fail(err)
+			}
+			i.currentRows = rows
+			i.currentHeader = nil
+			i.currentSheetRows = 0
+			text.WriteString(spreadsheetSheetBegin(i.sheets[i.currentSheetIndex].name))
+		}
+
+		sheetCompleted := false
+		for batch := 0; batch < spreadsheetStreamBatchRows; {
+			number, cells, err := i.currentRows.next()
+			if err == io.EOF {
+				sheetCompleted = true
+				break
+			} else if err != nil {
+				return i.fail(err)
+			}
+
+			cells = trimSpreadsheetRow(cells)
+			if len(cells) == 0 {
+				continue
+			}
+			if i.currentHeader == nil {
+				i.currentHeader = spreadsheetHeader(cells)
+				text.WriteString(spreadsheetColumnsLine(i.currentHeader))
+				continue
+			}
+			if i.maxRows > 0 && i.currentSheetRows >= i.maxRows {
+				text.WriteString(spreadsheetTruncatedLine(i.currentSheetRows))
+				sheetCompleted = true
+				break
+			}
+
+			text.WriteString(spreadsheetRowLine(i.currentHeader, SpreadsheetRow{Number: number, Cells: cells}))
+			i.currentSheetRows += 1
+			batch += 1
+		}
+
+		if sheetCompleted {
+			text.WriteString("\n")
+			i.currentRows.close()
+			i.currentRows = nil
+			i.currentSheetIndex += 1
+		}
+	}
+
+	i.current = &SpreadsheetParserStreamResult{
+		FullPath:        i.path,
+		CurrentStage:    ProgressUpdate,
+		CurrentProgress: uint8(float64(i.currentSheetIndex) / float64(len(i.sheets)) * 100),
+		Text:            text.String(),
+	}
+	return true
+}
+
+func (i *SpreadsheetStreamResultIterator) fail(err error) bool {
+	i.completed = true
+	i.current = &SpreadsheetParserStreamResult{
+		FullPath:     i.path,
+		CurrentStage: ProgressCompleted,
+		Err:          err,
+	}
+	return true
+}
+
+func (i *SpreadsheetStreamResultIterator) Current() StreamResult {
+	return i.current
+}
+
+func (i *SpreadsheetStreamResultIterator) Close() {
+	if i.currentRows != nil {
+		i.currentRows.close()
+		i.currentRows = nil
+	}
+}
+
+type SpreadsheetParserResult struct {
+	FullPath string             `json:"path"`
+	Sheets   []SpreadsheetSheet `json:"sheets"`
+	Err      error              `json:"error"`
+}
+
+func (r *SpreadsheetParserResult) Path() string {
+	return r.FullPath
+}
+
+func (r *SpreadsheetParserResult) String() string {
+	var result strings.Builder
+
+	result.WriteString(r.Document().String())
+
+	return result.String()
+}
+
+// Sheets of the book. Every sheet begins with page break.
+func (r *SpreadsheetParserResult) Document() *Document {
+	document := &Document{}
+	for i := range r.Sheets {
+		blocks := r.Sheets[i].blocks(i + 1)
+		appendSeparator(blocks, "\n")
+		document.Blocks = append(document.Blocks, blocks...)
+	}
+	return document
+}
+
+func (r *SpreadsheetParserResult) Error() error {
+	return r.Err
+}
+
+func (r *SpreadsheetParserResult) Subfiles() []Result {
+	return nil
+}
+
+type SpreadsheetParserStreamResult struct {
+	FullPath        string             `json:"path"`
+	CurrentStage    ParseProgressStage `json:"stage"`
+	CurrentProgress uint8              `json:"progress"`
+	Text            string             `json:"text"`
+	Err             error              `json:"error"`
+}
+
+func (r *SpreadsheetParserStreamResult) Path() string {
+	return r.FullPath
+}
+
+func (r *SpreadsheetParserStreamResult) Stage() ParseProgressStage {
+	return r.CurrentStage
+}
+
+func (r *SpreadsheetParserStreamResult) Progress() uint8 {
+	return r.CurrentProgress
+}
+
+func (r *SpreadsheetParserStreamResult) SubResult() StreamResult {
+	return nil
+}
+
+func (r *SpreadsheetParserStreamResult) String() string {
+	return r.Text
+}
+
+func (r *SpreadsheetParserStreamResult) Error() error {
+	return r.Err
+}
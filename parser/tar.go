@@ -31,7 +31,7 @@ func (p *TARParser) Parse(ctx context.Context, file io.Reader, path string) Resu
 	}
 
 	reader := tar.NewReader(file)
-	for {
+	for entries := 1; ; entries++ {
 		header, err := reader.Next()
 		if err != nil {
 			if err == io.EOF {
@@ -40,6 +40,10 @@ func (p *TARParser) Parse(ctx context.Context, file io.Reader, path string) Resu
 
 			return &TARParserResult{Err: errors.Join(ErrBadFile, err), FullPath: path}
 		}
+		if err := checkEntryLimit(ctx, entries, path); err != nil {
+			result.Err = err
+			break
+		}
 
 		subfileResult := p.innerParser.Parse(ctx, reader, pathlib.Join(path, header.Name))
 		result.SubfilesResults = append(result.SubfilesResults, subfileResult)
@@ -64,6 +68,7 @@ type TARStreamResultIterator struct {
 	innerParser Parser
 
 	completed   bool
+	entries     int
 	reader      *tar.Reader
 	parseStream StreamResultIterator
 	current     StreamResult
@@ -86,6 +91,7 @@ func (i *TARStreamResultIterator) Next(ctx context.Context) bool {
 
 	if i.parseStream != nil {
 		if i.parseStream.Next(ctx) {
+			i.current = &TARParserStreamResult{FullPath: i.path, CurrentStage: ProgressUpdate, CurrentSubfile: i.parseStream.Current()}
 			return true
 		} else {
 			i.parseStream.Close()
@@ -105,6 +111,13 @@ func (i *TARStreamResultIterator) Next(ctx context.Context) bool {
 		return true
 	}
 
+	i.entries += 1
+	if err := checkEntryLimit(ctx, i.entries, i.path); err != nil {
+		i.completed = true
+		i.current = &TARParserStreamResult{Err: err, FullPath: i.path, CurrentStage: ProgressCompleted}
+		return true
+	}
+
 	i.parseStream = i.innerParser.ParseStream(ctx, i.reader, pathlib.Join(i.path, header.Name))
 	return i.Next(ctx)
 }
@@ -153,6 +166,13 @@ func (r *TARParserResult) Subfiles() []Result {
 	return r.SubfilesResults
 }
 
+// Copy of the result with other subfiles in the same order, so they are rendered in its text
+func (r *TARParserResult) withSubfiles(subfiles []Result) Result {
+	result := *r
+	result.SubfilesResults = subfiles
+	return &result
+}
+
 type TARParserStreamResult struct {
 	FullPath       string             `json:"path"`
 	CurrentStage   ParseProgressStage `json:"stage"`
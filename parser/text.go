@@ -0,0 +1,390 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+type textParserConfig struct {
+	fallbackCodepage int
+}
+
+// Configures [TextParser]
+type TextOption func(c *textParserConfig)
+
+// Code page used for the files that are not valid UTF-8 or UTF-16. By default Windows-1251 is used for text that looks cyrillic and Windows-1252 otherwise.
+func WithTextFallbackCodepage(codepage int) TextOption {
+	return func(c *textParserConfig) {
+		c.fallbackCodepage = codepage
+	}
+}
+
+// Size of the text sent in one stream update
+const textStreamBlockSize = 256 * 1024
+
+// Size of the beginning of the file used to detect encoding
+const textEncodingSampleSize = 64 * 1024
+
+// Parses plain text, Markdown and source code files. Text is converted to UTF-8 with `\n` line endings.
+type TextParser struct {
+	config textParserConfig
+}
+
+func NewTextParser(options ...TextOption) *TextParser {
+	parser := &TextParser{}
+
+	for _, option := range options {
+		option(&parser.config)
+	}
+
+	return parser
+}
+
+// Configuration that changes the result. Used in the cache keys.
+func (p *TextParser) CacheFingerprint() string {
+	return fmt.Sprintf("%+v", p.config)
+}
+
+func (p *TextParser) SupportedMimeTypes() []string {
+	return []string{
+		"text/plain", "text/markdown", "text/x-markdown", "text/x-log",
+		"text/x-python", "text/x-php", "text/javascript", "text/x-lua", "text/x-perl", "text/x-tcl", "text/x-shellscript",
+		"text/x-c", "text/x-c++", "text/x-csharp", "text/x-go", "text/x-java", "text/x-rust", "text/x-ruby", "text/x-sql",
+		"text/x-kotlin", "text/x-swift", "text/x-typescript", "text/css", "text/x-yaml", "text/xml",
+		"application/json", "application/x-ndjson", "application/yaml", "application/toml", "application/sql",
+	}
+}
+
+func (p *TextParser) Parse(ctx context.Context, file io.Reader, path string) Result {
+	reader, encodingName, err := p.newTextReader(file)
+	if err != nil {
+		return &TextParserResult{Err: err, FullPath: path}
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return &TextParserResult{Err: errors.Join(ErrBadFile, errors.New("failed to decode text"), err), FullPath: path, Encoding: encodingName}
+	}
+
+	text := strings.ToValidUTF8(string(data), "\uFFFD")
+	return &TextParserResult{
+		FullPath: path,
+		Encoding: encodingName,
+		Text:     text,
+		Blocks:   textBlocks(text, Location{}),
+	}
+}
+
+func (p *TextParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
+	return &TextStreamResultIterator{
+		textParser: p,
+		file:       file,
+		path:       path,
+	}
+}
+
+// Detects encoding of the file and returns reader that decodes it to UTF-8 and normalizes line endings
+func (p *TextParser) newTextReader(file io.Reader) (io.Reader, string, error) {
+	buffered := bufio.NewReaderSize(file, textEncodingSampleSize)
+	sample, err := buffered.Peek(textEncodingSampleSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, "", errors.Join(errors.New("failed to read beginning of the file"), err)
+	}
+
+	enc, name, bomLength := detectTextEncoding(sample, p.config.fallbackCodepage)
+	buffered.Discard(bomLength)
+
+	return transform.NewReader(buffered, transform.Chain(enc.NewDecoder(), &textNewlineTransformer{})), name, nil
+}
+
+// Detects encoding from the BOM or content of the sample. Returns encoding, its name and length of the BOM.
+func detectTextEncoding(sample []byte, fallbackCodepage int) (encoding.Encoding, string, int) {
+	switch {
+	case bytes.HasPrefix(sample, []byte{0xEF, 0xBB, 0xBF}):
+		return encoding.Nop, "utf-8", 3
+	case bytes.HasPrefix(sample, []byte{0xFF, 0xFE}):
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), "utf-16le", 2
+	case bytes.HasPrefix(sample, []byte{0xFE, 0xFF}):
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), "utf-16be", 2
+	}
+
+	// UTF-16 without BOM has zero high bytes for the ASCII characters
+	if len(sample) >= 4 {
+		evenZeros, oddZeros := 0, 0
+		for i := 0; i+1 < len(sample); i += 2 {
+			if sample[i] == 0 {
+				evenZeros += 1
+			}
+			if sample[i+1] == 0 {
+				oddZeros += 1
+			}
+		}
+		pairs := len(sample) / 2
+		if oddZeros > pairs*2/5 && evenZeros < pairs/10 {
+			return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), "utf-16le", 0
+		}
+		if evenZeros > pairs*2/5 && oddZeros < pairs/10 {
+			return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), "utf-16be", 0
+		}
+	}
+
+	if validUTF8Prefix(sample) {
+		return encoding.Nop, "utf-8", 0
+	}
+
+	codepage := fallbackCodepage
+	if codepage == 0 {
+		codepage = guessSingleByteCodepage(sample)
+	}
+	return codepageEncoding(codepage), codepageName(codepage), 0
+}
+
+// Same as [utf8.Valid] but allows rune to be cut at the end of the sample
+func validUTF8Prefix(sample []byte) bool {
+	for i := len(sample) - 1; i >= 0 && i > len(sample)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(sample[i]) {
+			if !utf8.FullRune(sample[i:]) {
+				sample = sample[:i]
+			}
+			break
+		}
+	}
+	return utf8.Valid(sample)
+}
+
+// Cyrillic text encoded with Windows-1251 consists mostly of bytes above 0xC0, while in Windows-1252 they are rare accented letters
+func guessSingleByteCodepage(sample []byte) int {
+	letters, high := 0, 0
+	for _, c := range sample {
+		switch {
+		case c >= 0xC0:
+			high += 1
+			letters += 1
+		case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			letters += 1
+		}
+	}
+	if letters != 0 && high*2 > letters {
+		return 1251
+	}
+	return 1252
+}
+
+// Converts `\r\n` and `\r` line endings to `\n`
+type textNewlineTransformer struct {
+	transform.NopResetter
+}
+
+func (t *textNewlineTransformer) Transform(dst, src []byte, atEOF bool) (int, int, error) {
+	nDst, nSrc := 0, 0
+	for nSrc < len(src) {
+		if nDst >= len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+
+		c := src[nSrc]
+		if c != '\r' {
+			dst[nDst] = c
+			nDst += 1
+			nSrc += 1
+			continue
+		}
+
+		if nSrc+1 >= len(src) && !atEOF {
+			return nDst, nSrc, transform.ErrShortSrc
+		}
+		dst[nDst] = '\n'
+		nDst += 1
+		nSrc += 1
+		if nSrc < len(src) && src[nSrc] == '\n' {
+			nSrc += 1
+		}
+	}
+	return nDst, nSrc, nil
+}
+
+type TextStreamResultIterator struct {
+	textParser *TextParser
+	file       io.Reader
+	path       string
+
+	started   bool
+	eof       bool
+	completed bool
+	reader    io.Reader
+	encoding  string
+	// Text after the last line break of the previous block
+	rest []byte
+
+	current StreamResult
+}
+
+func (i *TextStreamResultIterator) Next(ctx context.Context) bool {
+	if i.completed {
+		i.current = nil
+		return false
+	}
+
+	if !i.started {
+		i.started = true
+		i.current = &TextParserStreamResult{
+			FullPath:     i.path,
+			CurrentStage: ProgressNew,
+		}
+		return true
+	}
+
+	if ctx.Err() != nil {
+		i.current = nil
+		return false
+	}
+
+	if i.reader == nil {
+		reader, encodingName, err := i.textParser.newTextReader(i.file)
+		if err != nil {
+			return i.fail(err)
+		}
+		i.reader = reader
+		i.encoding = encodingName
+	}
+
+	if i.eof {
+		i.completed = true
+		i.current = &TextParserStreamResult{
+			FullPath:        i.path,
+			CurrentStage:    ProgressCompleted,
+			CurrentProgress: 100,
+			Encoding:        i.encoding,
+		}
+		return true
+	}
+
+	block := make([]byte, textStreamBlockSize)
+	copy(block, i.rest)
+	n, err := io.ReadFull(i.reader, block[len(i.rest):])
+	block = block[:len(i.rest)+n]
+	i.rest = nil
+
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		i.eof = true
+	} else if err != nil {
+		return i.fail(errors.Join(ErrBadFile, errors.New("failed to decode text"), err))
+	} else {
+		// Block ends on the line break or at least on the rune boundary, so lines are not split between updates
+		cut := bytes.LastIndexByte(block, '\n') + 1
+		if cut == 0 {
+			cut = len(block)
+			for cut > 0 && cut > len(block)-utf8.UTFMax && !utf8.RuneStart(block[cut-1]) {
+				cut -= 1
+			}
+			if cut > 0 && !utf8.FullRune(block[cut-1:]) {
+				cut -= 1
+			}
+		}
+		i.rest = bytes.Clone(block[cut:])
+		block = block[:cut]
+	}
+
+	i.current = &TextParserStreamResult{
+		FullPath:     i.path,
+		CurrentStage: ProgressUpdate,
+		Encoding:     i.encoding,
+		Text:         strings.ToValidUTF8(string(block), "\uFFFD"),
+	}
+	return true
+}
+
+func (i *TextStreamResultIterator) fail(err error) bool {
+	i.completed = true
+	i.current = &TextParserStreamResult{
+		FullPath:     i.path,
+		CurrentStage: ProgressCompleted,
+		Encoding:     i.encoding,
+		Err:          err,
+	}
+	return true
+}
+
+func (i *TextStreamResultIterator) Current() StreamResult {
+	return i.current
+}
+
+func (i *TextStreamResultIterator) Close() {
+}
+
+type TextParserResult struct {
+	FullPath string `json:"path"`
+	// Detected encoding of the original file
+	Encoding string `json:"encoding"`
+	Text     string `json:"text"`
+	// Paragraphs of the text
+	Blocks []Block `json:"blocks"`
+	Err    error   `json:"error"`
+}
+
+func (r *TextParserResult) Path() string {
+	return r.FullPath
+}
+
+func (r *TextParserResult) String() string {
+	return r.Document().String()
+}
+
+// Paragraphs of the text. Offsets are in the text converted to UTF-8.
+func (r *TextParserResult) Document() *Document {
+	if r.Blocks == nil && r.Text != "" {
+		return &Document{Blocks: textBlocks(r.Text, Location{})}
+	}
+	return &Document{Blocks: r.Blocks}
+}
+
+func (r *TextParserResult) Error() error {
+	return r.Err
+}
+
+func (r *TextParserResult) Subfiles() []Result {
+	return nil
+}
+
+type TextParserStreamResult struct {
+	FullPath        string             `json:"path"`
+	CurrentStage    ParseProgressStage `json:"stage"`
+	CurrentProgress uint8              `json:"progress"`
+	Encoding        string             `json:"encoding"`
+	Text            string             `json:"text"`
+	Err             error              `json:"error"`
+}
+
+func (r *TextParserStreamResult) Path() string {
+	return r.FullPath
+}
+
+func (r *TextParserStreamResult) Stage() ParseProgressStage {
+	return r.CurrentStage
+}
+
+func (r *TextParserStreamResult) Progress() uint8 {
+	return r.CurrentProgress
+}
+
+func (r *TextParserStreamResult) SubResult() StreamResult {
+	return nil
+}
+
+func (r *TextParserStreamResult) String() string {
+	return r.Text
+}
+
+func (r *TextParserStreamResult) Error() error {
+	return r.Err
+}
@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestText(t *testing.T) {
+	data, _ := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().Bytes([]byte("# Notes\r\nПривет\r\nold mac line\rend"))
+	result := NewCompositeParser(NewTextParser()).Parse(context.Background(), bytes.NewReader(data), "notes.md")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+
+	textResult := result.(*CompositeParserResult).Inner.(*TextParserResult)
+	if textResult.Encoding != "utf-16le" {
+		t.Errorf("unexpected encoding %s", textResult.Encoding)
+	}
+	if result.String() != "# Notes\nПривет\nold mac line\nend" {
+		t.Errorf("unexpected text %q", result.String())
+	}
+}
+
+func TestTextEncodings(t *testing.T) {
+	utf16be, _ := unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewEncoder().Bytes([]byte("plain text"))
+	cases := []struct {
+		data     []byte
+		encoding string
+		text     string
+	}{
+		{[]byte("\xEF\xBB\xBFcafé"), "utf-8", "café"},
+		{[]byte("caf\xe9 au lait"), "windows-1252", "café au lait"},
+		{[]byte("\xcf\xf0\xe8\xe2\xe5\xf2 \xec\xe8\xf0"), "windows-1251", "Привет мир"},
+		{utf16be, "utf-16be", "plain text"},
+	}
+	for _, c := range cases {
+		result := NewTextParser().Parse(context.Background(), bytes.NewReader(c.data), "file.txt").(*TextParserResult)
+		if result.Encoding != c.encoding || result.Text != c.text {
+			t.Errorf("expected %s %q, got %s %q", c.encoding, c.text, result.Encoding, result.Text)
+		}
+	}
+
+	result := NewTextParser(WithTextFallbackCodepage(1250)).Parse(context.Background(), bytes.NewReader([]byte("\x9a")), "file.txt").(*TextParserResult)
+	if result.Encoding != "windows-1250" || result.Text != "š" {
+		t.Errorf("unexpected fallback code page result %s %q", result.Encoding, result.Text)
+	}
+}
+
+func TestTextStream(t *testing.T) {
+	var data bytes.Buffer
+	for line := 0; data.Len() < 3*textStreamBlockSize; line++ {
+		fmt.Fprintf(&data, "log line %d with some text\r\n", line)
+	}
+	expected := NewTextParser().Parse(context.Background(), bytes.NewReader(data.Bytes()), "app.log").String()
+
+	stream := NewTextParser().ParseStream(context.Background(), bytes.NewReader(data.Bytes()), "app.log")
+	defer stream.Close()
+
+	var text strings.Builder
+	var last StreamResult
+	updates := 0
+	for stream.Next(context.Background()) {
+		last = stream.Current()
+		if last.Error() != nil {
+			t.Fatal(last.Error())
+		}
+		if last.Stage() == ProgressUpdate {
+			updates += 1
+			if !strings.HasSuffix(last.String(), "\n") {
+				t.Errorf("block is not ended on the line break")
+			}
+		}
+		text.WriteString(last.String())
+	}
+
+	if last == nil || last.Stage() != ProgressCompleted {
+		t.Errorf("stream is not completed")
+	}
+	if updates < 3 {
+		t.Errorf("expected file to be streamed in blocks, got %d updates", updates)
+	}
+	if text.String() != expected || strings.Contains(expected, "\r") {
+		t.Errorf("stream text differs from the parse result")
+	}
+}
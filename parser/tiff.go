@@ -4,52 +4,49 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 
-	"image/png"
-
 	"github.com/opengs/file2llm/ocr"
 	"golang.org/x/image/tiff"
 )
 
-// Parses `image/tiff` files
+// Parses `image/tiff` files. Every page of the multi page file is recognized separately
 type TiffParser struct {
 	ocrProvider ocr.Provider
+	config      imageConfig
 }
 
-func NewTiffParser(ocrProvider ocr.Provider) *TiffParser {
+func NewTiffParser(ocrProvider ocr.Provider, options ...ImageOption) *TiffParser {
 	return &TiffParser{
 		ocrProvider: ocrProvider,
+		config:      newImageConfig(options),
 	}
 }
 
+// Configuration that changes the result. Used in the cache keys.
+func (p *TiffParser) CacheFingerprint() string {
+	return fmt.Sprintf("ocr=%s %+v", cacheFingerprint(p.ocrProvider), p.config)
+}
+
 func (p *TiffParser) SupportedMimeTypes() []string {
 	return []string{"image/tiff"}
 }
 
-func (p *TiffParser) prepareData(file io.Reader) (io.Reader, error) {
-	var imageData io.Reader
-
-	if p.ocrProvider.IsMimeTypeSupported("image/tiff") {
-		imageData = file
-	} else {
-		img, err := tiff.Decode(file)
-		if err != nil {
-			return nil, errors.Join(ErrBadFile, errors.New("failed to decode tiff image for transcoding"), err)
-		}
-
-		var outBuf bytes.Buffer
-		if err := png.Encode(&outBuf, img); err != nil {
-			return nil, errors.Join(errors.New("failed to transcode image to PNG"), err)
-		}
-		imageData = &outBuf
-	}
-
-	return imageData, nil
+func (p *TiffParser) prepareData(file io.Reader) (io.Reader, *ImageMetadata, error) {
+	return prepareImageWithMetadata(p.ocrProvider, "image/tiff", file, tiff.Decode)
 }
 
 func (p *TiffParser) Parse(ctx context.Context, file io.Reader, path string) Result {
-	imageData, err := p.prepareData(file)
+	data, frames, err := readImageFrames(file, decodeTIFFFrames)
+	if err != nil {
+		return &ImageParserResult{Err: err, FullPath: path}
+	}
+	if frames != nil {
+		return parseImageFrames(ctx, p.ocrProvider, p.config, frames, readImageMetadata("image/tiff", data), path)
+	}
+
+	imageData, metadata, err := p.prepareData(bytes.NewReader(data))
 	if err != nil {
 		return &ImageParserResult{Err: errors.Join(errors.New("failed to prepare image data"), err), FullPath: path}
 	}
@@ -59,15 +56,26 @@ func (p *TiffParser) Parse(ctx context.Context, file io.Reader, path string) Res
 		return &ImageParserResult{Err: errors.Join(errors.New("errors while running OCR"), err), FullPath: path}
 	}
 
-	return &ImageParserResult{Text: text, FullPath: path}
+	return &ImageParserResult{Text: text, Metadata: metadata, FullPath: path}
 }
 
 func (p *TiffParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
-	return &ImageStreamResultIterator{
-		path:             path,
-		file:             file,
-		imagePreparation: p.prepareData,
-		ocrProvider:      p.ocrProvider,
-		baseContext:      ctx,
+	return &ImageFramesStreamResultIterator{
+		path:        path,
+		mimeType:    "image/tiff",
+		ocrProvider: p.ocrProvider,
+		config:      p.config,
+		file:        file,
+		decode:      decodeTIFFFrames,
+		single: func(file io.Reader) StreamResultIterator {
+			return &ImageStreamResultIterator{
+				path:             path,
+				file:             file,
+				imagePreparation: p.prepareData,
+				ocrProvider:      p.ocrProvider,
+				baseContext:      ctx,
+			}
+		},
+		baseContext: ctx,
 	}
 }
@@ -0,0 +1,353 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Parses `winmail.dat` files (Transport Neutral Encapsulation Format) attached by Outlook to the emails.
+// Attached Outlook messages are parsed recursively, other attachments with inner parser. Pass nil inner parser to ignore them.
+type TNEFParser struct {
+	innerParser Parser
+}
+
+func NewTNEFParser(innerParser Parser) *TNEFParser {
+	return &TNEFParser{
+		innerParser: innerParser,
+	}
+}
+
+func (p *TNEFParser) SupportedMimeTypes() []string {
+	return []string{"application/ms-tnef", "application/vnd.ms-tnef"}
+}
+
+func (p *TNEFParser) Parse(ctx context.Context, file io.Reader, path string) Result {
+	message, err := readTNEF(file)
+	if err != nil {
+		return &EMLParserResult{Err: err, FullPath: path}
+	}
+	return parseOutlookMessage(ctx, p.innerParser, message, path)
+}
+
+func (p *TNEFParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
+	return newOutlookStreamResultIterator(p.innerParser, path, func() (*outlookMessage, error) {
+		return readTNEF(file)
+	})
+}
+
+var tnefSignature = []byte{0x78, 0x9F, 0x3E, 0x22}
+
+// TNEF attributes
+const (
+	tnefOEMCodepage      = 0x00069007
+	tnefFrom             = 0x00008000
+	tnefSubject          = 0x00018004
+	tnefDateSent         = 0x00038005
+	tnefDateReceived     = 0x00038006
+	tnefBody             = 0x0002800C
+	tnefMAPIProperties   = 0x00069003
+	tnefRecipientTable   = 0x00069004
+	tnefAttachRendering  = 0x00069002
+	tnefAttachData       = 0x0006800F
+	tnefAttachTitle      = 0x00018010
+	tnefAttachProperties = 0x00069005
+
+	tnefLevelMessage    = 1
+	tnefLevelAttachment = 2
+)
+
+func readTNEF(file io.Reader) (*outlookMessage, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to read data to the bytes buffer"), err)
+	}
+	message, err := decodeTNEF(data)
+	if err != nil {
+		return nil, errors.Join(ErrBadFile, err)
+	}
+	return message, nil
+}
+
+type tnefAttachment struct {
+	title      string
+	data       []byte
+	properties mapiProperties
+}
+
+func decodeTNEF(data []byte) (*outlookMessage, error) {
+	if !bytes.HasPrefix(data, tnefSignature) || len(data) < 6 {
+		return nil, errors.New("not a TNEF stream")
+	}
+	data = data[6:] // Signature and legacy key
+
+	message := &outlookMessage{properties: make(mapiProperties)}
+	codepage := 1252
+	var attachments []*tnefAttachment
+	for len(data) != 0 {
+		if len(data) < 9 {
+			return nil, errors.New("truncated TNEF attribute")
+		}
+		level := data[0]
+		attribute := binary.LittleEndian.Uint32(data[1:])
+		length := int(binary.LittleEndian.Uint32(data[5:]))
+		if length < 0 || 9+length+2 > len(data) {
+			return nil, fmt.Errorf("TNEF attribute 0x%08X is out of the stream", attribute)
+		}
+		value := data[9 : 9+length]
+		data = data[9+length+2:] // Value and checksum
+
+		if level == tnefLevelAttachment {
+			if attribute == tnefAttachRendering || len(attachments) == 0 {
+				attachments = append(attachments, &tnefAttachment{})
+			}
+			attachment := attachments[len(attachments)-1]
+			switch attribute {
+			case tnefAttachTitle:
+				attachment.title = tnefString(value, codepage)
+			case tnefAttachData:
+				attachment.data = value
+			case tnefAttachProperties:
+				properties, _, err := decodeTNEFProperties(value)
+				if err != nil {
+					return nil, errors.Join(errors.New("failed to read attachment properties"), err)
+				}
+				attachment.properties = properties
+			}
+			continue
+		}
+		if level != tnefLevelMessage {
+			continue
+		}
+
+		switch attribute {
+		case tnefOEMCodepage:
+			if len(value) >= 4 {
+				codepage = int(binary.LittleEndian.Uint32(value))
+			}
+		case tnefFrom:
+			message.from = tnefSender(value, codepage)
+		case tnefSubject:
+			message.subject = tnefString(value, codepage)
+		case tnefBody:
+			message.body = tnefString(value, codepage)
+		case tnefDateSent:
+			message.sent = tnefDate(value)
+		case tnefDateReceived:
+			message.received = tnefDate(value)
+		case tnefMAPIProperties:
+			properties, _, err := decodeTNEFProperties(value)
+			if err != nil {
+				return nil, errors.Join(errors.New("failed to read message properties"), err)
+			}
+			for id, property := range properties {
+				message.properties[id] = property
+			}
+		case tnefRecipientTable:
+			if len(value) < 4 {
+				continue
+			}
+			rows := int(binary.LittleEndian.Uint32(value))
+			value = value[4:]
+			for range rows {
+				if len(value) == 0 {
+					break
+				}
+				recipient, rest, err := decodeTNEFProperties(value)
+				if err != nil {
+					return nil, errors.Join(errors.New("failed to read recipients"), err)
+				}
+				message.recipients = append(message.recipients, recipient)
+				value = rest
+			}
+		}
+	}
+	if _, ok := message.properties[mapiMessageCodepage]; !ok {
+		message.properties[mapiMessageCodepage] = mapiProperty{kind: mapiTypeLong, data: binary.LittleEndian.AppendUint32(nil, uint32(codepage))}
+	}
+
+	for index, attachment := range attachments {
+		properties := attachment.properties
+		if properties == nil {
+			properties = make(mapiProperties)
+		}
+		if _, ok := properties[mapiAttachFilename]; !ok && attachment.title != "" {
+			properties[mapiAttachFilename] = mapiProperty{kind: mapiTypeUnicode, data: encodeUTF16LE(attachment.title)}
+		}
+		result := outlookAttachment{name: outlookAttachmentName(properties, index), data: attachment.data}
+
+		// Attached messages are stored as TNEF stream after the interface identifier
+		if object, ok := properties[mapiAttachData]; ok && object.kind == mapiTypeObject && len(object.data) > 16 {
+			embedded, err := decodeTNEF(object.data[16:])
+			if err == nil {
+				result.message = embedded
+				if !strings.Contains(result.name, ".") {
+					result.name += ".msg"
+				}
+			}
+		} else if len(result.data) == 0 {
+			result.data = properties.binary(mapiAttachData)
+		}
+		if result.message == nil && len(result.data) == 0 {
+			continue
+		}
+		message.attachments = append(message.attachments, result)
+	}
+	return message, nil
+}
+
+func tnefString(value []byte, codepage int) string {
+	return strings.TrimRight(decodeCodepage(value, codepage), "\x00")
+}
+
+// Dates are stored as year, month, day, hour, minute, second and day of week
+func tnefDate(value []byte) time.Time {
+	if len(value) < 12 {
+		return time.Time{}
+	}
+	field := func(index int) int {
+		return int(binary.LittleEndian.Uint16(value[index*2:]))
+	}
+	return time.Date(field(0), time.Month(field(1)), field(2), field(3), field(4), field(5), 0, time.UTC)
+}
+
+// Reads sender name and address from the TRP structure
+func tnefSender(value []byte, codepage int) string {
+	if len(value) < 8 {
+		return ""
+	}
+	nameLength := int(binary.LittleEndian.Uint16(value[4:]))
+	addressLength := int(binary.LittleEndian.Uint16(value[6:]))
+	value = value[8:]
+	if nameLength+addressLength > len(value) {
+		return ""
+	}
+	name := tnefString(value[:nameLength], codepage)
+	address := tnefString(value[nameLength:nameLength+addressLength], codepage)
+	if _, smtp, ok := strings.Cut(address, ":"); ok {
+		address = smtp
+	}
+	return outlookAddress(name, address, "")
+}
+
+// Size of the fixed length MAPI property values in the TNEF
+func tnefFixedSize(kind uint16) int {
+	switch kind {
+	case 0x0001, mapiTypeShort, mapiTypeLong, 0x0004, 0x000A, mapiTypeBoolean:
+		return 4
+	case 0x0005, 0x0006, 0x0007, 0x0014, mapiTypeTime:
+		return 8
+	case 0x0048:
+		return 16
+	}
+	return -1
+}
+
+// Decodes list of MAPI properties. Named properties are skipped. Only first value of the multi value properties is kept.
+func decodeTNEFProperties(data []byte) (mapiProperties, []byte, error) {
+	if len(data) < 4 {
+		return nil, nil, errors.New("truncated property list")
+	}
+	count := int(binary.LittleEndian.Uint32(data))
+	data = data[4:]
+
+	read := func(size int) ([]byte, error) {
+		if size < 0 || size > len(data) {
+			return nil, errors.New("truncated property value")
+		}
+		value := data[:size]
+		data = data[size:]
+		return value, nil
+	}
+	readUint32 := func() (int, error) {
+		value, err := read(4)
+		if err != nil {
+			return 0, err
+		}
+		return int(binary.LittleEndian.Uint32(value)), nil
+	}
+
+	// Count comes from the file, so the map is not pre-sized with it
+	properties := make(mapiProperties, min(count, len(data)/4))
+	for range count {
+		header, err := read(4)
+		if err != nil {
+			return nil, nil, err
+		}
+		kind := binary.LittleEndian.Uint16(header)
+		id := binary.LittleEndian.Uint16(header[2:])
+
+		named := id >= 0x8000
+		if named {
+			if _, err := read(16); err != nil { // Property set GUID
+				return nil, nil, err
+			}
+			nameKind, err := readUint32()
+			if err != nil {
+				return nil, nil, err
+			}
+			if nameKind == 0 {
+				_, err = read(4)
+			} else {
+				var nameLength int
+				if nameLength, err = readUint32(); err == nil {
+					_, err = read((nameLength + 3) &^ 3)
+				}
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		baseKind := kind &^ 0x3000
+		variable := baseKind == mapiTypeString8 || baseKind == mapiTypeUnicode || baseKind == mapiTypeBinary || baseKind == mapiTypeObject
+		values := 1
+		if variable || kind&0x1000 != 0 {
+			if values, err = readUint32(); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		var first []byte
+		for index := range values {
+			var value []byte
+			if variable {
+				length, err := readUint32()
+				if err != nil {
+					return nil, nil, err
+				}
+				if value, err = read(length); err != nil {
+					return nil, nil, err
+				}
+				if _, err = read((4 - length%4) % 4); err != nil {
+					return nil, nil, err
+				}
+			} else {
+				size := tnefFixedSize(baseKind)
+				if size < 0 {
+					return nil, nil, fmt.Errorf("unknown property type 0x%04X", kind)
+				}
+				if value, err = read(size); err != nil {
+					return nil, nil, err
+				}
+			}
+			if index == 0 {
+				first = value
+			}
+		}
+
+		if !named {
+			properties[id] = mapiProperty{kind: baseKind, data: first}
+		}
+	}
+	return properties, data, nil
+}
+
+func tnefMimeDetector(data []byte) bool {
+	return bytes.HasPrefix(data, tnefSignature)
+}
@@ -4,52 +4,49 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 
-	"image/png"
-
 	"github.com/opengs/file2llm/ocr"
 	"golang.org/x/image/webp"
 )
 
-// Parses `image/webp` files
+// Parses `image/webp` files. Every frame of the animation is recognized, near identical frames are skipped
 type WebPParser struct {
 	ocrProvider ocr.Provider
+	config      imageConfig
 }
 
-func NewWebPParser(ocrProvider ocr.Provider) *WebPParser {
+func NewWebPParser(ocrProvider ocr.Provider, options ...ImageOption) *WebPParser {
 	return &WebPParser{
 		ocrProvider: ocrProvider,
+		config:      newImageConfig(options),
 	}
 }
 
+// Configuration that changes the result. Used in the cache keys.
+func (p *WebPParser) CacheFingerprint() string {
+	return fmt.Sprintf("ocr=%s %+v", cacheFingerprint(p.ocrProvider), p.config)
+}
+
 func (p *WebPParser) SupportedMimeTypes() []string {
 	return []string{"image/webp"}
 }
 
-func (p *WebPParser) prepareData(file io.Reader) (io.Reader, error) {
-	var imageData io.Reader
-
-	if p.ocrProvider.IsMimeTypeSupported("image/webp") {
-		imageData = file
-	} else {
-		img, err := webp.Decode(file)
-		if err != nil {
-			return nil, errors.Join(ErrBadFile, errors.New("failed to decode webp image for transcoding"), err)
-		}
-
-		var outBuf bytes.Buffer
-		if err := png.Encode(&outBuf, img); err != nil {
-			return nil, errors.Join(errors.New("failed to transcode image to PNG"), err)
-		}
-		imageData = &outBuf
-	}
-
-	return imageData, nil
+func (p *WebPParser) prepareData(file io.Reader) (io.Reader, *ImageMetadata, error) {
+	return prepareImageWithMetadata(p.ocrProvider, "image/webp", file, webp.Decode)
 }
 
 func (p *WebPParser) Parse(ctx context.Context, file io.Reader, path string) Result {
-	imageData, err := p.prepareData(file)
+	data, frames, err := readImageFrames(file, decodeWebPFrames)
+	if err != nil {
+		return &ImageParserResult{Err: err, FullPath: path}
+	}
+	if frames != nil {
+		return parseImageFrames(ctx, p.ocrProvider, p.config, frames, readImageMetadata("image/webp", data), path)
+	}
+
+	imageData, metadata, err := p.prepareData(bytes.NewReader(data))
 	if err != nil {
 		return &ImageParserResult{Err: errors.Join(errors.New("failed to prepare image data"), err), FullPath: path}
 	}
@@ -59,15 +56,26 @@ func (p *WebPParser) Parse(ctx context.Context, file io.Reader, path string) Res
 		return &ImageParserResult{Err: errors.Join(errors.New("errors while running OCR"), err), FullPath: path}
 	}
 
-	return &ImageParserResult{Text: text, FullPath: path}
+	return &ImageParserResult{Text: text, Metadata: metadata, FullPath: path}
 }
 
 func (p *WebPParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
-	return &ImageStreamResultIterator{
-		path:             path,
-		file:             file,
-		imagePreparation: p.prepareData,
-		ocrProvider:      p.ocrProvider,
-		baseContext:      ctx,
+	return &ImageFramesStreamResultIterator{
+		path:        path,
+		mimeType:    "image/webp",
+		ocrProvider: p.ocrProvider,
+		config:      p.config,
+		file:        file,
+		decode:      decodeWebPFrames,
+		single: func(file io.Reader) StreamResultIterator {
+			return &ImageStreamResultIterator{
+				path:             path,
+				file:             file,
+				imagePreparation: p.prepareData,
+				ocrProvider:      p.ocrProvider,
+				baseContext:      ctx,
+			}
+		},
+		baseContext: ctx,
 	}
 }
@@ -0,0 +1,547 @@
+package parser
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parses `application/vnd.openxmlformats-officedocument.spreadsheetml.sheet` files (.xlsx)
+type XLSXParser struct {
+	config spreadsheetConfig
+}
+
+func NewXLSXParser(options ...SpreadsheetOption) *XLSXParser {
+	parser := &XLSXParser{
+		config: defaultSpreadsheetConfig(),
+	}
+
+	for _, option := range options {
+		option(&parser.config)
+	}
+
+	return parser
+}
+
+// Configuration that changes the result. Used in the cache keys.
+func (p *XLSXParser) CacheFingerprint() string {
+	return fmt.Sprintf("%+v", p.config)
+}
+
+func (p *XLSXParser) SupportedMimeTypes() []string {
+	return []string{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"}
+}
+
+func (p *XLSXParser) Parse(ctx context.Context, file io.Reader, path string) Result {
+	sheets, err := p.openWorkbook(ctx, file, path)
+	if err != nil {
+		return &SpreadsheetParserResult{Err: err, FullPath: path}
+	}
+
+	result := &SpreadsheetParserResult{FullPath: path}
+	for _, source := range sheets {
+		sheet, err := readSpreadsheetSheet(source, p.config.maxRowsPerSheet)
+		if err != nil {
+			result.Err = errors.Join(ErrBadFile, fmt.Errorf("failed to read sheet %s", source.name), err)
+			return result
+		}
+		result.Sheets = append(result.Sheets, sheet)
+	}
+
+	return result
+}
+
+func (p *XLSXParser) ParseStream(ctx context.Context, file io.Reader, path string) StreamResultIterator {
+	return &SpreadsheetStreamResultIterator{
+		path:    path,
+		maxRows: p.config.maxRowsPerSheet,
+		openBook: func() ([]spreadsheetSheetSource, error) {
+			return p.openWorkbook(ctx, file, path)
+		},
+	}
+}
+
+func (p *XLSXParser) openWorkbook(ctx context.Context, file io.Reader, path string) ([]spreadsheetSheetSource, error) {
+	pkg, err := openOOXMLPackage(ctx, file, path)
+	if err != nil {
+		return nil, err
+	}
+
+	rootRels, err := pkg.relationships("")
+	if err != nil {
+		return nil, errors.Join(ErrBadFile, err)
+	}
+	workbookPart := "xl/workbook.xml"
+	if rel, ok := ooxmlRelationshipByType(rootRels, "/officeDocument"); ok {
+		workbookPart = rel.Target
+	}
+	workbookRels, err := pkg.relationships(workbookPart)
+	if err != nil {
+		return nil, errors.Join(ErrBadFile, err)
+	}
+
+	var date1904 string
+	var sheets []xml.StartElement
+	if err := p.readWorkbook(pkg, workbookPart, &date1904, &sheets); err != nil {
+		return nil, errors.Join(ErrBadFile, err)
+	}
+
+	book := &xlsxWorkbook{
+		date1904: date1904 == "1" || date1904 == "true",
+	}
+	if rel, ok := ooxmlRelationshipByType(workbookRels, "/sharedStrings"); ok {
+		if book.sharedStrings, err = p.readSharedStrings(pkg, rel.Target); err != nil {
+			return nil, errors.Join(ErrBadFile, err)
+		}
+	}
+	if rel, ok := ooxmlRelationshipByType(workbookRels, "/styles"); ok {
+		if book.cellFormats, err = p.readCellFormats(pkg, rel.Target); err != nil {
+			return nil, errors.Join(ErrBadFile, err)
+		}
+	}
+
+	sources := make([]spreadsheetSheetSource, 0, len(sheets))
+	for _, sheet := range sheets {
+		var relID string
+		for _, attr := range sheet.Attr {
+			if attr.Name.Local == "id" && attr.Name.Space != "" {
+				relID = attr.Value
+			}
+		}
+		rel, ok := workbookRels[relID]
+		if !ok {
+			continue
+		}
+
+		sheetPart := rel.Target
+		sources = append(sources, spreadsheetSheetSource{
+			name: xmlAttr(sheet, "name"),
+			open: func() (spreadsheetRowReader, error) {
+				r, err := pkg.open(sheetPart)
+				if err != nil {
+					return nil, err
+				}
+				return &xlsxRowReader{book: book, reader: r, decoder: xml.NewDecoder(r)}, nil
+			},
+		})
+	}
+
+	return sources, nil
+}
+
+// Reads workbook properties and list of sheets. Sheet elements are kept raw because relationship ID is namespaced attribute.
+func (p *XLSXParser) readWorkbook(pkg *ooxmlPackage, partName string, date1904 *string, sheets *[]xml.StartElement) error {
+	r, err := pkg.open(partName)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.Join(errors.New("failed to decode workbook"), err)
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch element.Name.Local {
+		case "workbookPr":
+			*date1904 = xmlAttr(element, "date1904")
+		case "sheet":
+			*sheets = append(*sheets, element.Copy())
+		}
+	}
+}
+
+func (p *XLSXParser) readSharedStrings(pkg *ooxmlPackage, partName string) ([]string, error) {
+	r, err := pkg.open(partName)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var sharedStrings []string
+	var item *strings.Builder
+	var inText bool
+	// Phonetic hints duplicate the text in asian languages
+	var inPhonetic bool
+
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return sharedStrings, nil
+		} else if err != nil {
+			return nil, errors.Join(errors.New("failed to decode shared strings"), err)
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "si":
+				item = &strings.Builder{}
+			case "t":
+				inText = true
+			case "rPh":
+				inPhonetic = true
+			}
+		case xml.CharData:
+			if item != nil && inText && !inPhonetic {
+				item.Write(element)
+			}
+		case xml.EndElement:
+			switch element.Name.Local {
+			case "si":
+				sharedStrings = append(sharedStrings, item.String())
+				item = nil
+			case "t":
+				inText = false
+			case "rPh":
+				inPhonetic = false
+			}
+		}
+	}
+}
+
+// Returns number format codes indexed by cell style index
+func (p *XLSXParser) readCellFormats(pkg *ooxmlPackage, partName string) ([]string, error) {
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := pkg.readXML(partName, &styles); err != nil {
+		return nil, err
+	}
+
+	customFormats := make(map[int]string, len(styles.NumFmts))
+	for _, numFmt := range styles.NumFmts {
+		customFormats[numFmt.ID] = numFmt.Code
+	}
+
+	formats := make([]string, len(styles.CellXfs))
+	for i, xf := range styles.CellXfs {
+		if code, ok := customFormats[xf.NumFmtID]; ok {
+			formats[i] = code
+		} else {
+			formats[i] = xlsxBuiltinFormats[xf.NumFmtID]
+		}
+	}
+
+	return formats, nil
+}
+
+// Built in number formats that are not stored in the styles part
+var xlsxBuiltinFormats = map[int]string{
+	0: "General", 1: "0", 2: "0.00", 3: "#,##0", 4: "#,##0.00",
+	9: "0%", 10: "0.00%", 11: "0.00E+00", 12: "General", 13: "General",
+	14: "yyyy-mm-dd", 15: "d-mmm-yy", 16: "d-mmm", 17: "mmm-yy",
+	18: "h:mm AM/PM", 19: "h:mm:ss AM/PM", 20: "h:mm", 21: "h:mm:ss", 22: "yyyy-mm-dd h:mm",
+	37: "#,##0", 38: "#,##0", 39: "#,##0.00", 40: "#,##0.00",
+	45: "mm:ss", 46: "[h]:mm:ss", 47: "mm:ss.0", 48: "0.0E+0", 49: "@",
+}
+
+type xlsxWorkbook struct {
+	date1904      bool
+	sharedStrings []string
+	cellFormats   []string
+}
+
+// Formats numeric cell value according to the number format of the cell style
+func (b *xlsxWorkbook) formatNumber(value string, styleIndex int) string {
+	if styleIndex < 0 || styleIndex >= len(b.cellFormats) {
+		return value
+	}
+	code := b.cellFormats[styleIndex]
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || code == "" || code == "General" || code == "@" {
+		return value
+	}
+
+	// Only first section of the format applies to the positive numbers and is enough to get the kind of the format
+	section := xlsxFormatSection(code)
+	lower := strings.ToLower(section)
+
+	hasDate := strings.ContainsAny(lower, "yd")
+	hasTime := strings.ContainsAny(lower, "hs")
+	if !hasDate && !hasTime && strings.Contains(lower, "m") {
+		hasDate = true
+	}
+	if hasDate || hasTime {
+		base := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+		if b.date1904 {
+			base = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+		}
+		seconds := math.Round(number * 24 * 60 * 60)
+		date := base.Add(time.Duration(seconds) * time.Second)
+
+		switch {
+		case hasDate && hasTime:
+			return date.Format("2006-01-02 15:04:05")
+		case hasDate:
+			return date.Format("2006-01-02")
+		default:
+			return date.Format("15:04:05")
+		}
+	}
+
+	var formatted string
+	switch {
+	case strings.Contains(lower, "e"):
+		formatted = strconv.FormatFloat(number, 'E', xlsxFormatDecimals(section), 64)
+	case strings.Contains(section, "%"):
+		formatted = strconv.FormatFloat(number*100, 'f', xlsxFormatDecimals(section), 64) + "%"
+	default:
+		formatted = strconv.FormatFloat(number, 'f', xlsxFormatDecimals(section), 64)
+	}
+
+	prefix, suffix := xlsxFormatAffixes(code)
+	return prefix + formatted + suffix
+}
+
+// Returns literal text (for example currency) placed before and after the number in the first section of the format
+func xlsxFormatAffixes(code string) (string, string) {
+	var prefix, suffix strings.Builder
+	seenPlaceholder := false
+	literal := func(text string) {
+		if seenPlaceholder {
+			suffix.WriteString(text)
+		} else {
+			prefix.WriteString(text)
+		}
+	}
+
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		switch c {
+		case ';':
+			return strings.TrimSpace(prefix.String()), strings.TrimRight(suffix.String(), " ")
+		case '0', '#', '?':
+			seenPlaceholder = true
+		case '"':
+			end := strings.IndexByte(code[i+1:], '"')
+			if end < 0 {
+				end = len(code) - i - 1
+			}
+			literal(code[i+1 : i+1+end])
+			i += end + 1
+		case '\\':
+			if i+1 < len(code) {
+				literal(code[i+1 : i+2])
+				i++
+			}
+		case '_':
+			literal(" ")
+			i++
+		case '*':
+			i++
+		case '[':
+			end := strings.IndexByte(code[i:], ']')
+			if end < 0 {
+				end = len(code) - i
+			}
+			// Locale currency looks like [$€-407]
+			if bracket := code[i+1 : i+end]; strings.HasPrefix(bracket, "$") {
+				symbol, _, _ := strings.Cut(bracket[1:], "-")
+				literal(symbol)
+			}
+			i += end
+		case '$', '-', '+', '(', ')', ':', '^', '\'', '{', '}', '<', '>', '=', ' ':
+			literal(string(c))
+		}
+	}
+
+	return strings.TrimSpace(prefix.String()), strings.TrimRight(suffix.String(), " ")
+}
+
+// Returns first section of the number format without quoted literals, escaped characters and bracketed colors or conditions
+func xlsxFormatSection(code string) string {
+	var section strings.Builder
+	inQuotes := false
+	inBrackets := false
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		switch {
+		case inQuotes:
+			inQuotes = c != '"'
+		case inBrackets:
+			inBrackets = c != ']'
+			// Elapsed time like [h] is still a time format
+			if c == 'h' || c == 'H' || c == 's' || c == 'S' {
+				section.WriteByte(c)
+			}
+		case c == '"':
+			inQuotes = true
+		case c == '[':
+			inBrackets = true
+		case c == '\\' || c == '_' || c == '*':
+			i++
+		case c == ';':
+			return section.String()
+		default:
+			section.WriteByte(c)
+		}
+	}
+	return section.String()
+}
+
+func xlsxFormatDecimals(section string) int {
+	dot := strings.IndexByte(section, '.')
+	if dot < 0 {
+		return 0
+	}
+	decimals := 0
+	for _, c := range section[dot+1:] {
+		if c != '0' && c != '#' && c != '?' {
+			break
+		}
+		decimals += 1
+	}
+	return decimals
+}
+
+// Streams rows of the worksheet part
+type xlsxRowReader struct {
+	book    *xlsxWorkbook
+	reader  io.ReadCloser
+	decoder *xml.Decoder
+	lastRow int
+}
+
+func (r *xlsxRowReader) next() (int, []string, error) {
+	var cells []string
+	var rowNumber int
+	inRow := false
+
+	var cellColumn int
+	var cellType string
+	var cellStyle int
+	var cellValue strings.Builder
+	var inValue bool
+
+	for {
+		token, err := r.decoder.Token()
+		if err == io.EOF {
+			return 0, nil, io.EOF
+		} else if err != nil {
+			return 0, nil, errors.Join(errors.New("failed to decode worksheet"), err)
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "row":
+				inRow = true
+				rowNumber, err = strconv.Atoi(xmlAttr(element, "r"))
+				if err != nil {
+					rowNumber = r.lastRow + 1
+				}
+			case "c":
+				cellColumn = len(cells)
+				if reference := xmlAttr(element, "r"); reference != "" {
+					column, ok := xlsxColumnIndex(reference)
+					if !ok {
+						// Cells with broken references and columns beyond XFD are skipped
+						column = -1
+					}
+					cellColumn = column
+				}
+				cellType = xmlAttr(element, "t")
+				cellStyle, err = strconv.Atoi(xmlAttr(element, "s"))
+				if err != nil {
+					cellStyle = -1
+				}
+				cellValue.Reset()
+			case "v", "t":
+				// Inline strings store text in `is>t`, all other cells in `v`. Formula text in `f` is ignored, cached value is used instead.
+				inValue = true
+			}
+		case xml.CharData:
+			if inValue {
+				cellValue.Write(element)
+			}
+		case xml.EndElement:
+			switch element.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				if !inRow || cellColumn < 0 || cellColumn >= xlsxMaxColumns {
+					continue
+				}
+				for len(cells) <= cellColumn {
+					cells = append(cells, "")
+				}
+				cells[cellColumn] = r.book.cellText(cellType, cellStyle, cellValue.String())
+			case "row":
+				r.lastRow = rowNumber
+				return rowNumber, cells, nil
+			}
+		}
+	}
+}
+
+func (r *xlsxRowReader) close() {
+	r.reader.Close()
+}
+
+func (b *xlsxWorkbook) cellText(cellType string, cellStyle int, value string) string {
+	switch cellType {
+	case "s":
+		index, err := strconv.Atoi(value)
+		if err != nil || index < 0 || index >= len(b.sharedStrings) {
+			return ""
+		}
+		return b.sharedStrings[index]
+	case "b":
+		if value == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	case "str", "inlineStr", "e", "d":
+		return value
+	default:
+		return b.formatNumber(value, cellStyle)
+	}
+}
+
+// Number of columns in the sheet, last column is XFD
+const xlsxMaxColumns = 16384
+
+// Converts cell reference like `AB12` to the zero based column index. Returns false for columns beyond XFD.
+func xlsxColumnIndex(reference string) (int, bool) {
+	column := 0
+	letters := 0
+	for _, c := range reference {
+		if c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		column = column*26 + int(c-'A'+1)
+		letters += 1
+		if column > xlsxMaxColumns {
+			return 0, false
+		}
+	}
+	if letters == 0 {
+		return 0, false
+	}
+	return column - 1, true
+}
@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	testdata "github.com/opengs/file2llm/test_data"
+)
+
+func TestXLSX(t *testing.T) {
+	xlsxParser := NewXLSXParser()
+	result := xlsxParser.Parse(context.Background(), bytes.NewReader(testdata.XLSX), "book.xlsx")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+
+	sheets := result.(*SpreadsheetParserResult).Sheets
+	if len(sheets) != 2 || sheets[0].Name != "Budget" || sheets[1].Name != "Plan" {
+		t.Fatalf("unexpected sheets: %+v", sheets)
+	}
+
+	resultString := result.String()
+	expected := []string{
+		"Columns: Department | Amount | Date | Share",
+		"Row 2: Department: Marketing | Amount: 1250.50 EUR | Date: 2024-01-01 | Share: 25.00%",
+		"Row 3: Department: Sales | Amount: 3751.50 EUR | Share: 75.00%",
+		"Row 5: Department: Total | Amount: 5002.00 EUR",
+		"Row 2: Task: Hire analyst | Done: TRUE",
+		"Row 3: Task: Close books | Done: FALSE",
+	}
+	for _, line := range expected {
+		if !strings.Contains(resultString, line) {
+			t.Errorf("missing %q in:\n%s", line, resultString)
+		}
+	}
+}
+
+func TestXLSXMaxRows(t *testing.T) {
+	xlsxParser := NewXLSXParser(WithMaxRowsPerSheet(1))
+	result := xlsxParser.Parse(context.Background(), bytes.NewReader(testdata.XLSX), "book.xlsx")
+	if result.Error() != nil {
+		t.Fatal(result.Error())
+	}
+
+	sheets := result.(*SpreadsheetParserResult).Sheets
+	if len(sheets[0].Rows) != 1 || !sheets[0].Truncated {
+		t.Errorf("unexpected first sheet: %+v", sheets[0])
+	}
+	if !strings.Contains(result.String(), "------ Sheet truncated after 1 rows ------") {
+		t.Error(result.String())
+	}
+}
+
+func TestXLSXStream(t *testing.T) {
+	xlsxParser := NewXLSXParser()
+
+	hasNewStage := false
+	hasCompletedStage := false
+	var lastResult StreamResult
+
+	var resultString string
+	parseProgress := xlsxParser.ParseStream(context.Background(), bytes.NewReader(testdata.XLSX), "book.xlsx")
+	defer parseProgress.Close()
+	for parseProgress.Next(t.Context()) {
+		progress := parseProgress.Current()
+		resultString += progress.String()
+		hasNewStage = hasNewStage || (progress.Stage() == ProgressNew)
+		hasCompletedStage = hasCompletedStage || (progress.Stage() == ProgressCompleted)
+		lastResult = progress
+	}
+	if !hasNewStage || !hasCompletedStage {
+		t.Fail()
+	}
+	if lastResult.Error() != nil {
+		t.Fatal(lastResult.Error())
+	}
+
+	parseResult := xlsxParser.Parse(context.Background(), bytes.NewReader(testdata.XLSX), "book.xlsx")
+	if resultString != parseResult.String() {
+		t.Errorf("stream and parse results differ:\n%s\n%s", resultString, parseResult.String())
+	}
+}
+
+func TestXLSXColumnIndex(t *testing.T) {
+	for reference, expected := range map[string]int{"A1": 0, "z7": 25, "AB12": 27, "XFD3": 16383} {
+		if column, ok := xlsxColumnIndex(reference); !ok || column != expected {
+			t.Errorf("column of %s: got %d, expected %d", reference, column, expected)
+		}
+	}
+	for _, reference := range []string{"XFE1", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAA1", "12"} {
+		if _, ok := xlsxColumnIndex(reference); ok {
+			t.Errorf("reference %s must be rejected", reference)
+		}
+	}
+}
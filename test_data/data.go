@@ -19,23 +19,89 @@ var ODT []byte
 //go:embed file.pdf
 var PDF []byte
 
+//go:embed file_encrypted.pdf
+var PDFEncrypted []byte
+
+//go:embed file_extras.pdf
+var PDFExtras []byte
+
+//go:embed file.pptx
+var PPTX []byte
+
+//go:embed file.xlsx
+var XLSX []byte
+
+//go:embed file.csv
+var CSV []byte
+
+//go:embed file.rtf
+var RTF []byte
+
+//go:embed file.html
+var HTML []byte
+
+//go:embed file.epub
+var EPUB []byte
+
+//go:embed file.eml
+var EML []byte
+
+//go:embed file_html.eml
+var EMLHTML []byte
+
+//go:embed file_thread.eml
+var EMLThread []byte
+
+//go:embed file.mbox
+var MBOX []byte
+
+//go:embed file_tnef.eml
+var EMLTNEF []byte
+
+//go:embed file.msg
+var MSG []byte
+
+//go:embed winmail.dat
+var TNEF []byte
+
 //go:embed image.png
 var PNG []byte
 
+//go:embed image_metadata.png
+var PNGMetadata []byte
+
 //go:embed image.jpeg
 var JPEG []byte
 
+//go:embed image_metadata.jpeg
+var JPEGMetadata []byte
+
 //go:embed image.bmp
 var BMP []byte
 
 //go:embed image.gif
 var GIF []byte
 
+//go:embed image_animated.gif
+var GIFAnimated []byte
+
 //go:embed image.tiff
 var TIFF []byte
 
+//go:embed image_multipage.tiff
+var TIFFMultipage []byte
+
 //go:embed image.webp
 var WEBP []byte
 
+//go:embed image_metadata.webp
+var WEBPMetadata []byte
+
+//go:embed image_animated.webp
+var WEBPAnimated []byte
+
 //go:embed fs/*
 var FS embed.FS
+
+//go:embed all:maildir
+var Maildir embed.FS